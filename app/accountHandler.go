@@ -1,7 +1,6 @@
 package app
 
 import (
-	"encoding/json"
 	"net/http"
 
 	"github.com/gtaylor314/Banking-MS/dto"
@@ -19,19 +18,20 @@ func (acctHandler AccountHandler) newAccount(w http.ResponseWriter, r *http.Requ
 	// Vars() returns the route variables, if any, in the current request
 	vars := mux.Vars(r)
 	var req dto.NewAccountRequest
-	// we populate the customer_id in our NewAccountRequest
+	// the account_type and amount for the new account are provided via the JSON body - decodeJSONBody() reads the request
+	// body and stores the data in the NewAccountRequest (req), writing the error response itself if the body is unusable
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	// we populate the customer_id in our NewAccountRequest after decoding so that the body cannot override the URL
 	req.CustomerID = vars["customer_id"]
-	// the account_type and amount for the new account are provided via the JSON body - here we create a new decoder which
-	// reads the response body and stores the data in the NewAccountRequest (req)
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		// if there is an error, it must be a bad request
-		writeResponse(w, http.StatusBadRequest, err.Error())
+	// validateRequest() reports every invalid field at once
+	if !validateRequest(w, r, req) {
 		return
 	}
-	resp, appErr := acctHandler.service.NewAccount(req)
+	resp, appErr := acctHandler.service.NewAccount(r.Context(), req)
 	if appErr != nil {
-		writeResponse(w, appErr.Code, appErr.MessageOnly())
+		writeError(w, r, appErr)
 		return
 	}
 	writeResponse(w, http.StatusCreated, resp)
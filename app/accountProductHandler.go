@@ -0,0 +1,80 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/service"
+
+	"github.com/gorilla/mux"
+)
+
+type AccountProductHandler struct {
+	service service.AccountProductService
+	version apiVersion // maps the AccountProductResponse to the shape returned by the API version being served
+}
+
+func (productHandler AccountProductHandler) getAllProducts(w http.ResponseWriter, r *http.Request) {
+	products, appErr := productHandler.service.GetAllProducts(r.Context())
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	resp := make([]interface{}, 0, len(products))
+	for _, product := range products {
+		resp = append(resp, productHandler.version.accountProduct(product))
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func (productHandler AccountProductHandler) getProduct(w http.ResponseWriter, r *http.Request) {
+	product, appErr := productHandler.service.GetProduct(r.Context(), mux.Vars(r)["code"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, productHandler.version.accountProduct(*product))
+}
+
+func (productHandler AccountProductHandler) newProduct(w http.ResponseWriter, r *http.Request) {
+	var req dto.AccountProductRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	product, appErr := productHandler.service.NewProduct(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusCreated, productHandler.version.accountProduct(*product))
+}
+
+func (productHandler AccountProductHandler) updateProduct(w http.ResponseWriter, r *http.Request) {
+	var req dto.AccountProductRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	// the code is populated after decoding so that the body cannot rename the product
+	req.Code = mux.Vars(r)["code"]
+	if !validateRequest(w, r, req) {
+		return
+	}
+	product, appErr := productHandler.service.UpdateProduct(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, productHandler.version.accountProduct(*product))
+}
+
+func (productHandler AccountProductHandler) deleteProduct(w http.ResponseWriter, r *http.Request) {
+	if appErr := productHandler.service.DeleteProduct(r.Context(), mux.Vars(r)["code"]); appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// a deleted product has nothing left to return
+	w.WriteHeader(http.StatusNoContent)
+}
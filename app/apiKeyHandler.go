@@ -0,0 +1,62 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/service"
+
+	"github.com/gorilla/mux"
+)
+
+// APIKeyHandler serves the API key management routes - they are admin operations and an API key can never be granted
+// them (see domain.ValidateScopes())
+type APIKeyHandler struct {
+	service service.APIKeyService
+}
+
+func (keyHandler APIKeyHandler) getAllKeys(w http.ResponseWriter, r *http.Request) {
+	keys, appErr := keyHandler.service.GetAllAPIKeys(r.Context())
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, keys)
+}
+
+func (keyHandler APIKeyHandler) newKey(w http.ResponseWriter, r *http.Request) {
+	var req dto.NewAPIKeyRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	key, appErr := keyHandler.service.NewAPIKey(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// the response is the only place the key is ever shown, so it must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, http.StatusCreated, key)
+}
+
+func (keyHandler APIKeyHandler) revokeKey(w http.ResponseWriter, r *http.Request) {
+	key, appErr := keyHandler.service.RevokeAPIKey(r.Context(), mux.Vars(r)["key_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, key)
+}
+
+func (keyHandler APIKeyHandler) rotateKey(w http.ResponseWriter, r *http.Request) {
+	key, appErr := keyHandler.service.RotateAPIKey(r.Context(), mux.Vars(r)["key_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, http.StatusOK, key)
+}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// serveAPIKey() sends a GET request authorized with an API key to router
+func serveAPIKey(router *mux.Router, path string, key string) int {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("X-API-Key", key)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Code
+}
+
+// TestAPIKeyFlow() should pass when a key created by an admin may call only the routes in its scopes, for any customer,
+// a rotated key replaces the old one at once and a revoked key is refused
+func TestAPIKeyFlow(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+	login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "admin", "password": "passw0rd123"}`)
+	var tokens dto.LoginResponse
+	if err := json.Unmarshal(login.Body.Bytes(), &tokens); login.Code != http.StatusOK || err != nil {
+		t.Fatalf("login received %d %s, want 200", login.Code, login.Body.String())
+	}
+	created := serveAuth(router, http.MethodPost, "/v1/api-keys", tokens.AccessToken, `{"name": "statements", "scopes": ["GetCustomer"]}`)
+	var key dto.APIKeyResponse
+	if err := json.Unmarshal(created.Body.Bytes(), &key); created.Code != http.StatusCreated || err != nil || key.Key == "" {
+		t.Fatalf("creating a key received %d %s, want 201 with the key", created.Code, created.Body.String())
+	}
+
+	// Act - execute test
+	inScope := serveAPIKey(router, "/v1/customers/1002", key.Key)
+	outOfScope := serveAPIKey(router, "/v1/customers", key.Key)
+	manageKeys := serveAPIKey(router, "/v1/api-keys", key.Key)
+	wrongSecret := serveAPIKey(router, "/v1/customers/1002", key.Key+"x")
+	rotated := serveAuth(router, http.MethodPost, "/v1/api-keys/"+key.KeyID+"/rotate", tokens.AccessToken, "")
+	var rotatedKey dto.APIKeyResponse
+	_ = json.Unmarshal(rotated.Body.Bytes(), &rotatedKey)
+	oldAfterRotate := serveAPIKey(router, "/v1/customers/1002", key.Key)
+	newAfterRotate := serveAPIKey(router, "/v1/customers/1002", rotatedKey.Key)
+	revoked := serveAuth(router, http.MethodPost, "/v1/api-keys/"+key.KeyID+"/revoke", tokens.AccessToken, "")
+	afterRevoke := serveAPIKey(router, "/v1/customers/1002", rotatedKey.Key)
+	revokedAgain := serveAuth(router, http.MethodPost, "/v1/api-keys/"+key.KeyID+"/revoke", tokens.AccessToken, "")
+
+	// Assert - test expectations
+	if created.Header().Get("Cache-Control") != "no-store" {
+		t.Error("expected the response holding the key not to be cached")
+	}
+	for name, got := range map[string]struct{ got, want int }{
+		"in scope":     {inScope, http.StatusOK},
+		"out of scope": {outOfScope, http.StatusForbidden},
+		"manage keys":  {manageKeys, http.StatusForbidden},
+		"wrong secret": {wrongSecret, http.StatusForbidden},
+		"rotate":       {rotated.Code, http.StatusOK},
+		"old key":      {oldAfterRotate, http.StatusForbidden},
+		"rotated key":  {newAfterRotate, http.StatusOK},
+		"revoke":       {revoked.Code, http.StatusOK},
+		"revoked key":  {afterRevoke, http.StatusForbidden},
+		"revoke twice": {revokedAgain.Code, http.StatusConflict},
+	} {
+		if got.got != got.want {
+			t.Errorf("%s: received %d, want %d", name, got.got, got.want)
+		}
+	}
+	if rotatedKey.KeyID != key.KeyID || rotatedKey.Key == key.Key {
+		t.Errorf("received key %s, want a new key for key_id %s", rotatedKey.KeyID, key.KeyID)
+	}
+}
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// NextCursorHeader carries the cursor of the next page of customers for /v1, whose response body is a plain list
+const NextCursorHeader = "X-Next-Cursor"
+
+// apiVersion holds the path prefix of an API version and the mappings from the dtos returned by the services to the
+// response shapes of that version - the services are unaware of versions, only the handlers apply the mappings
+type apiVersion struct {
+	prefix string
+	// unpagedCustomers is set for the versions which returned every customer before customers were paged - a search
+	// sending neither a limit nor a cursor still returns every customer there
+	unpagedCustomers bool
+	customerPage     func(http.Header, dto.CustomerPageResponse) interface{}
+	newTransaction   func(dto.NewTransactionResponse) interface{}
+	accountProduct   func(dto.AccountProductResponse) interface{}
+}
+
+// apiV1 returns the dtos as is, apart from a page of customers which is returned as a plain list with the next cursor in
+// the X-Next-Cursor header, and only paged when a limit or cursor is sent - the unversioned (deprecated) routes are
+// aliases of /v1
+var apiV1 = apiVersion{
+	prefix:           "/v1",
+	unpagedCustomers: true,
+	customerPage: func(header http.Header, page dto.CustomerPageResponse) interface{} {
+		if page.NextCursor != "" {
+			header.Set(NextCursorHeader, page.NextCursor)
+		}
+		return page.Customers
+	},
+	newTransaction: func(resp dto.NewTransactionResponse) interface{} { return resp },
+	accountProduct: func(resp dto.AccountProductResponse) interface{} { return resp },
+}
+
+// apiV2 returns money as decimal strings and a page of customers as an object holding the customers and the next cursor
+var apiV2 = apiVersion{
+	prefix:         "/v2",
+	customerPage:   func(header http.Header, page dto.CustomerPageResponse) interface{} { return page },
+	newTransaction: func(resp dto.NewTransactionResponse) interface{} { return resp.ToV2() },
+	accountProduct: func(resp dto.AccountProductResponse) interface{} { return resp.ToV2() },
+}
@@ -1,96 +1,401 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"sort"
+	"strings"
 	"time"
 
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/config"
 	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/openapi"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
 	"github.com/gtaylor314/Banking-MS/service"
+	"github.com/gtaylor314/Banking-MS/tracing"
 
 	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
 )
 
-// environmentVariableCheck() checks that all environment variables have been injected and, if not, exits with an error
-func environmentVariableCheck() {
-	switch {
-	case os.Getenv("SERVER_ADDRESS") == "":
-		log.Fatal("environment variable SERVER_ADDRESS not defined")
-	case os.Getenv("SERVER_PORT") == "":
-		log.Fatal("environment variable SERVER_PORT not defined")
-	case os.Getenv("DB_USER") == "":
-		log.Fatal("environment variable DB_USER not defined")
-	case os.Getenv("DB_PASSWD") == "":
-		log.Fatal("environment variable DB_PASSWD not defined")
-	case os.Getenv("DB_ADDRESS") == "":
-		log.Fatal("environment variable DB_ADDRESS not defined")
-	case os.Getenv("DB_PORT") == "":
-		log.Fatal("environment variable DB_PORT not defined")
-	case os.Getenv("DB_NAME") == "":
-		log.Fatal("environment variable DB_NAME not defined")
+// loadConfig() loads the configuration from the file named by the CONFIG_FILE environment variable (if set) and the
+// environment variable overrides - if the configuration is invalid, every problem is logged at once and we exit
+func loadConfig() *config.Config {
+	cfg, err := config.Load(os.Getenv("CONFIG_FILE"))
+	if err != nil {
+		log.Fatal(err)
 	}
+	return cfg
 }
 
 func Start() {
 
-	environmentVariableCheck()
+	cfg := loadConfig()
+
+	// install the tracer provider and trace context propagator selected by the config (stdout, otlp, or none)
+	shutdownTracing, err := tracing.Setup(context.Background(), cfg.Tracing)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// create db connection
+	db_conn := getDbConnection(cfg.DB)
+	// export the connection pool statistics (sqlx.DB.Stats()) on /metrics
+	metrics.RegisterDBStats(db_conn.DB, cfg.DB.Name)
+
+	// these repositories connect to the MySQL database - the in-memory stubs (e.g. NewCustomerRepositoryStub()) can be
+	// swapped in to test without one
+	repos := repositories{
+		customers:    domain.NewCustomerRepositoryDb(db_conn),
+		accounts:     domain.NewAccountRepositoryDb(db_conn),
+		transactions: domain.NewTransactionRepositoryDb(db_conn),
+		products:     domain.NewAccountProductRepositoryDb(db_conn),
+		users:        domain.NewUserRepositoryDb(db_conn),
+		apiKeys:      domain.NewAPIKeyRepositoryDb(db_conn),
+		audit:        domain.NewAuditRepositoryDb(db_conn),
+		webhooks:     domain.NewWebhookRepositoryDb(db_conn),
+		imports:      domain.NewTransactionImportRepositoryDb(db_conn),
+		rateLimits:   ratelimit.NewMemoryStore(),
+	}
+
+	// the database is always a readiness dependency - the auth server is only one when tokens are verified remotely
+	readinessChecks := []dependencyCheck{{name: "database", check: db_conn.PingContext}}
+	switch cfg.Auth.Mode {
+	case config.AuthModeRemote:
+		remoteAuthRepository := domain.NewAuthRepository(cfg.Auth.ServerURL)
+		repos.auth = remoteAuthRepository
+		readinessChecks = append(readinessChecks, dependencyCheck{name: "auth", check: remoteAuthRepository.Ping})
+	case config.AuthModeLocal:
+		// tokens are issued by POST /auth/login, verified in-process and authorized by the role and route policy
+		policy, err := authPolicy(cfg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		repos.auth = domain.NewPolicyAuthRepository(tokenSigner(cfg), *policy)
+	}
+
+	if repos.auth == nil {
+		logger.Info("auth mode is none - requests will not be authorized")
+	}
+	if err := checkRateLimitRoutes(cfg); err != nil {
+		log.Fatal(err)
+	}
+	router := newRouter(cfg, repos, readinessChecks)
+
+	// the outbox relay publishes the events the repositories write alongside their changes - with it disabled, the
+	// events are left for the standalone relay (cmd/outboxrelay)
+	relayCtx, stopRelay := context.WithCancel(context.Background())
+	closeRelay := func() {}
+	if cfg.Outbox.Enabled {
+		closeRelay = startOutboxRelay(relayCtx, cfg.Outbox, db_conn)
+	}
+	// the webhook worker delivers what the relay's subscriptions publisher queues for the webhook subscriptions
+	if cfg.Webhooks.Enabled {
+		startWebhookWorker(relayCtx, cfg.Webhooks, repos.webhooks)
+	}
+
+	// the gRPC server runs alongside the http server on its own port - it shares the repositories, services and auth
+	var grpcServer *grpc.Server
+	if cfg.Server.GRPCPort != "" {
+		grpcServer = newGRPCServer(cfg, repos)
+		listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", cfg.Server.Address, cfg.Server.GRPCPort))
+		if err != nil {
+			log.Fatal(err)
+		}
+		go func() {
+			// Serve() only returns once the listener fails or the server is stopped
+			if err := grpcServer.Serve(listener); err != nil {
+				log.Fatal(err)
+			}
+		}()
+	}
 
+	// the server address and port are injected via the config file or environment variables (see Makefile)
+	serverAdd := cfg.Server.Address
+	serverPort := cfg.Server.Port
+	// ListenAndServe listens on the TCP network address specified and then calls Serve with the handler to handle
+	// incoming requests - if we were using the DefaultServeMux, we would pass nil for the handler
+	// ListenAndServe always returns an error - before reporting it with log.Fatal, any spans still buffered are flushed
+	err = http.ListenAndServe(fmt.Sprintf("%s:%s", serverAdd, serverPort), router)
+	if grpcServer != nil {
+		grpcServer.Stop()
+	}
+	stopRelay()
+	closeRelay()
+	if shutdownErr := shutdownTracing(context.Background()); shutdownErr != nil {
+		logger.Error("error while flushing spans", zap.Error(shutdownErr))
+	}
+	log.Fatal(err)
+}
+
+// repositories holds the "adapters" the handlers are built on - auth is nil when requests are not authorized (auth mode
+// "none")
+type repositories struct {
+	customers    domain.CustomerRepository
+	accounts     domain.AccountRepository
+	transactions domain.TransactionRepository
+	products     domain.AccountProductRepository
+	users        domain.UserRepository
+	apiKeys      domain.APIKeyRepository
+	audit        domain.AuditRepository
+	webhooks     domain.WebhookRepository
+	imports      domain.TransactionImportRepository
+	auth         domain.AuthRepository
+	// rateLimits holds the token buckets of the rate limiting middleware - it is shared by every router
+	rateLimits ratelimit.Store
+}
+
+// newRouter() wires the handlers, middleware and routes of the service on top of repos - it is separate from Start() so
+// that tests can build the full router around in-memory repositories
+func newRouter(cfg *config.Config, repos repositories, readinessChecks []dependencyCheck) *mux.Router {
 	// using gorilla/mux package - creating a router instance
 	// gorilla/mux is idiomatic - uses expressions native to standard HTTP library (like HandleFunc())
 	router := mux.NewRouter()
+	// unmatched requests receive the same json error response as every other error
+	router.NotFoundHandler = notFoundHandler()
+	router.MethodNotAllowedHandler = methodNotAllowedHandler()
 
-	// create db connection
-	db_conn := getDbConnection()
-	// create customer repository
-	customerRepositoryDb := domain.NewCustomerRepositoryDb(db_conn)
-	// create account repository
-	accountRepositoryDb := domain.NewAccountRepositoryDb(db_conn)
-	// create transaction repository
-	transactionRepositoryDb := domain.NewTransactionRepositoryDb(db_conn)
-
-	// this custHandler uses NewCustomerRepositoryStub() which creates a predefined set of customers to test with
-	// custHandler := CustomerHandlers{service: service.NewCustomerService(domain.NewCustomerRepositoryStub())}
-
-	// this custHandler uses customerRepositoryDb (defined above) which connects to the MySQL database
-	custHandler := CustomerHandlers{service: service.NewCustomerService(customerRepositoryDb)}
-	// this acctHandler uses accountRepositoryDb (defined above) which connects to the MySQL database
-	acctHandler := AccountHandler{service: service.NewAccountService(accountRepositoryDb)}
-	// this tranHandler uses transactionRepositoryDb (defined above) which connects to the MySQL database
-	tranHandler := TransactionHandler{service: service.NewTransactionService(transactionRepositoryDb)}
+	// the health probes are registered on the root router ahead of the API subrouter so that they are matched first and
+	// never pass through the limits and auth middleware
+	healthHandler := HealthHandler{checks: readinessChecks, timeout: cfg.Limits.ReadinessTimeout.Std()}
+	router.HandleFunc("/healthz", healthHandler.liveness).Methods(http.MethodGet).Name("Liveness")
+	router.HandleFunc("/readyz", healthHandler.readiness).Methods(http.MethodGet).Name("Readiness")
+	// /metrics is scraped by Prometheus and, like the probes, sits outside the auth middleware
+	router.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet).Name("Metrics")
+	// the OpenAPI document describing every route is public so that clients can generate code from it
+	router.HandleFunc("/openapi.json", openAPISpec).Methods(http.MethodGet).Name("OpenAPISpec")
+
+	// in local auth mode the service issues its own tokens - the login and refresh routes sit outside the auth middleware,
+	// on their own subrouter with the limits middleware, and are not versioned as they are not part of the banking API
+	if cfg.Auth.Mode == config.AuthModeLocal {
+		passwordPolicy := domain.PasswordPolicy{MinLength: cfg.Auth.PasswordMinLength}
+		authHandler := AuthHandler{service: service.NewAuthService(repos.users, tokenSigner(cfg), passwordPolicy, cfg.Auth.PasswordHashCost)}
+		authRouter := router.PathPrefix("/auth").Subrouter()
+		authRouter.HandleFunc("/login", authHandler.login).Methods(http.MethodPost).Name("Login")
+		authRouter.HandleFunc("/refresh", authHandler.refresh).Methods(http.MethodPost).Name("RefreshToken")
+		// changing a password needs an access token, so the route gets a nested subrouter of its own with the auth
+		// middleware - the limits middleware of authRouter still runs first
+		passwordRouter := authRouter.NewRoute().Subrouter()
+		passwordRouter.HandleFunc("/password", authHandler.changePassword).Methods(http.MethodPost).Name("ChangePassword")
+		// a password change is audited like the mutating API routes - login and refresh change nothing and are not
+		passwordRouter.Use(AuditMiddleware{repo: repos.audit}.auditHandler())
+		passwordRouter.Use(authMiddleware(repos).authorizationHandler())
+		authRouter.Use(LimitsMiddleware{timeout: cfg.Limits.RequestTimeout.Std(), maxBodyBytes: cfg.Limits.MaxBodyBytes}.limitsHandler())
+		// the auth routes are rate limited by IP address as the middleware runs ahead of the auth middleware of the
+		// password route - which also slows down guessing of the current password
+		if cfg.RateLimits.Enabled {
+			rateLimitMidware := newRateLimitMiddleware(repos.rateLimits, cfg.RateLimits)
+			authRouter.Use(rateLimitMidware.rateLimitHandler())
+		}
+	}
+
+	// the tracing middleware is registered first on the root router so that its server span is the parent of every span
+	// started by the middleware, handlers, services, and repositories which follow
+	tracingMidware := TracingMiddleware{}
+	router.Use(tracingMidware.tracingHandler())
+
+	// the logging middleware follows so that its log line can include the trace ID of the server span
+	loggingMidware := LoggingMiddleware{}
+	router.Use(loggingMidware.loggingHandler())
+
+	// the metrics middleware is registered on the root router so that every matched route, including the probes, is
+	// counted - root router middleware also wraps the routes of the API subrouter below
+	metricsMidware := MetricsMiddleware{}
+	router.Use(metricsMidware.metricsHandler())
+
+	// each API version is served by its own subrouter under its path prefix - the limits and auth middleware are registered
+	// on the API subrouters rather than on the root router
+	registerAPIRoutes(router.PathPrefix(apiV1.prefix).Subrouter(), cfg, repos, apiV1)
+	registerAPIRoutes(router.PathPrefix(apiV2.prefix).Subrouter(), cfg, repos, apiV2)
+
+	// the unversioned routes (e.g. /customers) are kept as deprecated aliases of /v1 - the deprecation middleware wraps
+	// the limits and auth middleware so that every response, including an auth error, carries the deprecation headers
+	// NewRoute().Subrouter() creates a subrouter without any matcher of its own
+	legacyRouter := router.NewRoute().Subrouter()
+	// the dates were validated when the configuration was loaded
+	deprecatedAt, _ := time.Parse(config.DateLayout, cfg.API.LegacyDeprecatedAt)
+	sunsetAt, _ := time.Parse(config.DateLayout, cfg.API.LegacySunsetAt)
+	deprecationMidware := DeprecationMiddleware{deprecatedAt: deprecatedAt, sunsetAt: sunsetAt, successor: apiV1}
+	legacyRouter.Use(deprecationMidware.deprecationHandler())
+	registerAPIRoutes(legacyRouter.NewRoute().Subrouter(), cfg, repos, apiV1)
+
+	return router
+}
+
+// registerAPIRoutes() registers every API route, along with the limits, audit and auth middleware, on apiRouter - the handlers
+// return the response shapes of version
+// every version uses the same route names so that the auth server's permissions and the metrics apply to all of them
+func registerAPIRoutes(apiRouter *mux.Router, cfg *config.Config, repos repositories, version apiVersion) {
+	custHandler := CustomerHandlers{service: service.NewCustomerService(repos.customers), version: version}
+	acctHandler := AccountHandler{service: service.NewAccountService(repos.accounts, repos.customers, repos.products, accountPolicy(cfg))}
+	productHandler := AccountProductHandler{service: service.NewAccountProductService(repos.products), version: version}
+	tranService := service.NewTransactionService(repos.transactions, repos.customers)
+	tranHandler := TransactionHandler{service: tranService, version: version}
+	importHandler := TransactionImportHandler{service: service.NewTransactionImportService(repos.imports, tranService,
+		repos.transactions, repos.customers, cfg.Imports.MaxRows, cfg.Imports.StaleAfter.Std())}
+	keyHandler := APIKeyHandler{service: service.NewAPIKeyService(repos.apiKeys, apiKeyScopes())}
+	auditHandler := AuditHandler{service: service.NewAuditService(repos.audit)}
+	webhookHandler := WebhookHandler{service: service.NewWebhookService(repos.webhooks)}
 
 	// registering the handler functions for the given patterns (routes)
-	router.HandleFunc("/customers", custHandler.getAllCustomers).Methods(http.MethodGet).Name("GetAllCustomers")
+	apiRouter.HandleFunc("/customers", custHandler.getAllCustomers).Methods(http.MethodGet).Name("GetAllCustomers")
 	// here we use a variable (customer_id) in the path and use a regular expression (:[0-9]+) to indicate that the customer_id
 	// will only be comprised of numerical digits 0 - 9 which can repeat (+)
-	router.HandleFunc("/customers/{customer_id:[0-9]+}", custHandler.getCustomer).Methods(http.MethodGet).Name("GetCustomer")
-	// handler for creating an account - customer_id is required as accounts can only be created by existing customers
-	router.HandleFunc("/customers/{customer_id:[0-9]+}/account", acctHandler.newAccount).Methods(http.MethodPost).Name("NewAccount")
-	// handler for creating a transaction - customer_id is required as transactions can only be created by existing customers
-	router.HandleFunc("/customers/{customer_id:[0-9]+}/transaction", tranHandler.newTransaction).Methods(http.MethodPost).Name("NewTransaction")
-
-	authMidware := AuthMiddleware{repo: domain.NewAuthRepository()}
-	router.Use(authMidware.authorizationHandler())
-
-	// we use environment variables to inject the server address and port (see Makefile)
-	serverAdd := os.Getenv("SERVER_ADDRESS")
-	serverPort := os.Getenv("SERVER_PORT")
-	// ListenAndServe listens on the TCP network address specified and then calls Serve with the handler to handle
-	// incoming requests - if we were using the DefaultServeMux, we would pass nil for the handler
-	// ListenAndServe returns an error, so we wrap it with log.Fatal so we will know if an error occurs during server startup
-	log.Fatal(http.ListenAndServe(fmt.Sprintf("%s:%s", serverAdd, serverPort), router))
+	apiRouter.HandleFunc("/customers/{customer_id:[0-9]+}", custHandler.getCustomer).Methods(http.MethodGet).Name("GetCustomer")
+	// deactivating and reactivating a customer are admin operations - the auth server (or the auth policy in local mode)
+	// only grants these routes to admins
+	apiRouter.HandleFunc("/customers/{customer_id:[0-9]+}/deactivate", custHandler.deactivateCustomer).Methods(http.MethodPost).Name("DeactivateCustomer")
+	apiRouter.HandleFunc("/customers/{customer_id:[0-9]+}/reactivate", custHandler.reactivateCustomer).Methods(http.MethodPost).Name("ReactivateCustomer")
+	// the account product catalogue - the account types which may be opened - is read by anyone allowed to open accounts
+	// but only changed by admins, as granted by the auth server or the auth policy
+	apiRouter.HandleFunc("/account-products", productHandler.getAllProducts).Methods(http.MethodGet).Name("GetAllAccountProducts")
+	apiRouter.HandleFunc("/account-products", productHandler.newProduct).Methods(http.MethodPost).Name("NewAccountProduct")
+	apiRouter.HandleFunc("/account-products/{code:[A-Za-z0-9_]+}", productHandler.getProduct).Methods(http.MethodGet).Name("GetAccountProduct")
+	apiRouter.HandleFunc("/account-products/{code:[A-Za-z0-9_]+}", productHandler.updateProduct).Methods(http.MethodPut).Name("UpdateAccountProduct")
+	apiRouter.HandleFunc("/account-products/{code:[A-Za-z0-9_]+}", productHandler.deleteProduct).Methods(http.MethodDelete).Name("DeleteAccountProduct")
+	// API keys let batch jobs and partner systems call the routes named in the key's scopes without logging in - the keys
+	// are managed by admins
+	apiRouter.HandleFunc("/api-keys", keyHandler.getAllKeys).Methods(http.MethodGet).Name("GetAllAPIKeys")
+	apiRouter.HandleFunc("/api-keys", keyHandler.newKey).Methods(http.MethodPost).Name("NewAPIKey")
+	apiRouter.HandleFunc("/api-keys/{key_id:[0-9a-f]+}/revoke", keyHandler.revokeKey).Methods(http.MethodPost).Name("RevokeAPIKey")
+	apiRouter.HandleFunc("/api-keys/{key_id:[0-9a-f]+}/rotate", keyHandler.rotateKey).Methods(http.MethodPost).Name("RotateAPIKey")
+	// the audit log records every mutating call - it is read by admins and auditors
+	apiRouter.HandleFunc("/audit-events", auditHandler.getAuditEvents).Methods(http.MethodGet).Name("GetAuditEvents")
+	apiRouter.HandleFunc("/audit-events/verify", auditHandler.verifyAuditLog).Methods(http.MethodGet).Name("VerifyAuditLog")
+	// webhook subscriptions push the outbox events to partner systems - the subscriptions and their deliveries are managed
+	// by admins
+	apiRouter.HandleFunc("/webhooks", webhookHandler.getAllSubscriptions).Methods(http.MethodGet).Name("GetAllWebhookSubscriptions")
+	apiRouter.HandleFunc("/webhooks", webhookHandler.newSubscription).Methods(http.MethodPost).Name("NewWebhookSubscription")
+	apiRouter.HandleFunc("/webhooks/{subscription_id:[0-9]+}", webhookHandler.getSubscription).Methods(http.MethodGet).Name("GetWebhookSubscription")
+	apiRouter.HandleFunc("/webhooks/{subscription_id:[0-9]+}/disable", webhookHandler.disableSubscription).Methods(http.MethodPost).Name("DisableWebhookSubscription")
+	apiRouter.HandleFunc("/webhooks/{subscription_id:[0-9]+}/deliveries", webhookHandler.getDeliveries).Methods(http.MethodGet).Name("GetWebhookDeliveries")
+	apiRouter.HandleFunc("/webhooks/{subscription_id:[0-9]+}/deliveries/{delivery_id:[0-9]+}", webhookHandler.getDelivery).Methods(http.MethodGet).Name("GetWebhookDelivery")
+	apiRouter.HandleFunc("/webhooks/{subscription_id:[0-9]+}/deliveries/{delivery_id:[0-9]+}/replay", webhookHandler.replayDelivery).Methods(http.MethodPost).Name("ReplayWebhookDelivery")
+	// the account and transaction routes are only registered when their feature toggles are enabled
+	if cfg.Features.AccountCreation {
+		// handler for creating an account - customer_id is required as accounts can only be created by existing customers
+		apiRouter.HandleFunc("/customers/{customer_id:[0-9]+}/account", acctHandler.newAccount).Methods(http.MethodPost).Name("NewAccount")
+	}
+	if cfg.Features.Transactions {
+		// handler for creating a transaction - customer_id is required as transactions can only be created by existing
+		// customers
+		apiRouter.HandleFunc("/customers/{customer_id:[0-9]+}/transaction", tranHandler.newTransaction).Methods(http.MethodPost).Name("NewTransaction")
+		// the bulk import posts the rows of a CSV file (e.g. a payroll deposit file) - it is an admin operation
+		apiRouter.HandleFunc("/transaction-imports", importHandler.importTransactions).Methods(http.MethodPost).Name("ImportTransactions")
+	}
+
+	// the limits middleware is registered first so that the auth call made by the auth middleware shares the deadline
+	limitsMidware := LimitsMiddleware{timeout: cfg.Limits.RequestTimeout.Std(), maxBodyBytes: cfg.Limits.MaxBodyBytes}
+	apiRouter.Use(limitsMidware.limitsHandler())
+
+	// the audit middleware wraps the auth and rate limiting middleware so that the calls they refuse are audited too
+	auditMidware := AuditMiddleware{repo: repos.audit}
+	apiRouter.Use(auditMidware.auditHandler())
+
+	rateLimitMidware := newRateLimitMiddleware(repos.rateLimits, cfg.RateLimits)
+	// auth mode "none" skips authorization entirely - it is only meant for local development
+	if repos.auth != nil {
+		authMidware := authMiddleware(repos)
+		// a request with a missing or invalid token or API key never reaches the rate limiting middleware below, so the
+		// auth middleware counts it against the auth failure limit of its IP address - it slows down the guessing of
+		// credentials
+		if cfg.RateLimits.Enabled {
+			authMidware.failures = &rateLimitMidware
+		}
+		apiRouter.Use(authMidware.authorizationHandler())
+	}
+
+	// the rate limiting middleware follows the auth middleware so that an authenticated client is limited by its subject
+	// wherever it connects from
+	if cfg.RateLimits.Enabled {
+		apiRouter.Use(rateLimitMidware.rateLimitHandler())
+	}
+}
+
+// authMiddleware() returns the auth middleware for repos - tokens are checked by the auth repository of the auth mode and
+// API keys against the api_keys table in every mode
+func authMiddleware(repos repositories) AuthMiddleware {
+	return AuthMiddleware{repo: repos.auth, keys: domain.NewAPIKeyAuthRepository(repos.apiKeys)}
+}
+
+// checkRateLimitRoutes() returns an error naming each route of rate_limits.routes which is not a route of the service - a
+// misspelt route name would otherwise quietly fall back to the default limit
+func checkRateLimitRoutes(cfg *config.Config) error {
+	if !cfg.RateLimits.Enabled {
+		return nil
+	}
+	routeNames, err := openapi.RouteNames()
+	if err != nil {
+		return fmt.Errorf("reading the route names of openapi.json: %w", err)
+	}
+	var problems []string
+	for route := range cfg.RateLimits.Routes {
+		if !routeNames[route] {
+			problems = append(problems, "rate_limits.routes: unknown route "+route)
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	// sorted so that the problems are reported in the same order every time
+	sort.Strings(problems)
+	return errors.New("invalid rate limits:\n  - " + strings.Join(problems, "\n  - "))
+}
+
+// apiKeyScopes() returns the route names an API key's scopes may name - every route documented in the embedded
+// openapi.json, which the conformance tests keep in step with the router
+func apiKeyScopes() map[string]bool {
+	routeNames, err := openapi.RouteNames()
+	if err != nil {
+		panic(fmt.Sprintf("reading the route names of openapi.json: %v", err))
+	}
+	return routeNames
+}
+
+// tokenSigner() returns the signer of the tokens issued in local auth mode
+func tokenSigner(cfg *config.Config) domain.TokenSigner {
+	return domain.NewTokenSigner(cfg.Auth.TokenSecret, cfg.Auth.AccessTokenTTL.Std(), cfg.Auth.RefreshTokenTTL.Std())
+}
+
+// authPolicy() returns the role and route policy of local auth mode - the file at auth.policy_file or, when none is set,
+// the default policy - every route it names is checked against the routes documented in openapi.json so that a misspelt
+// route name fails startup rather than quietly denying the route
+func authPolicy(cfg *config.Config) (*domain.AuthPolicy, error) {
+	policy := domain.DefaultAuthPolicy()
+	if cfg.Auth.PolicyFile != "" {
+		loaded, err := domain.LoadAuthPolicy(cfg.Auth.PolicyFile)
+		if err != nil {
+			return nil, err
+		}
+		policy = *loaded
+	}
+	routeNames, err := openapi.RouteNames()
+	if err != nil {
+		return nil, fmt.Errorf("reading the route names of openapi.json: %w", err)
+	}
+	if problems := policy.Validate(routeNames); len(problems) > 0 {
+		return nil, errors.New("invalid auth policy:\n  - " + strings.Join(problems, "\n  - "))
+	}
+	return &policy, nil
+}
+
+// accountPolicy() returns the account opening policy set by the configuration
+func accountPolicy(cfg *config.Config) domain.AccountPolicy {
+	return domain.AccountPolicy{MaxPerType: cfg.Accounts.MaxPerType, MinimumAge: cfg.Accounts.MinimumAge}
 }
 
-func getDbConnection() *sqlx.DB {
-	// we use environment variables to inject the database connection information (see Makefile)
-	dbUser := os.Getenv("DB_USER")
-	dbPasswd := os.Getenv("DB_PASSWD")
-	dbAddress := os.Getenv("DB_ADDRESS")
-	dbPort := os.Getenv("DB_PORT")
-	dbName := os.Getenv("DB_NAME")
-	dbSource := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbUser, dbPasswd, dbAddress, dbPort, dbName)
+func getDbConnection(dbCfg config.DBConfig) *sqlx.DB {
+	// the database connection information is injected via the config file or environment variables (see Makefile)
+	dbSource := fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", dbCfg.User, dbCfg.Passwd, dbCfg.Address, dbCfg.Port, dbCfg.Name)
 
 	db, err := sqlx.Open("mysql", dbSource)
 	if err != nil {
@@ -98,11 +403,11 @@ func getDbConnection() *sqlx.DB {
 	}
 
 	// SetConnMaxLifetime sets the maximum amount of time that a connection may be reused
-	db.SetConnMaxLifetime(time.Minute * 3)
+	db.SetConnMaxLifetime(dbCfg.ConnMaxLifetime.Std())
 	// SetMaxOpenConns sets the maximum number of open connections to the database
-	db.SetMaxOpenConns(10)
+	db.SetMaxOpenConns(dbCfg.MaxOpenConns)
 	// SetMaxIdleConns sets the maximum number of connections in the idle connection pool
-	db.SetMaxIdleConns(10)
+	db.SetMaxIdleConns(dbCfg.MaxIdleConns)
 
 	return db
 }
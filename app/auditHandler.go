@@ -0,0 +1,53 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/service"
+)
+
+// AuditHandler serves the audit log routes - the log is written by AuditMiddleware and the routes only read it
+type AuditHandler struct {
+	service service.AuditService
+}
+
+func (auditHandler AuditHandler) getAuditEvents(w http.ResponseWriter, r *http.Request) {
+	// as with the customer search, an unknown parameter is rejected rather than ignored
+	queryParams := r.URL.Query()
+	var fieldErrs []dto.FieldError
+	for param := range queryParams {
+		if !dto.OneOf(param, dto.AuditEventSearchParams...) {
+			fieldErrs = append(fieldErrs, dto.FieldError{Field: param, Message: "error: unknown query parameter"})
+		}
+	}
+	req := dto.AuditEventSearchRequest{
+		Actor:      queryParams.Get("actor"),
+		Action:     queryParams.Get("action"),
+		Outcome:    queryParams.Get("outcome"),
+		CustomerID: queryParams.Get("customer_id"),
+		From:       queryParams.Get("from"),
+		To:         queryParams.Get("to"),
+		Limit:      queryParams.Get("limit"),
+		Cursor:     queryParams.Get("cursor"),
+	}
+	if fieldErrs = append(fieldErrs, req.ValidateFields()...); len(fieldErrs) > 0 {
+		writeErrorFields(w, r, badRequestErr("invalid query parameters"), fieldErrs)
+		return
+	}
+	page, appErr := auditHandler.service.GetAuditEvents(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, page)
+}
+
+func (auditHandler AuditHandler) verifyAuditLog(w http.ResponseWriter, r *http.Request) {
+	result, appErr := auditHandler.service.VerifyAuditLog(r.Context())
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, result)
+}
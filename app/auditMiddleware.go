@@ -0,0 +1,208 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+
+	"go.uber.org/zap"
+)
+
+const (
+	// auditCaptureBytes is the most of a request or response body kept for the audit entry - larger bodies are only
+	// counted, not recorded
+	auditCaptureBytes = 4096
+	// auditSummaryBytes is the longest summary recorded
+	auditSummaryBytes = 1024
+	// auditWriteTimeout bounds the append of an audit entry, which runs after the request's own deadline may have passed
+	auditWriteTimeout = 5 * time.Second
+	// auditAnonymous is the actor of a call which was not authorized, as its credential cannot be trusted to name anyone
+	auditAnonymous = "anonymous"
+	// auditRedacted replaces the value of a secret field in a summary
+	auditRedacted = "[REDACTED]"
+)
+
+// auditTargetFields are the response fields recorded as targets of a call, along with the route variables - e.g. the
+// account_id of the account opened by NewAccount
+var auditTargetFields = []string{"customer_id", "account_id", "transaction_id", "key_id", "code", "subscription_id"}
+
+// AuditMiddleware appends an entry to the audit log for every mutating request (any method but GET, HEAD and OPTIONS) -
+// it is registered ahead of the auth middleware so that calls refused by the auth and rate limiting middleware are
+// recorded too, and reads the actor the auth middleware recorded once the request has been served
+type AuditMiddleware struct {
+	repo domain.AuditRepository
+}
+
+func (auditMid AuditMiddleware) auditHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				nextMidware.ServeHTTP(w, r)
+				return
+			}
+			// the bodies are captured as the handler reads and writes them, so the handler still sees the body limit set
+			// by the limits middleware
+			body := &captureReader{ReadCloser: r.Body}
+			if r.Body != nil {
+				r.Body = body
+			}
+			rec := &auditRecorder{ResponseWriter: w, status: http.StatusOK}
+			nextMidware.ServeHTTP(rec, r)
+
+			routeVars := mux.Vars(r)
+			targets := make(map[string]string, len(routeVars))
+			for name, value := range routeVars {
+				targets[name] = value
+			}
+			if rec.status < 300 {
+				addResponseTargets(targets, rec.body.Bytes(), rec.written)
+			}
+			actor := logging.Subject(r.Context())
+			if actor == "" {
+				actor = auditAnonymous
+			}
+			entry := domain.AuditEntry{
+				OccurredAt: time.Now().Format("2006-01-02 15:04:05"),
+				Actor:      actor,
+				Action:     mux.CurrentRoute(r).GetName(),
+				Targets:    domain.AuditTargets(targets),
+				Summary:    auditSummary(r.Method+" "+r.URL.Path, body.buf.Bytes(), body.read),
+				Outcome:    domain.AuditOutcome(rec.status),
+				Status:     strconv.Itoa(rec.status),
+				RequestID:  logging.RequestID(r.Context()),
+			}
+			auditMid.append(r.Context(), entry)
+		})
+	}
+}
+
+// append() writes entry to the audit log - the response has already been written, so a failure can only be logged and
+// counted for alerting
+func (auditMid AuditMiddleware) append(ctx context.Context, entry domain.AuditEntry) {
+	// the audit entry must be written even when the request timed out or the client went away
+	ctx, cancel := context.WithTimeout(logging.Detach(ctx), auditWriteTimeout)
+	defer cancel()
+	if _, appErr := auditMid.repo.Append(ctx, entry); appErr != nil {
+		metrics.AuditWriteFailed(entry.Action)
+		logging.Error(ctx, "error while appending to the audit log", zap.String("action", entry.Action),
+			zap.String("actor", entry.Actor), zap.String("outcome", entry.Outcome), zap.String("error", appErr.Message))
+	}
+}
+
+// auditSummary() describes a call as its method and path followed by its JSON body with every secret field redacted - a
+// body too large to capture, or which is not JSON, is only described by its size
+func auditSummary(request string, body []byte, size int64) string {
+	if size == 0 {
+		return request
+	}
+	var decoded interface{}
+	if size > int64(len(body)) || json.Unmarshal(body, &decoded) != nil {
+		return request + " (body of " + strconv.FormatInt(size, 10) + " bytes)"
+	}
+	// Marshal() cannot fail for a value produced by Unmarshal()
+	redacted, _ := json.Marshal(redact(decoded))
+	summary := request + " " + string(redacted)
+	if len(summary) > auditSummaryBytes {
+		summary = strings.ToValidUTF8(summary[:auditSummaryBytes], "") + "..."
+	}
+	return summary
+}
+
+// redact() replaces the value of every field of value which may hold a secret (e.g. current_password) with auditRedacted
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for field, fieldValue := range v {
+			if isSecretField(field) {
+				v[field] = auditRedacted
+			} else {
+				v[field] = redact(fieldValue)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+// isSecretField() reports whether the value of a JSON field with the given name may be a secret
+func isSecretField(field string) bool {
+	field = strings.ToLower(field)
+	return field == "key" || strings.Contains(field, "password") || strings.Contains(field, "secret") || strings.Contains(field, "token")
+}
+
+// addResponseTargets() adds the auditTargetFields found in a captured JSON response body to targets - a response too large
+// to capture adds none
+func addResponseTargets(targets map[string]string, body []byte, size int64) {
+	if size > int64(len(body)) {
+		return
+	}
+	var fields map[string]interface{}
+	if json.Unmarshal(body, &fields) != nil {
+		return
+	}
+	for _, field := range auditTargetFields {
+		switch value := fields[field].(type) {
+		case string:
+			targets[field] = value
+		case float64:
+			targets[field] = strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+}
+
+// captureReader keeps the first auditCaptureBytes of a request body as it is read and counts every byte read
+type captureReader struct {
+	io.ReadCloser
+	buf  bytes.Buffer
+	read int64
+}
+
+func (reader *captureReader) Read(p []byte) (int, error) {
+	n, err := reader.ReadCloser.Read(p)
+	reader.read += int64(n)
+	if room := auditCaptureBytes - reader.buf.Len(); room > 0 {
+		if n < room {
+			room = n
+		}
+		reader.buf.Write(p[:room])
+	}
+	return n, err
+}
+
+// auditRecorder is a statusRecorder which also keeps the first auditCaptureBytes of the response body and counts every
+// byte written
+type auditRecorder struct {
+	http.ResponseWriter
+	status  int
+	body    bytes.Buffer
+	written int64
+}
+
+func (rec *auditRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+func (rec *auditRecorder) Write(p []byte) (int, error) {
+	rec.written += int64(len(p))
+	if room := auditCaptureBytes - rec.body.Len(); room > 0 {
+		if len(p) < room {
+			room = len(p)
+		}
+		rec.body.Write(p[:room])
+	}
+	return rec.ResponseWriter.Write(p)
+}
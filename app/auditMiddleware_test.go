@@ -0,0 +1,120 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// TestAuditLog() should pass when every mutating call - refused or not - is audited with its actor, targets and outcome,
+// reads are not, secrets are redacted from the summaries and the chain of the entries written verifies
+func TestAuditLog(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+	token := func(username string) string {
+		var tokens dto.LoginResponse
+		login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "`+username+`", "password": "passw0rd123"}`)
+		if err := json.Unmarshal(login.Body.Bytes(), &tokens); login.Code != http.StatusOK || err != nil {
+			t.Fatalf("login of %s received %d %s, want 200", username, login.Code, login.Body.String())
+		}
+		return tokens.AccessToken
+	}
+	admin, user := token("admin"), token("1001")
+	events := func(query string) dto.AuditEventPageResponse {
+		var page dto.AuditEventPageResponse
+		resp := serveAuth(router, http.MethodGet, "/v1/audit-events"+query, admin, "")
+		if err := json.Unmarshal(resp.Body.Bytes(), &page); resp.Code != http.StatusOK || err != nil {
+			t.Fatalf("audit events%s received %d %s, want 200", query, resp.Code, resp.Body.String())
+		}
+		return page
+	}
+
+	// Act - execute test
+	deposit := serveAuth(router, http.MethodPost, "/v1/customers/1001/transaction", user, `{"account_id": "101", "amount": 50, "transaction_type": "deposit"}`)
+	read := serveAuth(router, http.MethodGet, "/v1/customers/1001", user, "")
+	otherCustomer := serveAuth(router, http.MethodPost, "/v1/customers/1002/transaction", user, `{"account_id": "102", "amount": 50, "transaction_type": "withdrawal"}`)
+	changePassword := serveAuth(router, http.MethodPost, "/auth/password", user, `{"current_password": "passw0rd123", "new_password": "correct horse 42"}`)
+	userReadsLog := serveAuth(router, http.MethodGet, "/v1/audit-events", user, "")
+	all := events("")
+	denied := events("?outcome=denied&customer_id=1002")
+	page := events("?actor=1001&limit=1")
+	nextPage := events("?actor=1001&limit=1&cursor=" + page.NextCursor)
+	var verification dto.AuditVerificationResponse
+	verify := serveAuth(router, http.MethodGet, "/v1/audit-events/verify", admin, "")
+	_ = json.Unmarshal(verify.Body.Bytes(), &verification)
+
+	// Assert - test expectations
+	for name, got := range map[string]struct{ got, want int }{
+		"deposit":         {deposit.Code, http.StatusCreated},
+		"read":            {read.Code, http.StatusOK},
+		"other customer":  {otherCustomer.Code, http.StatusForbidden},
+		"change password": {changePassword.Code, http.StatusNoContent},
+		"user reads log":  {userReadsLog.Code, http.StatusForbidden},
+	} {
+		if got.got != got.want {
+			t.Errorf("%s: received %d, want %d", name, got.got, got.want)
+		}
+	}
+	// the reads and logins are not audited - newest first: change password, refused withdrawal, deposit
+	if len(all.Events) != 3 {
+		t.Fatalf("received %d audit events, want 3: %+v", len(all.Events), all.Events)
+	}
+	changed, refused, deposited := all.Events[0], all.Events[1], all.Events[2]
+	var tran dto.NewTransactionResponse
+	_ = json.Unmarshal(deposit.Body.Bytes(), &tran)
+	if deposited.Actor != "1001" || deposited.Action != "NewTransaction" || deposited.Outcome != "success" || deposited.Status != "201" ||
+		deposited.Targets["customer_id"] != "1001" || deposited.Targets["transaction_id"] != tran.TransactionID ||
+		!strings.Contains(deposited.Summary, `"transaction_type":"deposit"`) {
+		t.Errorf("received deposit event %+v", deposited)
+	}
+	if refused.Actor != "anonymous" || refused.Outcome != "denied" || refused.Status != "403" || refused.Targets["customer_id"] != "1002" {
+		t.Errorf("received refused event %+v", refused)
+	}
+	if changed.Action != "ChangePassword" || strings.Contains(changed.Summary, "passw0rd123") || strings.Contains(changed.Summary, "correct horse") {
+		t.Errorf("received change password event %+v, want the passwords redacted", changed)
+	}
+	if len(denied.Events) != 1 || denied.Events[0].EntryID != refused.EntryID {
+		t.Errorf("received denied events %+v, want only the refused withdrawal", denied.Events)
+	}
+	if len(page.Events) != 1 || page.Events[0].EntryID != changed.EntryID || len(nextPage.Events) != 1 || nextPage.Events[0].EntryID != deposited.EntryID || nextPage.NextCursor != "" {
+		t.Errorf("received pages %+v and %+v, want the password change then the deposit", page, nextPage)
+	}
+	if changed.PrevHash != refused.Hash || refused.PrevHash != deposited.Hash {
+		t.Error("expected each event to be chained to the event before it")
+	}
+	if verify.Code != http.StatusOK || !verification.Intact || verification.VerifiedEntries != 3 || verification.HeadHash != changed.Hash {
+		t.Errorf("verify received %d %s, want an intact chain of 3 entries", verify.Code, verify.Body.String())
+	}
+}
+
+// TestAuditSummary() should pass when secret fields are redacted at any depth and bodies which are not JSON or too large
+// to capture are described by their size only
+func TestAuditSummary(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		size int64
+		want string
+	}{
+		{"no body", "", 0, "POST /v1/x"},
+		{"json", `{"amount": 50}`, 14, `POST /v1/x {"amount":50}`},
+		{"secrets", `{"name": "batch", "auth": {"refresh_token": "abc", "key": "bk_1_x"}, "new_password": "p"}`, 82,
+			`POST /v1/x {"auth":{"key":"[REDACTED]","refresh_token":"[REDACTED]"},"name":"batch","new_password":"[REDACTED]"}`},
+		{"not json", `amount=50`, 9, "POST /v1/x (body of 9 bytes)"},
+		{"too large", `{"amount": 5`, 5000, "POST /v1/x (body of 5000 bytes)"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act - execute test
+			got := auditSummary("POST /v1/x", []byte(test.body), test.size)
+
+			// Assert - test expectations
+			if got != test.want {
+				t.Errorf("received %s, want %s", got, test.want)
+			}
+		})
+	}
+}
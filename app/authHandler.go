@@ -0,0 +1,71 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/service"
+)
+
+// AuthHandler serves the login, refresh and change password routes of local auth mode - only the change password route
+// sits behind the auth middleware as the other two are how a token is obtained
+type AuthHandler struct {
+	service service.AuthService
+}
+
+func (authHandler AuthHandler) login(w http.ResponseWriter, r *http.Request) {
+	var req dto.LoginRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	resp, appErr := authHandler.service.Login(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func (authHandler AuthHandler) refresh(w http.ResponseWriter, r *http.Request) {
+	var req dto.RefreshTokenRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	resp, appErr := authHandler.service.Refresh(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+// changePassword() changes the password of the user the access token was issued to - the auth middleware records the
+// token's username as the request's subject
+func (authHandler AuthHandler) changePassword(w http.ResponseWriter, r *http.Request) {
+	username := logging.Subject(r.Context())
+	if username == "" {
+		writeError(w, r, errs.AuthorizationErr("missing token"))
+		return
+	}
+	var req dto.ChangePasswordRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	if appErr := authHandler.service.ChangePassword(r.Context(), username, req); appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// the new password is not echoed back
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,146 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+)
+
+// newLocalAuthRouter() builds the full router with local auth - unlike newTestRouter() the tokens issued by /auth/login
+// are the ones checked by the auth middleware
+func newLocalAuthRouter() *mux.Router {
+	cfg := config.Default()
+	cfg.Auth.Mode = config.AuthModeLocal
+	cfg.Auth.TokenSecret = testTokenSecret
+	repos := repositories{
+		customers:    domain.NewCustomerRepositoryStub(),
+		accounts:     domain.NewAccountRepositoryStub(),
+		transactions: domain.NewTransactionRepositoryStub(),
+		products:     domain.NewAccountProductRepositoryStub(),
+		audit:        domain.NewAuditRepositoryStub(),
+		users:        domain.NewUserRepositoryStub(),
+		apiKeys:      domain.NewAPIKeyRepositoryStub(),
+		webhooks:     domain.NewWebhookRepositoryStub(),
+		imports:      domain.NewTransactionImportRepositoryStub(),
+		rateLimits:   ratelimit.NewMemoryStore(),
+		auth:         domain.NewPolicyAuthRepository(tokenSigner(&cfg), domain.DefaultAuthPolicy()),
+	}
+	checks := []dependencyCheck{{name: "database", check: func(context.Context) error { return nil }}}
+	return newRouter(&cfg, repos, checks)
+}
+
+// serveAuth() sends a request with an optional bearer token and json body to router
+func serveAuth(router *mux.Router, method string, path string, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// TestLocalAuthFlow() should pass when a user can log in, call their own customer's routes with the access token, is
+// denied other customers and can exchange the refresh token - but not the access token - for new tokens
+func TestLocalAuthFlow(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+
+	// Act - execute test
+	login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "1001", "password": "passw0rd123"}`)
+	var tokens dto.LoginResponse
+	if err := json.Unmarshal(login.Body.Bytes(), &tokens); login.Code != http.StatusOK || err != nil {
+		t.Fatalf("login received %d %s, want 200", login.Code, login.Body.String())
+	}
+	own := serveAuth(router, http.MethodGet, "/v1/customers/1001", tokens.AccessToken, "")
+	other := serveAuth(router, http.MethodGet, "/v1/customers/1002", tokens.AccessToken, "")
+	adminOnly := serveAuth(router, http.MethodGet, "/v1/customers", tokens.AccessToken, "")
+	refreshWithAccess := serveAuth(router, http.MethodPost, "/auth/refresh", "", `{"refresh_token": "`+tokens.AccessToken+`"}`)
+	refresh := serveAuth(router, http.MethodPost, "/auth/refresh", "", `{"refresh_token": "`+tokens.RefreshToken+`"}`)
+	var refreshed dto.LoginResponse
+	_ = json.Unmarshal(refresh.Body.Bytes(), &refreshed)
+	afterRefresh := serveAuth(router, http.MethodGet, "/v1/customers/1001", refreshed.AccessToken, "")
+
+	// Assert - test expectations
+	if tokens.TokenType != "Bearer" || tokens.ExpiresIn != 900 {
+		t.Errorf("received token type %q expiring in %d, want Bearer expiring in 900", tokens.TokenType, tokens.ExpiresIn)
+	}
+	for name, got := range map[string]struct{ got, want int }{
+		"own customer":               {own.Code, http.StatusOK},
+		"other customer":             {other.Code, http.StatusForbidden},
+		"admin route":                {adminOnly.Code, http.StatusForbidden},
+		"refresh with access":        {refreshWithAccess.Code, http.StatusUnauthorized},
+		"refresh":                    {refresh.Code, http.StatusOK},
+		"own customer after refresh": {afterRefresh.Code, http.StatusOK},
+	} {
+		if got.got != got.want {
+			t.Errorf("%s: received %d, want %d", name, got.got, got.want)
+		}
+	}
+}
+
+// TestChangePasswordFlow() should pass when a user can change their password with an access token, after which only the
+// new password logs in
+func TestChangePasswordFlow(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+	login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "1001", "password": "passw0rd123"}`)
+	var tokens dto.LoginResponse
+	if err := json.Unmarshal(login.Body.Bytes(), &tokens); login.Code != http.StatusOK || err != nil {
+		t.Fatalf("login received %d %s, want 200", login.Code, login.Body.String())
+	}
+
+	// Act - execute test
+	weak := serveAuth(router, http.MethodPost, "/auth/password", tokens.AccessToken, `{"current_password": "passw0rd123", "new_password": "short"}`)
+	change := serveAuth(router, http.MethodPost, "/auth/password", tokens.AccessToken, `{"current_password": "passw0rd123", "new_password": "correct horse 42"}`)
+	oldLogin := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "1001", "password": "passw0rd123"}`)
+	newLogin := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "1001", "password": "correct horse 42"}`)
+
+	// Assert - test expectations
+	for name, got := range map[string]struct{ got, want int }{
+		"weak password": {weak.Code, http.StatusUnprocessableEntity},
+		"change":        {change.Code, http.StatusNoContent},
+		"old password":  {oldLogin.Code, http.StatusUnauthorized},
+		"new password":  {newLogin.Code, http.StatusOK},
+	} {
+		if got.got != got.want {
+			t.Errorf("%s: received %d, want %d", name, got.got, got.want)
+		}
+	}
+}
+
+// TestAuthPolicy() should pass when the default policy only names documented routes and a policy file naming a route
+// which does not exist is refused
+func TestAuthPolicy(t *testing.T) {
+	// Arrange - setup test
+	cfg := config.Default()
+	misspelt := config.Default()
+	misspelt.Auth.PolicyFile = filepath.Join(t.TempDir(), "policy.yaml")
+	os.WriteFile(misspelt.Auth.PolicyFile, []byte("roles:\n  auditor:\n    - route: GetAllCustomer\n"), 0o600)
+
+	// Act - execute test
+	_, defaultErr := authPolicy(&cfg)
+	_, misspeltErr := authPolicy(&misspelt)
+
+	// Assert - test expectations
+	if defaultErr != nil {
+		t.Errorf("unexpected error for the default policy: %v", defaultErr)
+	}
+	if misspeltErr == nil || !strings.Contains(misspeltErr.Error(), "unknown route GetAllCustomer") {
+		t.Errorf("received %v, want an unknown route error", misspeltErr)
+	}
+}
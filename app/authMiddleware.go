@@ -1,16 +1,25 @@
 package app
 
 import (
+	"encoding/base64"
+	"encoding/json"
 	"net/http"
 	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
 )
 
+// AuthMiddleware authorizes each request with the bearer token in the Authorization header (repo) or, when the request
+// carries an X-API-Key header instead, with the API key (keys) - a request with a missing or invalid credential is
+// counted against the auth failure rate limit of its IP address by failures, when rate limiting is enabled
 type AuthMiddleware struct {
-	repo domain.AuthRepository
+	repo     domain.AuthRepository
+	keys     domain.AuthRepository
+	failures *RateLimitMiddleware
 }
 
 func (authMid AuthMiddleware) authorizationHandler() func(http.Handler) http.Handler {
@@ -20,29 +29,58 @@ func (authMid AuthMiddleware) authorizationHandler() func(http.Handler) http.Han
 			currentRoute := mux.CurrentRoute(r)
 			// grab the route variables from the http.Request object
 			routeVars := mux.Vars(r)
+			// service-to-service clients send an API key rather than a token - an API key's scopes are route names
+			if apiKey := r.Header.Get("X-API-Key"); apiKey != "" && authMid.keys != nil {
+				keyID, _ := domain.APIKeyID(apiKey)
+				authMid.authorize(w, r, nextMidware, authMid.keys, apiKey, currentRoute.GetName(), routeVars, "apikey:"+keyID)
+				return
+			}
 			// Get() returns the first value at the specified key - the header is essentially a map[string][]string object
 			authHeader := r.Header.Get("Authorization")
 			if authHeader == "" {
-				writeResponse(w, http.StatusUnauthorized, "missing token")
+				authMid.refuse(w, r, errs.AuthorizationErr("missing token"), true)
 				return
 			}
 			// grab token using getTokenFromHeader()
 			token := getTokenFromHeader(authHeader)
-			// verify if the token, routeName, and route variables are authorized for the individual
-			if !authMid.repo.IsAuthorized(token, currentRoute.GetName(), routeVars) {
-				// if the verification fails
-				appErr := errs.AppError{Code: http.StatusForbidden, Message: "Unauthorized"}
-				writeResponse(w, appErr.Code, appErr.MessageOnly())
-				return
-			}
-			// if the verification passes
-			// ServeHTTP() will write reply headers and data to the response writer and then return - upon return, the request
-			// is completed
-			nextMidware.ServeHTTP(w, r)
+			authMid.authorize(w, r, nextMidware, authMid.repo, token, currentRoute.GetName(), routeVars, tokenSubject(token))
 		})
 	}
 }
 
+// authorize() asks repo whether credential may call routeName and, if so, records subject for the request log line and
+// passes the request on to nextMidware
+func (authMid AuthMiddleware) authorize(w http.ResponseWriter, r *http.Request, nextMidware http.Handler,
+	repo domain.AuthRepository, credential string, routeName string, routeVars map[string]string, subject string) {
+	// the span covers the authorization step of the middleware chain
+	ctx, span := tracing.Start(r.Context(), "AuthMiddleware.authorize")
+	// verify if the credential, routeName, and route variables are authorized for the individual
+	isAuthorized := repo.IsAuthorized(ctx, credential, routeName, routeVars)
+	span.End()
+	if !isAuthorized {
+		// if the verification fails - only an invalid credential is a failed authentication, while a valid one which is
+		// not granted the route is a client calling a route it should not, which is not counted
+		verifier, canVerify := repo.(domain.CredentialVerifier)
+		invalid := canVerify && !verifier.IsValid(r.Context(), credential)
+		authMid.refuse(w, r, &errs.AppError{Code: http.StatusForbidden, Message: "Unauthorized"}, invalid)
+		return
+	}
+	// if the verification passes, the subject is recorded for the request log line
+	logging.SetSubject(r.Context(), subject)
+	// ServeHTTP() will write reply headers and data to the response writer and then return - upon return, the request
+	// is completed
+	nextMidware.ServeHTTP(w, r)
+}
+
+// refuse() writes appErr - a request with a missing or invalid credential first takes a token from the auth failure
+// bucket of its IP address, and is refused with 429 instead once that is empty
+func (authMid AuthMiddleware) refuse(w http.ResponseWriter, r *http.Request, appErr *errs.AppError, credentialFailed bool) {
+	if credentialFailed && authMid.failures != nil && !authMid.failures.allowAuthFailure(w, r) {
+		return
+	}
+	writeError(w, r, appErr)
+}
+
 func getTokenFromHeader(h string) string {
 	// the token value is preceded by its type - in this case "Bearer" e.g. Bearer aaaa.bbbb.cccc
 	splitToken := strings.Split(h, "Bearer")
@@ -52,3 +90,28 @@ func getTokenFromHeader(h string) string {
 	// TrimSpace() removes all leading and trailing white space from the string passed in
 	return strings.TrimSpace(splitToken[1])
 }
+
+// tokenSubject() returns the username (or, failing that, the sub claim) of a JWT for logging purposes - the token has
+// already been verified by the auth server, so the payload is only decoded here, not verified
+func tokenSubject(token string) string {
+	// a JWT is made up of three base64url encoded segments - header.payload.signature
+	segments := strings.Split(token, ".")
+	if len(segments) != 3 {
+		return ""
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(segments[1])
+	if err != nil {
+		return ""
+	}
+	var claims struct {
+		Username string `json:"username"`
+		Subject  string `json:"sub"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return ""
+	}
+	if claims.Username != "" {
+		return claims.Username
+	}
+	return claims.Subject
+}
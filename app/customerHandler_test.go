@@ -1,6 +1,7 @@
 package app
 
 import (
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -24,6 +25,7 @@ func setup(t *testing.T) func() {
 	// define customer handler
 	custHandler = CustomerHandlers{
 		service: mockCustServ,
+		version: apiV1,
 	}
 	// create a new router using gorilla mux NewRouter()
 	router = mux.NewRouter()
@@ -67,7 +69,7 @@ func TestGetAllCustomersSuccess(t *testing.T) {
 	}
 
 	// define expectations for the mock customer service
-	mockCustServ.EXPECT().GetAllCustomers("").Return(dummyCustList, nil)
+	mockCustServ.EXPECT().GetAllCustomers(gomock.Any(), dto.CustomerSearchRequest{Unpaged: true}).Return(&dto.CustomerPageResponse{Customers: dummyCustList}, nil)
 
 	// create http request
 	// we use MethodGet as getAllCustomers is a Get operation, we provide the url path, and no request body
@@ -96,7 +98,7 @@ func TestGetAllCustomersInternalErr(t *testing.T) {
 	defer resetRouter()
 
 	// define expectations for the mock customer service
-	mockCustServ.EXPECT().GetAllCustomers("").Return(nil, errs.UnexpectedErr("database error"))
+	mockCustServ.EXPECT().GetAllCustomers(gomock.Any(), dto.CustomerSearchRequest{Unpaged: true}).Return(nil, errs.UnexpectedErr("database error"))
 
 	// create http request
 	// we use MethodGet as getAllCustomers is a Get operation, we provide the url path, and no request body
@@ -115,3 +117,47 @@ func TestGetAllCustomersInternalErr(t *testing.T) {
 		t.Error("fail while testing the status code 500")
 	}
 }
+
+// TestGetAllCustomersInvalidFilters() should pass when unknown query parameters and invalid filter values are all reported
+// in a single http.StatusBadRequest response without calling the service
+func TestGetAllCustomersInvalidFilters(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	resetRouter := setup(t)
+	defer resetRouter()
+	// no expectations are defined - the mock fails the test if the service is called
+	req, _ := http.NewRequest(http.MethodGet, "/customers?status=closed&born_after=1990-13-01&limit=0&sort=-age&zip=11219", nil)
+
+	// Act - execute test
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	// Assert - test expectations
+	var resp dto.ErrorResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusBadRequest || resp.Code != dto.ErrBadRequest || len(resp.Fields) != 5 {
+		t.Errorf("received %d %+v, want 400 with five field errors", recorder.Code, resp)
+	}
+}
+
+// TestGetAllCustomersNextCursor() should pass when the next page's cursor is returned in the X-Next-Cursor header
+func TestGetAllCustomersNextCursor(t *testing.T) {
+	// Arrange - setup test
+	resetRouter := setup(t)
+	defer resetRouter()
+	search := dto.CustomerSearchRequest{City: "Brooklyn", Sort: "-name", Limit: "1", Unpaged: true}
+	page := &dto.CustomerPageResponse{Customers: []dto.CustomerResponse{{ID: "5", Name: "Rosa"}}, NextCursor: "next"}
+	mockCustServ.EXPECT().GetAllCustomers(gomock.Any(), search).Return(page, nil)
+	req, _ := http.NewRequest(http.MethodGet, "/customers?city=Brooklyn&sort=-name&limit=1", nil)
+
+	// Act - execute test
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	// Assert - test expectations
+	if recorder.Code != http.StatusOK || recorder.Header().Get(NextCursorHeader) != "next" {
+		t.Errorf("received %d with cursor %q", recorder.Code, recorder.Header().Get(NextCursorHeader))
+	}
+}
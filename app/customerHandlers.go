@@ -1,9 +1,12 @@
 package app
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
 	"github.com/gtaylor314/Banking-MS/service"
 
 	"github.com/gorilla/mux"
@@ -12,22 +15,47 @@ import (
 // CustomerHandler "connects" to the CustomerService interface (in other words, it is dependent on it)
 type CustomerHandlers struct {
 	service service.CustomerService
+	version apiVersion // maps the CustomerPageResponse to the shape returned by the API version being served
 }
 
 func (custHandler *CustomerHandlers) getAllCustomers(w http.ResponseWriter, r *http.Request) {
 
-	// check if there is a query parameter (specifically for key "status")
-	queryParam := r.URL.Query().Get("status")
-	// grab the list of customers and an error if any
-	customers, err := custHandler.service.GetAllCustomers(queryParam)
+	// the filters, sort order and page are provided as query parameters - an unknown parameter is rejected rather than
+	// ignored so that a misspelt filter (e.g. ?zip=) does not silently return every customer
+	queryParams := r.URL.Query()
+	var fieldErrs []dto.FieldError
+	for param := range queryParams {
+		if !dto.OneOf(param, dto.CustomerSearchParams...) {
+			fieldErrs = append(fieldErrs, dto.FieldError{Field: param, Message: "error: unknown query parameter"})
+		}
+	}
+	req := dto.CustomerSearchRequest{
+		Status:     queryParams.Get("status"),
+		NamePrefix: queryParams.Get("name"),
+		City:       queryParams.Get("city"),
+		Zipcode:    queryParams.Get("zipcode"),
+		BornAfter:  queryParams.Get("born_after"),
+		BornBefore: queryParams.Get("born_before"),
+		Sort:       queryParams.Get("sort"),
+		Limit:      queryParams.Get("limit"),
+		Cursor:     queryParams.Get("cursor"),
+		Unpaged:    custHandler.version.unpagedCustomers,
+	}
+	// invalid filters are the client's error (400) - every problem is reported at once
+	if fieldErrs = append(fieldErrs, req.ValidateFields()...); len(fieldErrs) > 0 {
+		writeErrorFields(w, r, badRequestErr("invalid query parameters"), fieldErrs)
+		return
+	}
+	// grab the page of customers and an error if any
+	page, err := custHandler.service.GetAllCustomers(r.Context(), req)
 	if err != nil {
-		// if there are errors, set response header content-type, status code, and response body via writeResponse()
-		// and return
-		writeResponse(w, err.Code, err.MessageOnly())
+		// if there are errors, write the error response via writeError() and return
+		writeError(w, r, err)
 		return
 	}
-	// if there are no errors, set response header content-type, status code, and response body via writeResponse()
-	writeResponse(w, http.StatusOK, customers)
+	// if there are no errors, set response header content-type, status code, and response body (in the shape of the API
+	// version being served) via writeResponse()
+	writeResponse(w, http.StatusOK, custHandler.version.customerPage(w.Header(), *page))
 	return
 
 }
@@ -37,16 +65,45 @@ func (custHandler *CustomerHandlers) getCustomer(w http.ResponseWriter, r *http.
 	// grab the customer_id from the http.Request
 	// mux.Vars() is provided by the gorilla mux package - it returns the route variables for the current http.Request, if any
 	vars := mux.Vars(r)
-	customer, err := custHandler.service.GetCustomer(vars["customer_id"])
+	// r.Context() carries the request's deadline and is cancelled if the client disconnects
+	customer, err := custHandler.service.GetCustomer(r.Context(), vars["customer_id"])
 	if err != nil {
 		// customer_id was not found
-		writeResponse(w, err.Code, err.MessageOnly())
+		writeError(w, r, err)
 		return
 	}
 	// customer_id was found
 	writeResponse(w, http.StatusOK, customer)
 }
 
+// deactivateCustomer() and reactivateCustomer() change the customer's status - the reason for the change is provided via the
+// JSON body and the updated customer is returned
+func (custHandler *CustomerHandlers) deactivateCustomer(w http.ResponseWriter, r *http.Request) {
+	custHandler.changeStatus(w, r, custHandler.service.DeactivateCustomer)
+}
+
+func (custHandler *CustomerHandlers) reactivateCustomer(w http.ResponseWriter, r *http.Request) {
+	custHandler.changeStatus(w, r, custHandler.service.ReactivateCustomer)
+}
+
+// changeStatus() decodes and validates the CustomerStatusRequest before passing it to change
+func (custHandler *CustomerHandlers) changeStatus(w http.ResponseWriter, r *http.Request,
+	change func(context.Context, string, dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError)) {
+	var req dto.CustomerStatusRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	customer, err := change(r.Context(), mux.Vars(r)["customer_id"], req)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	writeResponse(w, http.StatusOK, customer)
+}
+
 // writeResponse sets our response header (content-type to application/json), the status code for the response header, and
 // finally encodes the response body in the json format (data interface{} allows us to pass anything for the response body)
 func writeResponse(w http.ResponseWriter, code int, data interface{}) {
@@ -0,0 +1,77 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// decodeJSONBody() is the shared decoding layer for request bodies - it requires a json Content-Type, rejects unknown
+// fields and anything after the json value, and reports bodies over the size limit set by the LimitsMiddleware - on failure
+// the error response has already been written and false is returned
+func decodeJSONBody(w http.ResponseWriter, r *http.Request, dst interface{}) bool {
+	// ParseMediaType() allows parameters such as charset=utf-8 after the media type
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "application/json" {
+		writeError(w, r, &errs.AppError{Code: http.StatusUnsupportedMediaType, Message: "Content-Type must be application/json"})
+		return false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	// DisallowUnknownFields() makes Decode() fail on a field which dst does not have (e.g. a misspelled "ammount")
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(dst); err != nil {
+		appErr, fieldErrs := decodeError(err)
+		writeErrorFields(w, r, appErr, fieldErrs)
+		return false
+	}
+	// a second Decode() must reach the end of the body - anything else means there is trailing data after the json value
+	if err := decoder.Decode(&struct{}{}); !errors.Is(err, io.EOF) {
+		writeError(w, r, badRequestErr("request body must contain a single json object"))
+		return false
+	}
+	return true
+}
+
+// decodeError() turns an error from json.Decoder into an AppError - with a field error when the problem is a single field
+func decodeError(err error) (*errs.AppError, []dto.FieldError) {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+
+	switch {
+	case errors.As(err, &maxBytesErr):
+		return &errs.AppError{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit)}, nil
+	case errors.As(err, &syntaxErr):
+		return badRequestErr(fmt.Sprintf("malformed json at position %d", syntaxErr.Offset)), nil
+	case errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, io.EOF):
+		return badRequestErr("request body must contain a complete json object"), nil
+	case errors.As(err, &typeErr):
+		message := fmt.Sprintf("must be of type %s", typeErr.Type)
+		return badRequestErr("invalid value for field " + typeErr.Field), []dto.FieldError{{Field: typeErr.Field, Message: message}}
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		// encoding/json does not export an error type for unknown fields - the field name is quoted in the message
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return badRequestErr("unknown field " + field), []dto.FieldError{{Field: field, Message: "unknown field"}}
+	default:
+		return badRequestErr("malformed request body"), nil
+	}
+}
+
+// validateRequest() runs the dto's field validation and writes every problem found as a single 422 error response - false
+// is returned when the dto is invalid
+func validateRequest(w http.ResponseWriter, r *http.Request, req dto.Validatable) bool {
+	fieldErrs := req.ValidateFields()
+	if len(fieldErrs) == 0 {
+		return true
+	}
+	writeErrorFields(w, r, errs.ValidationErr("request failed validation"), fieldErrs)
+	return false
+}
@@ -0,0 +1,88 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// TestDecodeJSONBody() should pass when every malformed body is rejected with the expected status and error code and a
+// well formed body is accepted
+func TestDecodeJSONBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		maxBytes    int64
+		wantOK      bool
+		wantStatus  int
+		wantCode    string
+	}{
+		{"valid", "application/json; charset=utf-8", `{"account_id": "101", "amount": 10, "transaction_type": "deposit"}`, 1024, true, 0, ""},
+		{"wrong content type", "text/plain", `{}`, 1024, false, http.StatusUnsupportedMediaType, dto.ErrUnsupportedMedia},
+		{"unknown field", "application/json", `{"ammount": 10}`, 1024, false, http.StatusBadRequest, dto.ErrBadRequest},
+		{"wrong type", "application/json", `{"amount": "ten"}`, 1024, false, http.StatusBadRequest, dto.ErrBadRequest},
+		{"trailing data", "application/json", `{"amount": 10} garbage`, 1024, false, http.StatusBadRequest, dto.ErrBadRequest},
+		{"truncated", "application/json", `{"amount": 10`, 1024, false, http.StatusBadRequest, dto.ErrBadRequest},
+		{"too large", "application/json", `{"account_id": "101", "amount": 10}`, 10, false, http.StatusRequestEntityTooLarge, dto.ErrBodyTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			req, _ := http.NewRequest(http.MethodPost, "/customers/1/transaction", strings.NewReader(test.body))
+			req.Header.Set("Content-Type", test.contentType)
+			recorder := httptest.NewRecorder()
+			// the LimitsMiddleware normally wraps the body with MaxBytesReader()
+			req.Body = http.MaxBytesReader(recorder, req.Body, test.maxBytes)
+			var tranReq dto.NewTransactionRequest
+
+			// Act - execute test
+			ok := decodeJSONBody(recorder, req, &tranReq)
+
+			// Assert - test expectations
+			if ok != test.wantOK {
+				t.Fatalf("decodeJSONBody() = %v, want %v", ok, test.wantOK)
+			}
+			if ok {
+				return
+			}
+			var resp dto.ErrorResponse
+			if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+				t.Fatal(err)
+			}
+			if recorder.Code != test.wantStatus || resp.Code != test.wantCode {
+				t.Errorf("received %d %s, want %d %s", recorder.Code, resp.Code, test.wantStatus, test.wantCode)
+			}
+		})
+	}
+}
+
+// TestValidateRequestAllFields() should pass when every invalid field is returned in a single 422 response
+func TestValidateRequestAllFields(t *testing.T) {
+	// Arrange - setup test
+	req, _ := http.NewRequest(http.MethodPost, "/customers/1/account", nil)
+	recorder := httptest.NewRecorder()
+	// both the amount and the account type are invalid
+	acctReq := dto.NewAccountRequest{CustomerID: "1", AccountType: " ", Amount: -100}
+
+	// Act - execute test
+	ok := validateRequest(recorder, req, acctReq)
+
+	// Assert - test expectations
+	if ok {
+		t.Fatal("expected validation to fail")
+	}
+	var resp dto.ErrorResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if recorder.Code != http.StatusUnprocessableEntity || resp.Code != dto.ErrValidationFailed || len(resp.Fields) != 2 {
+		t.Errorf("received %d %+v, want 422 with two field errors", recorder.Code, resp)
+	}
+}
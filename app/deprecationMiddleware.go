@@ -0,0 +1,35 @@
+package app
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/metrics"
+)
+
+// DeprecationMiddleware marks the responses of the unversioned routes as deprecated - Deprecation (RFC 9745) and Sunset
+// (RFC 8594) tell clients when the route was deprecated and when it will be removed, and Link points to its /v1 successor
+type DeprecationMiddleware struct {
+	deprecatedAt time.Time
+	sunsetAt     time.Time
+	successor    apiVersion
+}
+
+func (deprecationMid DeprecationMiddleware) deprecationHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// the headers are set before the handler writes the response - including error responses from the auth
+			// middleware, as a rejected client still needs to know the route is going away
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecationMid.deprecatedAt.Unix()))
+			w.Header().Set("Sunset", deprecationMid.sunsetAt.UTC().Format(http.TimeFormat))
+			w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", deprecationMid.successor.prefix, r.URL.Path))
+
+			if currentRoute := mux.CurrentRoute(r); currentRoute != nil {
+				metrics.DeprecatedRequest(currentRoute.GetName())
+			}
+			nextMidware.ServeHTTP(w, r)
+		})
+	}
+}
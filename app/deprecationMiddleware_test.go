@@ -0,0 +1,89 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestDeprecatedAliases() should pass when the unversioned routes, and only those, carry the Deprecation, Sunset and Link
+// headers - including on an auth error
+func TestDeprecatedAliases(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	router := newTestRouter()
+	tests := []struct {
+		path           string
+		token          string
+		wantDeprecated bool
+		wantStatus     int
+	}{
+		{"/customers/1001", "valid", true, http.StatusOK},
+		{"/customers/1001", "", true, http.StatusUnauthorized},
+		{"/v1/customers/1001", "valid", false, http.StatusOK},
+		{"/v2/customers/1001", "valid", false, http.StatusOK},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, test.path, nil)
+		if test.token != "" {
+			req.Header.Set("Authorization", "Bearer "+test.token)
+		}
+		recorder := httptest.NewRecorder()
+
+		// Act - execute test
+		router.ServeHTTP(recorder, req)
+
+		// Assert - test expectations
+		if recorder.Code != test.wantStatus {
+			t.Errorf("%s: received %d, want %d", test.path, recorder.Code, test.wantStatus)
+		}
+		// config.Default() deprecates the aliases on 2026-10-19 and removes them on 2027-04-30
+		deprecation, sunset, link := recorder.Header().Get("Deprecation"), recorder.Header().Get("Sunset"), recorder.Header().Get("Link")
+		if !test.wantDeprecated {
+			if deprecation != "" || sunset != "" || link != "" {
+				t.Errorf("%s: unexpected deprecation headers %q %q %q", test.path, deprecation, sunset, link)
+			}
+			continue
+		}
+		if deprecation != "@1792368000" || sunset != "Fri, 30 Apr 2027 00:00:00 GMT" || link != `</v1/customers/1001>; rel="successor-version"` {
+			t.Errorf("%s: received deprecation headers %q %q %q", test.path, deprecation, sunset, link)
+		}
+	}
+}
+
+// TestV2MoneyAsString() should pass when /v2 returns the balance as a decimal string while /v1 returns a number
+func TestV2MoneyAsString(t *testing.T) {
+	// Arrange - setup test
+	router := newTestRouter()
+	body := `{"account_id": "101", "amount": 0.1, "transaction_type": "deposit"}`
+
+	// each request deposits 0.1 to account 101 - /v2 is called second, taking the balance from 1000.00 to 1000.20
+	for _, prefix := range []string{"/v1", "/v2"} {
+		req := httptest.NewRequest(http.MethodPost, prefix+"/customers/1001/transaction", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer valid")
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		// Act - execute test
+		router.ServeHTTP(recorder, req)
+
+		// Assert - test expectations
+		var resp map[string]interface{}
+		if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+			t.Fatal(err)
+		}
+		switch balance := resp["balance"].(type) {
+		case string:
+			if prefix != "/v2" || balance != "1000.20" {
+				t.Errorf("%s: unexpected balance %q", prefix, balance)
+			}
+		default:
+			if prefix != "/v1" {
+				t.Errorf("%s: unexpected balance %#v", prefix, balance)
+			}
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+)
+
+// errorCodes maps specific AppErrors to their error code - the errors are matched by identity rather than by message, so
+// that rewording a message never changes the code clients rely on - any AppError not listed here falls back to the code
+// for its http status (see statusCodes)
+var errorCodes = map[*errs.AppError]string{
+	dto.InsufficientFundsErr:      dto.ErrInsufficientFunds,
+	domain.CustomerNotFoundErr:    dto.ErrCustomerNotFound,
+	domain.AccountNotFoundErr:     dto.ErrAccountNotFound,
+	domain.ProductNotFoundErr:     dto.ErrProductNotFound,
+	domain.CustomerInactiveErr:    dto.ErrCustomerInactive,
+	domain.CustomerUnderageErr:    dto.ErrCustomerUnderage,
+	domain.AccountLimitReachedErr: dto.ErrAccountLimitReached,
+}
+
+// statusCodes maps a http status to the error code used when no more specific code applies
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            dto.ErrBadRequest,
+	http.StatusUnauthorized:          dto.ErrUnauthorized,
+	http.StatusForbidden:             dto.ErrForbidden,
+	http.StatusNotFound:              dto.ErrNotFound,
+	http.StatusMethodNotAllowed:      dto.ErrMethodNotAllowed,
+	http.StatusConflict:              dto.ErrConflict,
+	http.StatusRequestEntityTooLarge: dto.ErrBodyTooLarge,
+	http.StatusUnsupportedMediaType:  dto.ErrUnsupportedMedia,
+	http.StatusUnprocessableEntity:   dto.ErrValidationFailed,
+	http.StatusTooManyRequests:       dto.ErrRateLimited,
+	http.StatusInternalServerError:   dto.ErrInternal,
+	http.StatusServiceUnavailable:    dto.ErrServiceUnavailable,
+	http.StatusGatewayTimeout:        dto.ErrTimeout,
+}
+
+// errorCode() returns the machine-readable error code for appErr
+func errorCode(appErr *errs.AppError) string {
+	if code, ok := errorCodes[appErr]; ok {
+		return code
+	}
+	if code, ok := statusCodes[appErr.Code]; ok {
+		return code
+	}
+	// any other 4xx is the client's error, anything else is ours
+	if appErr.Code >= 400 && appErr.Code < 500 {
+		return dto.ErrBadRequest
+	}
+	return dto.ErrInternal
+}
+
+// writeError() writes appErr as a dto.ErrorResponse - every handler and middleware reports errors through writeError() so
+// that clients always receive the same shape, including the request ID to quote when contacting support
+func writeError(w http.ResponseWriter, r *http.Request, appErr *errs.AppError) {
+	writeErrorFields(w, r, appErr, nil)
+}
+
+// writeErrorFields() is writeError() with a list of field-level problems attached
+func writeErrorFields(w http.ResponseWriter, r *http.Request, appErr *errs.AppError, fields []dto.FieldError) {
+	// a database or auth call cut short by the request deadline surfaces as an unexpected error - the client is told the
+	// request timed out instead
+	if appErr.Code == http.StatusInternalServerError && errors.Is(r.Context().Err(), context.DeadlineExceeded) {
+		appErr = &errs.AppError{Code: http.StatusGatewayTimeout, Message: "request timed out"}
+	}
+	writeResponse(w, appErr.Code, dto.ErrorResponse{
+		Code:      errorCode(appErr),
+		Message:   appErr.Message,
+		Fields:    fields,
+		RequestID: logging.RequestID(r.Context()),
+	})
+}
+
+// badRequestErr() returns an AppError for a request which could not be read (e.g. malformed json) - errs does not provide
+// a constructor for http.StatusBadRequest
+func badRequestErr(message string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusBadRequest, Message: message}
+}
+
+// notFoundHandler() and methodNotAllowedHandler() replace mux's plain text responses for unmatched requests
+func notFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, http.StatusNotFound, dto.ErrorResponse{
+			Code:      dto.ErrRouteNotFound,
+			Message:   "no route matches " + r.URL.Path,
+			RequestID: logging.RequestID(r.Context()),
+		})
+	})
+}
+
+func methodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, r, &errs.AppError{Code: http.StatusMethodNotAllowed, Message: "method " + r.Method + " is not allowed on " + r.URL.Path})
+	})
+}
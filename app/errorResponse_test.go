@@ -0,0 +1,68 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+)
+
+// TestErrorCode() should pass when specific errors map to their own code and everything else - including another error
+// with the same message as a specific one - falls back to the code for its http status
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		appErr *errs.AppError
+		want   string
+	}{
+		{dto.InsufficientFundsErr, dto.ErrInsufficientFunds},
+		{domain.CustomerNotFoundErr, dto.ErrCustomerNotFound},
+		{domain.AccountNotFoundErr, dto.ErrAccountNotFound},
+		{domain.ProductNotFoundErr, dto.ErrProductNotFound},
+		{errs.NotFoundErr(domain.MsgCustomerNotFound), dto.ErrNotFound},
+		{errs.ValidationErr("error: transaction type must be either withdrawal or deposit"), dto.ErrValidationFailed},
+		{errs.AuthorizationErr("missing token"), dto.ErrUnauthorized},
+		{errs.UnexpectedErr("unexpected database error"), dto.ErrInternal},
+		{domain.CustomerInactiveErr, dto.ErrCustomerInactive},
+		{domain.CustomerUnderageErr, dto.ErrCustomerUnderage},
+		{domain.AccountLimitReachedErr, dto.ErrAccountLimitReached},
+		{errs.ValidationErr(dto.MsgInsufficientFunds), dto.ErrValidationFailed},
+		{&errs.AppError{Code: http.StatusConflict, Message: "customer is already active"}, dto.ErrConflict},
+		{&errs.AppError{Code: http.StatusTooManyRequests, Message: "rate limit exceeded"}, dto.ErrRateLimited},
+		{&errs.AppError{Code: http.StatusTeapot, Message: "teapot"}, dto.ErrBadRequest},
+	}
+	for _, test := range tests {
+		if got := errorCode(test.appErr); got != test.want {
+			t.Errorf("errorCode(%q) = %s, want %s", test.appErr.Message, got, test.want)
+		}
+	}
+}
+
+// TestWriteError() should pass when the error response carries the status, code, message and request ID
+func TestWriteError(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	req, _ := http.NewRequest(http.MethodGet, "/customers/9", nil)
+	req = req.WithContext(logging.NewContext(req.Context(), "req-1"))
+	recorder := httptest.NewRecorder()
+
+	// Act - execute test
+	writeError(recorder, req, domain.CustomerNotFoundErr)
+
+	// Assert - test expectations
+	if recorder.Code != http.StatusNotFound {
+		t.Error("fail while testing the status code 404")
+	}
+	var resp dto.ErrorResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	want := dto.ErrorResponse{Code: dto.ErrCustomerNotFound, Message: domain.MsgCustomerNotFound, RequestID: "req-1"}
+	if resp.Code != want.Code || resp.Message != want.Message || resp.RequestID != want.RequestID {
+		t.Errorf("received %+v, want %+v", resp, want)
+	}
+}
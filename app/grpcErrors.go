@@ -0,0 +1,66 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcErrorDomain is the domain of the ErrorInfo detail attached to every gRPC error
+const grpcErrorDomain = "banking-ms"
+
+// grpcCodes maps the error codes of the json error envelope to gRPC status codes - an error code not listed here falls
+// back to InvalidArgument for a client error and Internal for anything else (see grpcCode())
+var grpcCodes = map[string]codes.Code{
+	dto.ErrBadRequest:          codes.InvalidArgument,
+	dto.ErrValidationFailed:    codes.InvalidArgument,
+	dto.ErrInsufficientFunds:   codes.FailedPrecondition,
+	dto.ErrCustomerInactive:    codes.FailedPrecondition,
+	dto.ErrCustomerUnderage:    codes.FailedPrecondition,
+	dto.ErrAccountLimitReached: codes.FailedPrecondition,
+	dto.ErrConflict:            codes.FailedPrecondition,
+	dto.ErrUnauthorized:        codes.Unauthenticated,
+	dto.ErrForbidden:           codes.PermissionDenied,
+	dto.ErrNotFound:            codes.NotFound,
+	dto.ErrCustomerNotFound:    codes.NotFound,
+	dto.ErrAccountNotFound:     codes.NotFound,
+	dto.ErrProductNotFound:     codes.NotFound,
+	dto.ErrRateLimited:         codes.ResourceExhausted,
+	dto.ErrTimeout:             codes.DeadlineExceeded,
+	dto.ErrInternal:            codes.Internal,
+	dto.ErrServiceUnavailable:  codes.Unavailable,
+}
+
+// grpcCode() returns the gRPC status code for appErr
+func grpcCode(appErr *errs.AppError) codes.Code {
+	if code, ok := grpcCodes[errorCode(appErr)]; ok {
+		return code
+	}
+	if appErr.Code >= 400 && appErr.Code < 500 {
+		return codes.InvalidArgument
+	}
+	return codes.Internal
+}
+
+// grpcError() converts appErr to a gRPC status error - the error code of the json envelope (e.g. INSUFFICIENT_FUNDS) is
+// attached as the reason of an ErrorInfo detail so that gRPC clients can tell apart errors which share a status code
+func grpcError(ctx context.Context, appErr *errs.AppError) error {
+	// as with writeErrorFields(), an unexpected error caused by the request deadline is reported as a timeout
+	if appErr.Code == http.StatusInternalServerError && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		appErr = &errs.AppError{Code: http.StatusGatewayTimeout, Message: "request timed out"}
+	}
+	st := status.New(grpcCode(appErr), appErr.Message)
+	withDetails, err := st.WithDetails(&errdetails.ErrorInfo{Reason: errorCode(appErr), Domain: grpcErrorDomain})
+	if err != nil {
+		// WithDetails() only fails if the detail cannot be marshalled - the status is still usable without it
+		return st.Err()
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,297 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// rpcName() returns the method name of a full gRPC method (e.g. /banking.v1.CustomerService/GetCustomer becomes
+// GetCustomer) - the rpcs are named after the http route names so that the auth server's permissions apply to both
+func rpcName(fullMethod string) string {
+	return path.Base(fullMethod)
+}
+
+// GRPCTracingInterceptor starts a server span for every rpc - the gRPC counterpart of TracingMiddleware
+type GRPCTracingInterceptor struct{}
+
+func (tracingInt GRPCTracingInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// continue the caller's trace if the rpc's metadata carries a traceparent
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = tracing.ExtractHeaders(ctx, metadataCarrier(md))
+	ctx, span := tracing.Start(ctx, rpcName(info.FullMethod),
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(tracing.Attr("rpc.system", "grpc"), tracing.Attr("rpc.method", info.FullMethod)),
+	)
+	defer span.End()
+
+	resp, err := handler(ctx, req)
+	code := status.Code(err)
+	span.SetAttributes(tracing.Attr("rpc.grpc.status_code", code.String()))
+	// as with http, only server errors mark the span as failed
+	if code == codes.Internal || code == codes.Unknown || code == codes.Unavailable || code == codes.DeadlineExceeded {
+		span.SetStatus(otelcodes.Error, code.String())
+	}
+	return resp, err
+}
+
+// GRPCLoggingInterceptor assigns each rpc a correlation ID and writes one structured log line per rpc - the gRPC
+// counterpart of LoggingMiddleware, using the x-request-id metadata key in place of the X-Request-ID header
+type GRPCLoggingInterceptor struct{}
+
+func (loggingInt GRPCLoggingInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(logging.RequestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if !logging.ValidRequestID(requestID) {
+		requestID = logging.NewRequestID()
+	}
+	// the request ID is returned in the response header metadata - SetHeader() only fails once the headers have been sent
+	_ = grpc.SetHeader(ctx, metadata.Pairs(logging.RequestIDHeader, requestID))
+	ctx = logging.NewContext(ctx, requestID)
+
+	resp, err := handler(ctx, req)
+
+	fields := []zap.Field{
+		zap.String("rpc", info.FullMethod),
+		zap.String("route", rpcName(info.FullMethod)),
+		zap.String("code", status.Code(err).String()),
+		zap.Int64("latency_ms", time.Since(start).Milliseconds()),
+	}
+	if subject := logging.Subject(ctx); subject != "" {
+		fields = append(fields, zap.String("subject", subject))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+		fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+	}
+	logging.Info(ctx, "rpc served", fields...)
+	return resp, err
+}
+
+// GRPCLimitsInterceptor places the request timeout on every rpc - the gRPC counterpart of LimitsMiddleware (the message
+// size limit is set on the server itself with grpc.MaxRecvMsgSize())
+type GRPCLimitsInterceptor struct {
+	timeout time.Duration
+}
+
+func (limitsInt GRPCLimitsInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	// a shorter deadline sent by the client still applies as WithTimeout() never extends the parent's deadline
+	ctx, cancel := context.WithTimeout(ctx, limitsInt.timeout)
+	defer cancel()
+	return handler(ctx, req)
+}
+
+// GRPCAuthInterceptor authorizes every rpc through the same AuthRepository as AuthMiddleware - the bearer token is read
+// from the authorization metadata key or, when the rpc carries an x-api-key metadata key instead, the API key is checked
+// by keys - the rpc's method name stands in for the route name and the request's customer_id (if it has one) stands in
+// for the route variables - an rpc with a missing or invalid credential is counted against the auth failure rate limit of
+// its IP address by failures, when rate limiting is enabled
+type GRPCAuthInterceptor struct {
+	repo     domain.AuthRepository
+	keys     domain.AuthRepository
+	failures *RateLimitMiddleware
+}
+
+// customerIDGetter is implemented by every request message which carries a customer_id
+type customerIDGetter interface {
+	GetCustomerId() string
+}
+
+func (authInt GRPCAuthInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	routeName := rpcName(info.FullMethod)
+	routeVars := map[string]string{}
+	if getter, ok := req.(customerIDGetter); ok {
+		routeVars["customer_id"] = getter.GetCustomerId()
+	}
+	// as with http, service-to-service clients send an API key rather than a token
+	if apiKey := metadataCarrier(md).Get("x-api-key"); apiKey != "" && authInt.keys != nil {
+		keyID, _ := domain.APIKeyID(apiKey)
+		return authInt.authorize(ctx, req, handler, authInt.keys, apiKey, routeName, routeVars, "apikey:"+keyID)
+	}
+	authHeader := metadataCarrier(md).Get("authorization")
+	if authHeader == "" {
+		return nil, authInt.refuse(ctx, routeName, status.Error(codes.Unauthenticated, "missing token"), true)
+	}
+	token := getTokenFromHeader(authHeader)
+	return authInt.authorize(ctx, req, handler, authInt.repo, token, routeName, routeVars, tokenSubject(token))
+}
+
+// authorize() asks repo whether credential may call routeName and, if so, records subject for the rpc log line and
+// passes the rpc on to handler
+func (authInt GRPCAuthInterceptor) authorize(ctx context.Context, req interface{}, handler grpc.UnaryHandler,
+	repo domain.AuthRepository, credential string, routeName string, routeVars map[string]string, subject string) (interface{}, error) {
+	authCtx, span := tracing.Start(ctx, "GRPCAuthInterceptor.authorize")
+	isAuthorized := repo.IsAuthorized(authCtx, credential, routeName, routeVars)
+	span.End()
+	if !isAuthorized {
+		// as with http, only an invalid credential is a failed authentication
+		verifier, canVerify := repo.(domain.CredentialVerifier)
+		invalid := canVerify && !verifier.IsValid(ctx, credential)
+		return nil, authInt.refuse(ctx, routeName, status.Error(codes.PermissionDenied, "Unauthorized"), invalid)
+	}
+	logging.SetSubject(ctx, subject)
+	return handler(ctx, req)
+}
+
+// refuse() returns err - an rpc with a missing or invalid credential first takes a token from the auth failure bucket of
+// its IP address, and is refused with ResourceExhausted instead once that is empty
+func (authInt GRPCAuthInterceptor) refuse(ctx context.Context, routeName string, err error, credentialFailed bool) error {
+	if credentialFailed && authInt.failures != nil {
+		if decision, allowed := authInt.failures.takeAuthFailure(ctx, grpcClientIP(ctx, *authInt.failures)); !allowed {
+			return grpcRateLimited(ctx, routeName, decision)
+		}
+	}
+	return err
+}
+
+// GRPCRateLimitInterceptor takes a token from the bucket of the caller and rpc - the gRPC counterpart of
+// RateLimitMiddleware, registered after GRPCAuthInterceptor so that an authenticated caller is limited by its subject - it
+// shares the store and route names of the http routes, so a client's bucket for a route is shared by http and gRPC
+type GRPCRateLimitInterceptor struct {
+	rateMid RateLimitMiddleware
+}
+
+func (rateInt GRPCRateLimitInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	routeName := rpcName(info.FullMethod)
+	if decision, allowed := rateInt.rateMid.take(ctx, routeName, grpcClientIP(ctx, rateInt.rateMid)); !allowed {
+		return nil, grpcRateLimited(ctx, routeName, decision)
+	}
+	return handler(ctx, req)
+}
+
+// grpcRateLimited() returns the ResourceExhausted error of an rpc refused by decision - the retry-after metadata key of
+// the response header stands in for the Retry-After header
+func grpcRateLimited(ctx context.Context, routeName string, decision ratelimit.Decision) error {
+	metrics.RateLimited(routeName)
+	_ = grpc.SetHeader(ctx, metadata.Pairs("retry-after", retryAfterSeconds(decision)))
+	return grpcError(ctx, &errs.AppError{Code: http.StatusTooManyRequests, Message: "rate limit exceeded"})
+}
+
+// grpcClientIP() returns the IP address the rpc of ctx came from, as RateLimitMiddleware.clientIP() does for http - the
+// x-forwarded-for metadata key stands in for the X-Forwarded-For header
+func grpcClientIP(ctx context.Context, rateMid RateLimitMiddleware) string {
+	remoteAddr := ""
+	if caller, ok := peer.FromContext(ctx); ok {
+		remoteAddr = caller.Addr.String()
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	return rateMid.remoteIP(remoteAddr, md.Get("x-forwarded-for"))
+}
+
+// GRPCAuditInterceptor appends an entry to the audit log for every mutating rpc (any rpc not named Get...) - the gRPC
+// counterpart of AuditMiddleware, registered ahead of GRPCAuthInterceptor so that refused rpcs are audited too
+type GRPCAuditInterceptor struct {
+	repo domain.AuditRepository
+}
+
+// accountIDGetter and transactionIDGetter are implemented by the responses of the rpcs which create an account or a
+// transaction
+type accountIDGetter interface {
+	GetAccountId() string
+}
+
+type transactionIDGetter interface {
+	GetTransactionId() string
+}
+
+func (auditInt GRPCAuditInterceptor) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	action := rpcName(info.FullMethod)
+	if strings.HasPrefix(action, "Get") {
+		return handler(ctx, req)
+	}
+	resp, err := handler(ctx, req)
+
+	targets := map[string]string{}
+	if getter, ok := req.(customerIDGetter); ok {
+		targets["customer_id"] = getter.GetCustomerId()
+	}
+	if getter, ok := resp.(accountIDGetter); ok && err == nil {
+		targets["account_id"] = getter.GetAccountId()
+	}
+	if getter, ok := resp.(transactionIDGetter); ok && err == nil {
+		targets["transaction_id"] = getter.GetTransactionId()
+	}
+	var body []byte
+	if message, ok := req.(proto.Message); ok {
+		// the summary uses the field names of the .proto file, as the http summary uses those of the json body
+		body, _ = protojson.MarshalOptions{UseProtoNames: true}.Marshal(message)
+	}
+	actor := logging.Subject(ctx)
+	if actor == "" {
+		actor = auditAnonymous
+	}
+	code := status.Code(err)
+	entry := domain.AuditEntry{
+		OccurredAt: time.Now().Format("2006-01-02 15:04:05"),
+		Actor:      actor,
+		Action:     action,
+		Targets:    domain.AuditTargets(targets),
+		Summary:    auditSummary("rpc "+info.FullMethod, body, int64(len(body))),
+		Outcome:    grpcAuditOutcome(code),
+		Status:     code.String(),
+		RequestID:  logging.RequestID(ctx),
+	}
+	AuditMiddleware{repo: auditInt.repo}.append(ctx, entry)
+	return resp, err
+}
+
+// grpcAuditOutcome() is domain.AuditOutcome() for gRPC status codes
+func grpcAuditOutcome(code codes.Code) string {
+	switch code {
+	case codes.OK:
+		return domain.AuditSuccess
+	case codes.Unauthenticated, codes.PermissionDenied, codes.ResourceExhausted:
+		return domain.AuditDenied
+	default:
+		return domain.AuditFailure
+	}
+}
+
+// metadataCarrier adapts gRPC metadata to the propagation.TextMapCarrier interface used by tracing.ExtractHeaders()
+type metadataCarrier metadata.MD
+
+func (carrier metadataCarrier) Get(key string) string {
+	if values := metadata.MD(carrier).Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func (carrier metadataCarrier) Set(key string, value string) {
+	metadata.MD(carrier).Set(key, value)
+}
+
+func (carrier metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(carrier))
+	for key := range carrier {
+		// metadata keys are always lowercase
+		keys = append(keys, key)
+	}
+	return keys
+}
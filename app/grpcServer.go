@@ -0,0 +1,171 @@
+package app
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/pb"
+	"github.com/gtaylor314/Banking-MS/service"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCServer() builds the gRPC server on top of the same services (and repositories) as the http router - the
+// interceptors play the part of the http middleware: tracing and logging first, then the request timeout, audit, auth and
+// rate limiting
+func newGRPCServer(cfg *config.Config, repos repositories) *grpc.Server {
+	interceptors := []grpc.UnaryServerInterceptor{
+		GRPCTracingInterceptor{}.intercept,
+		GRPCLoggingInterceptor{}.intercept,
+		GRPCLimitsInterceptor{timeout: cfg.Limits.RequestTimeout.Std()}.intercept,
+		GRPCAuditInterceptor{repo: repos.audit}.intercept,
+	}
+	// the rate limiting interceptor takes its tokens from the store of the http routes
+	rateMid := newRateLimitMiddleware(repos.rateLimits, cfg.RateLimits)
+	// auth mode "none" skips authorization entirely - it is only meant for local development
+	if repos.auth != nil {
+		authMid := authMiddleware(repos)
+		authInt := GRPCAuthInterceptor{repo: authMid.repo, keys: authMid.keys}
+		if cfg.RateLimits.Enabled {
+			authInt.failures = &rateMid
+		}
+		interceptors = append(interceptors, authInt.intercept)
+	}
+	if cfg.RateLimits.Enabled {
+		interceptors = append(interceptors, GRPCRateLimitInterceptor{rateMid: rateMid}.intercept)
+	}
+	grpcServer := grpc.NewServer(grpc.ChainUnaryInterceptor(interceptors...), grpc.MaxRecvMsgSize(int(cfg.Limits.MaxBodyBytes)))
+
+	pb.RegisterCustomerServiceServer(grpcServer, CustomerGRPCServer{service: service.NewCustomerService(repos.customers)})
+	pb.RegisterAccountServiceServer(grpcServer, AccountGRPCServer{
+		service: service.NewAccountService(repos.accounts, repos.customers, repos.products, accountPolicy(cfg)),
+		enabled: cfg.Features.AccountCreation,
+	})
+	pb.RegisterTransactionServiceServer(grpcServer, TransactionGRPCServer{
+		service: service.NewTransactionService(repos.transactions, repos.customers),
+		enabled: cfg.Features.Transactions,
+	})
+	return grpcServer
+}
+
+// CustomerGRPCServer serves the CustomerService rpcs through service.CustomerService
+type CustomerGRPCServer struct {
+	pb.UnimplementedCustomerServiceServer
+	service service.CustomerService
+}
+
+func (custServer CustomerGRPCServer) GetAllCustomers(ctx context.Context, req *pb.GetAllCustomersRequest) (*pb.GetAllCustomersResponse, error) {
+	search := dto.CustomerSearchRequest{
+		Status:     req.GetStatus(),
+		NamePrefix: req.GetName(),
+		City:       req.GetCity(),
+		Zipcode:    req.GetZipcode(),
+		BornAfter:  req.GetBornAfter(),
+		BornBefore: req.GetBornBefore(),
+		Sort:       req.GetSort(),
+		Cursor:     req.GetCursor(),
+	}
+	// a limit of zero (not set) leaves the default in place
+	if req.GetLimit() != 0 {
+		search.Limit = strconv.Itoa(int(req.GetLimit()))
+	}
+	page, appErr := custServer.service.GetAllCustomers(ctx, search)
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	resp := &pb.GetAllCustomersResponse{Customers: make([]*pb.CustomerResponse, 0, len(page.Customers)), NextCursor: page.NextCursor}
+	for _, customer := range page.Customers {
+		resp.Customers = append(resp.Customers, customerToPb(customer))
+	}
+	return resp, nil
+}
+
+func (custServer CustomerGRPCServer) GetCustomer(ctx context.Context, req *pb.GetCustomerRequest) (*pb.CustomerResponse, error) {
+	customer, appErr := custServer.service.GetCustomer(ctx, req.GetCustomerId())
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	return customerToPb(*customer), nil
+}
+
+func (custServer CustomerGRPCServer) DeactivateCustomer(ctx context.Context, req *pb.CustomerStatusRequest) (*pb.CustomerResponse, error) {
+	customer, appErr := custServer.service.DeactivateCustomer(ctx, req.GetCustomerId(), dto.CustomerStatusRequest{Reason: req.GetReason()})
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	return customerToPb(*customer), nil
+}
+
+func (custServer CustomerGRPCServer) ReactivateCustomer(ctx context.Context, req *pb.CustomerStatusRequest) (*pb.CustomerResponse, error) {
+	customer, appErr := custServer.service.ReactivateCustomer(ctx, req.GetCustomerId(), dto.CustomerStatusRequest{Reason: req.GetReason()})
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	return customerToPb(*customer), nil
+}
+
+// AccountGRPCServer serves the AccountService rpcs through service.AccountService - enabled follows the account creation
+// feature toggle
+type AccountGRPCServer struct {
+	pb.UnimplementedAccountServiceServer
+	service service.AccountService
+	enabled bool
+}
+
+func (acctServer AccountGRPCServer) NewAccount(ctx context.Context, req *pb.NewAccountRequest) (*pb.NewAccountResponse, error) {
+	if !acctServer.enabled {
+		return nil, status.Error(codes.Unimplemented, "account creation is disabled")
+	}
+	resp, appErr := acctServer.service.NewAccount(ctx, dto.NewAccountRequest{
+		CustomerID:  req.GetCustomerId(),
+		AccountType: req.GetAccountType(),
+		Amount:      req.GetAmount(),
+	})
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	return &pb.NewAccountResponse{AccountId: resp.AccountID}, nil
+}
+
+// TransactionGRPCServer serves the TransactionService rpcs through service.TransactionService - enabled follows the
+// transactions feature toggle
+type TransactionGRPCServer struct {
+	pb.UnimplementedTransactionServiceServer
+	service service.TransactionService
+	enabled bool
+}
+
+func (tranServer TransactionGRPCServer) NewTransaction(ctx context.Context, req *pb.NewTransactionRequest) (*pb.NewTransactionResponse, error) {
+	if !tranServer.enabled {
+		return nil, status.Error(codes.Unimplemented, "transactions are disabled")
+	}
+	resp, appErr := tranServer.service.NewTransaction(ctx, dto.NewTransactionRequest{
+		// the auth interceptor authorized the caller for customer_id - the service refuses an account of another customer
+		CustomerID:      req.GetCustomerId(),
+		AccountID:       req.GetAccountId(),
+		Amount:          req.GetAmount(),
+		TransactionType: req.GetTransactionType(),
+	})
+	if appErr != nil {
+		return nil, grpcError(ctx, appErr)
+	}
+	return &pb.NewTransactionResponse{TransactionId: resp.TransactionID, Balance: resp.AcctAmount}, nil
+}
+
+// customerToPb() maps a dto.CustomerResponse to its protobuf message
+func customerToPb(customer dto.CustomerResponse) *pb.CustomerResponse {
+	return &pb.CustomerResponse{
+		CustomerId:      customer.ID,
+		FullName:        customer.Name,
+		City:            customer.City,
+		Zipcode:         customer.Zipcode,
+		DateOfBirth:     customer.DateofBirth,
+		Status:          customer.Status,
+		StatusReason:    customer.StatusReason,
+		StatusChangedAt: customer.StatusChangedAt,
+	}
+}
@@ -0,0 +1,259 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"reflect"
+	"testing"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/pb"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// newTestGRPCConn() serves the gRPC server, built on the in-memory repository stubs, over an in-memory listener and
+// returns a client connection to it
+func newTestGRPCConn(t *testing.T) *grpc.ClientConn {
+	t.Helper()
+	cfg := config.Default()
+	return serveTestGRPC(t, &cfg, newTestGRPCRepositories())
+}
+
+// newTestGRPCRepositories() returns the in-memory repository stubs the gRPC server of the tests is built on
+func newTestGRPCRepositories() repositories {
+	return repositories{
+		customers:    domain.NewCustomerRepositoryStub(),
+		accounts:     domain.NewAccountRepositoryStub(),
+		transactions: domain.NewTransactionRepositoryStub(),
+		products:     domain.NewAccountProductRepositoryStub(),
+		audit:        domain.NewAuditRepositoryStub(),
+		apiKeys:      domain.NewAPIKeyRepositoryStub(),
+		auth:         stubAuthRepository{},
+		rateLimits:   ratelimit.NewMemoryStore(),
+	}
+}
+
+// serveTestGRPC() serves the gRPC server built on cfg and repos over an in-memory listener and returns a client
+// connection to it
+func serveTestGRPC(t *testing.T, cfg *config.Config, repos repositories) *grpc.ClientConn {
+	t.Helper()
+	grpcServer := newGRPCServer(cfg, repos)
+	listener := bufconn.Listen(1 << 20)
+	go grpcServer.Serve(listener)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// withToken() returns a context carrying token in the authorization metadata
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// TestGRPCCustomerService() should pass when customers are returned for an authorized token and the missing token, denied
+// token and unknown customer errors map to their gRPC status codes
+func TestGRPCCustomerService(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	client := pb.NewCustomerServiceClient(newTestGRPCConn(t))
+
+	// Act - execute test
+	customers, err := client.GetAllCustomers(withToken("valid"), &pb.GetAllCustomersRequest{Status: "active"})
+	// Assert - test expectations
+	if err != nil || len(customers.GetCustomers()) != 3 {
+		t.Errorf("GetAllCustomers() = %v, %v", customers, err)
+	}
+
+	var header metadata.MD
+	customer, err := client.GetCustomer(withToken("valid"), &pb.GetCustomerRequest{CustomerId: "1002"}, grpc.Header(&header))
+	if err != nil || customer.GetFullName() != "Kimberly" || customer.GetStatus() != "active" {
+		t.Errorf("GetCustomer() = %v, %v", customer, err)
+	}
+	if len(header.Get("x-request-id")) != 1 {
+		t.Errorf("expected an x-request-id header, received %v", header)
+	}
+
+	tests := []struct {
+		ctx      context.Context
+		id       string
+		wantCode codes.Code
+	}{
+		{context.Background(), "1001", codes.Unauthenticated},
+		{withToken("denied"), "1001", codes.PermissionDenied},
+		{withToken("valid"), "9999", codes.NotFound},
+	}
+	for _, test := range tests {
+		_, err := client.GetCustomer(test.ctx, &pb.GetCustomerRequest{CustomerId: test.id})
+		if status.Code(err) != test.wantCode {
+			t.Errorf("customer %s: received %v, want %v", test.id, err, test.wantCode)
+		}
+	}
+}
+
+// TestGRPCTransactionService() should pass when a deposit returns the new balance and an overdrawing withdrawal returns
+// FailedPrecondition with the INSUFFICIENT_FUNDS reason
+func TestGRPCTransactionService(t *testing.T) {
+	// Arrange - setup test
+	client := pb.NewTransactionServiceClient(newTestGRPCConn(t))
+
+	// Act - execute test
+	resp, err := client.NewTransaction(withToken("valid"), &pb.NewTransactionRequest{
+		CustomerId: "1001", AccountId: "101", Amount: 50, TransactionType: "deposit",
+	})
+	// Assert - test expectations
+	if err != nil || resp.GetBalance() != 1050 {
+		t.Errorf("NewTransaction() = %v, %v", resp, err)
+	}
+
+	_, err = client.NewTransaction(withToken("valid"), &pb.NewTransactionRequest{
+		CustomerId: "1001", AccountId: "101", Amount: 5000, TransactionType: "withdrawal",
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.FailedPrecondition {
+		t.Fatalf("received %v, want FailedPrecondition", err)
+	}
+	if len(st.Details()) != 1 || st.Details()[0].(*errdetails.ErrorInfo).GetReason() != dto.ErrInsufficientFunds {
+		t.Errorf("unexpected details %v", st.Details())
+	}
+}
+
+// TestGRPCTransactionOtherCustomersAccount() should pass when an account of another customer than the request's
+// customer_id returns NotFound and is left untouched
+func TestGRPCTransactionOtherCustomersAccount(t *testing.T) {
+	// Arrange - setup test
+	client := pb.NewTransactionServiceClient(newTestGRPCConn(t))
+
+	// Act - execute test
+	// account 102 belongs to customer 1002
+	_, mismatchErr := client.NewTransaction(withToken("valid"), &pb.NewTransactionRequest{
+		CustomerId: "1001", AccountId: "102", Amount: 100, TransactionType: "withdrawal",
+	})
+	owner, ownerErr := client.NewTransaction(withToken("valid"), &pb.NewTransactionRequest{
+		CustomerId: "1002", AccountId: "102", Amount: 15000, TransactionType: "withdrawal",
+	})
+
+	// Assert - test expectations
+	if st := status.Convert(mismatchErr); st.Code() != codes.NotFound {
+		t.Errorf("received %v, want NotFound", mismatchErr)
+	}
+	// the whole balance can still be withdrawn, so the refused withdrawal was not posted
+	if ownerErr != nil || owner.GetBalance() != 0 {
+		t.Errorf("NewTransaction() by the owner = %v, %v, want a balance of 0", owner, ownerErr)
+	}
+}
+
+// TestGRPCRateLimit() should pass when a caller which used up its bucket for an rpc is refused with ResourceExhausted
+// and a retry-after header, while its other rpcs are served, and rpcs without a token are refused with ResourceExhausted
+// once their IP address used up its auth failure bucket
+func TestGRPCRateLimit(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	cfg := config.Default()
+	cfg.RateLimits.Routes["GetCustomer"] = config.RateLimit{Rate: 0.001, Burst: 1}
+	cfg.RateLimits.AuthFailures = config.RateLimit{Rate: 0.001, Burst: 2}
+	client := pb.NewCustomerServiceClient(serveTestGRPC(t, &cfg, newTestGRPCRepositories()))
+
+	// Act - execute test
+	_, firstErr := client.GetCustomer(withToken("valid"), &pb.GetCustomerRequest{CustomerId: "1002"})
+	var header metadata.MD
+	_, secondErr := client.GetCustomer(withToken("valid"), &pb.GetCustomerRequest{CustomerId: "1002"}, grpc.Header(&header))
+	_, otherRpcErr := client.GetAllCustomers(withToken("valid"), &pb.GetAllCustomersRequest{})
+	var missingCodes []codes.Code
+	for i := 0; i < 3; i++ {
+		_, err := client.GetAllCustomers(context.Background(), &pb.GetAllCustomersRequest{})
+		missingCodes = append(missingCodes, status.Code(err))
+	}
+
+	// Assert - test expectations
+	if firstErr != nil || otherRpcErr != nil {
+		t.Errorf("received %v and %v, want the first GetCustomer() and GetAllCustomers() served", firstErr, otherRpcErr)
+	}
+	if status.Code(secondErr) != codes.ResourceExhausted || len(header.Get("retry-after")) != 1 || header.Get("retry-after")[0] != "1000" {
+		t.Errorf("received %v with retry-after %v, want ResourceExhausted with retry-after 1000", secondErr, header.Get("retry-after"))
+	}
+	wantMissing := []codes.Code{codes.Unauthenticated, codes.Unauthenticated, codes.ResourceExhausted}
+	if !reflect.DeepEqual(missingCodes, wantMissing) {
+		t.Errorf("received %v for rpcs without a token, want %v", missingCodes, wantMissing)
+	}
+}
+
+// TestGRPCAPIKey() should pass when an API key sent in the x-api-key metadata key may call the rpcs in its scopes only and
+// a key with a wrong secret is refused
+func TestGRPCAPIKey(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	cfg := config.Default()
+	repos := newTestGRPCRepositories()
+	keyID, key, hash, err := domain.NewAPIKeySecret("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repos.apiKeys.Save(context.Background(), domain.APIKey{KeyID: keyID, Name: "statements", Hash: hash, Scopes: "GetCustomer"})
+	client := pb.NewCustomerServiceClient(serveTestGRPC(t, &cfg, repos))
+	withKey := func(key string) context.Context {
+		return metadata.AppendToOutgoingContext(context.Background(), "x-api-key", key)
+	}
+
+	// Act - execute test
+	customer, inScopeErr := client.GetCustomer(withKey(key), &pb.GetCustomerRequest{CustomerId: "1002"})
+	_, outOfScopeErr := client.GetAllCustomers(withKey(key), &pb.GetAllCustomersRequest{})
+	_, wrongSecretErr := client.GetCustomer(withKey(key+"x"), &pb.GetCustomerRequest{CustomerId: "1002"})
+
+	// Assert - test expectations
+	if inScopeErr != nil || customer.GetCustomerId() != "1002" {
+		t.Errorf("GetCustomer() = %v, %v", customer, inScopeErr)
+	}
+	if status.Code(outOfScopeErr) != codes.PermissionDenied || status.Code(wrongSecretErr) != codes.PermissionDenied {
+		t.Errorf("received %v and %v, want PermissionDenied out of scope and for a wrong secret", outOfScopeErr, wrongSecretErr)
+	}
+}
+
+// TestGRPCCode() should pass when every AppError maps to the expected gRPC status code
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		appErr   *errs.AppError
+		wantCode codes.Code
+	}{
+		{errs.ValidationErr("error: account type must be either saving or checking"), codes.InvalidArgument},
+		{dto.InsufficientFundsErr, codes.FailedPrecondition},
+		{domain.CustomerNotFoundErr, codes.NotFound},
+		{errs.AuthorizationErr("missing token"), codes.Unauthenticated},
+		{&errs.AppError{Code: http.StatusForbidden, Message: "Unauthorized"}, codes.PermissionDenied},
+		{domain.CustomerInactiveErr, codes.FailedPrecondition},
+		{&errs.AppError{Code: http.StatusConflict, Message: "customer is already inactive"}, codes.FailedPrecondition},
+		{&errs.AppError{Code: http.StatusTooManyRequests, Message: "rate limit exceeded"}, codes.ResourceExhausted},
+		{&errs.AppError{Code: http.StatusTeapot, Message: "teapot"}, codes.InvalidArgument},
+		{errs.UnexpectedErr("unexpected database error"), codes.Internal},
+		{&errs.AppError{Code: http.StatusGatewayTimeout, Message: "request timed out"}, codes.DeadlineExceeded},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		code := grpcCode(test.appErr)
+
+		// Assert - test expectations
+		if code != test.wantCode {
+			t.Errorf("grpcCode(%d %q) = %v, want %v", test.appErr.Code, test.appErr.Message, code, test.wantCode)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/domain"
+)
+
+// HashPasswords() is the one-off command (cmd/hashpasswords) which replaces every plaintext password left in the users
+// table with its bcrypt hash - it reads the same configuration as Start() and may be run more than once
+func HashPasswords() {
+	cfg := loadConfig()
+	db_conn := getDbConnection(cfg.DB)
+	defer db_conn.Close()
+
+	updated, err := domain.NewUserRepositoryDb(db_conn).HashPlaintextPasswords(context.Background(), cfg.Auth.PasswordHashCost)
+	if err != nil {
+		log.Fatalf("hashing passwords failed after %d users: %v", updated, err)
+	}
+	logger.Info(fmt.Sprintf("%d plaintext passwords hashed", updated))
+}
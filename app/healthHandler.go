@@ -0,0 +1,83 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+
+	"go.uber.org/zap"
+)
+
+// dependencyCheck is a named check run by /readyz - check returns nil when the dependency is usable
+type dependencyCheck struct {
+	name  string
+	check func(context.Context) error
+}
+
+// the errors /readyz reports for a dependency which is down - the error returned by the check is logged instead
+const (
+	dependencyErrUnavailable = "unavailable"
+	dependencyErrTimeout     = "timeout"
+)
+
+// HealthHandler serves the liveness (/healthz) and readiness (/readyz) probes - neither route sits behind the auth
+// middleware so that probes do not need a token
+type HealthHandler struct {
+	checks  []dependencyCheck
+	timeout time.Duration // applied to each dependency check
+}
+
+// liveness() reports that the process is up and serving requests - it does not touch any dependency
+func (healthHandler HealthHandler) liveness(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, http.StatusOK, dto.HealthResponse{Status: "ok"})
+}
+
+// readiness() runs every dependency check concurrently, each with its own timeout, and reports the status of each - the
+// service is only ready (200) when every dependency is up, otherwise 503 is returned so traffic is routed elsewhere
+func (healthHandler HealthHandler) readiness(w http.ResponseWriter, r *http.Request) {
+	resp := dto.HealthResponse{Status: "ready", Checks: make(map[string]dto.DependencyResponse)}
+	// mu guards resp.Checks as the checks write their results concurrently
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	for _, depCheck := range healthHandler.checks {
+		wg.Add(1)
+		go func(depCheck dependencyCheck) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(r.Context(), healthHandler.timeout)
+			defer cancel()
+
+			start := time.Now()
+			err := depCheck.check(ctx)
+			result := dto.DependencyResponse{Status: "up", LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				// /readyz is public, so the error itself (which may name hosts, DSNs or driver details) is only logged
+				logging.Error(r.Context(), "readiness check failed", zap.String("dependency", depCheck.name), zap.Error(err))
+				result.Status = "down"
+				result.Error = dependencyErrUnavailable
+				if errors.Is(err, context.DeadlineExceeded) {
+					result.Error = dependencyErrTimeout
+				}
+			}
+
+			mu.Lock()
+			resp.Checks[depCheck.name] = result
+			mu.Unlock()
+		}(depCheck)
+	}
+	wg.Wait()
+
+	code := http.StatusOK
+	for _, result := range resp.Checks {
+		if result.Status != "up" {
+			resp.Status = "not ready"
+			code = http.StatusServiceUnavailable
+		}
+	}
+	writeResponse(w, code, resp)
+}
@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// TestReadinessAllUp() should pass when every dependency check succeeds and a http.StatusOK code is returned
+func TestReadinessAllUp(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	healthHandler := HealthHandler{
+		checks: []dependencyCheck{
+			{name: "database", check: func(context.Context) error { return nil }},
+			{name: "auth", check: func(context.Context) error { return nil }},
+		},
+		timeout: time.Second,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	recorder := httptest.NewRecorder()
+
+	// Act - execute test
+	healthHandler.readiness(recorder, req)
+
+	// Assert - test expectations
+	if recorder.Code != http.StatusOK {
+		t.Error("fail while testing the status code 200")
+	}
+}
+
+// TestReadinessDependencyDown() should pass when a failing (or timed out) dependency check results in a
+// http.StatusServiceUnavailable code and its status is reported as down without the error of the check
+func TestReadinessDependencyDown(t *testing.T) {
+	// Arrange - setup test
+	healthHandler := HealthHandler{
+		checks: []dependencyCheck{
+			{name: "database", check: func(context.Context) error { return errors.New("dial tcp db.internal:3306: connection refused") }},
+			// the auth check blocks until its context times out
+			{name: "auth", check: func(ctx context.Context) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}},
+		},
+		timeout: 10 * time.Millisecond,
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/readyz", nil)
+	recorder := httptest.NewRecorder()
+
+	// Act - execute test
+	healthHandler.readiness(recorder, req)
+
+	// Assert - test expectations
+	if recorder.Code != http.StatusServiceUnavailable {
+		t.Error("fail while testing the status code 503")
+	}
+	var resp dto.HealthResponse
+	if err := json.NewDecoder(recorder.Body).Decode(&resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Checks["database"].Status != "down" || resp.Checks["auth"].Status != "down" {
+		t.Errorf("expected both dependencies to be down, received %+v", resp.Checks)
+	}
+	if resp.Checks["database"].Error != "unavailable" || resp.Checks["auth"].Error != "timeout" {
+		t.Errorf("expected the errors unavailable and timeout, received %+v", resp.Checks)
+	}
+}
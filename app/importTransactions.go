@@ -0,0 +1,65 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/service"
+
+	"go.uber.org/zap"
+)
+
+// importSubject is recorded as the creator of the imports made by cmd/importtransactions
+const importSubject = "cmd/importtransactions"
+
+// ImportTransactionFile() is the bulk import command (cmd/importtransactions) - it posts the CSV file at path as POST
+// /transaction-imports does, but without the request timeout and body limit, and writes the row by row report to
+// standard output - an interrupt stops the import before its next row, and running the command again with the same
+// file resumes it - the exit status is 1 unless every row was valid (dry run) or posted
+func ImportTransactionFile(path string, dryRun bool) {
+	cfg := loadConfig()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		log.Fatal(err)
+	}
+	db_conn := getDbConnection(cfg.DB)
+	defer db_conn.Close()
+
+	tranRepo := domain.NewTransactionRepositoryDb(db_conn)
+	custRepo := domain.NewCustomerRepositoryDb(db_conn)
+	importService := service.NewTransactionImportService(domain.NewTransactionImportRepositoryDb(db_conn),
+		service.NewTransactionService(tranRepo, custRepo), tranRepo, custRepo, cfg.Imports.MaxRows, cfg.Imports.StaleAfter.Std())
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = logging.NewContext(ctx, logging.NewRequestID())
+	logging.SetSubject(ctx, importSubject)
+
+	resp, appErr := importService.ImportTransactions(ctx, dto.TransactionImportRequest{
+		FileName: filepath.Base(path),
+		Content:  content,
+		DryRun:   dryRun,
+	})
+	if appErr != nil {
+		log.Fatal(appErr.Message)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(resp); err != nil {
+		log.Fatal(err)
+	}
+	logger.Info("transaction import finished", zap.String("status", resp.Status), zap.Int("rows", resp.RowCount),
+		zap.Int("posted", resp.PostedCount), zap.Int("failed", resp.FailedCount))
+	if resp.Status != dto.TransactionImportRowValid && (resp.Status != domain.TransactionImportCompleted || resp.FailedCount > 0) {
+		os.Exit(1)
+	}
+}
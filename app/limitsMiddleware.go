@@ -0,0 +1,31 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// LimitsMiddleware places the configured limits on every incoming request - a deadline which travels with the request's
+// context through the services and repositories (so that database queries and the auth call are cancelled once it passes)
+// and a cap on the size of the request body
+type LimitsMiddleware struct {
+	timeout      time.Duration
+	maxBodyBytes int64
+}
+
+func (limitsMid LimitsMiddleware) limitsHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// WithTimeout() derives a context from the request's context which is cancelled when the timeout elapses, the
+			// client disconnects, or cancel() is called - whichever happens first
+			ctx, cancel := context.WithTimeout(r.Context(), limitsMid.timeout)
+			// cancel() releases the resources held by the context once the request has been served
+			defer cancel()
+			// MaxBytesReader() returns an error to the reader (e.g. the json decoder) once more than maxBodyBytes are read
+			r.Body = http.MaxBytesReader(w, r.Body, limitsMid.maxBodyBytes)
+			// WithContext() returns a shallow copy of the request carrying the new context
+			nextMidware.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package app
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/logging"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// LoggingMiddleware assigns each request a correlation ID and writes one structured log line per request once it has been
+// served - the ID is taken from the X-Request-ID header when the caller sends a valid one, otherwise a new one is generated,
+// and it is returned in the X-Request-ID response header and carried in the request's context for the service and
+// repository log calls
+type LoggingMiddleware struct{}
+
+func (loggingMid LoggingMiddleware) loggingHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			requestID := r.Header.Get(logging.RequestIDHeader)
+			if !logging.ValidRequestID(requestID) {
+				requestID = logging.NewRequestID()
+			}
+			// the header is set before the handler runs as headers cannot be added once the status code is written
+			w.Header().Set(logging.RequestIDHeader, requestID)
+			ctx := logging.NewContext(r.Context(), requestID)
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			nextMidware.ServeHTTP(rec, r.WithContext(ctx))
+
+			routeName := "unknown"
+			if currentRoute := mux.CurrentRoute(r); currentRoute != nil && currentRoute.GetName() != "" {
+				routeName = currentRoute.GetName()
+			}
+			fields := []zap.Field{
+				zap.String("method", r.Method),
+				zap.String("route", routeName),
+				zap.String("path", r.URL.Path),
+				zap.Int("status", rec.status),
+				zap.Int64("latency_ms", time.Since(start).Milliseconds()),
+			}
+			// the subject is recorded by the auth middleware once the token has been authorized
+			if subject := logging.Subject(ctx); subject != "" {
+				fields = append(fields, zap.String("subject", subject))
+			}
+			// the trace ID ties the log line to the request's trace when tracing is enabled
+			if spanContext := trace.SpanContextFromContext(ctx); spanContext.HasTraceID() {
+				fields = append(fields, zap.String("trace_id", spanContext.TraceID().String()))
+			}
+			logging.Info(ctx, "request served", fields...)
+		})
+	}
+}
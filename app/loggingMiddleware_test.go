@@ -0,0 +1,50 @@
+package app
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/logging"
+)
+
+// TestLoggingMiddlewareRequestID() should pass when a valid X-Request-ID is reused and made available to the handler's
+// context, and an invalid one is replaced with a generated ID
+func TestLoggingMiddlewareRequestID(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	var handlerRequestID string
+	loggingRouter := mux.NewRouter()
+	loggingRouter.HandleFunc("/customers", func(w http.ResponseWriter, r *http.Request) {
+		handlerRequestID = logging.RequestID(r.Context())
+	}).Name("GetAllCustomers")
+	loggingRouter.Use(LoggingMiddleware{}.loggingHandler())
+
+	tests := []struct {
+		sent      string
+		wantReuse bool
+	}{
+		{sent: "abc-123", wantReuse: true},
+		{sent: "bad id\r\ninjected: header", wantReuse: false},
+		{sent: "", wantReuse: false},
+	}
+
+	for _, test := range tests {
+		req, _ := http.NewRequest(http.MethodGet, "/customers", nil)
+		req.Header.Set(logging.RequestIDHeader, test.sent)
+		recorder := httptest.NewRecorder()
+
+		// Act - execute test
+		loggingRouter.ServeHTTP(recorder, req)
+
+		// Assert - test expectations
+		returned := recorder.Header().Get(logging.RequestIDHeader)
+		if returned == "" || returned != handlerRequestID {
+			t.Errorf("sent %q: response header %q does not match the handler's request ID %q", test.sent, returned, handlerRequestID)
+		}
+		if test.wantReuse != (returned == test.sent) {
+			t.Errorf("sent %q: unexpected request ID %q", test.sent, returned)
+		}
+	}
+}
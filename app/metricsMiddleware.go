@@ -0,0 +1,44 @@
+package app
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/metrics"
+)
+
+// statusRecorder wraps a http.ResponseWriter to remember the status code written by the handler (or a middleware further
+// down the chain) so that it can be reported once the request has been served
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (rec *statusRecorder) WriteHeader(code int) {
+	rec.status = code
+	rec.ResponseWriter.WriteHeader(code)
+}
+
+// MetricsMiddleware records the count and latency of every request by mux route name, method, and status code
+type MetricsMiddleware struct{}
+
+func (metricsMid MetricsMiddleware) metricsHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			// the status defaults to 200 as a handler which never calls WriteHeader() implicitly responds with 200
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			nextMidware.ServeHTTP(rec, r)
+
+			// the route name (e.g. GetAllCustomers) is used rather than the path so that customer IDs don't create a new
+			// series per customer
+			routeName := "unknown"
+			if currentRoute := mux.CurrentRoute(r); currentRoute != nil && currentRoute.GetName() != "" {
+				routeName = currentRoute.GetName()
+			}
+			metrics.ObserveHTTPRequest(routeName, r.Method, strconv.Itoa(rec.status), time.Since(start))
+		})
+	}
+}
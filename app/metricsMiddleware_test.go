@@ -0,0 +1,64 @@
+package app
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/metrics"
+)
+
+// scrapeMetrics() returns the metrics exposed on /metrics in the Prometheus text format
+func scrapeMetrics(t *testing.T) string {
+	t.Helper()
+	recorder := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	body, err := io.ReadAll(recorder.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(body)
+}
+
+// TestMetricsMiddleware() should pass when every request served is counted and timed under its mux route name, method
+// and status code - including the implicit 200 of a handler which never calls WriteHeader()
+func TestMetricsMiddleware(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	// the route names are only used by this test, so the series start from zero whatever other tests served
+	metricsRouter := mux.NewRouter()
+	metricsRouter.HandleFunc("/metrics-test/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusCreated)
+	}).Methods(http.MethodPost).Name("MetricsTestCreate")
+	metricsRouter.HandleFunc("/metrics-test", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}).Methods(http.MethodGet).Name("MetricsTestList")
+	metricsRouter.Use(MetricsMiddleware{}.metricsHandler())
+
+	// Act - execute test
+	for _, path := range []string{"/metrics-test/1", "/metrics-test/2"} {
+		metricsRouter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, path, nil))
+	}
+	metricsRouter.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test", nil))
+	exposed := scrapeMetrics(t)
+
+	// Assert - test expectations
+	// both ids share the series of their route, so the customer IDs of real routes do not create a series each
+	want := []string{
+		`banking_http_requests_total{method="POST",route="MetricsTestCreate",status="201"} 2`,
+		`banking_http_request_duration_seconds_count{method="POST",route="MetricsTestCreate",status="201"} 2`,
+		`banking_http_requests_total{method="GET",route="MetricsTestList",status="200"} 1`,
+		`banking_http_request_duration_seconds_count{method="GET",route="MetricsTestList",status="200"} 1`,
+	}
+	for _, line := range want {
+		if !strings.Contains(exposed, line+"\n") {
+			t.Errorf("expected /metrics to expose %s", line)
+		}
+	}
+	if strings.Contains(exposed, "/metrics-test") {
+		t.Error("expected the series to be labelled by route name rather than path")
+	}
+}
@@ -0,0 +1,14 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/openapi"
+)
+
+// openAPISpec() serves the embedded OpenAPI document as is - it is public and sits outside the auth middleware
+func openAPISpec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Add("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openapi.Spec)
+}
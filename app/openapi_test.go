@@ -0,0 +1,311 @@
+package app
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers/gorillamux"
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/openapi"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+)
+
+// stubAuthRepository authorizes every token except "denied"
+type stubAuthRepository struct{}
+
+func (stubAuthRepository) IsAuthorized(ctx context.Context, token string, routeName string, vars map[string]string) bool {
+	return token != "denied"
+}
+
+// loadSpec() loads and validates the embedded OpenAPI document
+func loadSpec(t *testing.T) *openapi3.T {
+	t.Helper()
+	doc, err := openapi3.NewLoader().LoadFromData(openapi.Spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		t.Fatalf("openapi.json is not a valid OpenAPI document: %v", err)
+	}
+	return doc
+}
+
+// testTokenSecret signs the tokens issued by the login route of the test router
+const testTokenSecret = "0123456789abcdef0123456789abcdef"
+
+// newTestRouter() builds the full router on top of the in-memory repository stubs - local auth mode registers the login
+// routes while stubAuthRepository keeps the "valid" and "denied" tokens working for every other route
+func newTestRouter() *mux.Router {
+	cfg := config.Default()
+	cfg.Auth.Mode = config.AuthModeLocal
+	cfg.Auth.TokenSecret = testTokenSecret
+	repos := repositories{
+		customers:    domain.NewCustomerRepositoryStub(),
+		accounts:     domain.NewAccountRepositoryStub(),
+		transactions: domain.NewTransactionRepositoryStub(),
+		products:     domain.NewAccountProductRepositoryStub(),
+		audit:        domain.NewAuditRepositoryStub(),
+		users:        domain.NewUserRepositoryStub(),
+		apiKeys:      domain.NewAPIKeyRepositoryStub(),
+		webhooks:     domain.NewWebhookRepositoryStub(),
+		imports:      domain.NewTransactionImportRepositoryStub(),
+		rateLimits:   ratelimit.NewMemoryStore(),
+		auth:         stubAuthRepository{},
+	}
+	checks := []dependencyCheck{{name: "database", check: func(context.Context) error { return nil }}}
+	return newRouter(&cfg, repos, checks)
+}
+
+// TestOpenAPIConformance() should pass when every response returned by the router - successes and errors alike - has a
+// status code and body documented in openapi.json
+func TestOpenAPIConformance(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	doc := loadSpec(t)
+	specRouter, err := gorillamux.NewRouter(doc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := newTestRouter()
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		token       string
+		contentType string
+		body        string
+		wantStatus  int
+	}{
+		{"all customers", http.MethodGet, "/customers", "valid", "", "", http.StatusOK},
+		{"active customers", http.MethodGet, "/customers?status=active", "valid", "", "", http.StatusOK},
+		{"search customers", http.MethodGet, "/customers?name=k&city=seattle&born_after=1990-01-01&sort=-date_of_birth&limit=1", "valid", "", "", http.StatusOK},
+		{"invalid filters", http.MethodGet, "/customers?status=closed&limit=1000", "valid", "", "", http.StatusBadRequest},
+		{"customer", http.MethodGet, "/customers/1001", "valid", "", "", http.StatusOK},
+		{"customer not found", http.MethodGet, "/customers/9999", "valid", "", "", http.StatusNotFound},
+		{"missing token", http.MethodGet, "/customers", "", "", "", http.StatusUnauthorized},
+		{"forbidden", http.MethodGet, "/customers/1001", "denied", "", "", http.StatusForbidden},
+		{"new account", http.MethodPost, "/customers/1001/account", "valid", "application/json", `{"account_type": "saving", "amount": 6000}`, http.StatusCreated},
+		{"new account invalid", http.MethodPost, "/customers/1001/account", "valid", "application/json", `{"account_type": "loan", "amount": 10}`, http.StatusUnprocessableEntity},
+		{"new account malformed", http.MethodPost, "/customers/1001/account", "valid", "application/json", `{"account_type": `, http.StatusBadRequest},
+		{"new account wrong media type", http.MethodPost, "/customers/1001/account", "valid", "text/plain", `{}`, http.StatusUnsupportedMediaType},
+		{"new account too large", http.MethodPost, "/customers/1001/account", "valid", "application/json", `{"account_type": "` + strings.Repeat("a", 2<<20) + `"}`, http.StatusRequestEntityTooLarge},
+		{"deposit", http.MethodPost, "/customers/1001/transaction", "valid", "application/json", `{"account_id": "101", "amount": 50, "transaction_type": "deposit"}`, http.StatusCreated},
+		{"insufficient funds", http.MethodPost, "/customers/1001/transaction", "valid", "application/json", `{"account_id": "101", "amount": 50000, "transaction_type": "withdrawal"}`, http.StatusUnprocessableEntity},
+		{"account not found", http.MethodPost, "/customers/1001/transaction", "valid", "application/json", `{"account_id": "999", "amount": 50, "transaction_type": "deposit"}`, http.StatusNotFound},
+		{"another customer's account", http.MethodPost, "/v1/customers/1001/transaction", "valid", "application/json", `{"account_id": "102", "amount": 50, "transaction_type": "deposit"}`, http.StatusNotFound},
+		{"v1 customer", http.MethodGet, "/v1/customers/1001", "valid", "", "", http.StatusOK},
+		{"v1 customer not found", http.MethodGet, "/v1/customers/9999", "valid", "", "", http.StatusNotFound},
+		{"v1 new account", http.MethodPost, "/v1/customers/1001/account", "valid", "application/json", `{"account_type": "checking", "amount": 5000}`, http.StatusCreated},
+		{"v1 deposit", http.MethodPost, "/v1/customers/1002/transaction", "valid", "application/json", `{"account_id": "102", "amount": 25.5, "transaction_type": "deposit"}`, http.StatusCreated},
+		{"v2 customers", http.MethodGet, "/v2/customers?status=inactive", "valid", "", "", http.StatusOK},
+		{"v2 customer page", http.MethodGet, "/v2/customers?limit=1", "valid", "", "", http.StatusOK},
+		{"v2 invalid cursor", http.MethodGet, "/v2/customers?cursor=abc", "valid", "", "", http.StatusBadRequest},
+		{"v2 missing token", http.MethodGet, "/v2/customers", "", "", "", http.StatusUnauthorized},
+		{"v2 deposit", http.MethodPost, "/v2/customers/1002/transaction", "valid", "application/json", `{"account_id": "103", "amount": 0.1, "transaction_type": "deposit"}`, http.StatusCreated},
+		{"v2 insufficient funds", http.MethodPost, "/v2/customers/1002/transaction", "valid", "application/json", `{"account_id": "103", "amount": 90000, "transaction_type": "withdrawal"}`, http.StatusUnprocessableEntity},
+		{"v1 new account unknown customer", http.MethodPost, "/v1/customers/9999/account", "valid", "application/json", `{"account_type": "saving", "amount": 5000}`, http.StatusNotFound},
+		{"v1 deactivate", http.MethodPost, "/v1/customers/1003/deactivate", "valid", "application/json", `{"reason": "requested by the customer"}`, http.StatusOK},
+		{"v2 deactivate again", http.MethodPost, "/v2/customers/1003/deactivate", "valid", "application/json", `{"reason": "requested by the customer"}`, http.StatusConflict},
+		{"v1 new account inactive customer", http.MethodPost, "/v1/customers/1003/account", "valid", "application/json", `{"account_type": "saving", "amount": 5000}`, http.StatusUnprocessableEntity},
+		{"v1 deactivate without reason", http.MethodPost, "/v1/customers/1003/deactivate", "valid", "application/json", `{}`, http.StatusUnprocessableEntity},
+		{"reactivate", http.MethodPost, "/customers/1003/reactivate", "valid", "application/json", `{"reason": "identity confirmed"}`, http.StatusOK},
+		{"v2 new account underage", http.MethodPost, "/v2/customers/1003/account", "valid", "application/json", `{"account_type": "saving", "amount": 5000}`, http.StatusUnprocessableEntity},
+		{"reactivate unknown customer", http.MethodPost, "/customers/9999/reactivate", "valid", "application/json", `{"reason": "identity confirmed"}`, http.StatusNotFound},
+		{"v1 account products", http.MethodGet, "/v1/account-products", "valid", "", "", http.StatusOK},
+		{"v2 account product", http.MethodGet, "/v2/account-products/Saving", "valid", "", "", http.StatusOK},
+		{"account product not found", http.MethodGet, "/account-products/loan", "valid", "", "", http.StatusNotFound},
+		{"v1 new account product", http.MethodPost, "/v1/account-products", "valid", "application/json", `{"code": "Premier", "display_name": "Premier Account", "minimum_deposit": 25000, "interest_rate": 2.25, "monthly_fee": 15}`, http.StatusCreated},
+		{"v2 new account product exists", http.MethodPost, "/v2/account-products", "valid", "application/json", `{"code": "premier", "display_name": "Premier Account"}`, http.StatusConflict},
+		{"v1 new account product invalid", http.MethodPost, "/v1/account-products", "valid", "application/json", `{"code": "9 lives", "display_name": "", "interest_rate": 120}`, http.StatusUnprocessableEntity},
+		{"v2 update account product", http.MethodPut, "/v2/account-products/premier", "valid", "application/json", `{"display_name": "Premier Account", "minimum_deposit": 20000, "interest_rate": 2.5, "monthly_fee": 10}`, http.StatusOK},
+		{"v1 new account below product minimum", http.MethodPost, "/v1/customers/1001/account", "valid", "application/json", `{"account_type": "Premier", "amount": 10000}`, http.StatusUnprocessableEntity},
+		{"v1 delete account product in use", http.MethodDelete, "/v1/account-products/saving", "valid", "", "", http.StatusConflict},
+		{"delete account product", http.MethodDelete, "/account-products/premier", "valid", "", "", http.StatusNoContent},
+		{"v2 delete account product not found", http.MethodDelete, "/v2/account-products/premier", "valid", "", "", http.StatusNotFound},
+		{"all api keys", http.MethodGet, "/v1/api-keys", "valid", "", "", http.StatusOK},
+		{"new api key", http.MethodPost, "/v1/api-keys", "valid", "application/json", `{"name": "nightly batch", "scopes": ["GetCustomer"], "expires_in_days": 30}`, http.StatusCreated},
+		{"new api key unknown scope", http.MethodPost, "/v2/api-keys", "valid", "application/json", `{"name": "nightly batch", "scopes": ["NewAPIKey", "GetCustomr"]}`, http.StatusUnprocessableEntity},
+		{"new api key invalid", http.MethodPost, "/v1/api-keys", "valid", "application/json", `{"name": "", "scopes": []}`, http.StatusUnprocessableEntity},
+		{"revoke unknown api key", http.MethodPost, "/v1/api-keys/00ff/revoke", "valid", "", "", http.StatusNotFound},
+		{"rotate unknown api key", http.MethodPost, "/v2/api-keys/00ff/rotate", "valid", "", "", http.StatusNotFound},
+		{"legacy all api keys", http.MethodGet, "/api-keys", "valid", "", "", http.StatusOK},
+		{"audit events", http.MethodGet, "/v1/audit-events?outcome=success&limit=2", "valid", "", "", http.StatusOK},
+		{"v2 audit events of a customer", http.MethodGet, "/v2/audit-events?customer_id=1001&from=2020-01-01", "valid", "", "", http.StatusOK},
+		{"audit events invalid filter", http.MethodGet, "/v1/audit-events?outcome=maybe&limit=0", "valid", "", "", http.StatusBadRequest},
+		{"verify audit log", http.MethodGet, "/v1/audit-events/verify", "valid", "", "", http.StatusOK},
+		{"legacy audit events", http.MethodGet, "/audit-events", "valid", "", "", http.StatusOK},
+		{"new webhook subscription", http.MethodPost, "/v1/webhooks", "valid", "application/json", `{"url": "https://partner.example.com/hooks", "event_types": ["TransactionPosted"]}`, http.StatusCreated},
+		{"new webhook subscription unknown event", http.MethodPost, "/v2/webhooks", "valid", "application/json", `{"url": "https://partner.example.com/hooks", "event_types": ["BalanceChanged"]}`, http.StatusUnprocessableEntity},
+		{"new webhook subscription invalid", http.MethodPost, "/v1/webhooks", "valid", "application/json", `{"url": "partner.example.com", "event_types": [], "secret": "short"}`, http.StatusUnprocessableEntity},
+		{"all webhook subscriptions", http.MethodGet, "/v1/webhooks", "valid", "", "", http.StatusOK},
+		{"webhook subscription", http.MethodGet, "/v2/webhooks/1", "valid", "", "", http.StatusOK},
+		{"webhook deliveries", http.MethodGet, "/v1/webhooks/1/deliveries?status=pending&limit=10", "valid", "", "", http.StatusOK},
+		{"webhook deliveries invalid filter", http.MethodGet, "/v1/webhooks/1/deliveries?status=lost", "valid", "", "", http.StatusBadRequest},
+		{"unknown webhook delivery", http.MethodGet, "/v1/webhooks/1/deliveries/99", "valid", "", "", http.StatusNotFound},
+		{"replay unknown webhook delivery", http.MethodPost, "/v2/webhooks/1/deliveries/99/replay", "valid", "", "", http.StatusNotFound},
+		{"disable webhook subscription", http.MethodPost, "/v1/webhooks/1/disable", "valid", "", "", http.StatusOK},
+		{"disable webhook subscription twice", http.MethodPost, "/v1/webhooks/1/disable", "valid", "", "", http.StatusConflict},
+		{"legacy all webhook subscriptions", http.MethodGet, "/webhooks", "valid", "", "", http.StatusOK},
+		{"transaction import dry run", http.MethodPost, "/v1/transaction-imports?dry_run=true", "valid", "text/csv", "account_id,amount,transaction_type\n101,10,deposit\n999,5,deposit\n", http.StatusOK},
+		{"transaction import invalid row", http.MethodPost, "/v2/transaction-imports", "valid", "text/csv", "account_id,amount,transaction_type\n101,ten,deposit\n", http.StatusUnprocessableEntity},
+		{"transaction import missing column", http.MethodPost, "/v1/transaction-imports", "valid", "text/csv", "account_id,amount\n101,10\n", http.StatusUnprocessableEntity},
+		{"transaction import", http.MethodPost, "/v1/transaction-imports?file_name=payroll.csv", "valid", "text/csv", "account_id,amount,transaction_type\n101,10,deposit\n102,20,deposit\n", http.StatusCreated},
+		{"transaction import duplicate", http.MethodPost, "/v2/transaction-imports?dry_run=true", "valid", "text/csv", "account_id,amount,transaction_type\n101,10,deposit\n102,20,deposit\n", http.StatusConflict},
+		{"transaction import unknown parameter", http.MethodPost, "/v1/transaction-imports?dryrun=1", "valid", "text/csv", "account_id,amount,transaction_type\n", http.StatusBadRequest},
+		{"transaction import json", http.MethodPost, "/v1/transaction-imports", "valid", "application/json", `{}`, http.StatusUnsupportedMediaType},
+		{"legacy transaction import", http.MethodPost, "/transaction-imports?dry_run=1", "valid", "text/csv", "account_id,amount,transaction_type\n103,1,withdrawal\n", http.StatusOK},
+		{"login", http.MethodPost, "/auth/login", "", "application/json", `{"username": "1001", "password": "passw0rd123"}`, http.StatusOK},
+		{"login wrong password", http.MethodPost, "/auth/login", "", "application/json", `{"username": "1001", "password": "guess"}`, http.StatusUnauthorized},
+		{"login missing password", http.MethodPost, "/auth/login", "", "application/json", `{"username": "1001"}`, http.StatusUnprocessableEntity},
+		{"login malformed", http.MethodPost, "/auth/login", "", "application/json", `{"username": `, http.StatusBadRequest},
+		{"change password without token", http.MethodPost, "/auth/password", "", "application/json", `{"current_password": "passw0rd123", "new_password": "correct horse 42"}`, http.StatusUnauthorized},
+		{"change password denied", http.MethodPost, "/auth/password", "denied", "application/json", `{"current_password": "passw0rd123", "new_password": "correct horse 42"}`, http.StatusForbidden},
+		{"refresh invalid token", http.MethodPost, "/auth/refresh", "", "application/json", `{"refresh_token": "abc.def.ghi"}`, http.StatusUnauthorized},
+		{"liveness", http.MethodGet, "/healthz", "", "", "", http.StatusOK},
+		{"readiness", http.MethodGet, "/readyz", "", "", "", http.StatusOK},
+		{"metrics", http.MethodGet, "/metrics", "", "", "", http.StatusOK},
+		{"openapi", http.MethodGet, "/openapi.json", "", "", "", http.StatusOK},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// the host must match the server listed in openapi.json for the spec router to find the operation
+			req := httptest.NewRequest(test.method, "http://localhost:8000"+test.path, strings.NewReader(test.body))
+			if test.token != "" {
+				req.Header.Set("Authorization", "Bearer "+test.token)
+			}
+			if test.contentType != "" {
+				req.Header.Set("Content-Type", test.contentType)
+			}
+			recorder := httptest.NewRecorder()
+
+			// Act - execute test
+			router.ServeHTTP(recorder, req)
+
+			// Assert - test expectations
+			if recorder.Code != test.wantStatus {
+				t.Fatalf("received %d, want %d: %s", recorder.Code, test.wantStatus, recorder.Body.String())
+			}
+			route, pathParams, err := specRouter.FindRoute(req)
+			if err != nil {
+				t.Fatalf("%s %s is not documented: %v", test.method, test.path, err)
+			}
+			input := &openapi3filter.ResponseValidationInput{
+				RequestValidationInput: &openapi3filter.RequestValidationInput{Request: req, PathParams: pathParams, Route: route},
+				Status:                 recorder.Code,
+				Header:                 recorder.Header(),
+				Body:                   io.NopCloser(bytes.NewReader(recorder.Body.Bytes())),
+				// a status code missing from openapi.json fails the test as well as a body which does not match
+				Options: &openapi3filter.Options{IncludeResponseStatus: true, MultiError: true},
+			}
+			if err := openapi3filter.ValidateResponse(context.Background(), input); err != nil {
+				t.Errorf("response does not conform to openapi.json: %v", err)
+			}
+		})
+	}
+}
+
+// TestOpenAPIDocumentsEveryRoute() should pass when every route registered on the router is documented in openapi.json,
+// under an operationId matching the route name - the API versions share route names, so their operations carry the route
+// name in the x-route-name extension instead
+func TestOpenAPIDocumentsEveryRoute(t *testing.T) {
+	// Arrange - setup test
+	doc := loadSpec(t)
+	router := newTestRouter()
+	// mux path templates carry their regular expressions (e.g. {customer_id:[0-9]+}) which OpenAPI paths do not
+	varPattern := regexp.MustCompile(`\{([^}:]+):[^}]+\}`)
+
+	// Act - execute test
+	err := router.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			// the API subrouter has no path of its own
+			return nil
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		// Assert - test expectations
+		path := varPattern.ReplaceAllString(template, "{$1}")
+		pathItem := doc.Paths.Find(path)
+		if pathItem == nil {
+			t.Errorf("route %s (%s) is not documented", route.GetName(), path)
+			return nil
+		}
+		for _, method := range methods {
+			operation := pathItem.GetOperation(method)
+			if operation == nil {
+				t.Errorf("route %s: %s %s is not documented", route.GetName(), method, path)
+				continue
+			}
+			routeName := operation.OperationID
+			if name, ok := operation.Extensions["x-route-name"].(string); ok {
+				routeName = name
+			}
+			if routeName != route.GetName() {
+				t.Errorf("%s %s: documented route name %q does not match the route name %q", method, path, routeName, route.GetName())
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestCustomerDeactivation() should pass when transactions on a deactivated customer's accounts are refused with the
+// CUSTOMER_INACTIVE code while the customer remains readable, and are accepted again once the customer is reactivated
+func TestCustomerDeactivation(t *testing.T) {
+	// Arrange - setup test
+	router := newTestRouter()
+	// serve() sends an authorized request through the router
+	serve := func(method string, path string, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer valid")
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+	deposit := `{"account_id": "102", "amount": 50, "transaction_type": "deposit"}`
+
+	// Act - execute test
+	deactivated := serve(http.MethodPost, "/v1/customers/1002/deactivate", `{"reason": "suspected fraud"}`)
+	refused := serve(http.MethodPost, "/v1/customers/1002/transaction", deposit)
+	customer := serve(http.MethodGet, "/v1/customers/1002", "")
+	reactivated := serve(http.MethodPost, "/v1/customers/1002/reactivate", `{"reason": "fraud ruled out"}`)
+	accepted := serve(http.MethodPost, "/v1/customers/1002/transaction", deposit)
+
+	// Assert - test expectations
+	if deactivated.Code != http.StatusOK || reactivated.Code != http.StatusOK {
+		t.Fatalf("received %d and %d, want 200: %s %s", deactivated.Code, reactivated.Code, deactivated.Body, reactivated.Body)
+	}
+	var errResp dto.ErrorResponse
+	json.NewDecoder(refused.Body).Decode(&errResp)
+	if refused.Code != http.StatusUnprocessableEntity || errResp.Code != dto.ErrCustomerInactive {
+		t.Errorf("transaction for an inactive customer: received %d %s, want 422 %s", refused.Code, errResp.Code, dto.ErrCustomerInactive)
+	}
+	var custResp dto.CustomerResponse
+	json.NewDecoder(customer.Body).Decode(&custResp)
+	if customer.Code != http.StatusOK || custResp.Status != "inactive" || custResp.StatusReason != "suspected fraud" {
+		t.Errorf("inactive customer: received %d %+v", customer.Code, custResp)
+	}
+	if accepted.Code != http.StatusCreated {
+		t.Errorf("transaction after reactivation: received %d, want 201: %s", accepted.Code, accepted.Body)
+	}
+}
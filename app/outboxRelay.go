@@ -0,0 +1,68 @@
+package app
+
+import (
+	"context"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/outbox"
+	"github.com/gtaylor314/Banking-MS/webhook"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// startOutboxRelay() starts relaying the outbox's events in the background until ctx is cancelled - the returned
+// function closes the publisher once the relay has stopped
+func startOutboxRelay(ctx context.Context, cfg config.OutboxConfig, db_conn *sqlx.DB) func() {
+	publisher, err := outbox.NewPublisher(cfg, domain.NewWebhookRepositoryDb(db_conn))
+	if err != nil {
+		log.Fatal(err)
+	}
+	relay := outbox.NewRelay(domain.NewOutboxRepositoryDb(db_conn), publisher, cfg)
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		relay.Run(ctx)
+	}()
+	logger.Info("outbox relay started", zap.String("publisher", cfg.Publisher))
+
+	return func() {
+		<-stopped
+		if closer, ok := publisher.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				logger.Error("error while closing the outbox publisher", zap.Error(err))
+			}
+		}
+	}
+}
+
+// startWebhookWorker() starts delivering the webhook deliveries queued by the outbox relay in the background until ctx
+// is cancelled
+func startWebhookWorker(ctx context.Context, cfg config.WebhookConfig, repo domain.WebhookRepository) {
+	worker := webhook.NewWorker(repo, cfg)
+	go worker.Run(ctx)
+	logger.Info("webhook worker started")
+}
+
+// RunOutboxRelay() is the standalone relay (cmd/outboxrelay) - it reads the same configuration as Start() and relays
+// the outbox's events until interrupted, whether or not outbox.enabled is set, so that the relay can run apart from the
+// service - several relays may run at once as each event is leased to one of them
+func RunOutboxRelay() {
+	cfg := loadConfig()
+	db_conn := getDbConnection(cfg.DB)
+	defer db_conn.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	closePublisher := startOutboxRelay(ctx, cfg.Outbox, db_conn)
+	<-ctx.Done()
+	closePublisher()
+	logger.Info("outbox relay stopped")
+}
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"context"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+
+	"go.uber.org/zap"
+)
+
+// RateLimitMiddleware takes a token from the bucket of the client and route of each request - a client is the subject
+// recorded by the auth middleware (so it must be registered after it) or, for unauthenticated requests, the IP address
+// the request came from - a request finding its bucket empty is refused with 429 and a Retry-After header
+type RateLimitMiddleware struct {
+	store  ratelimit.Store
+	limits config.RateLimitConfig
+	// proxies are the parsed rate_limits.trusted_proxies
+	proxies []*net.IPNet
+}
+
+func (rateMid RateLimitMiddleware) rateLimitHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			routeName := mux.CurrentRoute(r).GetName()
+			if decision, allowed := rateMid.take(r.Context(), routeName, rateMid.clientIP(r)); !allowed {
+				rateMid.refuse(w, r, routeName, decision)
+				return
+			}
+			nextMidware.ServeHTTP(w, r)
+		})
+	}
+}
+
+// take() takes a token from the bucket of the client and routeName, and reports whether the request may be served - every
+// API version, and the gRPC server, shares the route names, so a client's bucket for a route is shared by all of them
+func (rateMid RateLimitMiddleware) take(ctx context.Context, routeName string, clientIP string) (ratelimit.Decision, bool) {
+	key := routeName + "|" + rateMid.clientKey(ctx, clientIP)
+	decision, err := rateMid.store.Take(ctx, key, rateMid.limit(routeName))
+	if err != nil {
+		// a store which cannot be reached must not take the API down with it - the request is let through
+		logging.Error(ctx, "error while taking a rate limit token", zap.Error(err))
+		return decision, true
+	}
+	return decision, decision.Allowed
+}
+
+// authFailureKey prefixes the bucket of the failed authentications of an IP address - it cannot clash with a route name
+const authFailureKey = "auth_failures|"
+
+// allowAuthFailure() takes a token from the rate_limits.auth_failures bucket of the client's IP address for a request
+// with a missing or invalid credential, and reports whether the auth middleware may refuse it as usual - once the bucket
+// is empty the request is refused with 429 instead - a request whose credential is valid never reaches it, so a client
+// which used up its bucket guessing is still served once it sends a valid token or API key
+func (rateMid RateLimitMiddleware) allowAuthFailure(w http.ResponseWriter, r *http.Request) bool {
+	decision, allowed := rateMid.takeAuthFailure(r.Context(), rateMid.clientIP(r))
+	if !allowed {
+		rateMid.refuse(w, r, mux.CurrentRoute(r).GetName(), decision)
+	}
+	return allowed
+}
+
+// takeAuthFailure() takes a token from the rate_limits.auth_failures bucket of clientIP, and reports whether the failed
+// authentication may be refused as usual
+func (rateMid RateLimitMiddleware) takeAuthFailure(ctx context.Context, clientIP string) (ratelimit.Decision, bool) {
+	limit := ratelimit.Limit{Rate: rateMid.limits.AuthFailures.Rate, Burst: rateMid.limits.AuthFailures.Burst}
+	decision, err := rateMid.store.Take(ctx, authFailureKey+clientIP, limit)
+	if err != nil {
+		logging.Error(ctx, "error while taking an auth failure rate limit token", zap.Error(err))
+		return decision, true
+	}
+	return decision, decision.Allowed
+}
+
+// refuse() writes the 429 response of a request refused by decision
+func (rateMid RateLimitMiddleware) refuse(w http.ResponseWriter, r *http.Request, routeName string, decision ratelimit.Decision) {
+	metrics.RateLimited(routeName)
+	w.Header().Set("Retry-After", retryAfterSeconds(decision))
+	writeError(w, r, &errs.AppError{Code: http.StatusTooManyRequests, Message: "rate limit exceeded"})
+}
+
+// retryAfterSeconds() returns the Retry-After value of decision - a whole number of seconds, rounded up so that a client
+// which waits for it is let through
+func retryAfterSeconds(decision ratelimit.Decision) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(decision.RetryAfter.Seconds()))))
+}
+
+// limit() returns the limit configured for routeName, or the default limit
+func (rateMid RateLimitMiddleware) limit(routeName string) ratelimit.Limit {
+	limit, ok := rateMid.limits.Routes[routeName]
+	if !ok {
+		limit = rateMid.limits.Default
+	}
+	return ratelimit.Limit{Rate: limit.Rate, Burst: limit.Burst}
+}
+
+// clientKey() identifies the client of a request - the subject recorded in ctx by the auth middleware or else clientIP
+func (rateMid RateLimitMiddleware) clientKey(ctx context.Context, clientIP string) string {
+	if subject := logging.Subject(ctx); subject != "" {
+		return "subject:" + subject
+	}
+	return "ip:" + clientIP
+}
+
+// clientIP() returns the IP address r came from - see remoteIP()
+func (rateMid RateLimitMiddleware) clientIP(r *http.Request) string {
+	return rateMid.remoteIP(r.RemoteAddr, r.Header.Values("X-Forwarded-For"))
+}
+
+// remoteIP() returns the IP address a request from remoteAddr came from - when that is a trusted proxy, the
+// X-Forwarded-For values are read from the right, past the addresses added by trusted proxies, as the addresses to their
+// left may have been set by the client itself - without trusted proxies X-Forwarded-For is ignored, so behind a proxy
+// unauthenticated clients share its bucket
+func (rateMid RateLimitMiddleware) remoteIP(remoteAddr string, forwardedFor []string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	if !rateMid.trusted(net.ParseIP(host)) {
+		return host
+	}
+	var forwarded []string
+	for _, header := range forwardedFor {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := net.ParseIP(strings.TrimSpace(forwarded[i]))
+		if ip == nil {
+			// a malformed address stops the walk at the last address a trusted proxy vouched for
+			break
+		}
+		host = ip.String()
+		if !rateMid.trusted(ip) {
+			break
+		}
+	}
+	return host
+}
+
+// trusted() reports whether ip is one of the trusted proxies
+func (rateMid RateLimitMiddleware) trusted(ip net.IP) bool {
+	if ip == nil {
+		return false
+	}
+	for _, proxy := range rateMid.proxies {
+		if proxy.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// newRateLimitMiddleware() returns the rate limiting middleware taking its tokens from store - the trusted proxies have
+// been checked by config validation, so one which cannot be parsed is left out
+func newRateLimitMiddleware(store ratelimit.Store, limits config.RateLimitConfig) RateLimitMiddleware {
+	proxies, _ := limits.ParseTrustedProxies()
+	return RateLimitMiddleware{store: store, limits: limits, proxies: proxies}
+}
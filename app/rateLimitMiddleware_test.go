@@ -0,0 +1,196 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/ratelimit"
+)
+
+// TestRateLimit() should pass when a client which used up its bucket for a route is refused with 429 and Retry-After,
+// while other clients and the client's other routes are not affected
+func TestRateLimit(t *testing.T) {
+	// Arrange - setup test
+	cfg := config.Default()
+	cfg.Auth.Mode = config.AuthModeLocal
+	cfg.Auth.TokenSecret = testTokenSecret
+	cfg.RateLimits.Routes["GetCustomer"] = config.RateLimit{Rate: 0.001, Burst: 1}
+	repos := repositories{
+		customers:    domain.NewCustomerRepositoryStub(),
+		accounts:     domain.NewAccountRepositoryStub(),
+		transactions: domain.NewTransactionRepositoryStub(),
+		products:     domain.NewAccountProductRepositoryStub(),
+		audit:        domain.NewAuditRepositoryStub(),
+		users:        domain.NewUserRepositoryStub(),
+		apiKeys:      domain.NewAPIKeyRepositoryStub(),
+		webhooks:     domain.NewWebhookRepositoryStub(),
+		imports:      domain.NewTransactionImportRepositoryStub(),
+		auth:         domain.NewPolicyAuthRepository(tokenSigner(&cfg), domain.DefaultAuthPolicy()),
+		rateLimits:   ratelimit.NewMemoryStore(),
+	}
+	router := newRouter(&cfg, repos, []dependencyCheck{{name: "database", check: func(context.Context) error { return nil }}})
+	token := func(username string) string {
+		var tokens dto.LoginResponse
+		login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "`+username+`", "password": "passw0rd123"}`)
+		json.Unmarshal(login.Body.Bytes(), &tokens)
+		return tokens.AccessToken
+	}
+	user, admin := token("1001"), token("admin")
+
+	// Act - execute test
+	first := serveAuth(router, http.MethodGet, "/v1/customers/1001", user, "")
+	// the legacy alias shares the bucket of the /v1 route
+	second := serveAuth(router, http.MethodGet, "/customers/1001", user, "")
+	otherClient := serveAuth(router, http.MethodGet, "/v1/customers/1001", admin, "")
+	otherRoute := serveAuth(router, http.MethodGet, "/v1/account-products", user, "")
+
+	// Assert - test expectations
+	if first.Code != http.StatusOK || otherClient.Code != http.StatusOK || otherRoute.Code != http.StatusOK {
+		t.Errorf("received %d, %d and %d, want 200 for the first request, another client and another route",
+			first.Code, otherClient.Code, otherRoute.Code)
+	}
+	var errResp dto.ErrorResponse
+	json.Unmarshal(second.Body.Bytes(), &errResp)
+	if second.Code != http.StatusTooManyRequests || errResp.Code != dto.ErrRateLimited || second.Header().Get("Retry-After") != "1000" {
+		t.Errorf("received %d %s with Retry-After %q, want 429 RATE_LIMITED with Retry-After 1000", second.Code, errResp.Code,
+			second.Header().Get("Retry-After"))
+	}
+}
+
+// TestAuthFailureRateLimit() should pass when an IP address sending missing or bad tokens is refused with 429 once it
+// used up its auth failure bucket, on every API version, while a valid token - from that address too - is served, and a
+// valid token the route is not granted to is not counted
+func TestAuthFailureRateLimit(t *testing.T) {
+	// Arrange - setup test
+	cfg := config.Default()
+	cfg.Auth.Mode = config.AuthModeLocal
+	cfg.Auth.TokenSecret = testTokenSecret
+	cfg.RateLimits.AuthFailures = config.RateLimit{Rate: 0.001, Burst: 3}
+	repos := repositories{
+		customers:    domain.NewCustomerRepositoryStub(),
+		accounts:     domain.NewAccountRepositoryStub(),
+		transactions: domain.NewTransactionRepositoryStub(),
+		products:     domain.NewAccountProductRepositoryStub(),
+		audit:        domain.NewAuditRepositoryStub(),
+		users:        domain.NewUserRepositoryStub(),
+		apiKeys:      domain.NewAPIKeyRepositoryStub(),
+		webhooks:     domain.NewWebhookRepositoryStub(),
+		imports:      domain.NewTransactionImportRepositoryStub(),
+		auth:         domain.NewPolicyAuthRepository(tokenSigner(&cfg), domain.DefaultAuthPolicy()),
+		rateLimits:   ratelimit.NewMemoryStore(),
+	}
+	router := newRouter(&cfg, repos, []dependencyCheck{{name: "database", check: func(context.Context) error { return nil }}})
+	var tokens dto.LoginResponse
+	login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "1001", "password": "passw0rd123"}`)
+	json.Unmarshal(login.Body.Bytes(), &tokens)
+	fromAddress := func(path string, token string, remoteAddr string) int {
+		request := httptest.NewRequest(http.MethodGet, path, nil)
+		request.RemoteAddr = remoteAddr
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	// Act - execute test
+	// httptest.NewRequest() sends every request from 192.0.2.1
+	var codes []int
+	paths := []string{"/v1/customers/1001", "/v2/customers/1001", "/customers/1001", "/v1/customers/1001", "/v2/customers/1001"}
+	for i, path := range paths {
+		token := "not-a-token"
+		if i == 1 {
+			token = ""
+		}
+		codes = append(codes, fromAddress(path, token, "192.0.2.1:1234"))
+	}
+	validAfterFailures := fromAddress("/v1/customers/1001", tokens.AccessToken, "192.0.2.1:1234")
+	// customer 1001 may not read customer 1002, however often it tries
+	var deniedCodes []int
+	for i := 0; i < 5; i++ {
+		deniedCodes = append(deniedCodes, fromAddress("/v1/customers/1002", tokens.AccessToken, "198.51.100.7:4321"))
+	}
+
+	// Assert - test expectations
+	// a missing token is refused with 401 and an invalid one with 403 until the bucket is empty
+	want := []int{http.StatusForbidden, http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, http.StatusTooManyRequests}
+	if !reflect.DeepEqual(codes, want) {
+		t.Errorf("received %v for missing and bad tokens, want %v", codes, want)
+	}
+	if validAfterFailures != http.StatusOK {
+		t.Errorf("received %d for a valid token from the limited address, want 200", validAfterFailures)
+	}
+	wantDenied := []int{http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden, http.StatusForbidden}
+	if !reflect.DeepEqual(deniedCodes, wantDenied) {
+		t.Errorf("received %v for a valid token not granted the route, want %v", deniedCodes, wantDenied)
+	}
+}
+
+// TestClientIP() should pass when X-Forwarded-For is only read behind a trusted proxy, from the right and past the
+// addresses added by trusted proxies, so that a client cannot pick its own address
+func TestClientIP(t *testing.T) {
+	limits := config.Default().RateLimits
+	limits.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.10"}
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"direct", "198.51.100.7:4321", nil, "198.51.100.7"},
+		{"untrusted sender", "198.51.100.7:4321", []string{"203.0.113.5"}, "198.51.100.7"},
+		{"trusted proxy", "192.0.2.10:4321", []string{"203.0.113.5"}, "203.0.113.5"},
+		{"spoofed address", "192.0.2.10:4321", []string{"1.2.3.4, 203.0.113.5"}, "203.0.113.5"},
+		{"chain of proxies", "10.0.0.2:4321", []string{"203.0.113.5, 10.0.0.9", "10.0.0.3"}, "203.0.113.5"},
+		{"no header", "192.0.2.10:4321", nil, "192.0.2.10"},
+		{"malformed", "192.0.2.10:4321", []string{"203.0.113.5, unknown"}, "192.0.2.10"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange - setup test
+			rateMid := newRateLimitMiddleware(ratelimit.NewMemoryStore(), limits)
+			request := httptest.NewRequest(http.MethodGet, "/v1/customers", nil)
+			request.RemoteAddr = test.remoteAddr
+			for _, header := range test.forwarded {
+				request.Header.Add("X-Forwarded-For", header)
+			}
+
+			// Act - execute test
+			got := rateMid.clientIP(request)
+
+			// Assert - test expectations
+			if got != test.want {
+				t.Errorf("received %s, want %s", got, test.want)
+			}
+		})
+	}
+}
+
+// TestCheckRateLimitRoutes() should pass when the default limits name real routes and a misspelt route is refused
+func TestCheckRateLimitRoutes(t *testing.T) {
+	// Arrange - setup test
+	cfg := config.Default()
+	misspelt := config.Default()
+	misspelt.RateLimits.Routes = map[string]config.RateLimit{"NewTransactions": {Rate: 1, Burst: 1}}
+
+	// Act - execute test
+	defaultErr := checkRateLimitRoutes(&cfg)
+	misspeltErr := checkRateLimitRoutes(&misspelt)
+
+	// Assert - test expectations
+	if defaultErr != nil {
+		t.Errorf("unexpected error for the default limits: %v", defaultErr)
+	}
+	if misspeltErr == nil || !strings.Contains(misspeltErr.Error(), "unknown route NewTransactions") {
+		t.Errorf("received %v, want an unknown route error", misspeltErr)
+	}
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracingMiddleware starts a server span for every request - it continues any W3C trace context sent by the caller and the
+// span is carried in the request's context so that the middleware, service, repository and auth spans become its children
+type TracingMiddleware struct{}
+
+func (tracingMid TracingMiddleware) tracingHandler() func(http.Handler) http.Handler {
+	return func(nextMidware http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// continue the caller's trace if the request carries a traceparent header
+			ctx := tracing.ExtractHeaders(r.Context(), propagation.HeaderCarrier(r.Header))
+
+			// spans are named after the mux route (e.g. NewTransaction) rather than the path so that customer IDs do not
+			// produce a distinct span name per customer
+			routeName := "unknown"
+			if currentRoute := mux.CurrentRoute(r); currentRoute != nil && currentRoute.GetName() != "" {
+				routeName = currentRoute.GetName()
+			}
+			ctx, span := tracing.Start(ctx, routeName,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPMethod(r.Method), semconv.HTTPTarget(r.URL.Path)),
+			)
+			defer span.End()
+
+			rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			nextMidware.ServeHTTP(rec, r.WithContext(ctx))
+
+			span.SetAttributes(semconv.HTTPStatusCode(rec.status))
+			// server spans are only marked as failed for 5xx responses - a 4xx is the client's error, not ours
+			if rec.status >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rec.status))
+			}
+		})
+	}
+}
@@ -1,34 +1,39 @@
 package app
 
 import (
-	"encoding/json"
 	"net/http"
 
+	"github.com/gorilla/mux"
+
 	"github.com/gtaylor314/Banking-MS/dto"
 	"github.com/gtaylor314/Banking-MS/service"
 )
 
 type TransactionHandler struct {
 	service service.TransactionService
+	version apiVersion // maps the NewTransactionResponse to the shape returned by the API version being served
 }
 
 func (tranHandler TransactionHandler) newTransaction(w http.ResponseWriter, r *http.Request) {
 	// creating a NewTransactionRequest
 	var req dto.NewTransactionRequest
-	// the account id, amount, and transaction type for the new transaction are provided via the JSON body - here we create
-	// a new decoder which reads the response body and stores the data in the NewTransactionRequest (req)
-	err := json.NewDecoder(r.Body).Decode(&req)
-	if err != nil {
-		// something with the request is incorrect
-		writeResponse(w, http.StatusBadRequest, err.Error())
+	// the account id, amount, and transaction type for the new transaction are provided via the JSON body - decodeJSONBody()
+	// reads the request body and stores the data in the NewTransactionRequest (req)
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	// the customer_id is taken from the URL, which the caller was authorized for - the account must belong to it
+	req.CustomerID = mux.Vars(r)["customer_id"]
+	// validateRequest() reports every invalid field at once - the balance check is left to the service
+	if !validateRequest(w, r, req) {
 		return
 	}
 	// if request is good, create a new transaction with the req
-	resp, appErr := tranHandler.service.NewTransaction(req)
+	resp, appErr := tranHandler.service.NewTransaction(r.Context(), req)
 	if appErr != nil {
-		writeResponse(w, appErr.Code, appErr.MessageOnly())
+		writeError(w, r, appErr)
 		return
 	}
-	// write the NewTransactionResponse to the ResponseWriter
-	writeResponse(w, http.StatusCreated, resp)
+	// write the NewTransactionResponse, in the shape of the API version being served, to the ResponseWriter
+	writeResponse(w, http.StatusCreated, tranHandler.version.newTransaction(*resp))
 }
@@ -0,0 +1,46 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// TestNewTransactionOtherCustomersAccount() should pass when a user posting to their own customer's route is refused an
+// account of another customer with a 404, and that account's balance is left untouched
+func TestNewTransactionOtherCustomersAccount(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+	tokens := make(map[string]string)
+	for _, username := range []string{"admin", "1001"} {
+		login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "`+username+`", "password": "passw0rd123"}`)
+		var loginResp dto.LoginResponse
+		if err := json.Unmarshal(login.Body.Bytes(), &loginResp); login.Code != http.StatusOK || err != nil {
+			t.Fatalf("login as %s received %d %s, want 200", username, login.Code, login.Body.String())
+		}
+		tokens[username] = loginResp.AccessToken
+	}
+
+	// Act - execute test
+	// account 102 belongs to customer 1002
+	mismatched := serveAuth(router, http.MethodPost, "/v1/customers/1001/transaction", tokens["1001"], `{"account_id": "102", "amount": 100, "transaction_type": "withdrawal"}`)
+	// an admin may post to any customer, but still only to the accounts of the customer in the route
+	byAdmin := serveAuth(router, http.MethodPost, "/v2/customers/1001/transaction", tokens["admin"], `{"account_id": "102", "amount": 100, "transaction_type": "withdrawal"}`)
+	owner := serveAuth(router, http.MethodPost, "/v1/customers/1002/transaction", tokens["admin"], `{"account_id": "102", "amount": 15000, "transaction_type": "withdrawal"}`)
+
+	// Assert - test expectations
+	var errResp dto.ErrorResponse
+	if err := json.Unmarshal(mismatched.Body.Bytes(), &errResp); mismatched.Code != http.StatusNotFound || err != nil || errResp.Code != dto.ErrAccountNotFound {
+		t.Errorf("withdrawal from another customer's account received %d %s, want 404 ACCOUNT_NOT_FOUND", mismatched.Code, mismatched.Body.String())
+	}
+	if byAdmin.Code != http.StatusNotFound {
+		t.Errorf("admin withdrawal through another customer's route received %d %s, want 404", byAdmin.Code, byAdmin.Body.String())
+	}
+	// the whole balance can still be withdrawn, so neither refused withdrawal was posted
+	var tranResp dto.NewTransactionResponse
+	if err := json.Unmarshal(owner.Body.Bytes(), &tranResp); owner.Code != http.StatusCreated || err != nil || tranResp.AcctAmount != 0 {
+		t.Errorf("withdrawal by the owner received %d %s, want 201 with a balance of 0", owner.Code, owner.Body.String())
+	}
+}
@@ -0,0 +1,81 @@
+package app
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/service"
+)
+
+// transactionImportParams are the query parameters accepted by POST /transaction-imports
+var transactionImportParams = []string{"dry_run", "file_name"}
+
+// TransactionImportHandler serves the bulk transaction import - an admin operation, as a file may post to the accounts
+// of any customer - larger files are better imported with cmd/importtransactions, which is not held to the request
+// timeout and body limit - an import the timeout interrupts is resumed by sending the same file again
+type TransactionImportHandler struct {
+	service service.TransactionImportService
+}
+
+func (importHandler TransactionImportHandler) importTransactions(w http.ResponseWriter, r *http.Request) {
+	// ParseMediaType() allows parameters such as charset=utf-8 after the media type
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil || mediaType != "text/csv" {
+		writeError(w, r, &errs.AppError{Code: http.StatusUnsupportedMediaType, Message: "Content-Type must be text/csv"})
+		return
+	}
+
+	// as with the audit log search, an unknown parameter is rejected rather than ignored
+	queryParams := r.URL.Query()
+	var fieldErrs []dto.FieldError
+	for param := range queryParams {
+		if !dto.OneOf(param, transactionImportParams...) {
+			fieldErrs = append(fieldErrs, dto.FieldError{Field: param, Message: "error: unknown query parameter"})
+		}
+	}
+	req := dto.TransactionImportRequest{FileName: queryParams.Get("file_name")}
+	if value := queryParams.Get("dry_run"); value != "" {
+		dryRun, err := strconv.ParseBool(value)
+		if err != nil {
+			fieldErrs = append(fieldErrs, dto.FieldError{Field: "dry_run", Message: "error: dry_run must be true or false"})
+		}
+		req.DryRun = dryRun
+	}
+	if len(req.FileName) > 255 {
+		fieldErrs = append(fieldErrs, dto.FieldError{Field: "file_name", Message: "error: file_name must be at most 255 characters"})
+	}
+	if len(fieldErrs) > 0 {
+		writeErrorFields(w, r, badRequestErr("invalid query parameters"), fieldErrs)
+		return
+	}
+
+	// the limits middleware caps the body at limits.max_body_bytes
+	req.Content, err = io.ReadAll(r.Body)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, r, &errs.AppError{Code: http.StatusRequestEntityTooLarge, Message: fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit)})
+			return
+		}
+		writeError(w, r, badRequestErr("request body could not be read"))
+		return
+	}
+
+	resp, appErr := importHandler.service.ImportTransactions(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// a dry run creates nothing - an import is recorded whether or not every row was posted
+	code := http.StatusCreated
+	if req.DryRun {
+		code = http.StatusOK
+	}
+	writeResponse(w, code, resp)
+}
@@ -0,0 +1,66 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// serveCSV() sends a CSV file to path with a bearer token
+func serveCSV(router *mux.Router, path string, token string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+// TestTransactionImportFlow() should pass when an admin can dry run and then import a file, the posted transactions show
+// in the account's balance, the file cannot be imported twice and a customer may not import at all
+func TestTransactionImportFlow(t *testing.T) {
+	// Arrange - setup test
+	router := newLocalAuthRouter()
+	tokens := make(map[string]string)
+	for _, username := range []string{"admin", "1001"} {
+		login := serveAuth(router, http.MethodPost, "/auth/login", "", `{"username": "`+username+`", "password": "passw0rd123"}`)
+		var loginResp dto.LoginResponse
+		if err := json.Unmarshal(login.Body.Bytes(), &loginResp); login.Code != http.StatusOK || err != nil {
+			t.Fatalf("login as %s received %d %s, want 200", username, login.Code, login.Body.String())
+		}
+		tokens[username] = loginResp.AccessToken
+	}
+	file := "account_id,amount,transaction_type\n101,250,deposit\n101,1250,withdrawal\n"
+
+	// Act - execute test
+	byCustomer := serveCSV(router, "/v1/transaction-imports?dry_run=true", tokens["1001"], file)
+	dryRun := serveCSV(router, "/v1/transaction-imports?dry_run=true", tokens["admin"], file)
+	imported := serveCSV(router, "/v1/transaction-imports?file_name=payroll.csv", tokens["admin"], file)
+	again := serveCSV(router, "/v2/transaction-imports", tokens["admin"], file)
+	// a withdrawal of the account's whole balance now leaves nothing for the file's withdrawal
+	afterImport := serveAuth(router, http.MethodPost, "/v1/customers/1001/transaction", tokens["admin"], `{"account_id": "101", "amount": 0.01, "transaction_type": "withdrawal"}`)
+
+	// Assert - test expectations
+	if byCustomer.Code != http.StatusForbidden {
+		t.Errorf("customer import received %d, want 403", byCustomer.Code)
+	}
+	var dryRunResp, importResp dto.TransactionImportResponse
+	if err := json.Unmarshal(dryRun.Body.Bytes(), &dryRunResp); dryRun.Code != http.StatusOK || err != nil || dryRunResp.Status != "valid" {
+		t.Errorf("dry run received %d %s, want 200 and valid", dryRun.Code, dryRun.Body.String())
+	}
+	if err := json.Unmarshal(imported.Body.Bytes(), &importResp); imported.Code != http.StatusCreated || err != nil ||
+		importResp.Status != "completed" || importResp.PostedCount != 2 || importResp.Rows[1].Balance != "0.00" {
+		t.Errorf("import received %d %s, want 201 with both rows posted", imported.Code, imported.Body.String())
+	}
+	if again.Code != http.StatusConflict {
+		t.Errorf("second import received %d %s, want 409", again.Code, again.Body.String())
+	}
+	if afterImport.Code != http.StatusUnprocessableEntity {
+		t.Errorf("withdrawal after the import received %d %s, want 422", afterImport.Code, afterImport.Body.String())
+	}
+}
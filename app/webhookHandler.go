@@ -0,0 +1,104 @@
+package app
+
+import (
+	"net/http"
+
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/service"
+
+	"github.com/gorilla/mux"
+)
+
+// WebhookHandler serves the webhook subscription routes - they are admin operations, as a subscription receives the
+// events of every customer
+type WebhookHandler struct {
+	service service.WebhookService
+}
+
+func (webhookHandler WebhookHandler) getAllSubscriptions(w http.ResponseWriter, r *http.Request) {
+	subs, appErr := webhookHandler.service.GetAllWebhookSubscriptions(r.Context())
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, subs)
+}
+
+func (webhookHandler WebhookHandler) newSubscription(w http.ResponseWriter, r *http.Request) {
+	var req dto.NewWebhookSubscriptionRequest
+	if !decodeJSONBody(w, r, &req) {
+		return
+	}
+	if !validateRequest(w, r, req) {
+		return
+	}
+	sub, appErr := webhookHandler.service.NewWebhookSubscription(r.Context(), req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// the response is the only place a generated secret is ever shown, so it must not be cached
+	w.Header().Set("Cache-Control", "no-store")
+	writeResponse(w, http.StatusCreated, sub)
+}
+
+func (webhookHandler WebhookHandler) getSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, appErr := webhookHandler.service.GetWebhookSubscription(r.Context(), mux.Vars(r)["subscription_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, sub)
+}
+
+func (webhookHandler WebhookHandler) disableSubscription(w http.ResponseWriter, r *http.Request) {
+	sub, appErr := webhookHandler.service.DisableWebhookSubscription(r.Context(), mux.Vars(r)["subscription_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, sub)
+}
+
+func (webhookHandler WebhookHandler) getDeliveries(w http.ResponseWriter, r *http.Request) {
+	// as with the audit log search, an unknown parameter is rejected rather than ignored
+	queryParams := r.URL.Query()
+	var fieldErrs []dto.FieldError
+	for param := range queryParams {
+		if !dto.OneOf(param, dto.WebhookDeliverySearchParams...) {
+			fieldErrs = append(fieldErrs, dto.FieldError{Field: param, Message: "error: unknown query parameter"})
+		}
+	}
+	req := dto.WebhookDeliverySearchRequest{Status: queryParams.Get("status"), Limit: queryParams.Get("limit")}
+	if fieldErrs = append(fieldErrs, req.ValidateFields()...); len(fieldErrs) > 0 {
+		writeErrorFields(w, r, badRequestErr("invalid query parameters"), fieldErrs)
+		return
+	}
+	deliveries, appErr := webhookHandler.service.GetWebhookDeliveries(r.Context(), mux.Vars(r)["subscription_id"], req)
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, deliveries)
+}
+
+func (webhookHandler WebhookHandler) getDelivery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	delivery, appErr := webhookHandler.service.GetWebhookDelivery(r.Context(), vars["subscription_id"], vars["delivery_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	writeResponse(w, http.StatusOK, delivery)
+}
+
+func (webhookHandler WebhookHandler) replayDelivery(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	delivery, appErr := webhookHandler.service.ReplayWebhookDelivery(r.Context(), vars["subscription_id"], vars["delivery_id"])
+	if appErr != nil {
+		writeError(w, r, appErr)
+		return
+	}
+	// the delivery is made by the webhook worker, not by this call
+	writeResponse(w, http.StatusAccepted, delivery)
+}
@@ -0,0 +1,13 @@
+// hashpasswords replaces the plaintext passwords left in the users table with bcrypt hashes - run it once after
+// migration 000006, with the same configuration as the service (see make hashpasswords)
+package main
+
+import (
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/app"
+)
+
+func main() {
+	logger.Info("Hashing plaintext passwords")
+	app.HashPasswords()
+}
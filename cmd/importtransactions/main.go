@@ -0,0 +1,26 @@
+// importtransactions posts the transactions of a CSV file (columns account_id, amount and transaction_type, named by a
+// header row) with the same configuration as the service (see make importtransactions) - every row is validated before
+// any is posted, -dry-run only validates, and a file which was imported before is refused
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/gtaylor314/Banking-MS/app"
+)
+
+func main() {
+	dryRun := flag.Bool("dry-run", false, "validate every row without posting any")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "usage: %s [-dry-run] file.csv\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	app.ImportTransactionFile(flag.Arg(0), *dryRun)
+}
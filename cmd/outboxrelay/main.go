@@ -0,0 +1,13 @@
+// outboxrelay publishes the events in the outbox_events table on its own, for when the service runs with the relay
+// disabled (outbox.enabled false) - run it with the same configuration as the service (see make outboxrelay)
+package main
+
+import (
+	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/app"
+)
+
+func main() {
+	logger.Info("Starting the outbox relay")
+	app.RunOutboxRelay()
+}
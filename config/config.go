@@ -0,0 +1,788 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the typed configuration for the Banking-MS service - it is populated from defaults, then an optional YAML or
+// JSON file, and finally environment variable overrides (see Load())
+type Config struct {
+	Server     ServerConfig    `yaml:"server" json:"server"`
+	DB         DBConfig        `yaml:"db" json:"db"`
+	Auth       AuthConfig      `yaml:"auth" json:"auth"`
+	Limits     LimitsConfig    `yaml:"limits" json:"limits"`
+	Features   FeatureConfig   `yaml:"features" json:"features"`
+	Tracing    TracingConfig   `yaml:"tracing" json:"tracing"`
+	API        APIConfig       `yaml:"api" json:"api"`
+	Accounts   AccountConfig   `yaml:"accounts" json:"accounts"`
+	RateLimits RateLimitConfig `yaml:"rate_limits" json:"rate_limits"`
+	Outbox     OutboxConfig    `yaml:"outbox" json:"outbox"`
+	Webhooks   WebhookConfig   `yaml:"webhooks" json:"webhooks"`
+	Imports    ImportConfig    `yaml:"imports" json:"imports"`
+}
+
+// ServerConfig holds the address and port the http server listens on - the gRPC server listens on the same address at
+// GRPCPort, or is not started when GRPCPort is empty
+type ServerConfig struct {
+	Address  string `yaml:"address" json:"address"`
+	Port     string `yaml:"port" json:"port"`
+	GRPCPort string `yaml:"grpc_port" json:"grpc_port"`
+}
+
+// DBConfig holds the MySQL connection details and the connection pool settings
+type DBConfig struct {
+	User            string   `yaml:"user" json:"user"`
+	Passwd          string   `yaml:"passwd" json:"passwd"`
+	Address         string   `yaml:"address" json:"address"`
+	Port            string   `yaml:"port" json:"port"`
+	Name            string   `yaml:"name" json:"name"`
+	MaxOpenConns    int      `yaml:"max_open_conns" json:"max_open_conns"`
+	MaxIdleConns    int      `yaml:"max_idle_conns" json:"max_idle_conns"`
+	ConnMaxLifetime Duration `yaml:"conn_max_lifetime" json:"conn_max_lifetime"`
+}
+
+// AuthConfig selects how incoming tokens are verified - "remote" sends each token to the Banking-Auth server at ServerURL,
+// "local" issues tokens from the users table (POST /auth/login), verifies them with TokenSecret and authorizes them with
+// the policy at PolicyFile, while "none" disables authorization entirely and is only meant for local development
+type AuthConfig struct {
+	Mode      string `yaml:"mode" json:"mode"`
+	ServerURL string `yaml:"server_url" json:"server_url"`
+	// TokenSecret signs the tokens issued in local mode - it must be kept private as anyone holding it can mint tokens
+	TokenSecret     string   `yaml:"token_secret" json:"token_secret"`
+	AccessTokenTTL  Duration `yaml:"access_token_ttl" json:"access_token_ttl"`
+	RefreshTokenTTL Duration `yaml:"refresh_token_ttl" json:"refresh_token_ttl"`
+	// PolicyFile is the YAML or JSON policy of the routes each role may call in local mode - the default policy (see
+	// auth-policy.example.yaml) applies when it is empty
+	PolicyFile string `yaml:"policy_file" json:"policy_file"`
+	// PasswordHashCost is the bcrypt cost of new password hashes - each step doubles the time taken by a login
+	PasswordHashCost int `yaml:"password_hash_cost" json:"password_hash_cost"`
+	// PasswordMinLength is the fewest characters accepted for a new password
+	PasswordMinLength int `yaml:"password_min_length" json:"password_min_length"`
+}
+
+// MinTokenSecretBytes is the shortest TokenSecret accepted - HMAC-SHA256 keys shorter than the hash add no strength
+const MinTokenSecretBytes = 32
+
+// the bcrypt costs accepted for AuthConfig.PasswordHashCost - costs below 10 are too cheap to slow down a guessing attack
+const (
+	MinPasswordHashCost = 10
+	MaxPasswordHashCost = 16
+)
+
+// LimitsConfig holds the limits placed on each incoming request
+type LimitsConfig struct {
+	RequestTimeout   Duration `yaml:"request_timeout" json:"request_timeout"`
+	MaxBodyBytes     int64    `yaml:"max_body_bytes" json:"max_body_bytes"`
+	ReadinessTimeout Duration `yaml:"readiness_timeout" json:"readiness_timeout"` // applied to each /readyz dependency check
+}
+
+// FeatureConfig toggles optional parts of the API - a disabled feature's routes are not registered
+type FeatureConfig struct {
+	AccountCreation bool `yaml:"account_creation" json:"account_creation"`
+	Transactions    bool `yaml:"transactions" json:"transactions"`
+}
+
+// TracingConfig selects where OpenTelemetry spans are exported - "none" disables tracing, "stdout" writes spans to
+// standard output and "otlp" sends them over OTLP/HTTP to OTLPEndpoint (host:port) - SampleRatio is the fraction of new
+// traces which are recorded (requests which arrive with a sampled parent are always recorded)
+type TracingConfig struct {
+	Exporter     string  `yaml:"exporter" json:"exporter"`
+	OTLPEndpoint string  `yaml:"otlp_endpoint" json:"otlp_endpoint"`
+	OTLPInsecure bool    `yaml:"otlp_insecure" json:"otlp_insecure"`
+	ServiceName  string  `yaml:"service_name" json:"service_name"`
+	SampleRatio  float64 `yaml:"sample_ratio" json:"sample_ratio"`
+}
+
+// APIConfig holds the deprecation schedule of the unversioned routes (e.g. /customers), which remain as aliases of /v1 -
+// both dates are YYYY-MM-DD and are returned to clients in the Deprecation and Sunset headers
+type APIConfig struct {
+	LegacyDeprecatedAt string `yaml:"legacy_deprecated_at" json:"legacy_deprecated_at"`
+	LegacySunsetAt     string `yaml:"legacy_sunset_at" json:"legacy_sunset_at"`
+}
+
+// AccountConfig holds the account opening policy - MaxPerType is the most active accounts of one type (e.g. saving) a
+// customer may hold and MinimumAge is the age in years a customer must have reached - zero disables either rule
+type AccountConfig struct {
+	MaxPerType int `yaml:"max_per_type" json:"max_per_type"`
+	MinimumAge int `yaml:"minimum_age" json:"minimum_age"`
+}
+
+// DateLayout is the layout of the dates held by APIConfig
+const DateLayout = "2006-01-02"
+
+// tracing exporters accepted by TracingConfig.Exporter
+const (
+	TracingExporterNone   = "none"
+	TracingExporterStdout = "stdout"
+	TracingExporterOTLP   = "otlp"
+)
+
+// RateLimitConfig holds the token bucket limits of each client - a client is the subject of its token or API key, or its
+// IP address when the request is not authenticated - Routes overrides Default for the mux route names it lists, and each
+// client has a bucket per route - AuthFailures limits the requests made from an IP address with a missing or invalid
+// token or API key, across every API route, so that credentials cannot be guessed faster than it allows - a valid
+// credential which is not granted the route is not counted
+// the IP address of a client is the address the request came from, unless that is one of TrustedProxies (IP addresses
+// or CIDR ranges), in which case it is the last address of X-Forwarded-For not added by a trusted proxy
+type RateLimitConfig struct {
+	Enabled        bool                 `yaml:"enabled" json:"enabled"`
+	Default        RateLimit            `yaml:"default" json:"default"`
+	Routes         map[string]RateLimit `yaml:"routes" json:"routes"`
+	AuthFailures   RateLimit            `yaml:"auth_failures" json:"auth_failures"`
+	TrustedProxies []string             `yaml:"trusted_proxies" json:"trusted_proxies"`
+}
+
+// ParseTrustedProxies() returns the trusted proxies as IP networks - a single IP address is a network of its own - and an
+// error naming the first entry which is neither an IP address nor a CIDR range
+func (limits RateLimitConfig) ParseTrustedProxies() ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(limits.TrustedProxies))
+	for _, proxy := range limits.TrustedProxies {
+		if ip := net.ParseIP(proxy); ip != nil {
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, network, err := net.ParseCIDR(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("has %q, which is neither an IP address nor a CIDR range", proxy)
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// RateLimit allows Burst requests at once and Rate requests per second after that
+type RateLimit struct {
+	Rate  float64 `yaml:"rate" json:"rate"`
+	Burst int     `yaml:"burst" json:"burst"`
+}
+
+// validate() returns the problems with a limit configured at name
+func (limit RateLimit) validate(name string) []string {
+	var problems []string
+	if limit.Rate <= 0 {
+		problems = append(problems, name+".rate must be greater than zero")
+	}
+	if limit.Burst < 1 {
+		problems = append(problems, name+".burst must be at least 1")
+	}
+	return problems
+}
+
+// OutboxConfig configures the outbox relay, which publishes the domain events written to the outbox_events table - the
+// relay runs inside the service when Enabled is set, or on its own (see make outboxrelay) - Publisher selects where the
+// events go: "subscriptions" queues a delivery of each event for every webhook subscription to its type (see
+// WebhookConfig), "stdout" writes one JSON line per event to standard output, "file" appends them to FilePath and
+// "webhook" POSTs each one to WebhookURL
+// every event is delivered at least once - a failed attempt is retried with exponential backoff up to MaxBackoff, and an
+// event is leased to one relay for Lease so that several relays may run side by side
+type OutboxConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`
+	Publisher      string   `yaml:"publisher" json:"publisher"`
+	FilePath       string   `yaml:"file_path" json:"file_path"`
+	WebhookURL     string   `yaml:"webhook_url" json:"webhook_url"`
+	WebhookTimeout Duration `yaml:"webhook_timeout" json:"webhook_timeout"`
+	PollInterval   Duration `yaml:"poll_interval" json:"poll_interval"`
+	BatchSize      int      `yaml:"batch_size" json:"batch_size"`
+	Lease          Duration `yaml:"lease" json:"lease"`
+	MaxBackoff     Duration `yaml:"max_backoff" json:"max_backoff"`
+	// Retention is how long published events are kept before the relay removes them - zero keeps them forever
+	Retention Duration `yaml:"retention" json:"retention"`
+}
+
+// outbox publishers accepted by OutboxConfig.Publisher
+const (
+	OutboxPublisherSubscriptions = "subscriptions"
+	OutboxPublisherStdout        = "stdout"
+	OutboxPublisherFile          = "file"
+	OutboxPublisherWebhook       = "webhook"
+)
+
+// WebhookConfig configures the worker which delivers the events queued for the webhook subscriptions - it runs inside the
+// service when Enabled is set - each delivery is POSTed to the subscription's URL, signed with the subscription's secret,
+// and a delivery which fails is retried after InitialBackoff, doubling up to MaxBackoff, until MaxAttempts attempts have
+// failed
+// as with the outbox relay, a delivery is leased to one worker for Lease so that several workers may run side by side
+type WebhookConfig struct {
+	Enabled        bool     `yaml:"enabled" json:"enabled"`
+	Timeout        Duration `yaml:"timeout" json:"timeout"`
+	PollInterval   Duration `yaml:"poll_interval" json:"poll_interval"`
+	BatchSize      int      `yaml:"batch_size" json:"batch_size"`
+	Lease          Duration `yaml:"lease" json:"lease"`
+	MaxAttempts    int      `yaml:"max_attempts" json:"max_attempts"`
+	InitialBackoff Duration `yaml:"initial_backoff" json:"initial_backoff"`
+	MaxBackoff     Duration `yaml:"max_backoff" json:"max_backoff"`
+}
+
+// ImportConfig holds the limits of the bulk transaction import - the import's progress is recorded after every row, and
+// an import still processing whose progress was not recorded for StaleAfter is taken to have crashed and may be resumed -
+// a file of more than MaxRows rows is refused
+type ImportConfig struct {
+	StaleAfter Duration `yaml:"stale_after" json:"stale_after"`
+	MaxRows    int      `yaml:"max_rows" json:"max_rows"`
+}
+
+// auth modes accepted by AuthConfig.Mode
+const (
+	AuthModeRemote = "remote"
+	AuthModeLocal  = "local"
+	AuthModeNone   = "none"
+)
+
+// Default() returns the configuration used when neither a file nor an environment variable sets a value - the pool
+// settings match the values previously hardcoded in getDbConnection()
+func Default() Config {
+	// the gRPC server is left off (GRPCPort is empty) - it must be enabled explicitly, as it is a second listener which is
+	// neither rate limited nor accepts API keys
+	return Config{
+		DB: DBConfig{
+			MaxOpenConns:    10,
+			MaxIdleConns:    10,
+			ConnMaxLifetime: Duration(3 * time.Minute),
+		},
+		Auth: AuthConfig{
+			Mode:            AuthModeRemote,
+			ServerURL:       "http://localhost:8181",
+			AccessTokenTTL:  Duration(15 * time.Minute),
+			RefreshTokenTTL: Duration(24 * time.Hour),
+			// 12 matches the cost of the seeded password hashes (see migration 000006)
+			PasswordHashCost:  12,
+			PasswordMinLength: 12,
+		},
+		Limits: LimitsConfig{
+			RequestTimeout:   Duration(10 * time.Second),
+			MaxBodyBytes:     1 << 20, // 1 MiB
+			ReadinessTimeout: Duration(2 * time.Second),
+		},
+		Features: FeatureConfig{
+			AccountCreation: true,
+			Transactions:    true,
+		},
+		Tracing: TracingConfig{
+			Exporter:     TracingExporterNone,
+			OTLPEndpoint: "localhost:4318",
+			ServiceName:  "banking-ms",
+			SampleRatio:  1,
+		},
+		API: APIConfig{
+			LegacyDeprecatedAt: "2026-10-19",
+			LegacySunsetAt:     "2027-04-30",
+		},
+		Accounts: AccountConfig{
+			MaxPerType: 3,
+			MinimumAge: 18,
+		},
+		RateLimits: RateLimitConfig{
+			Enabled: true,
+			Default: RateLimit{Rate: 20, Burst: 40},
+			// the routes which write or scan the most are held tighter - a login is limited per IP address to slow down
+			// password guessing
+			Routes: map[string]RateLimit{
+				"NewTransaction":  {Rate: 2, Burst: 10},
+				"GetAllCustomers": {Rate: 5, Burst: 10},
+				"Login":           {Rate: 0.2, Burst: 5},
+			},
+			AuthFailures: RateLimit{Rate: 0.2, Burst: 10},
+		},
+		Outbox: OutboxConfig{
+			Enabled:        true,
+			Publisher:      OutboxPublisherSubscriptions,
+			WebhookTimeout: Duration(5 * time.Second),
+			PollInterval:   Duration(time.Second),
+			BatchSize:      100,
+			Lease:          Duration(10 * time.Minute), // longer than 100 webhook calls timing out one after another
+			MaxBackoff:     Duration(10 * time.Minute),
+			Retention:      Duration(7 * 24 * time.Hour),
+		},
+		// a delivery is attempted 10 times over about 2 hours before it is given up - it may still be replayed after that
+		Webhooks: WebhookConfig{
+			Enabled:        true,
+			Timeout:        Duration(10 * time.Second),
+			PollInterval:   Duration(time.Second),
+			BatchSize:      50,
+			Lease:          Duration(10 * time.Minute), // longer than 50 deliveries timing out one after another
+			MaxAttempts:    10,
+			InitialBackoff: Duration(10 * time.Second),
+			MaxBackoff:     Duration(time.Hour),
+		},
+		Imports: ImportConfig{
+			StaleAfter: Duration(5 * time.Minute),
+			MaxRows:    10000,
+		},
+	}
+}
+
+// Load() builds the configuration - it starts from Default(), applies the file at path (if path is not empty) and then
+// applies any environment variable overrides - the result is validated and every problem found is reported in a single
+// *ValidationError rather than stopping at the first one
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	// problems collects every issue found with the file's keys, the environment overrides and the final values
+	var problems []string
+	if path != "" {
+		fileProblems, err := cfg.loadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		problems = append(problems, fileProblems...)
+	}
+	problems = append(problems, cfg.applyEnv(os.Getenv)...)
+	problems = append(problems, cfg.validate()...)
+	if len(problems) > 0 {
+		return nil, &ValidationError{Problems: problems}
+	}
+	return &cfg, nil
+}
+
+// loadFile() decodes the file at path into cfg - the file extension decides between JSON (.json) and YAML (.yaml or .yml)
+// - a key which is not a setting (e.g. a misspelt one) would otherwise be ignored, so each one is returned as a problem
+func (cfg *Config) loadFile(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading config file: %w", err)
+	}
+	// the file is also decoded into document, whose keys are checked against the fields of Config named by tag
+	var document map[string]interface{}
+	var tag string
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		tag = "json"
+		if err = json.Unmarshal(data, cfg); err == nil {
+			err = json.Unmarshal(data, &document)
+		}
+	case ".yaml", ".yml":
+		tag = "yaml"
+		if err = yaml.Unmarshal(data, cfg); err == nil {
+			err = yaml.Unmarshal(data, &document)
+		}
+	default:
+		return nil, fmt.Errorf("config file %s must have a .json, .yaml or .yml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding config file %s: %w", path, err)
+	}
+	var problems []string
+	for _, key := range unknownKeys(document, reflect.TypeOf(*cfg), tag, "") {
+		problems = append(problems, "unknown key "+key+" in "+filepath.Base(path))
+	}
+	return problems, nil
+}
+
+// unknownKeys() returns the dotted path (e.g. rate_limits.defualt) of every key of value with no field of typ named by the
+// tag - the keys of a map field (e.g. rate_limits.routes) are free, but their values are checked against its element type
+func unknownKeys(value interface{}, typ reflect.Type, tag string, prefix string) []string {
+	fields, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	// sorted so that the problems are reported in the same order every time
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var unknown []string
+	switch typ.Kind() {
+	case reflect.Map:
+		for _, key := range keys {
+			unknown = append(unknown, unknownKeys(fields[key], typ.Elem(), tag, prefix+key+".")...)
+		}
+	case reflect.Struct:
+		known := make(map[string]reflect.Type, typ.NumField())
+		for i := 0; i < typ.NumField(); i++ {
+			if name := strings.Split(typ.Field(i).Tag.Get(tag), ",")[0]; name != "" && name != "-" {
+				known[name] = typ.Field(i).Type
+			}
+		}
+		for _, key := range keys {
+			fieldType, ok := known[key]
+			if !ok {
+				unknown = append(unknown, prefix+key)
+				continue
+			}
+			unknown = append(unknown, unknownKeys(fields[key], fieldType, tag, prefix+key+".")...)
+		}
+	}
+	return unknown
+}
+
+// applyEnv() overrides cfg with any environment variables that are set - getenv is passed in (os.Getenv in production) so
+// that tests can supply their own environment - values which fail to parse are returned as problems
+func (cfg *Config) applyEnv(getenv func(string) string) []string {
+	var problems []string
+
+	// string settings are copied as-is when set
+	strs := map[string]*string{
+		"SERVER_ADDRESS":           &cfg.Server.Address,
+		"SERVER_PORT":              &cfg.Server.Port,
+		"GRPC_PORT":                &cfg.Server.GRPCPort,
+		"DB_USER":                  &cfg.DB.User,
+		"DB_PASSWD":                &cfg.DB.Passwd,
+		"DB_ADDRESS":               &cfg.DB.Address,
+		"DB_PORT":                  &cfg.DB.Port,
+		"DB_NAME":                  &cfg.DB.Name,
+		"AUTH_MODE":                &cfg.Auth.Mode,
+		"AUTH_SERVER_URL":          &cfg.Auth.ServerURL,
+		"AUTH_TOKEN_SECRET":        &cfg.Auth.TokenSecret,
+		"AUTH_POLICY_FILE":         &cfg.Auth.PolicyFile,
+		"TRACING_EXPORTER":         &cfg.Tracing.Exporter,
+		"TRACING_OTLP_ENDPOINT":    &cfg.Tracing.OTLPEndpoint,
+		"TRACING_SERVICE_NAME":     &cfg.Tracing.ServiceName,
+		"API_LEGACY_DEPRECATED_AT": &cfg.API.LegacyDeprecatedAt,
+		"API_LEGACY_SUNSET_AT":     &cfg.API.LegacySunsetAt,
+		"OUTBOX_PUBLISHER":         &cfg.Outbox.Publisher,
+		"OUTBOX_FILE_PATH":         &cfg.Outbox.FilePath,
+		"OUTBOX_WEBHOOK_URL":       &cfg.Outbox.WebhookURL,
+	}
+	for key, dest := range strs {
+		if value := getenv(key); value != "" {
+			*dest = value
+		}
+	}
+
+	ints := map[string]*int{
+		"DB_MAX_OPEN_CONNS":        &cfg.DB.MaxOpenConns,
+		"DB_MAX_IDLE_CONNS":        &cfg.DB.MaxIdleConns,
+		"ACCOUNTS_MAX_PER_TYPE":    &cfg.Accounts.MaxPerType,
+		"ACCOUNTS_MINIMUM_AGE":     &cfg.Accounts.MinimumAge,
+		"AUTH_PASSWORD_HASH_COST":  &cfg.Auth.PasswordHashCost,
+		"AUTH_PASSWORD_MIN_LENGTH": &cfg.Auth.PasswordMinLength,
+		"OUTBOX_BATCH_SIZE":        &cfg.Outbox.BatchSize,
+		"WEBHOOKS_MAX_ATTEMPTS":    &cfg.Webhooks.MaxAttempts,
+		"IMPORTS_MAX_ROWS":         &cfg.Imports.MaxRows,
+	}
+	for key, dest := range ints {
+		if value := getenv(key); value != "" {
+			n, err := strconv.Atoi(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("environment variable %s must be an integer", key))
+				continue
+			}
+			*dest = n
+		}
+	}
+
+	// the trusted proxies are a comma separated list, e.g. 10.0.0.0/8,192.0.2.10
+	if value := getenv("RATE_LIMITS_TRUSTED_PROXIES"); value != "" {
+		cfg.RateLimits.TrustedProxies = strings.Split(value, ",")
+		for i := range cfg.RateLimits.TrustedProxies {
+			cfg.RateLimits.TrustedProxies[i] = strings.TrimSpace(cfg.RateLimits.TrustedProxies[i])
+		}
+	}
+
+	if value := getenv("MAX_BODY_BYTES"); value != "" {
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			problems = append(problems, "environment variable MAX_BODY_BYTES must be an integer")
+		} else {
+			cfg.Limits.MaxBodyBytes = n
+		}
+	}
+
+	durations := map[string]*Duration{
+		"DB_CONN_MAX_LIFETIME":   &cfg.DB.ConnMaxLifetime,
+		"REQUEST_TIMEOUT":        &cfg.Limits.RequestTimeout,
+		"READINESS_TIMEOUT":      &cfg.Limits.ReadinessTimeout,
+		"AUTH_ACCESS_TOKEN_TTL":  &cfg.Auth.AccessTokenTTL,
+		"AUTH_REFRESH_TOKEN_TTL": &cfg.Auth.RefreshTokenTTL,
+		"OUTBOX_POLL_INTERVAL":   &cfg.Outbox.PollInterval,
+		"WEBHOOKS_TIMEOUT":       &cfg.Webhooks.Timeout,
+		"WEBHOOKS_POLL_INTERVAL": &cfg.Webhooks.PollInterval,
+		"IMPORTS_STALE_AFTER":    &cfg.Imports.StaleAfter,
+	}
+	for key, dest := range durations {
+		if value := getenv(key); value != "" {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("environment variable %s must be a duration (e.g. 30s)", key))
+				continue
+			}
+			*dest = Duration(d)
+		}
+	}
+
+	bools := map[string]*bool{
+		"FEATURE_ACCOUNT_CREATION": &cfg.Features.AccountCreation,
+		"FEATURE_TRANSACTIONS":     &cfg.Features.Transactions,
+		"TRACING_OTLP_INSECURE":    &cfg.Tracing.OTLPInsecure,
+		"RATE_LIMITS_ENABLED":      &cfg.RateLimits.Enabled,
+		"OUTBOX_ENABLED":           &cfg.Outbox.Enabled,
+		"WEBHOOKS_ENABLED":         &cfg.Webhooks.Enabled,
+	}
+	for key, dest := range bools {
+		if value := getenv(key); value != "" {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("environment variable %s must be true or false", key))
+				continue
+			}
+			*dest = b
+		}
+	}
+
+	if value := getenv("TRACING_SAMPLE_RATIO"); value != "" {
+		ratio, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			problems = append(problems, "environment variable TRACING_SAMPLE_RATIO must be a number")
+		} else {
+			cfg.Tracing.SampleRatio = ratio
+		}
+	}
+
+	return problems
+}
+
+// validate() checks the final configuration and returns every problem found
+func (cfg Config) validate() []string {
+	var problems []string
+
+	// required settings - these were previously checked one at a time by environmentVariableCheck()
+	required := []struct {
+		name  string
+		value string
+	}{
+		{"server.address (SERVER_ADDRESS)", cfg.Server.Address},
+		{"server.port (SERVER_PORT)", cfg.Server.Port},
+		{"db.user (DB_USER)", cfg.DB.User},
+		{"db.passwd (DB_PASSWD)", cfg.DB.Passwd},
+		{"db.address (DB_ADDRESS)", cfg.DB.Address},
+		{"db.port (DB_PORT)", cfg.DB.Port},
+		{"db.name (DB_NAME)", cfg.DB.Name},
+	}
+	for _, setting := range required {
+		if setting.value == "" {
+			problems = append(problems, setting.name+" is required")
+		}
+	}
+
+	if cfg.Server.Port != "" && !isPort(cfg.Server.Port) {
+		problems = append(problems, "server.port must be a number between 1 and 65535")
+	}
+	if cfg.Server.GRPCPort != "" {
+		if !isPort(cfg.Server.GRPCPort) {
+			problems = append(problems, "server.grpc_port must be a number between 1 and 65535")
+		} else if cfg.Server.GRPCPort == cfg.Server.Port {
+			problems = append(problems, "server.grpc_port must differ from server.port")
+		}
+	}
+	if cfg.DB.Port != "" && !isPort(cfg.DB.Port) {
+		problems = append(problems, "db.port must be a number between 1 and 65535")
+	}
+
+	if cfg.DB.MaxOpenConns < 1 {
+		problems = append(problems, "db.max_open_conns must be at least 1")
+	}
+	if cfg.DB.MaxIdleConns < 0 {
+		problems = append(problems, "db.max_idle_conns must not be negative")
+	}
+	if cfg.DB.MaxIdleConns > cfg.DB.MaxOpenConns {
+		problems = append(problems, "db.max_idle_conns must not exceed db.max_open_conns")
+	}
+	if cfg.DB.ConnMaxLifetime < 0 {
+		problems = append(problems, "db.conn_max_lifetime must not be negative")
+	}
+
+	switch cfg.Auth.Mode {
+	case AuthModeRemote:
+		if cfg.Auth.ServerURL == "" {
+			problems = append(problems, "auth.server_url is required when auth.mode is remote")
+		} else if u, err := url.Parse(cfg.Auth.ServerURL); err != nil || u.Scheme == "" || u.Host == "" {
+			problems = append(problems, "auth.server_url must be an absolute URL such as http://localhost:8181")
+		}
+	case AuthModeLocal:
+		if len(cfg.Auth.TokenSecret) < MinTokenSecretBytes {
+			problems = append(problems, fmt.Sprintf("auth.token_secret must be at least %d bytes when auth.mode is local", MinTokenSecretBytes))
+		}
+		if cfg.Auth.AccessTokenTTL <= 0 {
+			problems = append(problems, "auth.access_token_ttl must be greater than zero")
+		}
+		if cfg.Auth.RefreshTokenTTL <= cfg.Auth.AccessTokenTTL {
+			problems = append(problems, "auth.refresh_token_ttl must be longer than auth.access_token_ttl")
+		}
+	case AuthModeNone:
+	default:
+		problems = append(problems, fmt.Sprintf("auth.mode must be %s, %s or %s", AuthModeRemote, AuthModeLocal, AuthModeNone))
+	}
+	// the password settings are checked in every mode as the one-off password hashing command also reads them
+	if cfg.Auth.PasswordHashCost < MinPasswordHashCost || cfg.Auth.PasswordHashCost > MaxPasswordHashCost {
+		problems = append(problems, fmt.Sprintf("auth.password_hash_cost must be between %d and %d", MinPasswordHashCost, MaxPasswordHashCost))
+	}
+	// bcrypt ignores every byte past the 72nd, so a longer minimum could never be met
+	if cfg.Auth.PasswordMinLength < 8 || cfg.Auth.PasswordMinLength > 72 {
+		problems = append(problems, "auth.password_min_length must be between 8 and 72")
+	}
+
+	switch cfg.Tracing.Exporter {
+	case TracingExporterOTLP:
+		if cfg.Tracing.OTLPEndpoint == "" {
+			problems = append(problems, "tracing.otlp_endpoint is required when tracing.exporter is otlp")
+		}
+	case TracingExporterNone, TracingExporterStdout:
+	default:
+		problems = append(problems, fmt.Sprintf("tracing.exporter must be %s, %s or %s",
+			TracingExporterNone, TracingExporterStdout, TracingExporterOTLP))
+	}
+	if cfg.Tracing.SampleRatio < 0 || cfg.Tracing.SampleRatio > 1 {
+		problems = append(problems, "tracing.sample_ratio must be between 0 and 1")
+	}
+
+	if cfg.Limits.RequestTimeout <= 0 {
+		problems = append(problems, "limits.request_timeout must be greater than zero")
+	}
+	if cfg.Limits.ReadinessTimeout <= 0 {
+		problems = append(problems, "limits.readiness_timeout must be greater than zero")
+	}
+	if cfg.Limits.MaxBodyBytes <= 0 {
+		problems = append(problems, "limits.max_body_bytes must be greater than zero")
+	}
+
+	if cfg.Accounts.MaxPerType < 0 {
+		problems = append(problems, "accounts.max_per_type must not be negative")
+	}
+	if cfg.Accounts.MinimumAge < 0 {
+		problems = append(problems, "accounts.minimum_age must not be negative")
+	}
+
+	// the route names are checked against the router at startup, as the config does not know them
+	if cfg.RateLimits.Enabled {
+		problems = append(problems, cfg.RateLimits.Default.validate("rate_limits.default")...)
+		problems = append(problems, cfg.RateLimits.AuthFailures.validate("rate_limits.auth_failures")...)
+		if _, err := cfg.RateLimits.ParseTrustedProxies(); err != nil {
+			problems = append(problems, "rate_limits.trusted_proxies "+err.Error())
+		}
+		routes := make([]string, 0, len(cfg.RateLimits.Routes))
+		for route := range cfg.RateLimits.Routes {
+			routes = append(routes, route)
+		}
+		// sorted so that the problems are reported in the same order every time
+		sort.Strings(routes)
+		for _, route := range routes {
+			problems = append(problems, cfg.RateLimits.Routes[route].validate("rate_limits.routes."+route)...)
+		}
+	}
+
+	// the outbox settings are checked whether or not the relay runs inside the service, as the standalone relay reads them
+	problems = append(problems, cfg.Outbox.validate()...)
+	problems = append(problems, cfg.Webhooks.validate()...)
+	// an import is only taken over once it has been quiet for longer than any one row could take to post
+	if cfg.Imports.StaleAfter < Duration(time.Minute) {
+		problems = append(problems, "imports.stale_after must be at least 1m")
+	}
+	if cfg.Imports.MaxRows < 1 {
+		problems = append(problems, "imports.max_rows must be at least 1")
+	}
+
+	deprecatedAt, deprecatedErr := time.Parse(DateLayout, cfg.API.LegacyDeprecatedAt)
+	if deprecatedErr != nil {
+		problems = append(problems, "api.legacy_deprecated_at must be a date (YYYY-MM-DD)")
+	}
+	sunsetAt, sunsetErr := time.Parse(DateLayout, cfg.API.LegacySunsetAt)
+	if sunsetErr != nil {
+		problems = append(problems, "api.legacy_sunset_at must be a date (YYYY-MM-DD)")
+	}
+	if deprecatedErr == nil && sunsetErr == nil && !sunsetAt.After(deprecatedAt) {
+		problems = append(problems, "api.legacy_sunset_at must be after api.legacy_deprecated_at")
+	}
+
+	return problems
+}
+
+// validate() returns the problems with the outbox relay settings
+func (outbox OutboxConfig) validate() []string {
+	var problems []string
+	switch outbox.Publisher {
+	case OutboxPublisherSubscriptions, OutboxPublisherStdout:
+	case OutboxPublisherFile:
+		if outbox.FilePath == "" {
+			problems = append(problems, "outbox.file_path is required when outbox.publisher is file")
+		}
+	case OutboxPublisherWebhook:
+		if u, err := url.Parse(outbox.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, "outbox.webhook_url must be an absolute http or https URL when outbox.publisher is webhook")
+		}
+		if outbox.WebhookTimeout <= 0 {
+			problems = append(problems, "outbox.webhook_timeout must be greater than zero")
+		}
+	default:
+		problems = append(problems, fmt.Sprintf("outbox.publisher must be %s, %s, %s or %s",
+			OutboxPublisherSubscriptions, OutboxPublisherStdout, OutboxPublisherFile, OutboxPublisherWebhook))
+	}
+	if outbox.PollInterval <= 0 {
+		problems = append(problems, "outbox.poll_interval must be greater than zero")
+	}
+	if outbox.BatchSize < 1 || outbox.BatchSize > 1000 {
+		problems = append(problems, "outbox.batch_size must be between 1 and 1000")
+	}
+	// the events of a batch are published one after another, each taking up to the webhook timeout, so the whole batch
+	// must be published within one lease
+	if outbox.Lease <= outbox.WebhookTimeout*Duration(outbox.BatchSize) {
+		problems = append(problems, "outbox.lease must be longer than outbox.batch_size times outbox.webhook_timeout")
+	}
+	if outbox.MaxBackoff < Duration(time.Second) {
+		problems = append(problems, "outbox.max_backoff must be at least 1s")
+	}
+	if outbox.Retention < 0 {
+		problems = append(problems, "outbox.retention must not be negative")
+	}
+	return problems
+}
+
+// validate() returns the problems with the webhook delivery settings
+func (webhooks WebhookConfig) validate() []string {
+	var problems []string
+	if webhooks.Timeout <= 0 {
+		problems = append(problems, "webhooks.timeout must be greater than zero")
+	}
+	if webhooks.PollInterval <= 0 {
+		problems = append(problems, "webhooks.poll_interval must be greater than zero")
+	}
+	if webhooks.BatchSize < 1 || webhooks.BatchSize > 1000 {
+		problems = append(problems, "webhooks.batch_size must be between 1 and 1000")
+	}
+	// the deliveries of a batch are attempted one after another, each taking up to the timeout, so the whole batch must be
+	// delivered within one lease
+	if webhooks.Lease <= webhooks.Timeout*Duration(webhooks.BatchSize) {
+		problems = append(problems, "webhooks.lease must be longer than webhooks.batch_size times webhooks.timeout")
+	}
+	if webhooks.MaxAttempts < 1 || webhooks.MaxAttempts > 50 {
+		problems = append(problems, "webhooks.max_attempts must be between 1 and 50")
+	}
+	if webhooks.InitialBackoff < Duration(time.Second) {
+		problems = append(problems, "webhooks.initial_backoff must be at least 1s")
+	}
+	if webhooks.MaxBackoff < webhooks.InitialBackoff {
+		problems = append(problems, "webhooks.max_backoff must not be shorter than webhooks.initial_backoff")
+	}
+	return problems
+}
+
+// isPort() reports whether s is a valid TCP port number
+func isPort(s string) bool {
+	port, err := strconv.Atoi(s)
+	return err == nil && port > 0 && port <= 65535
+}
+
+// ValidationError lists every problem found while loading the configuration
+type ValidationError struct {
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return "invalid configuration:\n  - " + strings.Join(e.Problems, "\n  - ")
+}
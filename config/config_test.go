@@ -0,0 +1,358 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// validConfig() returns a configuration which passes validation
+func validConfig() Config {
+	cfg := Default()
+	cfg.Server = ServerConfig{Address: "localhost", Port: "8000"}
+	cfg.DB.User = "root"
+	cfg.DB.Passwd = "53cr3t01"
+	cfg.DB.Address = "localhost"
+	cfg.DB.Port = "3306"
+	cfg.DB.Name = "banking"
+	return cfg
+}
+
+// TestValidateReportsAllProblems() should pass when every invalid setting is reported rather than only the first
+func TestValidateReportsAllProblems(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	// the default configuration is missing all seven required settings
+	cfg := Default()
+	cfg.DB.MaxOpenConns = 0
+	cfg.Auth.Mode = "invalid"
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	// seven required settings, max_open_conns, max_idle_conns exceeding max_open_conns, and the auth mode
+	if len(problems) != 10 {
+		t.Errorf("expected 10 problems, received %d: %v", len(problems), problems)
+	}
+}
+
+// TestValidateSuccess() should pass when a complete configuration is valid
+func TestValidateSuccess(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	if len(problems) != 0 {
+		t.Errorf("expected no problems, received %v", problems)
+	}
+}
+
+// TestDefaultLeavesGRPCOff() should pass when the gRPC server is only started once grpc_port is set, e.g. by GRPC_PORT
+func TestDefaultLeavesGRPCOff(t *testing.T) {
+	// Arrange - setup test
+	cfg := Default()
+	enabled := Default()
+
+	// Act - execute test
+	problems := enabled.applyEnv(func(name string) string {
+		if name == "GRPC_PORT" {
+			return "9000"
+		}
+		return ""
+	})
+
+	// Assert - test expectations
+	if cfg.Server.GRPCPort != "" {
+		t.Errorf("received grpc_port %q by default, want it empty", cfg.Server.GRPCPort)
+	}
+	if len(problems) != 0 || enabled.Server.GRPCPort != "9000" {
+		t.Errorf("received grpc_port %q (problems %v), want 9000 from GRPC_PORT", enabled.Server.GRPCPort, problems)
+	}
+}
+
+// TestApplyEnvOverrides() should pass when environment variables override values and unparsable values are reported
+func TestApplyEnvOverrides(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	env := map[string]string{
+		"SERVER_PORT":          "9000",
+		"DB_MAX_OPEN_CONNS":    "25",
+		"DB_CONN_MAX_LIFETIME": "5m",
+		"FEATURE_TRANSACTIONS": "false",
+		"REQUEST_TIMEOUT":      "soon",
+		// a list is comma separated
+		"RATE_LIMITS_TRUSTED_PROXIES": "10.0.0.0/8, 192.0.2.10",
+	}
+	getenv := func(key string) string { return env[key] }
+
+	// Act - execute test
+	problems := cfg.applyEnv(getenv)
+
+	// Assert - test expectations
+	if cfg.Server.Port != "9000" || cfg.DB.MaxOpenConns != 25 || cfg.DB.ConnMaxLifetime.Std() != 5*time.Minute {
+		t.Errorf("environment overrides were not applied: %+v", cfg)
+	}
+	if cfg.Features.Transactions {
+		t.Error("expected the transactions feature to be disabled")
+	}
+	if !reflect.DeepEqual(cfg.RateLimits.TrustedProxies, []string{"10.0.0.0/8", "192.0.2.10"}) {
+		t.Errorf("received trusted proxies %v, want 10.0.0.0/8 and 192.0.2.10", cfg.RateLimits.TrustedProxies)
+	}
+	if len(problems) != 1 || !strings.Contains(problems[0], "REQUEST_TIMEOUT") {
+		t.Errorf("expected a single REQUEST_TIMEOUT problem, received %v", problems)
+	}
+}
+
+// TestLoadFile() should pass when both YAML and JSON config files decode into the same configuration
+func TestLoadFile(t *testing.T) {
+	// Arrange - setup test
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	jsonPath := filepath.Join(dir, "config.json")
+	yamlData := "db:\n  max_open_conns: 20\n  conn_max_lifetime: 1m\nlimits:\n  request_timeout: 2s\n"
+	jsonData := `{"db": {"max_open_conns": 20, "conn_max_lifetime": "1m"}, "limits": {"request_timeout": "2s"}}`
+	if err := os.WriteFile(yamlPath, []byte(yamlData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(jsonData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		cfg := Default()
+
+		// Act - execute test
+		problems, err := cfg.loadFile(path)
+
+		// Assert - test expectations
+		if err != nil || len(problems) != 0 {
+			t.Fatalf("unexpected error loading %s: %v %v", path, err, problems)
+		}
+		if cfg.DB.MaxOpenConns != 20 || cfg.DB.ConnMaxLifetime.Std() != time.Minute ||
+			cfg.Limits.RequestTimeout.Std() != 2*time.Second {
+			t.Errorf("%s was not decoded correctly: %+v", path, cfg)
+		}
+		// settings missing from the file keep their defaults
+		if cfg.DB.MaxIdleConns != 10 {
+			t.Errorf("%s overwrote a default it did not set", path)
+		}
+	}
+}
+
+// TestLoadFileUnknownKeys() should pass when every key of a YAML or JSON file which is not a setting is reported with its
+// path, including a misspelt field of a route's rate limit, and Load() refuses the file with them
+func TestLoadFileUnknownKeys(t *testing.T) {
+	// Arrange - setup test
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "config.yaml")
+	jsonPath := filepath.Join(dir, "config.json")
+	yamlData := "sever:\n  port: \"8000\"\nlimits:\n  request_timeot: 2s\nrate_limits:\n  routes:\n    Login: {rate: 1, burts: 5}\n"
+	jsonData := `{"sever": {"port": "8000"}, "limits": {"request_timeot": "2s"}, "rate_limits": {"routes": {"Login": {"rate": 1, "burts": 5}}}}`
+	if err := os.WriteFile(yamlPath, []byte(yamlData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(jsonPath, []byte(jsonData), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range []string{yamlPath, jsonPath} {
+		cfg := Default()
+
+		// Act - execute test
+		problems, err := cfg.loadFile(path)
+		_, loadErr := Load(path)
+
+		// Assert - test expectations
+		if err != nil {
+			t.Fatalf("unexpected error loading %s: %v", path, err)
+		}
+		name := filepath.Base(path)
+		want := []string{"unknown key limits.request_timeot in " + name, "unknown key rate_limits.routes.Login.burts in " + name,
+			"unknown key sever in " + name}
+		if !reflect.DeepEqual(problems, want) {
+			t.Errorf("received %v, want %v", problems, want)
+		}
+		var validationErr *ValidationError
+		if !errors.As(loadErr, &validationErr) || !strings.Contains(loadErr.Error(), "unknown key sever in "+name) {
+			t.Errorf("received %v from Load(), want a ValidationError naming the unknown keys", loadErr)
+		}
+	}
+}
+
+// TestValidateLegacySchedule() should pass when a sunset date on or before the deprecation date is reported
+func TestValidateLegacySchedule(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.API.LegacySunsetAt = cfg.API.LegacyDeprecatedAt
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	if len(problems) != 1 || !strings.Contains(problems[0], "legacy_sunset_at") {
+		t.Errorf("expected a single legacy_sunset_at problem, received %v", problems)
+	}
+}
+
+// TestValidateAccountPolicy() should pass when negative account policy limits are reported
+func TestValidateAccountPolicy(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.Accounts.MaxPerType = -1
+	cfg.Accounts.MinimumAge = -18
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	if len(problems) != 2 {
+		t.Errorf("expected two account policy problems, received %v", problems)
+	}
+}
+
+// TestValidateLocalAuth() should pass when local auth reports a short token secret and a refresh token which expires
+// before the access token
+func TestValidateLocalAuth(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.Auth.Mode = AuthModeLocal
+	cfg.Auth.TokenSecret = "too short"
+	cfg.Auth.RefreshTokenTTL = cfg.Auth.AccessTokenTTL
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	if len(problems) != 2 || !strings.Contains(problems[0], "token_secret") || !strings.Contains(problems[1], "refresh_token_ttl") {
+		t.Errorf("expected token_secret and refresh_token_ttl problems, received %v", problems)
+	}
+}
+
+// TestValidatePasswordSettings() should pass when a bcrypt cost outside the accepted range and a minimum password length
+// bcrypt could never honour are both reported
+func TestValidatePasswordSettings(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.Auth.PasswordHashCost = 4
+	cfg.Auth.PasswordMinLength = 100
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	if len(problems) != 2 || !strings.Contains(problems[0], "password_hash_cost") || !strings.Contains(problems[1], "password_min_length") {
+		t.Errorf("expected password_hash_cost and password_min_length problems, received %v", problems)
+	}
+}
+
+// TestValidateRateLimits() should pass when a non-positive rate and an empty burst are reported for the default, a route
+// and the auth failure limit alike, as is a trusted proxy which is not an address, and nothing is checked once rate
+// limiting is disabled
+func TestValidateRateLimits(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.RateLimits.Default = RateLimit{Rate: 0, Burst: 1}
+	cfg.RateLimits.Routes = map[string]RateLimit{"NewTransaction": {Rate: 1, Burst: 0}}
+	cfg.RateLimits.AuthFailures = RateLimit{Rate: -1, Burst: 1}
+	cfg.RateLimits.TrustedProxies = []string{"10.0.0.0/8", "192.0.2.10", "proxy.internal"}
+	disabled := cfg
+	disabled.RateLimits.Enabled = false
+
+	// Act - execute test
+	problems := cfg.validate()
+	disabledProblems := disabled.validate()
+
+	// Assert - test expectations
+	want := []string{"rate_limits.default.rate must be greater than zero", "rate_limits.auth_failures.rate must be greater than zero",
+		`rate_limits.trusted_proxies has "proxy.internal", which is neither an IP address nor a CIDR range`,
+		"rate_limits.routes.NewTransaction.burst must be at least 1"}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("received %v, want %v", problems, want)
+	}
+	if len(disabledProblems) != 0 {
+		t.Errorf("expected no problems once rate limiting is disabled, received %v", disabledProblems)
+	}
+}
+
+// TestValidateOutbox() should pass when each publisher's own settings are required and the relay's timings are checked
+func TestValidateOutbox(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(*OutboxConfig)
+		want   []string
+	}{
+		{"defaults", func(outbox *OutboxConfig) {}, nil},
+		{"file without path", func(outbox *OutboxConfig) { outbox.Publisher = OutboxPublisherFile }, []string{"outbox.file_path is required when outbox.publisher is file"}},
+		{"webhook without scheme", func(outbox *OutboxConfig) {
+			outbox.Publisher, outbox.WebhookURL = OutboxPublisherWebhook, "hooks.example.com/banking"
+		}, []string{"outbox.webhook_url must be an absolute http or https URL when outbox.publisher is webhook"}},
+		{"unknown publisher", func(outbox *OutboxConfig) { outbox.Publisher = "kafka" }, []string{"outbox.publisher must be subscriptions, stdout, file or webhook"}},
+		{"timings", func(outbox *OutboxConfig) {
+			outbox.BatchSize, outbox.Lease, outbox.MaxBackoff = 1001, Duration(time.Second), 0
+		}, []string{"outbox.batch_size must be between 1 and 1000", "outbox.lease must be longer than outbox.batch_size times outbox.webhook_timeout", "outbox.max_backoff must be at least 1s"}},
+		{"lease shorter than a batch", func(outbox *OutboxConfig) {
+			// 5m is longer than the webhook timeout but shorter than 100 webhook calls timing out one after another
+			outbox.Lease = Duration(5 * time.Minute)
+		}, []string{"outbox.lease must be longer than outbox.batch_size times outbox.webhook_timeout"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange - setup test
+			cfg := validConfig()
+			test.change(&cfg.Outbox)
+
+			// Act - execute test
+			problems := cfg.validate()
+
+			// Assert - test expectations
+			if !reflect.DeepEqual(problems, test.want) {
+				t.Errorf("received %v, want %v", problems, test.want)
+			}
+		})
+	}
+}
+
+// TestValidateWebhooks() should pass when the delivery worker's timings and retry limits are checked
+func TestValidateWebhooks(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	// 5m is longer than the timeout but shorter than a batch of 50 deliveries timing out one after another
+	cfg.Webhooks.Lease, cfg.Webhooks.MaxAttempts, cfg.Webhooks.MaxBackoff = Duration(5*time.Minute), 0, Duration(time.Second)
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	want := []string{
+		"webhooks.lease must be longer than webhooks.batch_size times webhooks.timeout",
+		"webhooks.max_attempts must be between 1 and 50",
+		"webhooks.max_backoff must not be shorter than webhooks.initial_backoff",
+	}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("received %v, want %v", problems, want)
+	}
+}
+
+// TestValidateImports() should pass when an import stale_after below 1m and a row limit below 1 are reported
+func TestValidateImports(t *testing.T) {
+	// Arrange - setup test
+	cfg := validConfig()
+	cfg.Imports.StaleAfter, cfg.Imports.MaxRows = Duration(30*time.Second), 0
+
+	// Act - execute test
+	problems := cfg.validate()
+
+	// Assert - test expectations
+	want := []string{"imports.stale_after must be at least 1m", "imports.max_rows must be at least 1"}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("received %v, want %v", problems, want)
+	}
+}
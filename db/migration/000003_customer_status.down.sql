@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS `customer_status_changes`;
+
+ALTER TABLE `customers`
+  DROP COLUMN `status_reason`,
+  DROP COLUMN `status_changed_at`;
@@ -0,0 +1,14 @@
+ALTER TABLE `customers`
+  ADD COLUMN `status_reason` VARCHAR(255) NULL,
+  ADD COLUMN `status_changed_at` DATETIME NULL;
+
+CREATE TABLE IF NOT EXISTS `customer_status_changes` (
+  `change_id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `customer_id` INT NOT NULL,
+  `status` TINYINT NOT NULL,
+  `reason` VARCHAR(255) NOT NULL,
+  `changed_by` VARCHAR(255) NOT NULL DEFAULT '',
+  `changed_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  INDEX `status_change_cust_fk` (`customer_id`),
+  CONSTRAINT `status_change_cust_fk` FOREIGN KEY (`customer_id`) REFERENCES `customers` (`customer_id`)
+);
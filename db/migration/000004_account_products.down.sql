@@ -0,0 +1,3 @@
+ALTER TABLE `accounts` DROP FOREIGN KEY `acct_product_fk`;
+
+DROP TABLE IF EXISTS `account_products`;
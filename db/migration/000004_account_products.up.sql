@@ -0,0 +1,19 @@
+CREATE TABLE IF NOT EXISTS `account_products` (
+  `code` varchar(32) NOT NULL PRIMARY KEY,
+  `display_name` varchar(255) NOT NULL,
+  `minimum_deposit` DECIMAL(20,2) NOT NULL DEFAULT '0.00',
+  `interest_rate` DECIMAL(7,4) NOT NULL DEFAULT '0.0000',
+  `overdraft_allowed` tinyint NOT NULL DEFAULT '0',
+  `monthly_fee` DECIMAL(20,2) NOT NULL DEFAULT '0.00'
+);
+
+-- the two account types previously hardcoded, with the 5,000.00 minimum which applied to both
+INSERT INTO `account_products` VALUES
+  ('saving', 'Savings Account', '5000.00', '1.5000', 0, '0.00'),
+  ('checking', 'Checking Account', '5000.00', '0.0000', 1, '0.00');
+
+-- account types were stored with whatever casing the client sent (e.g. Saving) - they are normalised to the product code
+UPDATE `accounts` SET `account_type` = LOWER(`account_type`);
+
+ALTER TABLE `accounts`
+  ADD CONSTRAINT `acct_product_fk` FOREIGN KEY (`account_type`) REFERENCES `account_products` (`code`);
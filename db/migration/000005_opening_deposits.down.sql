@@ -0,0 +1,2 @@
+-- opening deposits recorded by the application cannot be told apart from the backfilled ones, so both are removed
+DELETE FROM `transactions` WHERE `transaction_type` = 'opening_deposit';
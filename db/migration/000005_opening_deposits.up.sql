@@ -0,0 +1,18 @@
+-- accounts opened before opening deposits were recorded get one dated with the account - its amount is whatever balance
+-- remains once the deposits and withdrawals already recorded are taken back out, so that every account's transactions
+-- replay to its current balance
+INSERT INTO `transactions` (`account_id`, `amount`, `transaction_type`, `transaction_date`)
+SELECT
+  a.`account_id`,
+  a.`amount` - COALESCE(SUM(CASE t.`transaction_type`
+    WHEN 'deposit' THEN t.`amount`
+    WHEN 'withdrawal' THEN -t.`amount`
+    ELSE 0 END), 0),
+  'opening_deposit',
+  a.`opening_date`
+FROM `accounts` a
+LEFT JOIN `transactions` t ON t.`account_id` = a.`account_id`
+WHERE NOT EXISTS (
+  SELECT 1 FROM `transactions` o WHERE o.`account_id` = a.`account_id` AND o.`transaction_type` = 'opening_deposit'
+)
+GROUP BY a.`account_id`, a.`amount`, a.`opening_date`;
@@ -0,0 +1,5 @@
+-- a hash cannot be reversed - only the seeded password is restored, any other hashed password is left as it is
+UPDATE `users` SET `password` = 'passw0rd123'
+WHERE `password` = '$2a$12$6P8rHY9E.2lov5N5XbSQ3.ccnuzCy4iu7VEYtQprbtUZW4X6hP9VC';
+
+ALTER TABLE `users` DROP COLUMN `password_changed_at`;
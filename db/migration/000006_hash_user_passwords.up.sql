@@ -0,0 +1,6 @@
+ALTER TABLE `users` ADD COLUMN `password_changed_at` DATETIME NULL;
+
+-- the seeded users share the password passw0rd123 - it is replaced with its bcrypt hash (cost 12) here as SQL cannot
+-- compute one, any other plaintext passwords are hashed by the one-off command (make hashpasswords)
+UPDATE `users` SET `password` = '$2a$12$6P8rHY9E.2lov5N5XbSQ3.ccnuzCy4iu7VEYtQprbtUZW4X6hP9VC'
+WHERE `password` = 'passw0rd123';
@@ -0,0 +1,12 @@
+-- API keys let batch jobs and partner systems call the API without a login - only the SHA-256 hash of each key is kept
+CREATE TABLE IF NOT EXISTS `api_keys` (
+  `key_id` VARCHAR(32) NOT NULL PRIMARY KEY,
+  `name` VARCHAR(100) NOT NULL,
+  `key_hash` CHAR(64) NOT NULL,
+  `scopes` TEXT NOT NULL,
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `expires_at` DATETIME NULL,
+  `revoked_at` DATETIME NULL,
+  `rotated_at` DATETIME NULL
+);
@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS `audit_log_no_delete`;
+DROP TRIGGER IF EXISTS `audit_log_no_update`;
+DROP TABLE IF EXISTS `audit_chain_head`;
+DROP TABLE IF EXISTS `audit_log`;
@@ -0,0 +1,36 @@
+-- the audit log records every mutating call - each entry holds the SHA-256 hash of its fields and of the previous entry's
+-- hash, so that changing or removing an entry breaks the chain from that entry onwards
+CREATE TABLE IF NOT EXISTS `audit_log` (
+  `entry_id` BIGINT NOT NULL PRIMARY KEY,
+  `occurred_at` DATETIME NOT NULL,
+  `actor` VARCHAR(255) NOT NULL,
+  `action` VARCHAR(100) NOT NULL,
+  `targets` TEXT NOT NULL,
+  `summary` TEXT NOT NULL,
+  `outcome` VARCHAR(10) NOT NULL,
+  `status` VARCHAR(32) NOT NULL,
+  `request_id` VARCHAR(64) NOT NULL DEFAULT '',
+  `prev_hash` CHAR(64) NOT NULL,
+  `hash` CHAR(64) NOT NULL,
+  KEY `idx_audit_log_actor` (`actor`, `entry_id`),
+  KEY `idx_audit_log_action` (`action`, `entry_id`),
+  KEY `idx_audit_log_occurred_at` (`occurred_at`)
+);
+
+-- the single row of audit_chain_head holds the last entry of the chain - appends lock it so that entries are chained one
+-- at a time, and removing the newest entries leaves the head pointing past the end of the log
+CREATE TABLE IF NOT EXISTS `audit_chain_head` (
+  `id` TINYINT NOT NULL PRIMARY KEY,
+  `last_entry_id` BIGINT NOT NULL,
+  `last_hash` CHAR(64) NOT NULL
+);
+
+INSERT INTO `audit_chain_head` (`id`, `last_entry_id`, `last_hash`)
+VALUES (1, 0, '0000000000000000000000000000000000000000000000000000000000000000');
+
+-- the log is append-only - the service's database user should also be granted only INSERT and SELECT on audit_log
+CREATE TRIGGER `audit_log_no_update` BEFORE UPDATE ON `audit_log` FOR EACH ROW
+SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
+
+CREATE TRIGGER `audit_log_no_delete` BEFORE DELETE ON `audit_log` FOR EACH ROW
+SIGNAL SQLSTATE '45000' SET MESSAGE_TEXT = 'audit_log is append-only';
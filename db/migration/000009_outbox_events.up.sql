@@ -0,0 +1,18 @@
+-- the outbox holds the domain events (e.g. AccountOpened) written in the same sql transaction as the change they record,
+-- until the outbox relay has published them - claimed_by and claimed_until lease an event to one relay at a time
+CREATE TABLE IF NOT EXISTS `outbox_events` (
+  `id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `event_id` CHAR(32) NOT NULL UNIQUE,
+  `event_type` VARCHAR(64) NOT NULL,
+  `aggregate_id` VARCHAR(32) NOT NULL,
+  `payload` TEXT NOT NULL,
+  `occurred_at` DATETIME NOT NULL,
+  `attempts` INT NOT NULL DEFAULT 0,
+  `next_attempt_at` DATETIME NOT NULL,
+  `claimed_by` CHAR(32) NULL,
+  `claimed_until` DATETIME NULL,
+  `published_at` DATETIME NULL,
+  `last_error` VARCHAR(1024) NULL,
+  KEY `idx_outbox_events_due` (`published_at`, `next_attempt_at`),
+  KEY `idx_outbox_events_claimed_by` (`claimed_by`)
+);
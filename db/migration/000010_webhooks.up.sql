@@ -0,0 +1,44 @@
+-- webhook subscriptions receive the outbox events of the types they list - the secret signs every delivery, so unlike an
+-- API key it is kept as is rather than hashed
+CREATE TABLE IF NOT EXISTS `webhook_subscriptions` (
+  `subscription_id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `url` VARCHAR(2048) NOT NULL,
+  `event_types` VARCHAR(255) NOT NULL,
+  `secret` VARCHAR(128) NOT NULL,
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  `disabled_at` DATETIME NULL
+);
+
+-- a delivery is one event to one subscription - the unique key keeps an event the outbox relay publishes more than once
+-- from being delivered more than once - claimed_by and claimed_until lease a delivery to one worker at a time
+CREATE TABLE IF NOT EXISTS `webhook_deliveries` (
+  `delivery_id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `subscription_id` INT NOT NULL,
+  `event_id` CHAR(32) NOT NULL,
+  `event_type` VARCHAR(64) NOT NULL,
+  `payload` TEXT NOT NULL,
+  `status` VARCHAR(10) NOT NULL DEFAULT 'pending',
+  `attempts` INT NOT NULL DEFAULT 0,
+  `next_attempt_at` DATETIME NOT NULL,
+  `claimed_by` CHAR(32) NULL,
+  `claimed_until` DATETIME NULL,
+  `created_at` DATETIME NOT NULL,
+  `delivered_at` DATETIME NULL,
+  UNIQUE KEY `uq_webhook_deliveries_event` (`subscription_id`, `event_id`),
+  KEY `idx_webhook_deliveries_due` (`status`, `next_attempt_at`),
+  KEY `idx_webhook_deliveries_claimed_by` (`claimed_by`),
+  FOREIGN KEY (`subscription_id`) REFERENCES `webhook_subscriptions` (`subscription_id`)
+);
+
+-- every attempt to deliver is logged - status_code is 0 when no response was received
+CREATE TABLE IF NOT EXISTS `webhook_delivery_attempts` (
+  `attempt_id` BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `delivery_id` BIGINT NOT NULL,
+  `attempted_at` DATETIME NOT NULL,
+  `status_code` INT NOT NULL DEFAULT 0,
+  `error` VARCHAR(1024) NOT NULL DEFAULT '',
+  `duration_ms` INT NOT NULL DEFAULT 0,
+  KEY `idx_webhook_delivery_attempts_delivery` (`delivery_id`),
+  FOREIGN KEY (`delivery_id`) REFERENCES `webhook_deliveries` (`delivery_id`)
+);
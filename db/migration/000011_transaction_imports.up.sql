@@ -0,0 +1,16 @@
+-- every bulk transaction import which posted rows is recorded here - the unique checksum (the SHA-256 of the file) keeps
+-- the same file from being posted twice, and the counts are updated after each batch so that an interrupted import shows
+-- how far it got
+CREATE TABLE IF NOT EXISTS `transaction_imports` (
+  `import_id` INT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+  `checksum` CHAR(64) NOT NULL,
+  `file_name` VARCHAR(255) NOT NULL DEFAULT '',
+  `row_count` INT NOT NULL,
+  `posted_count` INT NOT NULL DEFAULT 0,
+  `failed_count` INT NOT NULL DEFAULT 0,
+  `status` VARCHAR(12) NOT NULL DEFAULT 'processing',
+  `created_by` VARCHAR(255) NOT NULL DEFAULT '',
+  `created_at` DATETIME NOT NULL,
+  `completed_at` DATETIME NULL,
+  UNIQUE KEY `uq_transaction_imports_checksum` (`checksum`)
+);
@@ -0,0 +1,4 @@
+ALTER TABLE `transaction_imports`
+  DROP COLUMN `updated_at`,
+  DROP COLUMN `attempt`,
+  DROP COLUMN `next_row`;
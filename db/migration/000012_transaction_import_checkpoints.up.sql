@@ -0,0 +1,11 @@
+-- next_row is the checkpoint of an import - the index of the first row of the file not yet attempted, recorded after every
+-- row so that a resumed import neither skips nor posts twice a row - updated_at tells a crashed import (still processing
+-- but no longer updated) from a running one, and attempt is raised each time an import is resumed so that the process it
+-- was taken over from can no longer record progress
+ALTER TABLE `transaction_imports`
+  ADD COLUMN `next_row` INT NOT NULL DEFAULT 0 AFTER `row_count`,
+  ADD COLUMN `attempt` INT NOT NULL DEFAULT 1 AFTER `status`,
+  ADD COLUMN `updated_at` DATETIME NULL AFTER `created_at`;
+
+-- an import recorded before the checkpoint existed attempted its posted and failed rows
+UPDATE `transaction_imports` SET `next_row` = `posted_count` + `failed_count`, `updated_at` = COALESCE(`completed_at`, `created_at`);
@@ -1,19 +1,22 @@
 package domain
 
 import (
+	"context"
 	"time"
 
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/dto"
 )
 
+// Account represents our business object - an account
+// db tags match the column names in the accounts table so that sqlx can scan rows into an Account
 type Account struct {
-	AccountID   string
-	CustomerID  string
-	OpeningDate string
-	AccountType string
-	Amount      float64
-	Status      string
+	AccountID   string  `db:"account_id"`
+	CustomerID  string  `db:"customer_id"`
+	OpeningDate string  `db:"opening_date"`
+	AccountType string  `db:"account_type"`
+	Amount      float64 `db:"amount"`
+	Status      string  `db:"status"`
 }
 
 // AccountRepository is a "port" implemented by the server side - AccountRepositoryDb is an adapater which implements the
@@ -21,9 +24,16 @@ type Account struct {
 //
 //go:generate mockgen -destination=../mocks/domain/mockAccountRepository.go -package=domain github.com/gtaylor314/Banking-MS/domain AccountRepository
 type AccountRepository interface {
-	Save(Account) (*Account, *errs.AppError)
+	// Save inserts the account once check allows it - check is passed the number of active accounts of the account's type
+	// the customer already holds, counted while no other account may be opened for the customer, so that accounts opened
+	// concurrently cannot all pass the account policy against the same count
+	Save(ctx context.Context, acct Account, check AccountCheck) (*Account, *errs.AppError)
 }
 
+// AccountCheck returns an error if an account may not be opened for a customer already holding openAccounts active accounts
+// of its type - the error is returned by AccountRepository.Save and nothing is inserted
+type AccountCheck func(openAccounts int) *errs.AppError
+
 func (acct Account) ToNewAccountResponseDto() *dto.NewAccountResponse {
 	return &dto.NewAccountResponse{AccountID: acct.AccountID}
 }
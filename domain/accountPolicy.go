@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// MsgAccountLimitReached and MsgCustomerUnderage are the messages of AccountLimitReachedErr and CustomerUnderageErr
+const (
+	MsgAccountLimitReached = "customer already holds the maximum number of accounts of this type"
+	MsgCustomerUnderage    = "customer is below the minimum age for opening an account"
+)
+
+// AccountLimitReachedErr and CustomerUnderageErr are returned when the AccountPolicy refuses a new account - the app maps
+// them to the ACCOUNT_LIMIT_REACHED and CUSTOMER_UNDERAGE error codes
+var (
+	AccountLimitReachedErr = errs.ValidationErr(MsgAccountLimitReached)
+	CustomerUnderageErr    = errs.ValidationErr(MsgCustomerUnderage)
+)
+
+// AccountPolicy holds the rules a customer must meet to open an account - a zero value disables the rule
+type AccountPolicy struct {
+	// MaxPerType is the most active accounts of a single type (e.g. saving) a customer may hold
+	MaxPerType int
+	// MinimumAge is the age in whole years, as of the opening date, a customer must have reached
+	MinimumAge int
+}
+
+// Check() returns an error if cust may not open another account given the number of active accounts of the same type
+// they already hold (openAccounts) - now is the opening date
+func (policy AccountPolicy) Check(cust Customer, openAccounts int, now time.Time) *errs.AppError {
+	if policy.MaxPerType > 0 && openAccounts >= policy.MaxPerType {
+		return AccountLimitReachedErr
+	}
+	if policy.MinimumAge > 0 {
+		// date_of_birth is returned by the database as YYYY-MM-DD
+		born, err := time.Parse("2006-01-02", cust.DateofBirth)
+		if err != nil {
+			return errs.UnexpectedErr("unexpected date of birth for customer " + cust.ID)
+		}
+		if age(born, now) < policy.MinimumAge {
+			return CustomerUnderageErr
+		}
+	}
+	return nil
+}
+
+// age() returns the number of whole years between born and now - the year only counts once the birthday has passed
+func age(born time.Time, now time.Time) int {
+	years := now.Year() - born.Year()
+	if now.Month() < born.Month() || (now.Month() == born.Month() && now.Day() < born.Day()) {
+		years--
+	}
+	return years
+}
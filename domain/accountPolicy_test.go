@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+// TestAccountPolicyCheck() should pass when the account limit and minimum age are enforced, a birthday counts from the
+// day itself and a zero policy allows every account
+func TestAccountPolicyCheck(t *testing.T) {
+	// Arrange - setup test
+	policy := AccountPolicy{MaxPerType: 2, MinimumAge: 18}
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name         string
+		policy       AccountPolicy
+		dateOfBirth  string
+		openAccounts int
+		wantMessage  string
+	}{
+		{"allowed", policy, "1989-10-01", 1, ""},
+		{"limit reached", policy, "1989-10-01", 2, MsgAccountLimitReached},
+		{"eighteenth birthday", policy, "2008-06-01", 0, ""},
+		{"day before eighteenth birthday", policy, "2008-06-02", 0, MsgCustomerUnderage},
+		{"no policy", AccountPolicy{}, "2021-03-01", 10, ""},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		appErr := test.policy.Check(Customer{ID: "1001", DateofBirth: test.dateOfBirth}, test.openAccounts, now)
+
+		// Assert - test expectations
+		if test.wantMessage == "" && appErr != nil {
+			t.Errorf("%s: unexpected error %q", test.name, appErr.Message)
+		}
+		if test.wantMessage != "" && (appErr == nil || appErr.Message != test.wantMessage || appErr.Code != http.StatusUnprocessableEntity) {
+			t.Errorf("%s: received %v, want 422 %q", test.name, appErr, test.wantMessage)
+		}
+	}
+}
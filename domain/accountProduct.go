@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// MsgProductNotFound is the message of ProductNotFoundErr
+const MsgProductNotFound = "account product not found"
+
+// ProductNotFoundErr is returned when an account product code does not exist - the app maps it to the PRODUCT_NOT_FOUND
+// error code
+var ProductNotFoundErr = errs.NotFoundErr(MsgProductNotFound)
+
+// AccountProduct represents an entry of the account product catalogue - an account's account_type is the code of its
+// product, which sets the deposit needed to open the account and the terms it is held on
+type AccountProduct struct {
+	// Code is lowercase (e.g. saving) and is what clients send as the account_type of a new account
+	Code             string  `db:"code"`
+	DisplayName      string  `db:"display_name"`
+	MinimumDeposit   float64 `db:"minimum_deposit"`
+	InterestRate     float64 `db:"interest_rate"` // annual percentage rate, e.g. 1.25
+	OverdraftAllowed bool    `db:"overdraft_allowed"`
+	MonthlyFee       float64 `db:"monthly_fee"`
+}
+
+// AccountProductRepository is a "port" implemented by the server side - AccountProductRepositoryDb and
+// AccountProductRepositoryStub are its adapters
+//
+//go:generate mockgen -destination=../mocks/domain/mockAccountProductRepository.go -package=domain github.com/gtaylor314/Banking-MS/domain AccountProductRepository
+type AccountProductRepository interface {
+	// FindAll returns every product ordered by code
+	FindAll(context.Context) ([]AccountProduct, *errs.AppError)
+	// FindByCode returns the product with the given (lowercase) code
+	FindByCode(context.Context, string) (*AccountProduct, *errs.AppError)
+	// Save adds a new product - a code which is already in use is a conflict
+	Save(context.Context, AccountProduct) (*AccountProduct, *errs.AppError)
+	// Update replaces every term of an existing product
+	Update(context.Context, AccountProduct) (*AccountProduct, *errs.AppError)
+	// Delete removes a product - a product which accounts are still opened under is a conflict
+	Delete(context.Context, string) *errs.AppError
+}
+
+// productExistsErr() and productInUseErr() return the conflicts reported when saving or deleting a product - errs does not
+// provide a constructor for http.StatusConflict
+func productExistsErr(code string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: "account product " + code + " already exists"}
+}
+
+func productInUseErr(code string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: "account product " + code + " is held by existing accounts"}
+}
+
+// ToDto converts a domain.AccountProduct object to a dto.AccountProductResponse object
+func (product AccountProduct) ToDto() dto.AccountProductResponse {
+	return dto.AccountProductResponse{
+		Code:             product.Code,
+		DisplayName:      product.DisplayName,
+		MinimumDeposit:   product.MinimumDeposit,
+		InterestRate:     product.InterestRate,
+		OverdraftAllowed: product.OverdraftAllowed,
+		MonthlyFee:       product.MonthlyFee,
+	}
+}
@@ -0,0 +1,145 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// MySQL error numbers returned when an insert repeats a primary key and when a delete is refused by a foreign key
+const (
+	mysqlDuplicateEntry  = 1062
+	mysqlRowIsReferenced = 1451
+)
+
+// productColumns are the columns scanned into an AccountProduct
+const productColumns = "code, display_name, minimum_deposit, interest_rate, overdraft_allowed, monthly_fee"
+
+// AccountProductRepositoryDb is an adapter that implements the AccountProductRepository (port) interface
+type AccountProductRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// FindAll() returns every account product ordered by code
+func (p AccountProductRepositoryDb) FindAll(ctx context.Context) ([]AccountProduct, *errs.AppError) {
+	findAllSql := "select " + productColumns + " from account_products order by code"
+
+	ctx, span := tracing.StartDB(ctx, "AccountProductRepositoryDb.FindAll", findAllSql)
+	defer span.End()
+
+	products := make([]AccountProduct, 0)
+	err := p.db_conn.SelectContext(ctx, &products, findAllSql)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of account_products table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return products, nil
+}
+
+// FindByCode() returns the account product with the given code
+func (p AccountProductRepositoryDb) FindByCode(ctx context.Context, code string) (*AccountProduct, *errs.AppError) {
+	findSql := "select " + productColumns + " from account_products where code = ?"
+
+	ctx, span := tracing.StartDB(ctx, "AccountProductRepositoryDb.FindByCode", findSql)
+	defer span.End()
+
+	var product AccountProduct
+	err := p.db_conn.GetContext(ctx, &product, findSql, code)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ProductNotFoundErr
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of account_products table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &product, nil
+}
+
+// Save() inserts a new account product - the primary key on code rejects a product which already exists
+func (p AccountProductRepositoryDb) Save(ctx context.Context, product AccountProduct) (*AccountProduct, *errs.AppError) {
+	insertSql := "INSERT INTO account_products (" + productColumns + ") values (?, ?, ?, ?, ?, ?)"
+
+	ctx, span := tracing.StartDB(ctx, "AccountProductRepositoryDb.Save", insertSql)
+	defer span.End()
+
+	_, err := p.db_conn.ExecContext(ctx, insertSql, product.Code, product.DisplayName, product.MinimumDeposit,
+		product.InterestRate, product.OverdraftAllowed, product.MonthlyFee)
+	if err != nil {
+		if isMySQLError(err, mysqlDuplicateEntry) {
+			return nil, productExistsErr(product.Code)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating account product", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during account product creation")
+	}
+	return &product, nil
+}
+
+// Update() replaces the terms of an existing account product - accounts already opened under the product keep their
+// balances, only the terms change
+func (p AccountProductRepositoryDb) Update(ctx context.Context, product AccountProduct) (*AccountProduct, *errs.AppError) {
+	updateSql := "UPDATE account_products SET display_name = ?, minimum_deposit = ?, interest_rate = ?, overdraft_allowed = ?, monthly_fee = ? WHERE code = ?"
+
+	ctx, span := tracing.StartDB(ctx, "AccountProductRepositoryDb.Update", updateSql)
+	defer span.End()
+
+	result, err := p.db_conn.ExecContext(ctx, updateSql, product.DisplayName, product.MinimumDeposit, product.InterestRate,
+		product.OverdraftAllowed, product.MonthlyFee, product.Code)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating account product", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during account product update")
+	}
+	// without CLIENT_FOUND_ROWS, RowsAffected() counts the rows changed rather than matched - an update which leaves every
+	// term as it was changes nothing, so the product is looked up to tell it apart from a code which does not exist
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		if _, appErr := p.FindByCode(ctx, product.Code); appErr != nil {
+			return nil, appErr
+		}
+	}
+	return &product, nil
+}
+
+// Delete() removes an account product - the foreign key from accounts.account_type refuses the delete while accounts are
+// held under the product
+func (p AccountProductRepositoryDb) Delete(ctx context.Context, code string) *errs.AppError {
+	deleteSql := "DELETE FROM account_products WHERE code = ?"
+
+	ctx, span := tracing.StartDB(ctx, "AccountProductRepositoryDb.Delete", deleteSql)
+	defer span.End()
+
+	result, err := p.db_conn.ExecContext(ctx, deleteSql, code)
+	if err != nil {
+		if isMySQLError(err, mysqlRowIsReferenced) {
+			return productInUseErr(code)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while deleting account product", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error during account product deletion")
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return ProductNotFoundErr
+	}
+	return nil
+}
+
+// isMySQLError() reports whether err is the MySQL error with the given number
+func isMySQLError(err error, number uint16) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == number
+}
+
+// NewAccountProductRepositoryDb() takes a db connection and returns an AccountProductRepositoryDb
+func NewAccountProductRepositoryDb(db_conn *sqlx.DB) AccountProductRepositoryDb {
+	return AccountProductRepositoryDb{db_conn: db_conn}
+}
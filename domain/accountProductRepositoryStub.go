@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// AccountProductRepositoryStub is an in-memory "adapter" for the AccountProductRepository "port" - it holds the same
+// products as the account_products migration
+type AccountProductRepositoryStub struct {
+	// mu guards products as handlers may change the catalogue concurrently
+	mu       sync.RWMutex
+	products map[string]AccountProduct
+	// inUse holds the codes which accounts are held under - the stub does not see the accounts themselves
+	inUse map[string]bool
+}
+
+// FindAll implementation for type AccountProductRepositoryStub
+func (p *AccountProductRepositoryStub) FindAll(ctx context.Context) ([]AccountProduct, *errs.AppError) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	products := make([]AccountProduct, 0, len(p.products))
+	for _, product := range p.products {
+		products = append(products, product)
+	}
+	sort.Slice(products, func(i, j int) bool { return products[i].Code < products[j].Code })
+	return products, nil
+}
+
+// FindByCode implementation for type AccountProductRepositoryStub
+func (p *AccountProductRepositoryStub) FindByCode(ctx context.Context, code string) (*AccountProduct, *errs.AppError) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	product, ok := p.products[code]
+	if !ok {
+		return nil, ProductNotFoundErr
+	}
+	return &product, nil
+}
+
+// Save implementation for type AccountProductRepositoryStub
+func (p *AccountProductRepositoryStub) Save(ctx context.Context, product AccountProduct) (*AccountProduct, *errs.AppError) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.products[product.Code]; ok {
+		return nil, productExistsErr(product.Code)
+	}
+	p.products[product.Code] = product
+	return &product, nil
+}
+
+// Update implementation for type AccountProductRepositoryStub
+func (p *AccountProductRepositoryStub) Update(ctx context.Context, product AccountProduct) (*AccountProduct, *errs.AppError) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.products[product.Code]; !ok {
+		return nil, ProductNotFoundErr
+	}
+	p.products[product.Code] = product
+	return &product, nil
+}
+
+// Delete implementation for type AccountProductRepositoryStub
+func (p *AccountProductRepositoryStub) Delete(ctx context.Context, code string) *errs.AppError {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.products[code]; !ok {
+		return ProductNotFoundErr
+	}
+	if p.inUse[code] {
+		return productInUseErr(code)
+	}
+	delete(p.products, code)
+	return nil
+}
+
+// NewAccountProductRepositoryStub is a helper function which creates a new account product repository stub holding the
+// saving and checking products - both are held by the seed accounts and so cannot be deleted
+func NewAccountProductRepositoryStub() *AccountProductRepositoryStub {
+	return &AccountProductRepositoryStub{
+		products: map[string]AccountProduct{
+			"saving":   {Code: "saving", DisplayName: "Savings Account", MinimumDeposit: 5000, InterestRate: 1.5},
+			"checking": {Code: "checking", DisplayName: "Checking Account", MinimumDeposit: 5000, OverdraftAllowed: true},
+		},
+		inUse: map[string]bool{"saving": true, "checking": true},
+	}
+}
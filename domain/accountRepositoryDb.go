@@ -1,12 +1,16 @@
 package domain
 
 import (
+	"context"
+	"database/sql"
 	"strconv"
 
 	"github.com/gtaylor314/Banking-Lib/errs"
-	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
 
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 // AccountRepositoryDb is an adapter that implements the AccountRepository (port) interface
@@ -14,31 +18,130 @@ type AccountRepositoryDb struct {
 	db_conn *sqlx.DB
 }
 
-// Save() takes an account object (without an account_id), inserts the account into the accounts table and updates the account
-// object with the account_id auto-incremented when the account was inserted
-func (a AccountRepositoryDb) Save(acct Account) (*Account, *errs.AppError) {
+// Save() takes an account object (without an account_id), inserts the account into the accounts table along with its
+// opening deposit into the transactions table, and updates the account object with the account_id auto-incremented when
+// the account was inserted - both rows, and the AccountOpened event, are inserted in one sql transaction so that an
+// account's history always explains its balance
+//
+// the customer's row is locked (SELECT ... FOR UPDATE) before their active accounts are counted and passed to check, so a
+// second account opened for the same customer at the same time waits for this sql transaction to end and then counts the
+// account inserted by it
+func (a AccountRepositoryDb) Save(ctx context.Context, acct Account, check AccountCheck) (*Account, *errs.AppError) {
+	// the Save span is the parent of the insert and commit spans below
+	ctx, span := tracing.Start(ctx, "AccountRepositoryDb.Save")
+	defer span.End()
+
+	// BeginTx() ties the sql transaction to ctx - if ctx is cancelled before Commit(), the sql transaction is rolled back
+	tx, err := a.db_conn.DB.BeginTx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating database transaction tx", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error creating database transaction tx")
+	}
+	// the deferred rollback becomes a no-op once the sql transaction is committed
+	defer tx.Rollback()
+
+	openAccounts, appErr := a.lockCustomer(ctx, tx, acct.CustomerID, acct.AccountType)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if check != nil {
+		if appErr = check(openAccounts); appErr != nil {
+			return nil, appErr
+		}
+	}
+
 	// define the SQL Insert command - accounts is the name of the table in our banking db
 	sqlInsertCmd := "INSERT INTO accounts (customer_id, opening_date, account_type, amount, status) values (?, ?, ?, ?, ?)"
 
-	// Exec() executes a db query/command without returning any rows
-	result, err := a.db_conn.Exec(sqlInsertCmd, acct.CustomerID, acct.OpeningDate, acct.AccountType, acct.Amount, acct.Status)
+	insertCtx, insertSpan := tracing.StartDB(ctx, "insert account", sqlInsertCmd)
+	// ExecContext() executes a db query/command without returning any rows - the command is cancelled along with ctx
+	result, err := tx.ExecContext(insertCtx, sqlInsertCmd, acct.CustomerID, acct.OpeningDate, acct.AccountType, acct.Amount, acct.Status)
+	insertSpan.End()
 	if err != nil {
-		logger.Error("error while creating new account " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating new account", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error during account creation")
 	}
 	// LastInsertId() returns the integer generated by the database in response to a command - in our case, the account_id
 	// which auto-increments upon inserting a row
 	acctID, err := result.LastInsertId()
 	if err != nil {
-		logger.Error("error retrieving account ID " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error retrieving account ID", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error retrieving account ID")
 	}
+
+	// the opening deposit is dated with the account so that the transactions replay to the balance from the start
+	sqlInsertTran := "INSERT INTO transactions (account_id, amount, transaction_type, transaction_date) values (?, ?, ?, ?)"
+	tranCtx, tranSpan := tracing.StartDB(ctx, "insert opening deposit", sqlInsertTran)
+	_, err = tx.ExecContext(tranCtx, sqlInsertTran, acctID, acct.Amount, TransactionTypeOpeningDeposit, acct.OpeningDate)
+	tranSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while recording the opening deposit", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during account creation")
+	}
+
 	// FormatInt() returns the string representation of the passed in int64 value using the given base (in this case
 	// base 10 for decimal)
 	acct.AccountID = strconv.FormatInt(acctID, 10)
+
+	// the AccountOpened event is written by the same sql transaction, so it is published if and only if the account exists
+	event := NewAccountOpenedEvent(acct)
+	eventCtx, eventSpan := tracing.StartDB(ctx, "insert outbox event", "INSERT INTO outbox_events")
+	err = insertOutboxEvent(eventCtx, tx, event)
+	eventSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while recording the AccountOpened event", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during account creation")
+	}
+
+	_, commitSpan := tracing.StartDB(ctx, "commit", "COMMIT")
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while committing the new account", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during account creation")
+	}
 	return &acct, nil
 }
 
+// lockCustomer() locks the customer's row until tx ends and returns the number of active accounts of accountType they hold
+// - the comparison of account_type ignores case under the table's default collation
+func (a AccountRepositoryDb) lockCustomer(ctx context.Context, tx *sql.Tx, customerID string, accountType string) (int, *errs.AppError) {
+	sqlLock := "SELECT customer_id FROM customers WHERE customer_id = ? FOR UPDATE"
+	lockCtx, lockSpan := tracing.StartDB(ctx, "lock customer", sqlLock)
+	var lockedID string
+	err := tx.QueryRowContext(lockCtx, sqlLock, customerID).Scan(&lockedID)
+	// the customer is checked by the service before saving, but may have been deleted since
+	if err == sql.ErrNoRows {
+		lockSpan.End()
+		return 0, CustomerNotFoundErr
+	}
+	if err != nil {
+		tracing.RecordError(lockSpan, err)
+		lockSpan.End()
+		logging.Error(ctx, "error while locking the customer", zap.Error(err))
+		return 0, errs.UnexpectedErr("unexpected database error during account creation")
+	}
+	lockSpan.End()
+
+	sqlCount := "SELECT COUNT(*) FROM accounts WHERE customer_id = ? AND account_type = ? AND status = 1"
+	countCtx, countSpan := tracing.StartDB(ctx, "count accounts", sqlCount)
+	defer countSpan.End()
+	var count int
+	err = tx.QueryRowContext(countCtx, sqlCount, customerID, accountType).Scan(&count)
+	if err != nil {
+		tracing.RecordError(countSpan, err)
+		logging.Error(ctx, "error while counting the customer's accounts", zap.Error(err))
+		return 0, errs.UnexpectedErr("unexpected database error while counting the customer's accounts")
+	}
+	return count, nil
+}
+
 // NewAccountRepositoryDb() will take in a db connection and create an AccountRepositoryDb which it then returns
 func NewAccountRepositoryDb(db_conn *sqlx.DB) AccountRepositoryDb {
 	return AccountRepositoryDb{db_conn: db_conn}
@@ -0,0 +1,165 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/jmoiron/sqlx"
+)
+
+// the statements Save() runs, in order
+const (
+	lockCustomerSql  = "SELECT customer_id FROM customers WHERE customer_id = ? FOR UPDATE"
+	countAccountsSql = "SELECT COUNT(*) FROM accounts WHERE customer_id = ? AND account_type = ? AND status = 1"
+	insertAccountSql = "INSERT INTO accounts (customer_id, opening_date, account_type, amount, status) values (?, ?, ?, ?, ?)"
+	insertDepositSql = "INSERT INTO transactions (account_id, amount, transaction_type, transaction_date) values (?, ?, ?, ?)"
+	insertEventSql   = "INSERT INTO outbox_events (event_id, event_type, aggregate_id, payload, occurred_at, next_attempt_at) values (?, ?, ?, ?, ?, ?)"
+)
+
+// newAccountRepositoryMock() returns an AccountRepositoryDb over a sqlmock connection which matches statements exactly - the
+// expectations are checked when the test ends
+func newAccountRepositoryMock(t *testing.T) (AccountRepositoryDb, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		db.Close()
+	})
+	return NewAccountRepositoryDb(sqlx.NewDb(db, "mysql")), mock
+}
+
+// TestSaveAccountLimitReached() should pass when the customer's accounts are counted after their row is locked, and a check
+// refusing the count rolls the sql transaction back without inserting anything
+func TestSaveAccountLimitReached(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	repo, mock := newAccountRepositoryMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCustomerSql).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow("1"))
+	mock.ExpectQuery(countAccountsSql).WithArgs("1", "saving").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(2))
+	mock.ExpectRollback()
+	policy := AccountPolicy{MaxPerType: 2}
+	acct := NewAccountNoID("1", "saving", 5000)
+	checked := -1
+
+	// Act - execute test
+	_, appErr := repo.Save(context.Background(), acct, func(openAccounts int) *errs.AppError {
+		checked = openAccounts
+		return policy.Check(Customer{ID: "1"}, openAccounts, time.Now())
+	})
+
+	// Assert - test expectations
+	if checked != 2 {
+		t.Errorf("check received %d open accounts, want 2", checked)
+	}
+	if appErr == nil || appErr.Message != MsgAccountLimitReached {
+		t.Errorf("received %v, want %q", appErr, MsgAccountLimitReached)
+	}
+}
+
+// TestSaveAccountUnknownCustomer() should pass when a customer with no row to lock is a 404 and the check is never called
+func TestSaveAccountUnknownCustomer(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	repo, mock := newAccountRepositoryMock(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCustomerSql).WithArgs("9").WillReturnRows(sqlmock.NewRows([]string{"customer_id"}))
+	mock.ExpectRollback()
+
+	// Act - execute test
+	_, appErr := repo.Save(context.Background(), NewAccountNoID("9", "saving", 5000), func(int) *errs.AppError {
+		t.Error("check called for an unknown customer")
+		return nil
+	})
+
+	// Assert - test expectations
+	if appErr == nil || appErr.Message != MsgCustomerNotFound {
+		t.Errorf("received %v, want %q", appErr, MsgCustomerNotFound)
+	}
+}
+
+// expectLockedCustomer() expects the sql transaction of Save() to begin and lock customer 1, who holds no accounts yet
+func expectLockedCustomer(mock sqlmock.Sqlmock) {
+	mock.ExpectBegin()
+	mock.ExpectQuery(lockCustomerSql).WithArgs("1").WillReturnRows(sqlmock.NewRows([]string{"customer_id"}).AddRow("1"))
+	mock.ExpectQuery(countAccountsSql).WithArgs("1", "saving").WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(0))
+}
+
+// TestSaveAccount() should pass when the account, its opening deposit dated with the account and its AccountOpened event
+// are inserted by one sql transaction, which is committed
+func TestSaveAccount(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	repo, mock := newAccountRepositoryMock(t)
+	acct := NewAccountNoID("1", "saving", 5000)
+	expectLockedCustomer(mock)
+	mock.ExpectExec(insertAccountSql).WithArgs("1", acct.OpeningDate, "saving", 5000.0, "1").WillReturnResult(sqlmock.NewResult(107, 1))
+	mock.ExpectExec(insertDepositSql).WithArgs(int64(107), 5000.0, TransactionTypeOpeningDeposit, acct.OpeningDate).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(insertEventSql).WithArgs(sqlmock.AnyArg(), EventAccountOpened, "107", sqlmock.AnyArg(), acct.OpeningDate, acct.OpeningDate).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	// Act - execute test
+	saved, appErr := repo.Save(context.Background(), acct, nil)
+
+	// Assert - test expectations
+	if appErr != nil {
+		t.Fatalf("unexpected error %q", appErr.Message)
+	}
+	if saved.AccountID != "107" {
+		t.Errorf("received account ID %q, want 107", saved.AccountID)
+	}
+}
+
+// TestSaveAccountRollsBack() should pass when a failure to insert the account, its opening deposit or its event rolls the
+// whole sql transaction back, so that no account is left without the deposit explaining its balance
+func TestSaveAccountRollsBack(t *testing.T) {
+	tests := []struct {
+		name string
+		// failAt is the number of inserts which succeed before one fails
+		failAt int
+	}{
+		{"account", 0},
+		{"opening deposit", 1},
+		{"event", 2},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			repo, mock := newAccountRepositoryMock(t)
+			acct := NewAccountNoID("1", "saving", 5000)
+			expectLockedCustomer(mock)
+			for i, insertSql := range []string{insertAccountSql, insertDepositSql, insertEventSql} {
+				insert := mock.ExpectExec(insertSql)
+				if i == test.failAt {
+					insert.WillReturnError(errors.New("connection reset"))
+					break
+				}
+				insert.WillReturnResult(sqlmock.NewResult(107, 1))
+			}
+			// the rollback is expected in place of a commit - a commit fails the expectations
+			mock.ExpectRollback()
+
+			// Act - execute test
+			saved, appErr := repo.Save(context.Background(), acct, nil)
+
+			// Assert - test expectations
+			if saved != nil || appErr == nil || appErr.Code != http.StatusInternalServerError {
+				t.Errorf("received %v, %v, want a 500 and no account", saved, appErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// AccountRepositoryStub is an in-memory "adapter" for the AccountRepository "port" - it allows the app to be exercised
+// (e.g. by the OpenAPI conformance tests) without a database
+type AccountRepositoryStub struct {
+	// mu guards accounts and lastID as handlers may save accounts concurrently
+	mu       sync.Mutex
+	accounts []Account
+	lastID   int
+}
+
+// Save implementation for type AccountRepositoryStub - account IDs are generated sequentially, as with auto_increment, and
+// check is passed the count of the customer's accounts under the same lock as the account is saved
+func (a *AccountRepositoryStub) Save(ctx context.Context, acct Account, check AccountCheck) (*Account, *errs.AppError) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if check != nil {
+		if appErr := check(a.countActive(acct.CustomerID, acct.AccountType)); appErr != nil {
+			return nil, appErr
+		}
+	}
+	a.lastID++
+	acct.AccountID = strconv.Itoa(a.lastID)
+	a.accounts = append(a.accounts, acct)
+	return &acct, nil
+}
+
+// countActive() returns the number of active accounts of accountType saved to the stub for the customer - as with MySQL's
+// default collation, the account type is compared ignoring case - a.mu must be held
+func (a *AccountRepositoryStub) countActive(customerID string, accountType string) int {
+	count := 0
+	for _, acct := range a.accounts {
+		if acct.CustomerID == customerID && strings.EqualFold(acct.AccountType, accountType) && acct.Status == "1" {
+			count++
+		}
+	}
+	return count
+}
+
+// NewAccountRepositoryStub is a helper function which creates a new, empty account repository stub - the first account
+// saved receives the account ID 107 to follow on from the seed data
+func NewAccountRepositoryStub() *AccountRepositoryStub {
+	return &AccountRepositoryStub{lastID: 106}
+}
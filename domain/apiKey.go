@@ -0,0 +1,184 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// MsgAPIKeyNotFound is returned for a key_id which does not exist
+const MsgAPIKeyNotFound = "api key not found"
+
+// apiKeyPrefix starts every API key so that a leaked key is easy to recognise (e.g. by secret scanners) - a key reads
+// bk_<key_id>_<secret>
+const apiKeyPrefix = "bk_"
+
+// API key statuses - only an active key is accepted
+const (
+	APIKeyActive  = "active"
+	APIKeyRevoked = "revoked"
+	APIKeyExpired = "expired"
+)
+
+// unscopedRoutes may not be granted to an API key - a key able to manage keys could mint itself more scopes, and a key
+// has no password to change
+var unscopedRoutes = map[string]bool{
+	"GetAllAPIKeys":  true,
+	"NewAPIKey":      true,
+	"RevokeAPIKey":   true,
+	"RotateAPIKey":   true,
+	"ChangePassword": true,
+}
+
+// APIKey represents a row of the api_keys table - only the SHA-256 hash of the key is stored, the key itself is shown
+// once when it is created or rotated - Scopes holds the comma separated route names the key may call
+type APIKey struct {
+	KeyID     string         `db:"key_id"`
+	Name      string         `db:"name"`
+	Hash      string         `db:"key_hash"`
+	Scopes    string         `db:"scopes"`
+	CreatedBy string         `db:"created_by"`
+	CreatedAt string         `db:"created_at"`
+	ExpiresAt sql.NullString `db:"expires_at"`
+	RevokedAt sql.NullString `db:"revoked_at"`
+	RotatedAt sql.NullString `db:"rotated_at"`
+}
+
+// APIKeyRepository is a "port" implemented by the server side - APIKeyRepositoryDb is its adapter
+type APIKeyRepository interface {
+	FindAll(context.Context) ([]APIKey, *errs.AppError)
+	// FindByID returns the key with the given key_id - an unknown key_id is MsgAPIKeyNotFound
+	FindByID(context.Context, string) (*APIKey, *errs.AppError)
+	Save(context.Context, APIKey) (*APIKey, *errs.AppError)
+	// Revoke marks the key revoked at revokedAt - revoking a revoked key is a 409
+	Revoke(ctx context.Context, keyID string, revokedAt string) (*APIKey, *errs.AppError)
+	// Rotate replaces the hash of an unrevoked key, so that its old secret stops working at once
+	Rotate(ctx context.Context, keyID string, hash string, rotatedAt string) (*APIKey, *errs.AppError)
+}
+
+// ScopeList() returns the route names the key may call
+func (key APIKey) ScopeList() []string {
+	if key.Scopes == "" {
+		return []string{}
+	}
+	return strings.Split(key.Scopes, ",")
+}
+
+// Status() returns whether the key is active, revoked or expired at now
+func (key APIKey) Status(now time.Time) string {
+	if key.RevokedAt.Valid {
+		return APIKeyRevoked
+	}
+	if key.ExpiresAt.Valid {
+		// the column holds local time to the second, as the other datetime columns do
+		expiresAt, err := time.ParseInLocation("2006-01-02 15:04:05", key.ExpiresAt.String, time.Local)
+		if err != nil || !now.Before(expiresAt) {
+			return APIKeyExpired
+		}
+	}
+	return APIKeyActive
+}
+
+// Allows() reports whether the key is active at now and has routeName among its scopes
+func (key APIKey) Allows(routeName string, now time.Time) bool {
+	if key.Status(now) != APIKeyActive {
+		return false
+	}
+	for _, scope := range key.ScopeList() {
+		if scope == routeName {
+			return true
+		}
+	}
+	return false
+}
+
+// Matches() reports whether the presented key hashes to the stored hash - the hashes are compared in constant time
+func (key APIKey) Matches(presented string) bool {
+	return subtle.ConstantTimeCompare([]byte(HashAPIKey(presented)), []byte(key.Hash)) == 1
+}
+
+// ToDto() returns the key without its hash - the key itself is only added to the response by the service when it is
+// first shown
+func (key APIKey) ToDto(now time.Time) dto.APIKeyResponse {
+	return dto.APIKeyResponse{
+		KeyID:     key.KeyID,
+		Name:      key.Name,
+		Scopes:    key.ScopeList(),
+		Status:    key.Status(now),
+		CreatedBy: key.CreatedBy,
+		CreatedAt: key.CreatedAt,
+		ExpiresAt: key.ExpiresAt.String,
+		RevokedAt: key.RevokedAt.String,
+		RotatedAt: key.RotatedAt.String,
+	}
+}
+
+// ValidateScopes() returns a 422 *errs.AppError when a scope is not one of routeNames or may not be granted to a key
+func ValidateScopes(scopes []string, routeNames map[string]bool) *errs.AppError {
+	var problems []string
+	for _, scope := range scopes {
+		switch {
+		case unscopedRoutes[scope]:
+			problems = append(problems, fmt.Sprintf("%s may not be granted to an api key", scope))
+		case !routeNames[scope]:
+			problems = append(problems, fmt.Sprintf("%s is not a route name", scope))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errs.ValidationErr("invalid scopes: " + strings.Join(problems, ", "))
+}
+
+// NewAPIKeySecret() returns a new key for keyID along with its hash - an empty keyID generates a new one
+func NewAPIKeySecret(keyID string) (id string, key string, hash string, err error) {
+	if keyID == "" {
+		idBytes := make([]byte, 8)
+		if _, err := rand.Read(idBytes); err != nil {
+			return "", "", "", err
+		}
+		keyID = hex.EncodeToString(idBytes)
+	}
+	// 32 random bytes put the key far beyond guessing, which is why a fast hash is enough to store it
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", "", "", err
+	}
+	key = apiKeyPrefix + keyID + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return keyID, key, HashAPIKey(key), nil
+}
+
+// HashAPIKey() returns the hex encoded SHA-256 hash of key as stored in the key_hash column
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// APIKeyID() returns the key_id of a presented key, or false when it is not shaped like an API key
+func APIKeyID(key string) (string, bool) {
+	if !strings.HasPrefix(key, apiKeyPrefix) {
+		return "", false
+	}
+	// the secret is base64url encoded and may itself contain underscores, the key_id is hex and cannot
+	parts := strings.SplitN(strings.TrimPrefix(key, apiKeyPrefix), "_", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", false
+	}
+	return parts[0], true
+}
+
+// apiKeyRevokedErr() returns the 409 *errs.AppError for changing a revoked key
+func apiKeyRevokedErr(keyID string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("api key %s is revoked", keyID)}
+}
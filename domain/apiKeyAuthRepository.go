@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// APIKeyAuthRepository is an adapter which implements the AuthRepository port for the X-API-Key header - a key is
+// authorized for the route names held in its scopes while it is neither revoked nor expired - route variables are not
+// checked as a key does not belong to a customer
+type APIKeyAuthRepository struct {
+	repo APIKeyRepository
+}
+
+// IsAuthorized() reports whether key is a known, active API key with routeName among its scopes
+func (a APIKeyAuthRepository) IsAuthorized(ctx context.Context, key string, routeName string, vars map[string]string) bool {
+	ctx, span := tracing.Start(ctx, "APIKeyAuthRepository.IsAuthorized", trace.WithAttributes(tracing.Attr("auth.route_name", routeName)))
+	defer span.End()
+
+	outcome := "invalid"
+	defer func() { span.SetAttributes(tracing.Attr("auth.outcome", outcome)) }()
+	keyID, ok := APIKeyID(key)
+	if !ok {
+		return false
+	}
+	span.SetAttributes(tracing.Attr("auth.key_id", keyID))
+	stored, appErr := a.repo.FindByID(ctx, keyID)
+	if appErr != nil || !stored.Matches(key) {
+		return false
+	}
+	if !stored.Allows(routeName, time.Now()) {
+		outcome = "denied"
+		return false
+	}
+	outcome = "authorized"
+	return true
+}
+
+// IsValid() reports whether key is a known API key - a revoked or expired key is valid in this sense, as it is not a
+// guess, and is refused by IsAuthorized() alone
+func (a APIKeyAuthRepository) IsValid(ctx context.Context, key string) bool {
+	keyID, ok := APIKeyID(key)
+	if !ok {
+		return false
+	}
+	stored, appErr := a.repo.FindByID(ctx, keyID)
+	return appErr == nil && stored.Matches(key)
+}
+
+// NewAPIKeyAuthRepository() returns an APIKeyAuthRepository which looks keys up in repo
+func NewAPIKeyAuthRepository(repo APIKeyRepository) APIKeyAuthRepository {
+	return APIKeyAuthRepository{repo: repo}
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// apiKeyColumns are the columns of the api_keys table read into an APIKey
+const apiKeyColumns = "key_id, name, key_hash, scopes, created_by, created_at, expires_at, revoked_at, rotated_at"
+
+// APIKeyRepositoryDb is an adapter that implements the APIKeyRepository (port) interface
+type APIKeyRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// FindAll() returns every API key, revoked and expired keys included, newest first
+func (a APIKeyRepositoryDb) FindAll(ctx context.Context) ([]APIKey, *errs.AppError) {
+	findAllSql := "select " + apiKeyColumns + " from api_keys order by created_at desc, key_id"
+
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepositoryDb.FindAll", findAllSql)
+	defer span.End()
+
+	keys := make([]APIKey, 0)
+	if err := a.db_conn.SelectContext(ctx, &keys, findAllSql); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of api_keys table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return keys, nil
+}
+
+// FindByID() returns the API key with the given key_id
+func (a APIKeyRepositoryDb) FindByID(ctx context.Context, keyID string) (*APIKey, *errs.AppError) {
+	findSql := "select " + apiKeyColumns + " from api_keys where key_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepositoryDb.FindByID", findSql)
+	defer span.End()
+
+	var key APIKey
+	if err := a.db_conn.GetContext(ctx, &key, findSql, keyID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFoundErr(MsgAPIKeyNotFound)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of api_keys table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &key, nil
+}
+
+// Save() stores a new API key
+func (a APIKeyRepositoryDb) Save(ctx context.Context, key APIKey) (*APIKey, *errs.AppError) {
+	insertSql := "insert into api_keys (key_id, name, key_hash, scopes, created_by, created_at, expires_at) values (?, ?, ?, ?, ?, ?, ?)"
+
+	ctx, span := tracing.StartDB(ctx, "APIKeyRepositoryDb.Save", insertSql)
+	defer span.End()
+
+	_, err := a.db_conn.ExecContext(ctx, insertSql, key.KeyID, key.Name, key.Hash, key.Scopes, key.CreatedBy, key.CreatedAt, key.ExpiresAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating api key", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during api key creation")
+	}
+	return &key, nil
+}
+
+// Revoke() marks an unrevoked API key revoked
+func (a APIKeyRepositoryDb) Revoke(ctx context.Context, keyID string, revokedAt string) (*APIKey, *errs.AppError) {
+	return a.update(ctx, "APIKeyRepositoryDb.Revoke", keyID,
+		"update api_keys set revoked_at = ? where key_id = ? and revoked_at is null", revokedAt, keyID)
+}
+
+// Rotate() replaces the hash of an unrevoked API key
+func (a APIKeyRepositoryDb) Rotate(ctx context.Context, keyID string, hash string, rotatedAt string) (*APIKey, *errs.AppError) {
+	return a.update(ctx, "APIKeyRepositoryDb.Rotate", keyID,
+		"update api_keys set key_hash = ?, rotated_at = ? where key_id = ? and revoked_at is null", hash, rotatedAt, keyID)
+}
+
+// update() runs an update of an unrevoked key and returns the key as updated - when no row is changed the key is read to
+// tell an unknown key (404) from a revoked one (409)
+func (a APIKeyRepositoryDb) update(ctx context.Context, spanName string, keyID string, updateSql string, args ...interface{}) (*APIKey, *errs.AppError) {
+	updateCtx, span := tracing.StartDB(ctx, spanName, updateSql)
+	result, err := a.db_conn.ExecContext(updateCtx, updateSql, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		span.End()
+		logging.Error(ctx, "error while updating api key", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during api key update")
+	}
+	span.End()
+	rows, err := result.RowsAffected()
+	key, appErr := a.FindByID(ctx, keyID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if err == nil && rows == 0 {
+		return nil, apiKeyRevokedErr(keyID)
+	}
+	return key, nil
+}
+
+// NewAPIKeyRepositoryDb() takes a db connection and returns an APIKeyRepositoryDb
+func NewAPIKeyRepositoryDb(db_conn *sqlx.DB) APIKeyRepositoryDb {
+	return APIKeyRepositoryDb{db_conn: db_conn}
+}
@@ -0,0 +1,92 @@
+package domain
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// APIKeyRepositoryStub is an in-memory "adapter" for the APIKeyRepository "port" - it starts empty
+type APIKeyRepositoryStub struct {
+	// mu guards keys as handlers may create and revoke keys concurrently
+	mu   sync.RWMutex
+	keys map[string]APIKey
+}
+
+// FindAll implementation for type APIKeyRepositoryStub
+func (a *APIKeyRepositoryStub) FindAll(ctx context.Context) ([]APIKey, *errs.AppError) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(a.keys))
+	for _, key := range a.keys {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt != keys[j].CreatedAt {
+			return keys[i].CreatedAt > keys[j].CreatedAt
+		}
+		return keys[i].KeyID < keys[j].KeyID
+	})
+	return keys, nil
+}
+
+// FindByID implementation for type APIKeyRepositoryStub
+func (a *APIKeyRepositoryStub) FindByID(ctx context.Context, keyID string) (*APIKey, *errs.AppError) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, errs.NotFoundErr(MsgAPIKeyNotFound)
+	}
+	return &key, nil
+}
+
+// Save implementation for type APIKeyRepositoryStub
+func (a *APIKeyRepositoryStub) Save(ctx context.Context, key APIKey) (*APIKey, *errs.AppError) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.keys[key.KeyID] = key
+	return &key, nil
+}
+
+// Revoke implementation for type APIKeyRepositoryStub
+func (a *APIKeyRepositoryStub) Revoke(ctx context.Context, keyID string, revokedAt string) (*APIKey, *errs.AppError) {
+	return a.update(keyID, func(key *APIKey) {
+		key.RevokedAt.String, key.RevokedAt.Valid = revokedAt, true
+	})
+}
+
+// Rotate implementation for type APIKeyRepositoryStub
+func (a *APIKeyRepositoryStub) Rotate(ctx context.Context, keyID string, hash string, rotatedAt string) (*APIKey, *errs.AppError) {
+	return a.update(keyID, func(key *APIKey) {
+		key.Hash = hash
+		key.RotatedAt.String, key.RotatedAt.Valid = rotatedAt, true
+	})
+}
+
+// update() applies change to an unrevoked key
+func (a *APIKeyRepositoryStub) update(keyID string, change func(*APIKey)) (*APIKey, *errs.AppError) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	key, ok := a.keys[keyID]
+	if !ok {
+		return nil, errs.NotFoundErr(MsgAPIKeyNotFound)
+	}
+	if key.RevokedAt.Valid {
+		return nil, apiKeyRevokedErr(keyID)
+	}
+	change(&key)
+	a.keys[keyID] = key
+	return &key, nil
+}
+
+// NewAPIKeyRepositoryStub is a helper function which creates a new, empty API key repository stub
+func NewAPIKeyRepositoryStub() *APIKeyRepositoryStub {
+	return &APIKeyRepositoryStub{keys: make(map[string]APIKey)}
+}
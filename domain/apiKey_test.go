@@ -0,0 +1,90 @@
+package domain
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAPIKeySecret() should pass when a new key carries its key_id, hashes to the returned hash and a rotated key keeps
+// the key_id but not the secret
+func TestAPIKeySecret(t *testing.T) {
+	// Arrange - setup test
+	keyID, key, hash, err := NewAPIKeySecret("")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Act - execute test
+	parsedID, ok := APIKeyID(key)
+	rotatedID, rotated, _, _ := NewAPIKeySecret(keyID)
+	stored := APIKey{KeyID: keyID, Hash: hash}
+
+	// Assert - test expectations
+	if !ok || parsedID != keyID || !strings.HasPrefix(key, "bk_") {
+		t.Errorf("received key_id %q from %q, want %q", parsedID, key, keyID)
+	}
+	if !stored.Matches(key) || stored.Matches(rotated) {
+		t.Error("expected the stored hash to match only the key it was created with")
+	}
+	if rotatedID != keyID || rotated == key {
+		t.Errorf("received %q for key_id %q, want a new key with the same key_id", rotated, keyID)
+	}
+	if _, ok := APIKeyID("Bearer abc.def.ghi"); ok {
+		t.Error("expected a token not to parse as an api key")
+	}
+}
+
+// TestAPIKeyAllows() should pass when only an active key is allowed, and only for the route names in its scopes
+func TestAPIKeyAllows(t *testing.T) {
+	// Arrange - setup test
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.Local)
+	active := APIKey{Scopes: "GetCustomer,NewTransaction"}
+	expired := active
+	expired.ExpiresAt = sql.NullString{String: "2026-06-01 12:00:00", Valid: true}
+	revoked := active
+	revoked.RevokedAt = sql.NullString{String: "2026-05-01 09:00:00", Valid: true}
+	tests := []struct {
+		name       string
+		key        APIKey
+		routeName  string
+		wantStatus string
+		want       bool
+	}{
+		{"in scope", active, "NewTransaction", APIKeyActive, true},
+		{"out of scope", active, "GetAllCustomers", APIKeyActive, false},
+		{"expired", expired, "GetCustomer", APIKeyExpired, false},
+		{"revoked", revoked, "GetCustomer", APIKeyRevoked, false},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		got := test.key.Allows(test.routeName, now)
+
+		// Assert - test expectations
+		if got != test.want || test.key.Status(now) != test.wantStatus {
+			t.Errorf("%s: received %v (%s), want %v (%s)", test.name, got, test.key.Status(now), test.want, test.wantStatus)
+		}
+	}
+}
+
+// TestValidateScopes() should pass when unknown route names and the routes which may not be granted to a key are
+// reported together
+func TestValidateScopes(t *testing.T) {
+	// Arrange - setup test
+	routeNames := map[string]bool{"GetCustomer": true, "NewAPIKey": true}
+
+	// Act - execute test
+	valid := ValidateScopes([]string{"GetCustomer"}, routeNames)
+	invalid := ValidateScopes([]string{"NewAPIKey", "GetCustomr"}, routeNames)
+
+	// Assert - test expectations
+	if valid != nil {
+		t.Errorf("unexpected error %q", valid.Message)
+	}
+	if invalid == nil || invalid.Code != 422 || !strings.Contains(invalid.Message, "NewAPIKey may not be granted") ||
+		!strings.Contains(invalid.Message, "GetCustomr is not a route name") {
+		t.Errorf("received %v, want both scopes reported", invalid)
+	}
+}
@@ -0,0 +1,187 @@
+package domain
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// audit outcomes - a call refused by the auth or rate limiting middleware is denied, any other error is a failure
+const (
+	AuditSuccess = "success"
+	AuditDenied  = "denied"
+	AuditFailure = "failure"
+)
+
+// AuditGenesisHash is the previous hash of the first entry of the audit log
+const AuditGenesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+
+// AuditEntry represents a row of the audit_log table - Targets holds a JSON object of the IDs the call acted on (e.g.
+// {"account_id":"95471","customer_id":"2000"}) and Status the http status code (e.g. 201) or, for an rpc, the gRPC code
+// (e.g. PermissionDenied)
+// Hash is the SHA-256 hash of the entry's fields and PrevHash, the hash of the entry before it - see ComputeHash()
+type AuditEntry struct {
+	EntryID    int64  `db:"entry_id"`
+	OccurredAt string `db:"occurred_at"`
+	Actor      string `db:"actor"`
+	Action     string `db:"action"`
+	Targets    string `db:"targets"`
+	Summary    string `db:"summary"`
+	Outcome    string `db:"outcome"`
+	Status     string `db:"status"`
+	RequestID  string `db:"request_id"`
+	PrevHash   string `db:"prev_hash"`
+	Hash       string `db:"hash"`
+}
+
+// AuditHead is the last entry of the audit log as recorded by the audit_chain_head table - EntryID is 0 and Hash is
+// AuditGenesisHash while the log is empty
+type AuditHead struct {
+	EntryID int64  `db:"last_entry_id"`
+	Hash    string `db:"last_hash"`
+}
+
+// AuditQuery holds the filters and page of an audit log search - the zero value of each filter matches every entry
+type AuditQuery struct {
+	Actor      string
+	Action     string
+	Outcome    string
+	CustomerID string // matches the customer_id of the entry's targets
+	From       string // inclusive, "2006-01-02 15:04:05"
+	To         string // exclusive, "2006-01-02 15:04:05"
+	Limit      int
+	// BeforeID is the entry_id of the last entry of the previous page - entries are returned newest first, so the page
+	// holds the entries before it - 0 for the first page
+	BeforeID int64
+}
+
+// AuditRepository is a "port" implemented by the server side - AuditRepositoryDb is its adapter - the log is append-only
+// so there is no way to change or remove an entry
+type AuditRepository interface {
+	// Append chains entry onto the end of the log - EntryID, PrevHash and Hash are set by the repository
+	Append(context.Context, AuditEntry) (*AuditEntry, *errs.AppError)
+	// Find returns the entries matching the query, newest first
+	Find(context.Context, AuditQuery) ([]AuditEntry, *errs.AppError)
+	// Chain returns up to limit entries after afterID, oldest first, for the chain to be verified
+	Chain(ctx context.Context, afterID int64, limit int) ([]AuditEntry, *errs.AppError)
+	// Head returns the last entry of the log as recorded by the chain head
+	Head(context.Context) (*AuditHead, *errs.AppError)
+}
+
+// ComputeHash() returns the hex SHA-256 hash of the entry's fields and PrevHash - each field is quoted so that moving
+// characters from one field to the next changes the hash
+func (entry AuditEntry) ComputeHash() string {
+	fields := []string{
+		strconv.FormatInt(entry.EntryID, 10), entry.OccurredAt, entry.Actor, entry.Action, entry.Targets, entry.Summary,
+		entry.Outcome, entry.Status, entry.RequestID, entry.PrevHash,
+	}
+	for i, field := range fields {
+		fields[i] = strconv.Quote(field)
+	}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// chainTo() sets the entry's ID, previous hash and hash so that it follows head
+func (entry *AuditEntry) chainTo(head AuditHead) {
+	entry.EntryID = head.EntryID + 1
+	entry.PrevHash = head.Hash
+	entry.Hash = entry.ComputeHash()
+}
+
+// TargetMap() returns the IDs of the entry's targets - an entry whose targets cannot be read has none
+func (entry AuditEntry) TargetMap() map[string]string {
+	targets := map[string]string{}
+	_ = json.Unmarshal([]byte(entry.Targets), &targets)
+	return targets
+}
+
+// ToDto() converts the AuditEntry to its response
+func (entry AuditEntry) ToDto() dto.AuditEventResponse {
+	return dto.AuditEventResponse{
+		EntryID:    strconv.FormatInt(entry.EntryID, 10),
+		OccurredAt: entry.OccurredAt,
+		Actor:      entry.Actor,
+		Action:     entry.Action,
+		Targets:    entry.TargetMap(),
+		Summary:    entry.Summary,
+		Outcome:    entry.Outcome,
+		Status:     entry.Status,
+		RequestID:  entry.RequestID,
+		PrevHash:   entry.PrevHash,
+		Hash:       entry.Hash,
+	}
+}
+
+// AuditTargets() returns targets as the JSON object stored in AuditEntry.Targets - the keys are sorted so that the same
+// targets always hash the same way
+func AuditTargets(targets map[string]string) string {
+	if targets == nil {
+		targets = map[string]string{}
+	}
+	// Marshal() sorts the keys of a map and cannot fail for a map of strings
+	b, _ := json.Marshal(targets)
+	return string(b)
+}
+
+// AuditOutcome() returns the outcome of a call answered with the http status code
+func AuditOutcome(status int) string {
+	switch {
+	case status < 400:
+		return AuditSuccess
+	case status == 401 || status == 403 || status == 429:
+		return AuditDenied
+	default:
+		return AuditFailure
+	}
+}
+
+// AuditChainVerifier checks the audit log entry by entry, oldest first - entries may be fed in batches with Next() and
+// the first break found is kept
+type AuditChainVerifier struct {
+	last     AuditHead
+	Verified int64
+	// BrokenAt is the entry_id of the first entry which does not follow the one before it, or 0 if none was found
+	BrokenAt int64
+}
+
+// NewAuditChainVerifier() returns a verifier starting at the beginning of the log
+func NewAuditChainVerifier() *AuditChainVerifier {
+	return &AuditChainVerifier{last: AuditHead{EntryID: 0, Hash: AuditGenesisHash}}
+}
+
+// Next() checks that each entry has the ID and previous hash following the entry before it and that its hash matches its
+// fields - false is returned once the chain is broken
+func (v *AuditChainVerifier) Next(entries []AuditEntry) bool {
+	for _, entry := range entries {
+		if v.BrokenAt != 0 {
+			return false
+		}
+		// a gap in the IDs is a removed entry, a wrong previous hash a changed or reordered one
+		if entry.EntryID != v.last.EntryID+1 || entry.PrevHash != v.last.Hash || entry.Hash != entry.ComputeHash() {
+			v.BrokenAt = v.last.EntryID + 1
+			return false
+		}
+		v.last = AuditHead{EntryID: entry.EntryID, Hash: entry.Hash}
+		v.Verified++
+	}
+	return v.BrokenAt == 0
+}
+
+// Finish() checks that the chain ends at head - entries removed from the end of the log leave head beyond the last entry
+// verified, while entries added without the head being moved are beyond head
+func (v *AuditChainVerifier) Finish(head AuditHead) bool {
+	if v.BrokenAt == 0 && v.last != head {
+		v.BrokenAt = v.last.EntryID + 1
+		if head.EntryID < v.last.EntryID {
+			v.BrokenAt = head.EntryID + 1
+		}
+	}
+	return v.BrokenAt == 0
+}
@@ -0,0 +1,132 @@
+package domain
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+// appendAuditEntries() returns a stub holding count chained entries
+func appendAuditEntries(t *testing.T, count int) *AuditRepositoryStub {
+	repo := NewAuditRepositoryStub()
+	for i := 0; i < count; i++ {
+		entry := AuditEntry{
+			OccurredAt: "2026-10-19 09:00:00",
+			Actor:      "teller1",
+			Action:     "NewTransaction",
+			Targets:    AuditTargets(map[string]string{"customer_id": "1001"}),
+			Summary:    `POST /v1/customers/1001/transaction {"amount":50}`,
+			Outcome:    AuditSuccess,
+			Status:     "201",
+		}
+		if _, appErr := repo.Append(context.Background(), entry); appErr != nil {
+			t.Fatalf("append received %v", appErr)
+		}
+	}
+	return repo
+}
+
+// TestAuditChainVerifier() should pass when an untouched chain verifies and changing, removing, reordering or appending an
+// entry outside the repository is reported at the first entry affected
+func TestAuditChainVerifier(t *testing.T) {
+	tests := []struct {
+		name       string
+		tamper     func(repo *AuditRepositoryStub)
+		wantBroken int64
+	}{
+		{"untouched", func(repo *AuditRepositoryStub) {}, 0},
+		{"changed amount", func(repo *AuditRepositoryStub) {
+			repo.entries[2].Summary = `POST /v1/customers/1001/transaction {"amount":5000}`
+		}, 3},
+		{"changed and rehashed", func(repo *AuditRepositoryStub) {
+			repo.entries[1].Actor = "admin"
+			repo.entries[1].Hash = repo.entries[1].ComputeHash()
+		}, 3},
+		{"removed entry", func(repo *AuditRepositoryStub) {
+			repo.entries = append(repo.entries[:1], repo.entries[2:]...)
+		}, 2},
+		{"removed newest entry", func(repo *AuditRepositoryStub) {
+			repo.entries = repo.entries[:4]
+		}, 5},
+		{"reordered entries", func(repo *AuditRepositoryStub) {
+			repo.entries[0], repo.entries[1] = repo.entries[1], repo.entries[0]
+		}, 1},
+		{"appended without the head", func(repo *AuditRepositoryStub) {
+			extra := repo.entries[4]
+			extra.chainTo(AuditHead{EntryID: extra.EntryID, Hash: extra.Hash})
+			repo.entries = append(repo.entries, extra)
+		}, 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange - setup test
+			repo := appendAuditEntries(t, 5)
+			test.tamper(repo)
+			head, _ := repo.Head(context.Background())
+			entries, _ := repo.Chain(context.Background(), 0, 100)
+
+			// Act - execute test
+			verifier := NewAuditChainVerifier()
+			// the entries are fed in two batches as the service does for a long log
+			verifier.Next(entries[:2])
+			verifier.Next(entries[2:])
+			intact := verifier.Finish(*head)
+
+			// Assert - test expectations
+			if verifier.BrokenAt != test.wantBroken || intact != (test.wantBroken == 0) {
+				t.Errorf("received broken at %d (intact %t), want %d", verifier.BrokenAt, intact, test.wantBroken)
+			}
+		})
+	}
+}
+
+// TestAuditRepositoryStubAppend() should pass when entries are numbered from 1 and each is chained to the one before it,
+// starting from the genesis hash
+func TestAuditRepositoryStubAppend(t *testing.T) {
+	// Arrange - setup test
+	repo := appendAuditEntries(t, 2)
+
+	// Act - execute test
+	entries, _ := repo.Chain(context.Background(), 0, 10)
+	head, _ := repo.Head(context.Background())
+
+	// Assert - test expectations
+	if len(entries) != 2 || entries[0].EntryID != 1 || entries[1].EntryID != 2 {
+		t.Fatalf("received entries %+v, want entries 1 and 2", entries)
+	}
+	if entries[0].PrevHash != AuditGenesisHash || entries[1].PrevHash != entries[0].Hash || entries[0].Hash == entries[1].Hash {
+		t.Error("expected the entries to be chained from the genesis hash")
+	}
+	if *head != (AuditHead{EntryID: 2, Hash: entries[1].Hash}) {
+		t.Errorf("received head %+v, want entry 2", head)
+	}
+}
+
+// TestBuildFindAuditSql() should pass when every filter becomes a placeholder condition and the cursor continues before
+// the last entry of the previous page
+func TestBuildFindAuditSql(t *testing.T) {
+	// Arrange - setup test
+	query := AuditQuery{
+		Actor:      "admin",
+		Outcome:    AuditDenied,
+		CustomerID: "1001",
+		From:       "2026-10-01 00:00:00",
+		Limit:      101,
+		BeforeID:   500,
+	}
+
+	// Act - execute test
+	findSql, args := buildFindAuditSql(query)
+
+	// Assert - test expectations
+	wantSql := "select " + auditColumns + " from audit_log" +
+		" where actor = ? and outcome = ? and json_unquote(json_extract(targets, '$.customer_id')) = ?" +
+		" and occurred_at >= ? and entry_id < ? order by entry_id desc limit ?"
+	wantArgs := []interface{}{"admin", "denied", "1001", "2026-10-01 00:00:00", int64(500), 101}
+	if findSql != wantSql {
+		t.Errorf("received sql %q\nwant %q", findSql, wantSql)
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("received args %v, want %v", args, wantArgs)
+	}
+}
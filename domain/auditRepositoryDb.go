@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// auditColumns are the columns of the audit_log table read into an AuditEntry
+const auditColumns = "entry_id, occurred_at, actor, action, targets, summary, outcome, status, request_id, prev_hash, hash"
+
+// AuditRepositoryDb is an adapter that implements the AuditRepository (port) interface
+type AuditRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// Append() chains entry onto the end of the log - the chain head row is locked for the length of the sql transaction so
+// that concurrent appends are chained one after the other rather than onto the same previous entry
+func (a AuditRepositoryDb) Append(ctx context.Context, entry AuditEntry) (*AuditEntry, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "AuditRepositoryDb.Append")
+	defer span.End()
+
+	tx, err := a.db_conn.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating database transaction tx", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error creating database transaction tx")
+	}
+	// the rollback is a no-op once the sql transaction has been committed
+	defer tx.Rollback()
+
+	headSql := "select last_entry_id, last_hash from audit_chain_head where id = 1 for update"
+	headCtx, headSpan := tracing.StartDB(ctx, "lock audit chain head", headSql)
+	var head AuditHead
+	err = tx.GetContext(headCtx, &head, headSql)
+	headSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while locking the audit chain head", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while appending to the audit log")
+	}
+
+	entry.chainTo(head)
+	insertSql := "insert into audit_log (" + auditColumns + ") values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+	insertCtx, insertSpan := tracing.StartDB(ctx, "insert audit entry", insertSql)
+	_, err = tx.ExecContext(insertCtx, insertSql, entry.EntryID, entry.OccurredAt, entry.Actor, entry.Action, entry.Targets,
+		entry.Summary, entry.Outcome, entry.Status, entry.RequestID, entry.PrevHash, entry.Hash)
+	insertSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while inserting audit entry", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while appending to the audit log")
+	}
+
+	updateSql := "update audit_chain_head set last_entry_id = ?, last_hash = ? where id = 1"
+	updateCtx, updateSpan := tracing.StartDB(ctx, "update audit chain head", updateSql)
+	_, err = tx.ExecContext(updateCtx, updateSql, entry.EntryID, entry.Hash)
+	updateSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating the audit chain head", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while appending to the audit log")
+	}
+
+	_, commitSpan := tracing.StartDB(ctx, "commit", "COMMIT")
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while committing the audit entry", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while appending to the audit log")
+	}
+	return &entry, nil
+}
+
+// Find() returns the entries matching query, newest first
+func (a AuditRepositoryDb) Find(ctx context.Context, query AuditQuery) ([]AuditEntry, *errs.AppError) {
+	findSql, args := buildFindAuditSql(query)
+
+	ctx, span := tracing.StartDB(ctx, "AuditRepositoryDb.Find", findSql)
+	defer span.End()
+
+	entries := make([]AuditEntry, 0)
+	if err := a.db_conn.SelectContext(ctx, &entries, findSql, args...); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of audit_log table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return entries, nil
+}
+
+// buildFindAuditSql() returns the MySQL query for an audit log search and the values for its placeholders - every value
+// sent by the client is passed as a placeholder
+func buildFindAuditSql(query AuditQuery) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	if query.Actor != "" {
+		conditions = append(conditions, "actor = ?")
+		args = append(args, query.Actor)
+	}
+	if query.Action != "" {
+		conditions = append(conditions, "action = ?")
+		args = append(args, query.Action)
+	}
+	if query.Outcome != "" {
+		conditions = append(conditions, "outcome = ?")
+		args = append(args, query.Outcome)
+	}
+	if query.CustomerID != "" {
+		// targets is a JSON object written by AuditTargets()
+		conditions = append(conditions, "json_unquote(json_extract(targets, '$.customer_id')) = ?")
+		args = append(args, query.CustomerID)
+	}
+	if query.From != "" {
+		conditions = append(conditions, "occurred_at >= ?")
+		args = append(args, query.From)
+	}
+	if query.To != "" {
+		conditions = append(conditions, "occurred_at < ?")
+		args = append(args, query.To)
+	}
+	if query.BeforeID > 0 {
+		conditions = append(conditions, "entry_id < ?")
+		args = append(args, query.BeforeID)
+	}
+
+	findSql := "select " + auditColumns + " from audit_log"
+	if len(conditions) > 0 {
+		findSql += " where " + strings.Join(conditions, " and ")
+	}
+	findSql += " order by entry_id desc limit ?"
+	args = append(args, query.Limit)
+	return findSql, args
+}
+
+// Chain() returns up to limit entries after afterID, oldest first
+func (a AuditRepositoryDb) Chain(ctx context.Context, afterID int64, limit int) ([]AuditEntry, *errs.AppError) {
+	chainSql := "select " + auditColumns + " from audit_log where entry_id > ? order by entry_id limit ?"
+
+	ctx, span := tracing.StartDB(ctx, "AuditRepositoryDb.Chain", chainSql)
+	defer span.End()
+
+	entries := make([]AuditEntry, 0)
+	if err := a.db_conn.SelectContext(ctx, &entries, chainSql, afterID, limit); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of audit_log table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return entries, nil
+}
+
+// Head() returns the last entry of the log as recorded by the audit_chain_head table
+func (a AuditRepositoryDb) Head(ctx context.Context) (*AuditHead, *errs.AppError) {
+	headSql := "select last_entry_id, last_hash from audit_chain_head where id = 1"
+
+	ctx, span := tracing.StartDB(ctx, "AuditRepositoryDb.Head", headSql)
+	defer span.End()
+
+	var head AuditHead
+	if err := a.db_conn.GetContext(ctx, &head, headSql); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of audit_chain_head table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &head, nil
+}
+
+// NewAuditRepositoryDb() takes a db connection and returns an AuditRepositoryDb
+func NewAuditRepositoryDb(db_conn *sqlx.DB) AuditRepositoryDb {
+	return AuditRepositoryDb{db_conn: db_conn}
+}
@@ -0,0 +1,80 @@
+package domain
+
+import (
+	"context"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// AuditRepositoryStub is an in-memory "adapter" for the AuditRepository "port" - it starts empty
+type AuditRepositoryStub struct {
+	// mu guards entries and head as every mutating request appends an entry
+	mu      sync.RWMutex
+	entries []AuditEntry
+	head    AuditHead
+}
+
+// Append implementation for type AuditRepositoryStub
+func (a *AuditRepositoryStub) Append(ctx context.Context, entry AuditEntry) (*AuditEntry, *errs.AppError) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	entry.chainTo(a.head)
+	a.entries = append(a.entries, entry)
+	a.head = AuditHead{EntryID: entry.EntryID, Hash: entry.Hash}
+	return &entry, nil
+}
+
+// Find implementation for type AuditRepositoryStub
+func (a *AuditRepositoryStub) Find(ctx context.Context, query AuditQuery) ([]AuditEntry, *errs.AppError) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	entries := make([]AuditEntry, 0)
+	for i := len(a.entries) - 1; i >= 0 && len(entries) < query.Limit; i-- {
+		if entry := a.entries[i]; query.matches(entry) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// matches() reports whether entry passes every filter of the query - the stub's counterpart of buildFindAuditSql()
+func (query AuditQuery) matches(entry AuditEntry) bool {
+	return (query.Actor == "" || entry.Actor == query.Actor) &&
+		(query.Action == "" || entry.Action == query.Action) &&
+		(query.Outcome == "" || entry.Outcome == query.Outcome) &&
+		(query.CustomerID == "" || entry.TargetMap()["customer_id"] == query.CustomerID) &&
+		(query.From == "" || entry.OccurredAt >= query.From) &&
+		(query.To == "" || entry.OccurredAt < query.To) &&
+		(query.BeforeID == 0 || entry.EntryID < query.BeforeID)
+}
+
+// Chain implementation for type AuditRepositoryStub
+func (a *AuditRepositoryStub) Chain(ctx context.Context, afterID int64, limit int) ([]AuditEntry, *errs.AppError) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	entries := make([]AuditEntry, 0)
+	for _, entry := range a.entries {
+		if entry.EntryID > afterID && len(entries) < limit {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// Head implementation for type AuditRepositoryStub
+func (a *AuditRepositoryStub) Head(ctx context.Context) (*AuditHead, *errs.AppError) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	head := a.head
+	return &head, nil
+}
+
+// NewAuditRepositoryStub is a helper function which creates a new, empty audit repository stub
+func NewAuditRepositoryStub() *AuditRepositoryStub {
+	return &AuditRepositoryStub{head: AuditHead{EntryID: 0, Hash: AuditGenesisHash}}
+}
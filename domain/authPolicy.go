@@ -0,0 +1,126 @@
+package domain
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// AnyRoute is the route name of a rule which grants every route
+const AnyRoute = "*"
+
+// user roles other than RoleAdmin and RoleUser - tellers serve every customer at a branch while auditors only read
+const (
+	RoleTeller  = "teller"
+	RoleAuditor = "auditor"
+)
+
+// AuthPolicy maps each role to the routes it may call - a role which is not listed may call nothing
+type AuthPolicy struct {
+	Roles map[string][]RouteRule `yaml:"roles" json:"roles"`
+}
+
+// RouteRule grants a route (by mux route name, or AnyRoute) - Owns lists the route variables which must equal the token
+// claim of the same name, e.g. owns: [customer_id] restricts a user to the routes of their own customer
+type RouteRule struct {
+	Route string   `yaml:"route" json:"route"`
+	Owns  []string `yaml:"owns" json:"owns"`
+}
+
+// Allows() reports whether role may call routeName with the route variables vars - claims holds the token claims an
+// ownership constraint is checked against
+func (policy AuthPolicy) Allows(role string, routeName string, vars map[string]string, claims map[string]string) bool {
+	for _, rule := range policy.Roles[role] {
+		if (rule.Route == routeName || rule.Route == AnyRoute) && rule.owned(vars, claims) {
+			return true
+		}
+	}
+	return false
+}
+
+// owned() reports whether every variable in Owns is present in vars and equals the non-empty claim of the same name - a
+// route without the variable is refused rather than waved through
+func (rule RouteRule) owned(vars map[string]string, claims map[string]string) bool {
+	for _, name := range rule.Owns {
+		value, ok := vars[name]
+		if !ok || claims[name] == "" || value != claims[name] {
+			return false
+		}
+	}
+	return true
+}
+
+// Validate() returns every problem with the policy - routeNames, when not nil, holds the route names of the router so
+// that a misspelt route name is caught at startup rather than silently denying the route
+func (policy AuthPolicy) Validate(routeNames map[string]bool) []string {
+	var problems []string
+	roles := make([]string, 0, len(policy.Roles))
+	for role := range policy.Roles {
+		roles = append(roles, role)
+	}
+	// sorted so that the problems are reported in the same order every time
+	sort.Strings(roles)
+	for _, role := range roles {
+		for i, rule := range policy.Roles[role] {
+			switch {
+			case rule.Route == "":
+				problems = append(problems, fmt.Sprintf("roles.%s[%d]: route is required", role, i))
+			case rule.Route != AnyRoute && routeNames != nil && !routeNames[rule.Route]:
+				problems = append(problems, fmt.Sprintf("roles.%s[%d]: unknown route %s", role, i, rule.Route))
+			}
+			for _, name := range rule.Owns {
+				if name == "" {
+					problems = append(problems, fmt.Sprintf("roles.%s[%d]: owns must not hold an empty variable name", role, i))
+				}
+			}
+		}
+	}
+	return problems
+}
+
+// LoadAuthPolicy() reads the policy at path - like the config file it may be YAML or JSON, chosen by its extension
+func LoadAuthPolicy(path string) (*AuthPolicy, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading auth policy: %w", err)
+	}
+	var policy AuthPolicy
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		err = json.Unmarshal(data, &policy)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &policy)
+	default:
+		return nil, fmt.Errorf("auth policy %s must have a .json, .yaml or .yml extension", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("decoding auth policy %s: %w", path, err)
+	}
+	return &policy, nil
+}
+
+// DefaultAuthPolicy() returns the policy used when no policy file is configured - auth-policy.example.yaml holds the
+// same policy
+func DefaultAuthPolicy() AuthPolicy {
+	ownCustomer := []string{"customer_id"}
+	return AuthPolicy{Roles: map[string][]RouteRule{
+		RoleAdmin: {{Route: AnyRoute}},
+		RoleTeller: {
+			{Route: "GetAllCustomers"}, {Route: "GetCustomer"}, {Route: "GetAllAccountProducts"}, {Route: "GetAccountProduct"},
+			{Route: "NewAccount"}, {Route: "NewTransaction"}, {Route: "ChangePassword"},
+		},
+		RoleAuditor: {
+			{Route: "GetAllCustomers"}, {Route: "GetCustomer"}, {Route: "GetAllAccountProducts"}, {Route: "GetAccountProduct"},
+			{Route: "GetAuditEvents"}, {Route: "VerifyAuditLog"}, {Route: "ChangePassword"},
+		},
+		RoleUser: {
+			{Route: "GetCustomer", Owns: ownCustomer}, {Route: "NewTransaction", Owns: ownCustomer},
+			{Route: "GetAllAccountProducts"}, {Route: "GetAccountProduct"}, {Route: "ChangePassword"},
+		},
+	}}
+}
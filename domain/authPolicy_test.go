@@ -0,0 +1,95 @@
+package domain
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// TestAuthPolicyAllows() should pass when a role is granted its listed routes, a wildcard grants every route, an
+// ownership constraint requires the route variable to equal the claim and an unlisted role is granted nothing
+func TestAuthPolicyAllows(t *testing.T) {
+	// Arrange - setup test
+	policy := AuthPolicy{Roles: map[string][]RouteRule{
+		"admin":   {{Route: AnyRoute}},
+		"auditor": {{Route: "GetCustomer"}},
+		"user":    {{Route: "NewTransaction", Owns: []string{"customer_id"}}},
+	}}
+	own := map[string]string{"customer_id": "1001"}
+	tests := []struct {
+		name      string
+		role      string
+		routeName string
+		vars      map[string]string
+		claims    map[string]string
+		want      bool
+	}{
+		{"wildcard", "admin", "DeleteAccountProduct", map[string]string{"code": "savings"}, nil, true},
+		{"listed route", "auditor", "GetCustomer", map[string]string{"customer_id": "1002"}, nil, true},
+		{"unlisted route", "auditor", "NewTransaction", map[string]string{"customer_id": "1002"}, nil, false},
+		{"owned", "user", "NewTransaction", map[string]string{"customer_id": "1001"}, own, true},
+		{"not owned", "user", "NewTransaction", map[string]string{"customer_id": "1002"}, own, false},
+		{"no claim", "user", "NewTransaction", map[string]string{"customer_id": "1001"}, map[string]string{"customer_id": ""}, false},
+		{"route without the variable", "user", "NewTransaction", map[string]string{}, own, false},
+		{"unknown role", "teller", "GetCustomer", map[string]string{"customer_id": "1001"}, own, false},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		got := policy.Allows(test.role, test.routeName, test.vars, test.claims)
+
+		// Assert - test expectations
+		if got != test.want {
+			t.Errorf("%s: received %v, want %v", test.name, got, test.want)
+		}
+	}
+}
+
+// TestAuthPolicyValidate() should pass when a missing route, a route the router does not know and an empty ownership
+// variable are all reported
+func TestAuthPolicyValidate(t *testing.T) {
+	// Arrange - setup test
+	policy := AuthPolicy{Roles: map[string][]RouteRule{
+		"admin": {{Route: AnyRoute}},
+		"user":  {{Route: ""}, {Route: "GetCustomr"}, {Route: "GetCustomer", Owns: []string{""}}},
+	}}
+
+	// Act - execute test
+	problems := policy.Validate(map[string]bool{"GetCustomer": true})
+
+	// Assert - test expectations
+	want := []string{"roles.user[0]: route is required", "roles.user[1]: unknown route GetCustomr",
+		"roles.user[2]: owns must not hold an empty variable name"}
+	if !reflect.DeepEqual(problems, want) {
+		t.Errorf("received %v, want %v", problems, want)
+	}
+}
+
+// TestLoadAuthPolicy() should pass when the example policy file holds the default policy, a JSON policy is read and a
+// file with another extension is refused
+func TestLoadAuthPolicy(t *testing.T) {
+	// Arrange - setup test
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "policy.json")
+	os.WriteFile(jsonPath, []byte(`{"roles": {"auditor": [{"route": "GetCustomer"}]}}`), 0o600)
+	txtPath := filepath.Join(dir, "policy.txt")
+	os.WriteFile(txtPath, []byte("roles: {}"), 0o600)
+
+	// Act - execute test
+	example, exampleErr := LoadAuthPolicy("../auth-policy.example.yaml")
+	fromJSON, jsonErr := LoadAuthPolicy(jsonPath)
+	_, txtErr := LoadAuthPolicy(txtPath)
+
+	// Assert - test expectations
+	if exampleErr != nil || !reflect.DeepEqual(*example, DefaultAuthPolicy()) {
+		t.Errorf("received %v, %v - want the example file to hold the default policy", example, exampleErr)
+	}
+	if jsonErr != nil || !fromJSON.Allows("auditor", "GetCustomer", nil, nil) {
+		t.Errorf("received %v, %v - want the json policy to grant GetCustomer to auditors", fromJSON, jsonErr)
+	}
+	if txtErr == nil || !strings.Contains(txtErr.Error(), "extension") {
+		t.Errorf("received %v, want an extension error", txtErr)
+	}
+}
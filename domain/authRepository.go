@@ -1,41 +1,125 @@
 package domain
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/url"
+	"time"
 
-	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
 )
 
+// AuthRepository is a "port" used by the AuthMiddleware to verify a token - ctx carries the incoming request's deadline so a
+// slow auth server cannot hold the request open
 type AuthRepository interface {
-	IsAuthorized(token string, routeName string, vars map[string]string) bool
+	IsAuthorized(ctx context.Context, token string, routeName string, vars map[string]string) bool
+}
+
+// CredentialVerifier is implemented by the AuthRepository adapters which can tell an invalid token or API key from a
+// valid one which is not granted the route - only an invalid one counts against the client's auth failure rate limit
+// RemoteAuthRepository does not implement it, as the Banking-Auth server only answers whether a token is authorized
+type CredentialVerifier interface {
+	IsValid(ctx context.Context, credential string) bool
 }
 
-type RemoteAuthRepository struct{}
+// RemoteAuthRepository is an adapter which implements the AuthRepository port by asking the Banking-Auth server at
+// serverURL to verify each token
+type RemoteAuthRepository struct {
+	serverURL string
+}
+
+func (r RemoteAuthRepository) IsAuthorized(ctx context.Context, token string, routeName string, vars map[string]string) bool {
+	// the client span covers the GET to the auth server - its trace context is sent along in the request headers
+	ctx, span := tracing.Start(ctx, "RemoteAuthRepository.IsAuthorized",
+		trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(tracing.Attr("auth.route_name", routeName)))
+	defer span.End()
 
-func (r RemoteAuthRepository) IsAuthorized(token string, routeName string, vars map[string]string) bool {
-	url := buildVerifyURL(token, routeName, vars)
-	// Get() issues a http GET to the passed in URL and returns a response and an error
-	resp, err := http.Get(url)
+	url, err := buildVerifyURL(r.serverURL, token, routeName, vars)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while building the verify URL", zap.Error(err))
+		return false
+	}
+	// NewRequestWithContext() builds the GET request bound to ctx - if ctx is cancelled, the outbound request is aborted
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while building the GET request", zap.Error(err))
+		return false
+	}
+	// InjectHeaders() adds the traceparent header so the auth server's spans join this trace
+	tracing.InjectHeaders(ctx, propagation.HeaderCarrier(req.Header))
+	// the request ID is forwarded so the auth server's logs can be correlated with ours
+	if requestID := logging.RequestID(ctx); requestID != "" {
+		req.Header.Set(logging.RequestIDHeader, requestID)
+	}
+	// start is used to record the latency of the call to the auth server
+	start := time.Now()
+	// Do() issues the http GET and returns a response and an error
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
-		logger.Error("error while issuing a GET to the URL " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while issuing a GET to the URL", zap.Error(err))
+		metrics.AuthFailure("request")
+		metrics.ObserveAuthRequest("error", time.Since(start))
 		return false
 	}
+	// the response body must be closed so the underlying connection can be reused
+	defer resp.Body.Close()
 	responseMap := make(map[string]bool)
 	// create a decoder that reads from the received response body and decode the data into the response map
 	err = json.NewDecoder(resp.Body).Decode(&responseMap)
 	if err != nil {
-		logger.Error("error while decoding response body " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while decoding response body", zap.Error(err))
+		metrics.AuthFailure("decode")
+		metrics.ObserveAuthRequest("error", time.Since(start))
 		return false
 	}
 	// Banking-Auth microservice returns a response with an "isAuthorized" key set to true or false
-	return responseMap["isAuthorized"]
+	isAuthorized := responseMap["isAuthorized"]
+	outcome := "denied"
+	if isAuthorized {
+		outcome = "authorized"
+	}
+	metrics.ObserveAuthRequest(outcome, time.Since(start))
+	span.SetAttributes(tracing.Attr("auth.outcome", outcome))
+	return isAuthorized
 }
 
-func buildVerifyURL(token string, routeName string, vars map[string]string) string {
-	// url.URL is a struct with which we initialize host, path, and scheme
-	url := url.URL{Host: "localhost:8181", Path: "/auth/verify", Scheme: "http"}
+// Ping() checks that the Banking-Auth server is reachable - any response below 500 means the server is up and able to
+// answer, even if it does not serve the root path
+func (r RemoteAuthRepository) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.serverURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("auth server responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func buildVerifyURL(serverURL string, token string, routeName string, vars map[string]string) (string, error) {
+	// Parse() splits the configured server URL (e.g. http://localhost:8181) into its scheme, host, and path
+	url, err := url.Parse(serverURL)
+	if err != nil {
+		return "", err
+	}
+	url.Path = "/auth/verify"
 	// Query() parses RawQuery and returns a map[string][]string with the values - we will use this map to fully build the URL
 	buildURL := url.Query()
 	// add token under key "token"
@@ -49,9 +133,10 @@ func buildVerifyURL(token string, routeName string, vars map[string]string) stri
 	// Encode encodes the buildURL in URL encoded form - meaning key1=value1&key2=value2 for the URL
 	// (e.g. ...routeName=GetCustomer&customer_id=1...)
 	url.RawQuery = buildURL.Encode()
-	return url.String()
+	return url.String(), nil
 }
 
-func NewAuthRepository() RemoteAuthRepository {
-	return RemoteAuthRepository{}
+// NewAuthRepository() takes the Banking-Auth server's URL and returns a RemoteAuthRepository which verifies tokens against it
+func NewAuthRepository(serverURL string) RemoteAuthRepository {
+	return RemoteAuthRepository{serverURL: serverURL}
 }
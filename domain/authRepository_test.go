@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// TestIsAuthorizedPropagatesTraceContext() should pass when the GET to the auth server carries a traceparent header and a
+// client span is recorded for the call
+func TestIsAuthorizedPropagatesTraceContext(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	// Setup() with the none exporter installs the W3C propagator - the span recorder stands in for a real exporter
+	if _, err := tracing.Setup(context.Background(), config.TracingConfig{Exporter: config.TracingExporterNone}); err != nil {
+		t.Fatal(err)
+	}
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var traceparent string
+	authServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"isAuthorized": true}`))
+	}))
+	defer authServer.Close()
+	repo := NewAuthRepository(authServer.URL)
+
+	// Act - execute test
+	isAuthorized := repo.IsAuthorized(context.Background(), "token", "GetCustomer", map[string]string{"customer_id": "1"})
+
+	// Assert - test expectations
+	if !isAuthorized {
+		t.Error("expected the token to be authorized")
+	}
+	if traceparent == "" {
+		t.Error("expected the auth server to receive a traceparent header")
+	}
+	if spans := recorder.Ended(); len(spans) != 1 || spans[0].Name() != "RemoteAuthRepository.IsAuthorized" {
+		t.Errorf("expected a single RemoteAuthRepository.IsAuthorized span, received %d spans", len(spans))
+	}
+}
@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"errors"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// token types carried in the token_type claim - a refresh token is only accepted by POST /auth/refresh and an access token
+// only by the auth middleware, so one cannot stand in for the other
+const (
+	TokenTypeAccess  = "access"
+	TokenTypeRefresh = "refresh"
+)
+
+// ErrInvalidToken is returned for a token which is malformed, wrongly signed, expired or of the wrong type
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenClaims are the claims of the tokens issued in local auth mode - username is also read by the logging middleware
+type TokenClaims struct {
+	Username   string `json:"username"`
+	Role       string `json:"role"`
+	CustomerID string `json:"customer_id,omitempty"`
+	TokenType  string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// values() returns the claims an AuthPolicy ownership constraint may refer to, keyed by their json names
+func (claims TokenClaims) values() map[string]string {
+	return map[string]string{"username": claims.Username, "role": claims.Role, "customer_id": claims.CustomerID}
+}
+
+// TokenSigner issues and verifies HS256 signed tokens
+type TokenSigner struct {
+	secret     []byte
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// IssuedTokens is a signed access and refresh token pair - AccessExpiresIn is the lifetime of the access token
+type IssuedTokens struct {
+	AccessToken     string
+	RefreshToken    string
+	AccessExpiresIn time.Duration
+}
+
+// Issue() signs an access and a refresh token for user, both issued at now
+func (signer TokenSigner) Issue(user User, now time.Time) (*IssuedTokens, error) {
+	access, err := signer.sign(user, TokenTypeAccess, now, signer.accessTTL)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := signer.sign(user, TokenTypeRefresh, now, signer.refreshTTL)
+	if err != nil {
+		return nil, err
+	}
+	return &IssuedTokens{AccessToken: access, RefreshToken: refresh, AccessExpiresIn: signer.accessTTL}, nil
+}
+
+// sign() signs a token of tokenType for user which expires ttl after now
+func (signer TokenSigner) sign(user User, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	claims := TokenClaims{
+		Username:   user.Username,
+		Role:       user.Role,
+		CustomerID: user.CustomerID.String,
+		TokenType:  tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.Username,
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(signer.secret)
+}
+
+// Verify() returns the claims of token if it is signed by this signer, unexpired and of tokenType
+func (signer TokenSigner) Verify(token string, tokenType string) (*TokenClaims, error) {
+	var claims TokenClaims
+	// only HS256 is accepted - a token naming another algorithm (e.g. none) is rejected before its signature is checked
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	_, err := parser.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) { return signer.secret, nil })
+	if err != nil || claims.TokenType != tokenType {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+// NewTokenSigner() returns a TokenSigner which signs with secret - access and refresh tokens expire after accessTTL and
+// refreshTTL respectively
+func NewTokenSigner(secret string, accessTTL time.Duration, refreshTTL time.Duration) TokenSigner {
+	return TokenSigner{secret: []byte(secret), accessTTL: accessTTL, refreshTTL: refreshTTL}
+}
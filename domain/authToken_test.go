@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// TestTokenSignerVerify() should pass when a token is only accepted unexpired, with its own type, signed by the same secret
+// and with HS256
+func TestTokenSignerVerify(t *testing.T) {
+	// Arrange - setup test
+	signer := NewTokenSigner("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	other := NewTokenSigner("fedcba9876543210fedcba9876543210", time.Minute, time.Hour)
+	user := User{Username: "1001", Role: RoleUser, CustomerID: sql.NullString{String: "1001", Valid: true}}
+	now := time.Now()
+	tokens, err := signer.Issue(user, now)
+	if err != nil {
+		t.Fatalf("unexpected error issuing tokens: %v", err)
+	}
+	expired, _ := signer.Issue(user, now.Add(-2*time.Minute))
+	forged, _ := other.Issue(user, now)
+	unsigned, _ := jwt.NewWithClaims(jwt.SigningMethodNone, TokenClaims{Username: "1001", Role: RoleAdmin, TokenType: TokenTypeAccess}).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	tests := []struct {
+		name      string
+		token     string
+		tokenType string
+		wantValid bool
+	}{
+		{"access token", tokens.AccessToken, TokenTypeAccess, true},
+		{"refresh token", tokens.RefreshToken, TokenTypeRefresh, true},
+		{"refresh token as access token", tokens.RefreshToken, TokenTypeAccess, false},
+		{"access token as refresh token", tokens.AccessToken, TokenTypeRefresh, false},
+		{"expired access token", expired.AccessToken, TokenTypeAccess, false},
+		{"other secret", forged.AccessToken, TokenTypeAccess, false},
+		{"unsigned", unsigned, TokenTypeAccess, false},
+		{"malformed", "abc.def.ghi", TokenTypeAccess, false},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		claims, err := signer.Verify(test.token, test.tokenType)
+
+		// Assert - test expectations
+		if test.wantValid && (err != nil || claims.Username != "1001" || claims.CustomerID != "1001") {
+			t.Errorf("%s: received %v, %v - want valid claims for 1001", test.name, claims, err)
+		}
+		if !test.wantValid && err != ErrInvalidToken {
+			t.Errorf("%s: received %v, want ErrInvalidToken", test.name, err)
+		}
+	}
+	if tokens.AccessExpiresIn != time.Minute {
+		t.Errorf("received access lifetime %v, want %v", tokens.AccessExpiresIn, time.Minute)
+	}
+}
+
+// TestPolicyAuthRepositoryIsAuthorized() should pass when a valid access token is authorized by the default policy for
+// its role - admins may call every route and users only their own customer's routes
+func TestPolicyAuthRepositoryIsAuthorized(t *testing.T) {
+	// Arrange - setup test
+	signer := NewTokenSigner("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	repo := NewPolicyAuthRepository(signer, DefaultAuthPolicy())
+	admin, _ := signer.Issue(User{Username: "admin", Role: RoleAdmin}, time.Now())
+	user, _ := signer.Issue(User{Username: "1001", Role: RoleUser, CustomerID: sql.NullString{String: "1001", Valid: true}}, time.Now())
+	tests := []struct {
+		name      string
+		token     string
+		routeName string
+		vars      map[string]string
+		want      bool
+	}{
+		{"admin", admin.AccessToken, "DeactivateCustomer", map[string]string{"customer_id": "1002"}, true},
+		{"user own customer", user.AccessToken, "GetCustomer", map[string]string{"customer_id": "1001"}, true},
+		{"user route without customer", user.AccessToken, "GetAllAccountProducts", map[string]string{}, true},
+		{"user other customer", user.AccessToken, "GetCustomer", map[string]string{"customer_id": "1002"}, false},
+		{"user admin route", user.AccessToken, "GetAllCustomers", map[string]string{}, false},
+		{"refresh token", user.RefreshToken, "GetCustomer", map[string]string{"customer_id": "1001"}, false},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		got := repo.IsAuthorized(context.Background(), test.token, test.routeName, test.vars)
+
+		// Assert - test expectations
+		if got != test.want {
+			t.Errorf("%s: received %v, want %v", test.name, got, test.want)
+		}
+	}
+}
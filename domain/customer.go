@@ -1,10 +1,34 @@
 package domain
 
 import (
+	"context"
+	"database/sql"
+	"net/http"
+
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/dto"
 )
 
+// MsgCustomerNotFound is the message of CustomerNotFoundErr
+const MsgCustomerNotFound = "customer not found"
+
+// MsgCustomerInactive is the message of CustomerInactiveErr
+const MsgCustomerInactive = "customer is deactivated"
+
+// CustomerNotFoundErr is returned when a customer_id does not exist, and CustomerInactiveErr when an account is opened for,
+// or a transaction posted to an account of, a deactivated customer - the app maps the errors themselves, not their
+// messages, to the CUSTOMER_NOT_FOUND and CUSTOMER_INACTIVE error codes, so the messages may be reworded freely
+var (
+	CustomerNotFoundErr = errs.NotFoundErr(MsgCustomerNotFound)
+	CustomerInactiveErr = errs.ValidationErr(MsgCustomerInactive)
+)
+
+// customer statuses as stored in the status column
+const (
+	CustomerActive   = "1"
+	CustomerInactive = "0"
+)
+
 // Customer represents our business object - a customer
 // Since we are using sqlx - we use db tags to match the column names in the database with the property name in the struct
 type Customer struct {
@@ -15,19 +39,61 @@ type Customer struct {
 	DateofBirth string `db:"date_of_birth"`
 	// if customer is active or not
 	Status string // column name matches property name already
+	// the reason for and time of the last deactivation or reactivation - NULL until the status is first changed
+	StatusReason    sql.NullString `db:"status_reason"`
+	StatusChangedAt sql.NullString `db:"status_changed_at"`
+}
+
+// CustomerStatusChange records a deactivation or reactivation - every change is kept in the customer_status_changes table
+// so that the customer's history stays readable
+type CustomerStatusChange struct {
+	CustomerID string
+	Status     string // CustomerActive or CustomerInactive
+	Reason     string
+	ChangedBy  string // the authenticated subject who made the change, if known
+	ChangedAt  string
 }
 
-// CustomerRepository represents our "port" (interface) for the server side to interact with our business object
+// CustomerRepository represents our "port" (interface) for the server side to interact with our business object - every
+// method takes the request's context so that a client disconnect or a deadline cancels the underlying database query
+//
+//go:generate mockgen -destination=../mocks/domain/mockCustomerRepository.go -package=domain github.com/gtaylor314/Banking-MS/domain CustomerRepository
 type CustomerRepository interface {
-	FindAll(string) ([]Customer, *errs.AppError)
+	// FindAll returns the customers matching the query, in the query's sort order, up to the query's limit
+	FindAll(context.Context, CustomerQuery) ([]Customer, *errs.AppError)
 	// FindById returns a pointer to a Customer object so that we may return nil if the ID is not found
-	FindById(string) (*Customer, *errs.AppError)
+	FindById(context.Context, string) (*Customer, *errs.AppError)
+	// UpdateStatus records the change and sets the customer's status - a customer already in the requested status is a
+	// conflict - the updated customer is returned
+	UpdateStatus(context.Context, CustomerStatusChange) (*Customer, *errs.AppError)
+}
+
+// applyStatusChange() sets the customer's status, reason and time to those of change
+func (cust *Customer) applyStatusChange(change CustomerStatusChange) {
+	cust.Status = change.Status
+	cust.StatusReason = sql.NullString{String: change.Reason, Valid: true}
+	cust.StatusChangedAt = sql.NullString{String: change.ChangedAt, Valid: true}
+}
+
+// statusUnchangedErr() returns the conflict reported when a customer is already in the requested status - errs does not
+// provide a constructor for http.StatusConflict
+func statusUnchangedErr(status string) *errs.AppError {
+	message := "customer is already active"
+	if status == CustomerInactive {
+		message = "customer is already inactive"
+	}
+	return &errs.AppError{Code: http.StatusConflict, Message: message}
+}
+
+// IsActive() reports whether the customer may open accounts and post transactions
+func (cust Customer) IsActive() bool {
+	return cust.Status != CustomerInactive
 }
 
 func (cust Customer) statusText() string {
 	// want to convert the internal status (0 or 1) to a user friendly status of inactive or active
 	statusText := "active"
-	if cust.Status == "0" {
+	if cust.Status == CustomerInactive {
 		statusText = "inactive"
 	}
 	return statusText
@@ -43,5 +109,8 @@ func (cust Customer) ToDto() dto.CustomerResponse {
 		Zipcode:     cust.Zipcode,
 		DateofBirth: cust.DateofBirth,
 		Status:      cust.statusText(),
+		// String is empty when the column is NULL
+		StatusReason:    cust.StatusReason.String,
+		StatusChangedAt: cust.StatusChangedAt.String,
 	}
 }
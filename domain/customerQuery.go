@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+)
+
+// CustomerQuery holds the filters, sort order and page of a customer search - the zero value of each filter matches every
+// customer
+type CustomerQuery struct {
+	Status     string // "1" (active), "0" (inactive) or empty for every customer
+	NamePrefix string
+	City       string
+	Zipcode    string
+	BornAfter  string // inclusive, YYYY-MM-DD
+	BornBefore string // inclusive, YYYY-MM-DD
+	SortBy     string // one of customerSortColumns
+	Descending bool
+	Limit      int // 0 for every matching customer
+	// After is the position of the last customer of the previous page - nil for the first page
+	After *CustomerCursor
+}
+
+// customerSortColumns are the columns a search may sort by - SortBy is checked against it before being placed in the query
+var customerSortColumns = map[string]bool{
+	"customer_id":   true,
+	"name":          true,
+	"city":          true,
+	"zipcode":       true,
+	"date_of_birth": true,
+}
+
+// CustomerCursor marks a position in the results of a search - pages are fetched with keyset pagination (customers after
+// the cursor's sort value and ID) rather than offsets so that a page is neither skipped nor repeated when customers are
+// added while a client pages through the results
+type CustomerCursor struct {
+	SortBy     string `json:"s"`
+	Descending bool   `json:"d,omitempty"`
+	Value      string `json:"v"`
+	ID         string `json:"id"`
+}
+
+// ErrInvalidCursor is returned by DecodeCustomerCursor() for a cursor which was not produced by Encode()
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// NewCustomerCursor() returns the cursor positioned at cust for the given sort order
+func NewCustomerCursor(sortBy string, descending bool, cust Customer) CustomerCursor {
+	return CustomerCursor{SortBy: sortBy, Descending: descending, Value: cust.sortValue(sortBy), ID: cust.ID}
+}
+
+// Encode() returns the cursor as the opaque string handed to clients
+func (cursor CustomerCursor) Encode() string {
+	// Marshal() cannot fail for a struct of strings and a bool
+	b, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCustomerCursor() parses a cursor produced by Encode()
+func DecodeCustomerCursor(s string) (*CustomerCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor CustomerCursor
+	if err := json.Unmarshal(b, &cursor); err != nil || !customerSortColumns[cursor.SortBy] || cursor.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// sortValue() returns the value of cust's column which customers are sorted by
+func (cust Customer) sortValue(sortBy string) string {
+	switch sortBy {
+	case "name":
+		return cust.Name
+	case "city":
+		return cust.City
+	case "zipcode":
+		return cust.Zipcode
+	case "date_of_birth":
+		return cust.DateofBirth
+	default:
+		return cust.ID
+	}
+}
@@ -1,77 +1,226 @@
 package domain
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"strings"
 
 	"github.com/gtaylor314/Banking-Lib/errs"
-	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
 
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
+// customerColumns are the columns scanned into a Customer
+const customerColumns = "customer_id, name, city, zipcode, date_of_birth, status, status_reason, status_changed_at"
+
 // CustomerRepositoryDb represents an "adapter" which will "connect" to our "port" which is CustomerRepository
 type CustomerRepositoryDb struct {
 	db_conn *sqlx.DB
 }
 
 // FindAll() implementation for type CustomerRepositoryDb - returns a slice of Customer objects and an error
-func (c CustomerRepositoryDb) FindAll(status string) ([]Customer, *errs.AppError) {
+func (c CustomerRepositoryDb) FindAll(ctx context.Context, query CustomerQuery) ([]Customer, *errs.AppError) {
 	// create a slice of customers with size zero to begin with
 	customers := make([]Customer, 0)
-	// create empty query string
-	var findCustSql string
-	// create an error variable
-	var err error
-
-	// define string for MySQL query - this is used if the status query parameter was left blank (we retrieve all customers)
-	if status == "" {
-		findCustSql = "select customer_id, name, city, zipcode, date_of_birth, status from customers"
-		// Select() both queries the database using the MySQL query (findCustSql) and scans the results into the
-		// destination (customers)
-		err = c.db_conn.Select(&customers, findCustSql)
-	}
-	// if status is equal to 1 or to 0 (active or inactive), we change the MySQL query to include the status
-	if status == "1" || status == "0" {
-		findCustSql = "select customer_id, name, city, zipcode, date_of_birth, status from customers where status = ?"
-		// Select() both queries the database using the MySQL query (findCustSql with status) and it scans the results into the
-		// destination (customers)
-		err = c.db_conn.Select(&customers, findCustSql, status)
-	}
+	findCustSql, args := buildFindAllSql(query)
 
+	ctx, span := tracing.StartDB(ctx, "CustomerRepositoryDb.FindAll", findCustSql)
+	defer span.End()
+	// SelectContext() both queries the database using the MySQL query (findCustSql) and scans the results into the
+	// destination (customers) - the query is cancelled if ctx is cancelled or its deadline passes
+	err := c.db_conn.SelectContext(ctx, &customers, findCustSql, args...)
 	if err != nil {
+		tracing.RecordError(span, err)
 		// err.Error() takes the error and returns it as a string
-		logger.Error("error during query of customers table " + err.Error())
+		logging.Error(ctx, "error during query of customers table", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error")
 	}
 
 	return customers, nil
 }
 
+// buildFindAllSql() returns the MySQL query for a customer search and the values for its placeholders - every value sent
+// by the client is passed as a placeholder, only the sort column (checked against customerSortColumns) is placed in the
+// query itself
+func buildFindAllSql(query CustomerQuery) (string, []interface{}) {
+	var conditions []string
+	// args holds the values for the query's placeholders, in order
+	var args []interface{}
+	if query.Status != "" {
+		conditions = append(conditions, "status = ?")
+		args = append(args, query.Status)
+	}
+	if query.NamePrefix != "" {
+		// backslash is MySQL's default escape character for LIKE
+		conditions = append(conditions, "name like ?")
+		args = append(args, escapeLike(query.NamePrefix)+"%")
+	}
+	if query.City != "" {
+		conditions = append(conditions, "city = ?")
+		args = append(args, query.City)
+	}
+	if query.Zipcode != "" {
+		conditions = append(conditions, "zipcode = ?")
+		args = append(args, query.Zipcode)
+	}
+	if query.BornAfter != "" {
+		conditions = append(conditions, "date_of_birth >= ?")
+		args = append(args, query.BornAfter)
+	}
+	if query.BornBefore != "" {
+		conditions = append(conditions, "date_of_birth <= ?")
+		args = append(args, query.BornBefore)
+	}
+
+	sortBy := query.SortBy
+	if !customerSortColumns[sortBy] {
+		sortBy = "customer_id"
+	}
+	direction, comparison := "asc", ">"
+	if query.Descending {
+		direction, comparison = "desc", "<"
+	}
+	// customers after the cursor - customer_id breaks ties between customers sharing the sort value
+	if query.After != nil {
+		if sortBy == "customer_id" {
+			conditions = append(conditions, "customer_id "+comparison+" ?")
+			args = append(args, query.After.ID)
+		} else {
+			conditions = append(conditions, fmt.Sprintf("(%[1]s %[2]s ? or (%[1]s = ? and customer_id %[2]s ?))", sortBy, comparison))
+			args = append(args, query.After.Value, query.After.Value, query.After.ID)
+		}
+	}
+
+	findCustSql := "select " + customerColumns + " from customers"
+	if len(conditions) > 0 {
+		findCustSql += " where " + strings.Join(conditions, " and ")
+	}
+	findCustSql += " order by " + sortBy + " " + direction
+	if sortBy != "customer_id" {
+		findCustSql += ", customer_id " + direction
+	}
+	if query.Limit > 0 {
+		findCustSql += " limit ?"
+		args = append(args, query.Limit)
+	}
+	return findCustSql, args
+}
+
+// escapeLike() escapes the wildcards of a LIKE pattern so that a name prefix containing % or _ is matched literally
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 // FindById() implementation for type CustomerRepositoryDb - returns a pointer to a Customer object so we can
 // use nil if the id is not found
-func (c CustomerRepositoryDb) FindById(id string) (*Customer, *errs.AppError) {
+func (c CustomerRepositoryDb) FindById(ctx context.Context, id string) (*Customer, *errs.AppError) {
 	// define string for MySQL query
-	customerIdSql := "select customer_id, name, city, zipcode, date_of_birth, status from customers where customer_id = ?"
+	customerIdSql := "select " + customerColumns + " from customers where customer_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "CustomerRepositoryDb.FindById", customerIdSql)
+	defer span.End()
 
 	var cust Customer
-	// Get() both queries the database for the row using the MySQL query (customerIdSql) and scans the result into
+	// GetContext() both queries the database for the row using the MySQL query (customerIdSql) and scans the result into
 	// the destination
-	err := c.db_conn.Get(&cust, customerIdSql, id)
+	err := c.db_conn.GetContext(ctx, &cust, customerIdSql, id)
 	if err != nil {
 		// err may be due to a request for a customer that doesn't exist or an issue with the database/scan method
 		// if err == sql.ErrNoRows, then the customer doesn't exist
 		if err == sql.ErrNoRows {
-			return nil, errs.NotFoundErr("customer not found")
+			return nil, CustomerNotFoundErr
 		}
 		// otherwise, the error is internal - either with the database
-		logger.Error("error during query of customer table " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of customer table", zap.Error(err))
 		// custom error for a better user's experience
 		return nil, errs.UnexpectedErr("unexpected database error")
 	}
 	return &cust, nil
 }
 
+// UpdateStatus() implementation for type CustomerRepositoryDb - the customer's row is locked while the change is recorded in
+// customer_status_changes, the customer's status, reason and time are updated and the CustomerDeactivated or
+// CustomerReactivated event is written to the outbox, all within one sql transaction
+func (c CustomerRepositoryDb) UpdateStatus(ctx context.Context, change CustomerStatusChange) (*Customer, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "CustomerRepositoryDb.UpdateStatus")
+	defer span.End()
+
+	// BeginTxx() ties the sql transaction to ctx - if ctx is cancelled before Commit(), the sql transaction is rolled back
+	tx, err := c.db_conn.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating database transaction tx", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error creating database transaction tx")
+	}
+	// the deferred rollback becomes a no-op once the sql transaction is committed
+	defer tx.Rollback()
+
+	// "for update" locks the customer's row so that two concurrent changes cannot both see the old status
+	selectSql := "select " + customerColumns + " from customers where customer_id = ? for update"
+	selectCtx, selectSpan := tracing.StartDB(ctx, "select customer", selectSql)
+	var cust Customer
+	err = tx.GetContext(selectCtx, &cust, selectSql, change.CustomerID)
+	selectSpan.End()
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, CustomerNotFoundErr
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of customer table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	if cust.Status == change.Status {
+		return nil, statusUnchangedErr(change.Status)
+	}
+
+	insertSql := "INSERT INTO customer_status_changes (customer_id, status, reason, changed_by, changed_at) values (?, ?, ?, ?, ?)"
+	insertCtx, insertSpan := tracing.StartDB(ctx, "insert status change", insertSql)
+	_, err = tx.ExecContext(insertCtx, insertSql, change.CustomerID, change.Status, change.Reason, change.ChangedBy, change.ChangedAt)
+	insertSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while recording customer status change", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while changing the customer's status")
+	}
+
+	updateSql := "UPDATE customers SET status = ?, status_reason = ?, status_changed_at = ? WHERE customer_id = ?"
+	updateCtx, updateSpan := tracing.StartDB(ctx, "update status", updateSql)
+	_, err = tx.ExecContext(updateCtx, updateSql, change.Status, change.Reason, change.ChangedAt, change.CustomerID)
+	updateSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating customer status", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while changing the customer's status")
+	}
+
+	eventCtx, eventSpan := tracing.StartDB(ctx, "insert outbox event", "INSERT INTO outbox_events")
+	err = insertOutboxEvent(eventCtx, tx, NewCustomerStatusEvent(change))
+	eventSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while recording the customer status event", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while changing the customer's status")
+	}
+
+	_, commitSpan := tracing.StartDB(ctx, "commit", "COMMIT")
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while committing the customer status change", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while changing the customer's status")
+	}
+
+	cust.applyStatusChange(change)
+	return &cust, nil
+}
+
 // NewCustomerRepositoryDb() is a helper function that creates a connection to the database
 func NewCustomerRepositoryDb(db_conn *sqlx.DB) CustomerRepositoryDb {
 	return CustomerRepositoryDb{db_conn: db_conn}
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestBuildFindAllSql() should pass when every filter becomes a placeholder condition and the cursor continues after the
+// last customer of the previous page in the query's sort order
+func TestBuildFindAllSql(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	query := CustomerQuery{
+		Status:     "1",
+		NamePrefix: "50%_off",
+		City:       "Brooklyn",
+		BornAfter:  "1980-01-01",
+		SortBy:     "name",
+		Descending: true,
+		Limit:      11,
+		After:      &CustomerCursor{SortBy: "name", Descending: true, Value: "Jake", ID: "1"},
+	}
+
+	// Act - execute test
+	findCustSql, args := buildFindAllSql(query)
+
+	// Assert - test expectations
+	wantSql := "select customer_id, name, city, zipcode, date_of_birth, status, status_reason, status_changed_at from customers" +
+		" where status = ? and name like ? and city = ? and date_of_birth >= ?" +
+		" and (name < ? or (name = ? and customer_id < ?))" +
+		" order by name desc, customer_id desc limit ?"
+	wantArgs := []interface{}{"1", `50\%\_off%`, "Brooklyn", "1980-01-01", "Jake", "Jake", "1", 11}
+	if findCustSql != wantSql {
+		t.Errorf("received sql %q\nwant %q", findCustSql, wantSql)
+	}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("received args %v, want %v", args, wantArgs)
+	}
+}
+
+// TestBuildFindAllSqlRejectsSortColumn() should pass when a sort column outside the allowed list is never placed in the query
+func TestBuildFindAllSqlRejectsSortColumn(t *testing.T) {
+	// Arrange - setup test
+	query := CustomerQuery{SortBy: "name; drop table customers", Limit: 1}
+
+	// Act - execute test
+	findCustSql, _ := buildFindAllSql(query)
+
+	// Assert - test expectations
+	if findCustSql != "select customer_id, name, city, zipcode, date_of_birth, status, status_reason, status_changed_at from customers order by customer_id asc limit ?" {
+		t.Errorf("unexpected sql %q", findCustSql)
+	}
+}
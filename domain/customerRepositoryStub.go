@@ -1,26 +1,124 @@
 package domain
 
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
 // CustomerRepositoryStub represents an "adapter" that will "connect" to our "port" which is CustomerRepository
 type CustomerRepositoryStub struct {
+	// mu guards customers as handlers may change a customer's status concurrently
+	mu        sync.RWMutex
 	customers []Customer
 }
 
-// FindAll implementation for type CustomerRepositoryStub
-func (c CustomerRepositoryStub) FindAll() ([]Customer, error) {
-	// return the list of customers and a nil error
-	return c.customers, nil
+// FindAll implementation for type CustomerRepositoryStub - the query is applied in memory with the same semantics as
+// CustomerRepositoryDb (case-insensitive matching, keyset pagination)
+func (c *CustomerRepositoryStub) FindAll(ctx context.Context, query CustomerQuery) ([]Customer, *errs.AppError) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	sortBy := query.SortBy
+	if !customerSortColumns[sortBy] {
+		sortBy = "customer_id"
+	}
+	// less() orders two customers by the sort column and then by customer_id, honouring the direction
+	less := func(a Customer, b Customer) bool {
+		if cmp := compareColumn(sortBy, a.sortValue(sortBy), b.sortValue(sortBy)); cmp != 0 {
+			return (cmp < 0) != query.Descending
+		}
+		cmp := compareColumn("customer_id", a.ID, b.ID)
+		return cmp != 0 && (cmp < 0) != query.Descending
+	}
+
+	customers := make([]Customer, 0)
+	for _, customer := range c.customers {
+		if query.matches(customer) {
+			customers = append(customers, customer)
+		}
+	}
+	sort.Slice(customers, func(i, j int) bool { return less(customers[i], customers[j]) })
+
+	if query.After != nil {
+		after := Customer{ID: query.After.ID, Name: query.After.Value, City: query.After.Value, Zipcode: query.After.Value, DateofBirth: query.After.Value}
+		// skip every customer up to and including the cursor's position
+		i := sort.Search(len(customers), func(i int) bool { return less(after, customers[i]) })
+		customers = customers[i:]
+	}
+	if query.Limit > 0 && len(customers) > query.Limit {
+		customers = customers[:query.Limit]
+	}
+	return customers, nil
+}
+
+// matches() reports whether cust passes every filter of the query
+func (query CustomerQuery) matches(cust Customer) bool {
+	return (query.Status == "" || cust.Status == query.Status) &&
+		(query.NamePrefix == "" || strings.HasPrefix(strings.ToLower(cust.Name), strings.ToLower(query.NamePrefix))) &&
+		(query.City == "" || strings.EqualFold(cust.City, query.City)) &&
+		(query.Zipcode == "" || cust.Zipcode == query.Zipcode) &&
+		(query.BornAfter == "" || cust.DateofBirth >= query.BornAfter) &&
+		(query.BornBefore == "" || cust.DateofBirth <= query.BornBefore)
+}
+
+// compareColumn() compares two values of a column as MySQL would - customer_id numerically and text case-insensitively
+// (dates are YYYY-MM-DD so they compare as text)
+func compareColumn(column string, a string, b string) int {
+	if column == "customer_id" {
+		x, _ := strconv.Atoi(a)
+		y, _ := strconv.Atoi(b)
+		return x - y
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// FindById implementation for type CustomerRepositoryStub
+func (c *CustomerRepositoryStub) FindById(ctx context.Context, id string) (*Customer, *errs.AppError) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	for _, customer := range c.customers {
+		if customer.ID == id {
+			return &customer, nil
+		}
+	}
+	return nil, CustomerNotFoundErr
+}
+
+// UpdateStatus implementation for type CustomerRepositoryStub - the status history is not kept in memory
+func (c *CustomerRepositoryStub) UpdateStatus(ctx context.Context, change CustomerStatusChange) (*Customer, *errs.AppError) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i := range c.customers {
+		if c.customers[i].ID != change.CustomerID {
+			continue
+		}
+		if c.customers[i].Status == change.Status {
+			return nil, statusUnchangedErr(change.Status)
+		}
+		c.customers[i].applyStatusChange(change)
+		customer := c.customers[i]
+		return &customer, nil
+	}
+	return nil, CustomerNotFoundErr
 }
 
 // NewCustomerRepositoryStub is a helper function which creates a new customer repository stub
-func NewCustomerRepositoryStub() CustomerRepositoryStub {
-	// creating dummy customers
+func NewCustomerRepositoryStub() *CustomerRepositoryStub {
+	// creating dummy customers - dates of birth are YYYY-MM-DD as they are returned by the database
 	customers := []Customer{
 		{
 			ID:          "1001",
 			Name:        "Garrett",
 			City:        "Bradenton",
 			Zipcode:     "34205",
-			DateofBirth: "10-01-1989",
+			DateofBirth: "1989-10-01",
 			Status:      "1", // one for active and zero for inactive
 		},
 		{
@@ -28,7 +126,7 @@ func NewCustomerRepositoryStub() CustomerRepositoryStub {
 			Name:        "Kimberly",
 			City:        "Seattle",
 			Zipcode:     "98101",
-			DateofBirth: "06-01-1992",
+			DateofBirth: "1992-06-01",
 			Status:      "1",
 		},
 		{
@@ -36,10 +134,10 @@ func NewCustomerRepositoryStub() CustomerRepositoryStub {
 			Name:        "Noah",
 			City:        "Orlando",
 			Zipcode:     "32789",
-			DateofBirth: "03-01-2021",
+			DateofBirth: "2021-03-01",
 			Status:      "1",
 		},
 	}
 
-	return CustomerRepositoryStub{customers: customers}
+	return &CustomerRepositoryStub{customers: customers}
 }
@@ -0,0 +1,102 @@
+//go:build cgo
+
+package domain
+
+import (
+	"os"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// openingDepositSchema is the part of the accounts and transactions tables the opening deposit backfill reads and writes -
+// in SQLite, which runs the backfill's MySQL unchanged
+const openingDepositSchema = `
+CREATE TABLE accounts (account_id INTEGER PRIMARY KEY, opening_date TEXT NOT NULL, amount DECIMAL(20,2) NOT NULL);
+CREATE TABLE transactions (
+  transaction_id INTEGER PRIMARY KEY AUTOINCREMENT,
+  account_id INTEGER NOT NULL,
+  amount DECIMAL(20,2) NOT NULL,
+  transaction_type TEXT NOT NULL,
+  transaction_date TEXT NOT NULL
+);
+INSERT INTO accounts VALUES (101, '2010-09-15 10:20:00', 1000), (102, '2000-01-13 08:15:05', 15000), (107, '2026-10-19 09:00:00', 5000);
+INSERT INTO transactions (account_id, amount, transaction_type, transaction_date) VALUES
+  (102, 500, 'deposit', '2001-01-01 00:00:00'),
+  (102, 200, 'withdrawal', '2002-01-01 00:00:00'),
+  (107, 5000, 'opening_deposit', '2026-10-19 09:00:00');
+`
+
+// TestOpeningDepositBackfill() should pass when migration 000005 gives every account without an opening deposit one dated
+// with the account, for the balance left once its other transactions are taken out, so that each account's transactions
+// replay to its balance - and the down migration removes the opening deposits again
+func TestOpeningDepositBackfill(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	db, err := sqlx.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// every connection to :memory: has a database of its own
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(openingDepositSchema); err != nil {
+		t.Fatal(err)
+	}
+	up, err := os.ReadFile("../db/migration/000005_opening_deposits.up.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	down, err := os.ReadFile("../db/migration/000005_opening_deposits.down.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act - execute test
+	_, upErr := db.Exec(string(up))
+	var deposits []struct {
+		AccountID string  `db:"account_id"`
+		Amount    float64 `db:"amount"`
+		Date      string  `db:"transaction_date"`
+	}
+	selectErr := db.Select(&deposits, "SELECT account_id, amount, transaction_date FROM transactions WHERE transaction_type = 'opening_deposit' ORDER BY account_id")
+	var unbalanced int
+	replayErr := db.Get(&unbalanced, `SELECT COUNT(*) FROM accounts a WHERE a.amount != (SELECT COALESCE(SUM(CASE t.transaction_type
+		WHEN 'withdrawal' THEN -t.amount ELSE t.amount END), 0) FROM transactions t WHERE t.account_id = a.account_id)`)
+	_, downErr := db.Exec(string(down))
+	var remaining int
+	remainingErr := db.Get(&remaining, "SELECT COUNT(*) FROM transactions WHERE transaction_type = 'opening_deposit'")
+
+	// Assert - test expectations
+	for _, err := range []error{upErr, selectErr, replayErr, downErr, remainingErr} {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	want := []struct {
+		accountID string
+		amount    float64
+		date      string
+	}{
+		{"101", 1000, "2010-09-15 10:20:00"},
+		// 15000 less the deposit of 500 plus the withdrawal of 200
+		{"102", 14700, "2000-01-13 08:15:05"},
+		// the opening deposit recorded by the application is kept as it is
+		{"107", 5000, "2026-10-19 09:00:00"},
+	}
+	if len(deposits) != len(want) {
+		t.Fatalf("received %d opening deposits, want %d", len(deposits), len(want))
+	}
+	for i, deposit := range deposits {
+		if deposit.AccountID != want[i].accountID || deposit.Amount != want[i].amount || deposit.Date != want[i].date {
+			t.Errorf("received opening deposit %+v, want %+v", deposit, want[i])
+		}
+	}
+	if unbalanced != 0 {
+		t.Errorf("%d accounts do not replay to their balance", unbalanced)
+	}
+	if remaining != 0 {
+		t.Errorf("%d opening deposits remain after the down migration", remaining)
+	}
+}
@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// the types of the domain events written to the outbox - downstream systems (e.g. notifications, analytics) subscribe to
+// them through the outbox relay
+const (
+	EventAccountOpened       = "AccountOpened"
+	EventTransactionPosted   = "TransactionPosted"
+	EventCustomerDeactivated = "CustomerDeactivated"
+	EventCustomerReactivated = "CustomerReactivated"
+)
+
+// OutboxEvent represents a row of the outbox_events table - the event is inserted in the same sql transaction as the
+// change it describes, so that an event is recorded if and only if the change is committed, and is published later by
+// the outbox relay - Payload holds the event's JSON (e.g. an AccountOpenedPayload)
+// EventID is unique to the event and is sent with every delivery of it, so that a consumer can ignore an event delivered
+// more than once
+type OutboxEvent struct {
+	ID            int64          `db:"id"`
+	EventID       string         `db:"event_id"`
+	EventType     string         `db:"event_type"`
+	AggregateID   string         `db:"aggregate_id"`
+	Payload       string         `db:"payload"`
+	OccurredAt    string         `db:"occurred_at"`
+	Attempts      int            `db:"attempts"`
+	NextAttemptAt string         `db:"next_attempt_at"`
+	PublishedAt   sql.NullString `db:"published_at"`
+	LastError     sql.NullString `db:"last_error"`
+	// ClaimedBy is the claim token of the relay holding the event's lease - the outcome of publishing the event is only
+	// recorded while that relay still holds it
+	ClaimedBy sql.NullString `db:"claimed_by"`
+}
+
+// AccountOpenedPayload is the payload of an AccountOpened event
+type AccountOpenedPayload struct {
+	AccountID      string  `json:"account_id"`
+	CustomerID     string  `json:"customer_id"`
+	AccountType    string  `json:"account_type"`
+	OpeningDeposit float64 `json:"opening_deposit"`
+	OpenedAt       string  `json:"opened_at"`
+}
+
+// TransactionPostedPayload is the payload of a TransactionPosted event - Balance is the account's balance once the
+// transaction was posted
+type TransactionPostedPayload struct {
+	TransactionID   string  `json:"transaction_id"`
+	AccountID       string  `json:"account_id"`
+	CustomerID      string  `json:"customer_id"`
+	TransactionType string  `json:"transaction_type"`
+	Amount          float64 `json:"amount"`
+	Balance         float64 `json:"balance"`
+	PostedAt        string  `json:"posted_at"`
+}
+
+// CustomerStatusPayload is the payload of the CustomerDeactivated and CustomerReactivated events
+type CustomerStatusPayload struct {
+	CustomerID string `json:"customer_id"`
+	Reason     string `json:"reason"`
+	ChangedBy  string `json:"changed_by,omitempty"`
+	ChangedAt  string `json:"changed_at"`
+}
+
+// OutboxRepository is a "port" implemented by the server side - OutboxRepositoryDb is its adapter - it is used by the
+// outbox relay, while the events themselves are inserted by the repositories making the changes
+type OutboxRepository interface {
+	// Claim leases up to limit unpublished events due at now, oldest first, until leaseUntil - a claimed event is not
+	// handed to another relay until it is published, fails or its lease ends
+	Claim(ctx context.Context, now string, leaseUntil string, limit int) ([]OutboxEvent, *errs.AppError)
+	// MarkPublished records that the event was published - nothing is recorded (a 409) when the event is no longer
+	// claimed by claimedBy, as its lease ended and another relay claimed it
+	MarkPublished(ctx context.Context, id int64, claimedBy string, publishedAt string) *errs.AppError
+	// MarkFailed records a failed attempt to publish the event, which is retried at nextAttemptAt - as with
+	// MarkPublished, only while the event is claimed by claimedBy
+	MarkFailed(ctx context.Context, id int64, claimedBy string, nextAttemptAt string, lastError string) *errs.AppError
+	// PurgePublished removes the events published before the given time and returns how many were removed
+	PurgePublished(ctx context.Context, before string) (int64, *errs.AppError)
+}
+
+// NewAccountOpenedEvent() returns the event recording that acct was opened
+func NewAccountOpenedEvent(acct Account) OutboxEvent {
+	return newOutboxEvent(EventAccountOpened, acct.AccountID, acct.OpeningDate, AccountOpenedPayload{
+		AccountID:      acct.AccountID,
+		CustomerID:     acct.CustomerID,
+		AccountType:    acct.AccountType,
+		OpeningDeposit: acct.Amount,
+		OpenedAt:       acct.OpeningDate,
+	})
+}
+
+// NewTransactionPostedEvent() returns the event recording that tran was posted - the event belongs to the account
+func NewTransactionPostedEvent(tran Transaction) OutboxEvent {
+	return newOutboxEvent(EventTransactionPosted, tran.AccountID, tran.TransactionDate, TransactionPostedPayload{
+		TransactionID:   tran.TransactionID,
+		AccountID:       tran.AccountID,
+		CustomerID:      tran.CustomerID,
+		TransactionType: tran.TransactionType,
+		Amount:          tran.Amount,
+		Balance:         tran.AcctAmount,
+		PostedAt:        tran.TransactionDate,
+	})
+}
+
+// NewCustomerStatusEvent() returns the CustomerDeactivated or CustomerReactivated event recording change
+func NewCustomerStatusEvent(change CustomerStatusChange) OutboxEvent {
+	eventType := EventCustomerReactivated
+	if change.Status == CustomerInactive {
+		eventType = EventCustomerDeactivated
+	}
+	return newOutboxEvent(eventType, change.CustomerID, change.ChangedAt, CustomerStatusPayload{
+		CustomerID: change.CustomerID,
+		Reason:     change.Reason,
+		ChangedBy:  change.ChangedBy,
+		ChangedAt:  change.ChangedAt,
+	})
+}
+
+// newOutboxEvent() returns an event due to be published at once
+func newOutboxEvent(eventType string, aggregateID string, occurredAt string, payload interface{}) OutboxEvent {
+	// Marshal() cannot fail for the payload structs above
+	b, _ := json.Marshal(payload)
+	return OutboxEvent{
+		EventID:       newEventID(),
+		EventType:     eventType,
+		AggregateID:   aggregateID,
+		Payload:       string(b),
+		OccurredAt:    occurredAt,
+		NextAttemptAt: occurredAt,
+	}
+}
+
+// newEventID() returns a random 128-bit event ID encoded as 32 hex characters
+func newEventID() string {
+	b := make([]byte, 16)
+	// Read() only fails if the operating system's random source is unavailable
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// execer is implemented by *sql.Tx and *sqlx.Tx - the events are inserted by the sql transaction of the change they record
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// insertOutboxEvent() inserts event into the outbox through tx
+func insertOutboxEvent(ctx context.Context, tx execer, event OutboxEvent) error {
+	insertSql := "INSERT INTO outbox_events (event_id, event_type, aggregate_id, payload, occurred_at, next_attempt_at) values (?, ?, ?, ?, ?, ?)"
+	_, err := tx.ExecContext(ctx, insertSql, event.EventID, event.EventType, event.AggregateID, event.Payload, event.OccurredAt, event.NextAttemptAt)
+	return err
+}
@@ -0,0 +1,112 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// outboxColumns are the columns of the outbox_events table read into an OutboxEvent
+const outboxColumns = "id, event_id, event_type, aggregate_id, payload, occurred_at, attempts, next_attempt_at, published_at, last_error, claimed_by"
+
+// OutboxRepositoryDb is an adapter that implements the OutboxRepository (port) interface
+type OutboxRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// Claim() leases the due events to a claim token of its own with a single update, so that relays running side by side
+// never claim the same event, and then reads back the events holding the token
+func (o OutboxRepositoryDb) Claim(ctx context.Context, now string, leaseUntil string, limit int) ([]OutboxEvent, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "OutboxRepositoryDb.Claim")
+	defer span.End()
+
+	claim := newEventID()
+	claimSql := "UPDATE outbox_events SET claimed_by = ?, claimed_until = ? WHERE published_at IS NULL AND next_attempt_at <= ?" +
+		" AND (claimed_until IS NULL OR claimed_until < ?) ORDER BY id LIMIT ?"
+	claimCtx, claimSpan := tracing.StartDB(ctx, "claim outbox events", claimSql)
+	_, err := o.db_conn.ExecContext(claimCtx, claimSql, claim, leaseUntil, now, now, limit)
+	claimSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while claiming outbox events", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+
+	selectSql := "SELECT " + outboxColumns + " FROM outbox_events WHERE claimed_by = ? ORDER BY id"
+	selectCtx, selectSpan := tracing.StartDB(ctx, "select claimed outbox events", selectSql)
+	events := make([]OutboxEvent, 0)
+	err = o.db_conn.SelectContext(selectCtx, &events, selectSql, claim)
+	selectSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of outbox_events table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return events, nil
+}
+
+// MarkPublished() records that the event was published and releases its claim
+func (o OutboxRepositoryDb) MarkPublished(ctx context.Context, id int64, claimedBy string, publishedAt string) *errs.AppError {
+	updateSql := "UPDATE outbox_events SET published_at = ?, last_error = NULL, claimed_by = NULL, claimed_until = NULL WHERE id = ? AND claimed_by = ?"
+	return o.exec(ctx, "OutboxRepositoryDb.MarkPublished", id, updateSql, publishedAt, id, claimedBy)
+}
+
+// MarkFailed() counts the failed attempt, schedules the next one and releases the event's claim
+func (o OutboxRepositoryDb) MarkFailed(ctx context.Context, id int64, claimedBy string, nextAttemptAt string, lastError string) *errs.AppError {
+	updateSql := "UPDATE outbox_events SET attempts = attempts + 1, next_attempt_at = ?, last_error = ?, claimed_by = NULL, claimed_until = NULL" +
+		" WHERE id = ? AND claimed_by = ?"
+	return o.exec(ctx, "OutboxRepositoryDb.MarkFailed", id, updateSql, nextAttemptAt, lastError, id, claimedBy)
+}
+
+// PurgePublished() removes the events published before the given time
+func (o OutboxRepositoryDb) PurgePublished(ctx context.Context, before string) (int64, *errs.AppError) {
+	deleteSql := "DELETE FROM outbox_events WHERE published_at IS NOT NULL AND published_at < ?"
+
+	ctx, span := tracing.StartDB(ctx, "OutboxRepositoryDb.PurgePublished", deleteSql)
+	defer span.End()
+
+	result, err := o.db_conn.ExecContext(ctx, deleteSql, before)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while purging published outbox events", zap.Error(err))
+		return 0, errs.UnexpectedErr("unexpected database error")
+	}
+	// RowsAffected() is supported by the MySQL driver
+	purged, _ := result.RowsAffected()
+	return purged, nil
+}
+
+// exec() runs an update of the outbox event with the given id, which only matches while the event holds the relay's
+// claim - a relay whose lease ended cannot overwrite the outcome recorded by the relay which claimed the event next
+func (o OutboxRepositoryDb) exec(ctx context.Context, spanName string, id int64, updateSql string, args ...interface{}) *errs.AppError {
+	ctx, span := tracing.StartDB(ctx, spanName, updateSql)
+	defer span.End()
+
+	result, err := o.db_conn.ExecContext(ctx, updateSql, args...)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating outbox event", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return outboxLeaseLostErr(id)
+	}
+	return nil
+}
+
+// outboxLeaseLostErr() returns the 409 *errs.AppError for recording the outcome of an event claimed by another relay
+func outboxLeaseLostErr(id int64) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("outbox event %d is claimed by another relay", id)}
+}
+
+// NewOutboxRepositoryDb() takes a db connection and returns an OutboxRepositoryDb
+func NewOutboxRepositoryDb(db_conn *sqlx.DB) OutboxRepositoryDb {
+	return OutboxRepositoryDb{db_conn: db_conn}
+}
@@ -0,0 +1,120 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// OutboxRepositoryStub is an in-memory "adapter" for the OutboxRepository "port" - it starts empty and events are added
+// with Add(), as the in-memory account and transaction stubs do not write events
+type OutboxRepositoryStub struct {
+	// mu guards events as a relay may run alongside the code adding events
+	mu     sync.Mutex
+	events []OutboxEvent
+	// claimedUntil holds the lease of each claimed event by id
+	claimedUntil map[int64]string
+	lastID       int64
+}
+
+// Add() appends event to the outbox as the repositories making changes do, and returns it with its id
+func (o *OutboxRepositoryStub) Add(event OutboxEvent) OutboxEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.lastID++
+	event.ID = o.lastID
+	o.events = append(o.events, event)
+	return event
+}
+
+// Events() returns a copy of every event in the outbox, published or not
+func (o *OutboxRepositoryStub) Events() []OutboxEvent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	return append([]OutboxEvent(nil), o.events...)
+}
+
+// Claim implementation for type OutboxRepositoryStub
+func (o *OutboxRepositoryStub) Claim(ctx context.Context, now string, leaseUntil string, limit int) ([]OutboxEvent, *errs.AppError) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	claim := sql.NullString{String: newEventID(), Valid: true}
+	claimed := make([]OutboxEvent, 0)
+	for i, event := range o.events {
+		if len(claimed) == limit {
+			break
+		}
+		lease, isClaimed := o.claimedUntil[event.ID]
+		if event.PublishedAt.Valid || event.NextAttemptAt > now || (isClaimed && lease >= now) {
+			continue
+		}
+		o.claimedUntil[event.ID] = leaseUntil
+		o.events[i].ClaimedBy = claim
+		event.ClaimedBy = claim
+		claimed = append(claimed, event)
+	}
+	return claimed, nil
+}
+
+// MarkPublished implementation for type OutboxRepositoryStub
+func (o *OutboxRepositoryStub) MarkPublished(ctx context.Context, id int64, claimedBy string, publishedAt string) *errs.AppError {
+	return o.update(id, claimedBy, func(event *OutboxEvent) {
+		event.PublishedAt = sql.NullString{String: publishedAt, Valid: true}
+		event.LastError = sql.NullString{}
+	})
+}
+
+// MarkFailed implementation for type OutboxRepositoryStub
+func (o *OutboxRepositoryStub) MarkFailed(ctx context.Context, id int64, claimedBy string, nextAttemptAt string, lastError string) *errs.AppError {
+	return o.update(id, claimedBy, func(event *OutboxEvent) {
+		event.Attempts++
+		event.NextAttemptAt = nextAttemptAt
+		event.LastError = sql.NullString{String: lastError, Valid: true}
+	})
+}
+
+// PurgePublished implementation for type OutboxRepositoryStub
+func (o *OutboxRepositoryStub) PurgePublished(ctx context.Context, before string) (int64, *errs.AppError) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	kept := o.events[:0]
+	for _, event := range o.events {
+		if !event.PublishedAt.Valid || event.PublishedAt.String >= before {
+			kept = append(kept, event)
+		}
+	}
+	purged := int64(len(o.events) - len(kept))
+	o.events = kept
+	return purged, nil
+}
+
+// update() applies change to the event with the given id and releases its claim - as with the database, nothing is
+// changed unless the event is claimed by claimedBy
+func (o *OutboxRepositoryStub) update(id int64, claimedBy string, change func(*OutboxEvent)) *errs.AppError {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	for i := range o.events {
+		if o.events[i].ID == id {
+			if !o.events[i].ClaimedBy.Valid || o.events[i].ClaimedBy.String != claimedBy {
+				return outboxLeaseLostErr(id)
+			}
+			change(&o.events[i])
+			o.events[i].ClaimedBy = sql.NullString{}
+			delete(o.claimedUntil, id)
+			return nil
+		}
+	}
+	return errs.NotFoundErr("outbox event not found")
+}
+
+// NewOutboxRepositoryStub is a helper function which creates a new, empty outbox repository stub
+func NewOutboxRepositoryStub() *OutboxRepositoryStub {
+	return &OutboxRepositoryStub{claimedUntil: make(map[int64]string)}
+}
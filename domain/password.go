@@ -0,0 +1,64 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MaxPasswordBytes is the longest password accepted - bcrypt ignores every byte past the 72nd
+const MaxPasswordBytes = 72
+
+// HashPassword() returns the bcrypt hash of password at cost - the hash carries its own salt and cost
+func HashPassword(password string, cost int) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+// IsPasswordHash() reports whether stored is a bcrypt hash rather than a plaintext password left from before passwords
+// were hashed
+func IsPasswordHash(stored string) bool {
+	_, err := bcrypt.Cost([]byte(stored))
+	return err == nil
+}
+
+// comparePassword() reports whether password matches the bcrypt hash - bcrypt compares the derived keys in constant
+// time, and a stored value which is not a hash never matches, so a plaintext row left unmigrated cannot be logged into
+func comparePassword(hash string, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// PasswordPolicy holds the rules a new password must meet - MinLength counts characters, not bytes
+type PasswordPolicy struct {
+	MinLength int
+}
+
+// Check() returns a 422 *errs.AppError listing every rule password breaks - the username may not appear in the password
+func (policy PasswordPolicy) Check(username string, password string) *errs.AppError {
+	var problems []string
+	if len([]rune(password)) < policy.MinLength {
+		problems = append(problems, fmt.Sprintf("must be at least %d characters", policy.MinLength))
+	}
+	if len(password) > MaxPasswordBytes {
+		problems = append(problems, fmt.Sprintf("must be at most %d bytes", MaxPasswordBytes))
+	}
+	if strings.IndexFunc(password, unicode.IsLetter) < 0 {
+		problems = append(problems, "must contain a letter")
+	}
+	if strings.IndexFunc(password, unicode.IsDigit) < 0 {
+		problems = append(problems, "must contain a digit")
+	}
+	if username != "" && strings.Contains(strings.ToLower(password), strings.ToLower(username)) {
+		problems = append(problems, "must not contain the username")
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errs.ValidationErr("password " + strings.Join(problems, ", "))
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPasswordPolicyCheck() should pass when every broken rule is reported in a single 422 error and a password meeting
+// the policy is accepted
+func TestPasswordPolicyCheck(t *testing.T) {
+	// Arrange - setup test
+	policy := PasswordPolicy{MinLength: 12}
+	tests := []struct {
+		name      string
+		password  string
+		wantRules []string
+	}{
+		{"valid", "correct horse 42", nil},
+		{"too short without a digit", "horse", []string{"at least 12 characters", "a digit"}},
+		{"no letter", "123456789012", []string{"a letter"}},
+		{"username", "Alice says 42 hi", []string{"the username"}},
+		{"too long", strings.Repeat("a1", 37), []string{"at most 72 bytes"}},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		appErr := policy.Check("alice", test.password)
+
+		// Assert - test expectations
+		if test.wantRules == nil && appErr != nil {
+			t.Errorf("%s: unexpected error %q", test.name, appErr.Message)
+		}
+		for _, rule := range test.wantRules {
+			if appErr == nil || appErr.Code != 422 || !strings.Contains(appErr.Message, rule) {
+				t.Errorf("%s: received %v, want a 422 mentioning %q", test.name, appErr, rule)
+			}
+		}
+	}
+}
+
+// TestPasswordHashing() should pass when a hashed password matches only itself and a plaintext password is neither
+// recognised as a hash nor matched
+func TestPasswordHashing(t *testing.T) {
+	// Arrange - setup test
+	hash, err := HashPassword("correct horse 42", 10)
+
+	// Act - execute test
+	user := User{Password: hash}
+	plaintext := User{Password: "correct horse 42"}
+
+	// Assert - test expectations
+	if err != nil || !IsPasswordHash(hash) || IsPasswordHash(plaintext.Password) {
+		t.Fatalf("received %q, %v - want a bcrypt hash", hash, err)
+	}
+	if !user.PasswordMatches("correct horse 42") || user.PasswordMatches("correct horse 43") {
+		t.Error("expected the hash to match only its own password")
+	}
+	if plaintext.PasswordMatches("correct horse 42") {
+		t.Error("expected an unhashed password never to match")
+	}
+}
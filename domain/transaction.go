@@ -1,13 +1,26 @@
 package domain
 
 import (
+	"context"
+
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/dto"
 )
 
+// MsgAccountNotFound is the message of AccountNotFoundErr
+const MsgAccountNotFound = "account id provided was not found in database"
+
+// AccountNotFoundErr is returned when an account_id does not exist - the app maps it to the ACCOUNT_NOT_FOUND error code
+var AccountNotFoundErr = errs.NotFoundErr(MsgAccountNotFound)
+
+// TransactionTypeOpeningDeposit is the type of the transaction recorded when an account is opened with its first deposit -
+// clients can only post deposits and withdrawals, so it is never confused with a later transaction
+const TransactionTypeOpeningDeposit = "opening_deposit"
+
 type Transaction struct {
 	TransactionID   string
 	AccountID       string
+	CustomerID      string // the customer holding the account, carried by the TransactionPosted event
 	Amount          float64
 	TransactionType string
 	TransactionDate string
@@ -16,8 +29,9 @@ type Transaction struct {
 
 // TransactionRepository is a "port" - implemented by the server side "adapter" TransactionRepositoryDb
 type TransactionRepository interface {
-	SaveTrans(Transaction) (*Transaction, *errs.AppError)
-	GetAcctAmount(string) (*float64, *errs.AppError)
+	SaveTrans(context.Context, Transaction) (*Transaction, *errs.AppError)
+	// GetAccount returns the account a transaction is posted to - its balance (Amount) and type (AccountType)
+	GetAccount(context.Context, string) (*Account, *errs.AppError)
 }
 
 // ToNewTransactionResponseDto() takes a transaction object and returns a NewTransactionResponse object
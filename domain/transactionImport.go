@@ -0,0 +1,87 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// MsgTransactionImportNotFound is returned when no import matches a checksum
+const MsgTransactionImportNotFound = "transaction import not found"
+
+// statuses of a transaction import - an import is interrupted when it was cancelled before its last row, leaving the
+// rows from NextRow on unposted - importing the same file again resumes it from NextRow, as it does an import which is
+// still processing but whose progress has not been updated for a while, i.e. whose process crashed
+const (
+	TransactionImportProcessing  = "processing"
+	TransactionImportCompleted   = "completed"
+	TransactionImportInterrupted = "interrupted"
+)
+
+// TransactionImport records a file of transactions posted by the bulk import - dry runs post nothing and are not recorded
+type TransactionImport struct {
+	ImportID    string         `db:"import_id"`
+	Checksum    string         `db:"checksum"` // the hex SHA-256 of the file
+	FileName    string         `db:"file_name"`
+	RowCount    int            `db:"row_count"`
+	NextRow     int            `db:"next_row"` // the index of the first row not yet attempted - recorded after every row
+	PostedCount int            `db:"posted_count"`
+	FailedCount int            `db:"failed_count"`
+	Status      string         `db:"status"`
+	Attempt     int            `db:"attempt"` // raised by Resume(), so that the process resumed from stops recording progress
+	CreatedBy   string         `db:"created_by"`
+	CreatedAt   string         `db:"created_at"`
+	UpdatedAt   sql.NullString `db:"updated_at"`
+	CompletedAt sql.NullString `db:"completed_at"`
+}
+
+// TransactionImportRepository is a "port" - implemented by the server side "adapter" TransactionImportRepositoryDb
+type TransactionImportRepository interface {
+	// FindByChecksum returns the import of the file with the given checksum - a 404 when the file was never imported
+	FindByChecksum(ctx context.Context, checksum string) (*TransactionImport, *errs.AppError)
+	// Start records an import before its first row is posted - a 409 when a file with the same checksum was imported
+	Start(ctx context.Context, imp TransactionImport) (*TransactionImport, *errs.AppError)
+	// Resume sets an import which is interrupted, or still processing but not updated since staleBefore, back to
+	// processing under the next attempt and returns it - a 409 when another process resumed it first or it is running
+	Resume(ctx context.Context, importID string, staleBefore string, updatedAt string) (*TransactionImport, *errs.AppError)
+	// UpdateProgress sets the checkpoint, counts, status, updated_at and completed_at of imp - completedAt is empty while
+	// the import is processing - a 409 when imp.Attempt is no longer the import's attempt, as it was taken over
+	UpdateProgress(ctx context.Context, imp TransactionImport) *errs.AppError
+}
+
+// DuplicateErr() returns the error a file matching the import is refused with
+func (imp TransactionImport) DuplicateErr() *errs.AppError {
+	return &errs.AppError{
+		Code:    http.StatusConflict,
+		Message: fmt.Sprintf("file was already imported as import %s on %s, which is %s (checksum %s)", imp.ImportID, imp.CreatedAt, imp.Status, imp.Checksum),
+	}
+}
+
+// Resumable() reports whether the import may be resumed - when it is interrupted, or still processing but not updated
+// since staleBefore (a "2006-01-02 15:04:05" time, which sorts as it compares)
+func (imp TransactionImport) Resumable(staleBefore string) bool {
+	if imp.Status == TransactionImportInterrupted {
+		return true
+	}
+	return imp.Status == TransactionImportProcessing && imp.UpdatedAt.Valid && imp.UpdatedAt.String < staleBefore
+}
+
+// transactionImportNotResumableErr() is returned when an import is resumed which is neither interrupted nor stale, e.g.
+// as another resume of it started first
+func transactionImportNotResumableErr(importID string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("transaction import %s is running and cannot be resumed", importID)}
+}
+
+// transactionImportTakenOverErr() is returned when the progress of an import is recorded by a process it was taken
+// over from
+func transactionImportTakenOverErr(importID string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("transaction import %s was taken over by another process", importID)}
+}
+
+// transactionImportExistsErr() is returned when an import is started for a file which another import started first
+func transactionImportExistsErr(checksum string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("file was already imported (checksum %s)", checksum)}
+}
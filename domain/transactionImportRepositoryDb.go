@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// transactionImportColumns are the columns scanned into a TransactionImport
+const transactionImportColumns = "import_id, checksum, file_name, row_count, next_row, posted_count, failed_count, status, attempt, " +
+	"created_by, created_at, updated_at, completed_at"
+
+// TransactionImportRepositoryDb is an adapter that implements the TransactionImportRepository (port) interface
+type TransactionImportRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// FindByChecksum() returns the import of the file with the given checksum
+func (t TransactionImportRepositoryDb) FindByChecksum(ctx context.Context, checksum string) (*TransactionImport, *errs.AppError) {
+	findSql := "select " + transactionImportColumns + " from transaction_imports where checksum = ?"
+
+	ctx, span := tracing.StartDB(ctx, "TransactionImportRepositoryDb.FindByChecksum", findSql)
+	defer span.End()
+
+	var imp TransactionImport
+	if err := t.db_conn.GetContext(ctx, &imp, findSql, checksum); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFoundErr(MsgTransactionImportNotFound)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of transaction_imports table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &imp, nil
+}
+
+// Start() inserts the import and sets the import_id auto-incremented on insert - the unique key on checksum refuses a
+// file which was imported before, even when two imports of it are started at once
+func (t TransactionImportRepositoryDb) Start(ctx context.Context, imp TransactionImport) (*TransactionImport, *errs.AppError) {
+	insertSql := "insert into transaction_imports (checksum, file_name, row_count, status, attempt, created_by, created_at, updated_at) values (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	ctx, span := tracing.StartDB(ctx, "TransactionImportRepositoryDb.Start", insertSql)
+	defer span.End()
+
+	imp.Status, imp.Attempt = TransactionImportProcessing, 1
+	imp.UpdatedAt = sql.NullString{String: imp.CreatedAt, Valid: true}
+	result, err := t.db_conn.ExecContext(ctx, insertSql, imp.Checksum, imp.FileName, imp.RowCount, imp.Status, imp.Attempt,
+		imp.CreatedBy, imp.CreatedAt, imp.UpdatedAt)
+	if err != nil {
+		if isMySQLError(err, mysqlDuplicateEntry) {
+			return nil, transactionImportExistsErr(imp.Checksum)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating transaction import", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during transaction import creation")
+	}
+	importID, err := result.LastInsertId()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while getting last insert id for new transaction import", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during transaction import creation")
+	}
+	imp.ImportID = strconv.FormatInt(importID, 10)
+	return &imp, nil
+}
+
+// Resume() takes the import over under its next attempt - the status and updated_at are checked by the update itself, so
+// that of two resumes started at once only one finds the import resumable
+func (t TransactionImportRepositoryDb) Resume(ctx context.Context, importID string, staleBefore string, updatedAt string) (*TransactionImport, *errs.AppError) {
+	updateSql := "update transaction_imports set status = ?, attempt = attempt + 1, updated_at = ?, completed_at = null " +
+		"where import_id = ? and (status = ? or (status = ? and updated_at < ?))"
+	findSql := "select " + transactionImportColumns + " from transaction_imports where import_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "TransactionImportRepositoryDb.Resume", updateSql)
+	defer span.End()
+
+	result, err := t.db_conn.ExecContext(ctx, updateSql, TransactionImportProcessing, updatedAt, importID,
+		TransactionImportInterrupted, TransactionImportProcessing, staleBefore)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while resuming transaction import", zap.String("import_id", importID), zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during transaction import update")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while getting rows affected by resuming transaction import", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during transaction import update")
+	}
+	if rows == 0 {
+		return nil, transactionImportNotResumableErr(importID)
+	}
+	// the import is read back for the checkpoint and attempt it was resumed with
+	var imp TransactionImport
+	if err := t.db_conn.GetContext(ctx, &imp, findSql, importID); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while reading resumed transaction import", zap.String("import_id", importID), zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during transaction import update")
+	}
+	return &imp, nil
+}
+
+// UpdateProgress() records the checkpoint, counts and status of the import - only while imp.Attempt is the import's
+// attempt, so that a process the import was taken over from cannot overwrite the progress of the one that took it over
+func (t TransactionImportRepositoryDb) UpdateProgress(ctx context.Context, imp TransactionImport) *errs.AppError {
+	updateSql := "update transaction_imports set next_row = ?, posted_count = ?, failed_count = ?, status = ?, updated_at = ?, completed_at = ? " +
+		"where import_id = ? and attempt = ?"
+
+	ctx, span := tracing.StartDB(ctx, "TransactionImportRepositoryDb.UpdateProgress", updateSql)
+	defer span.End()
+
+	result, err := t.db_conn.ExecContext(ctx, updateSql, imp.NextRow, imp.PostedCount, imp.FailedCount, imp.Status, imp.UpdatedAt,
+		imp.CompletedAt, imp.ImportID, imp.Attempt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating transaction import", zap.String("import_id", imp.ImportID), zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error during transaction import update")
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while getting rows affected by updating transaction import", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error during transaction import update")
+	}
+	if rows == 0 {
+		return transactionImportTakenOverErr(imp.ImportID)
+	}
+	return nil
+}
+
+// NewTransactionImportRepositoryDb() takes a db connection and returns a TransactionImportRepositoryDb
+func NewTransactionImportRepositoryDb(db_conn *sqlx.DB) TransactionImportRepositoryDb {
+	return TransactionImportRepositoryDb{db_conn: db_conn}
+}
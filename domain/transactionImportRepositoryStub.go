@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// TransactionImportRepositoryStub is an in-memory "adapter" for the TransactionImportRepository "port" - it starts empty
+type TransactionImportRepositoryStub struct {
+	// mu guards imports as files may be imported concurrently
+	mu      sync.Mutex
+	imports []TransactionImport
+}
+
+// FindByChecksum implementation for type TransactionImportRepositoryStub
+func (t *TransactionImportRepositoryStub) FindByChecksum(ctx context.Context, checksum string) (*TransactionImport, *errs.AppError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, imp := range t.imports {
+		if imp.Checksum == checksum {
+			return &imp, nil
+		}
+	}
+	return nil, errs.NotFoundErr(MsgTransactionImportNotFound)
+}
+
+// Start implementation for type TransactionImportRepositoryStub
+func (t *TransactionImportRepositoryStub) Start(ctx context.Context, imp TransactionImport) (*TransactionImport, *errs.AppError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for _, existing := range t.imports {
+		if existing.Checksum == imp.Checksum {
+			return nil, transactionImportExistsErr(imp.Checksum)
+		}
+	}
+	imp.ImportID = strconv.Itoa(len(t.imports) + 1)
+	imp.Status, imp.Attempt = TransactionImportProcessing, 1
+	imp.UpdatedAt = sql.NullString{String: imp.CreatedAt, Valid: true}
+	t.imports = append(t.imports, imp)
+	return &imp, nil
+}
+
+// Resume implementation for type TransactionImportRepositoryStub
+func (t *TransactionImportRepositoryStub) Resume(ctx context.Context, importID string, staleBefore string, updatedAt string) (*TransactionImport, *errs.AppError) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.imports {
+		if imp := &t.imports[i]; imp.ImportID == importID && imp.Resumable(staleBefore) {
+			imp.Status = TransactionImportProcessing
+			imp.Attempt++
+			imp.UpdatedAt = sql.NullString{String: updatedAt, Valid: true}
+			imp.CompletedAt = sql.NullString{}
+			resumed := *imp
+			return &resumed, nil
+		}
+	}
+	return nil, transactionImportNotResumableErr(importID)
+}
+
+// UpdateProgress implementation for type TransactionImportRepositoryStub
+func (t *TransactionImportRepositoryStub) UpdateProgress(ctx context.Context, imp TransactionImport) *errs.AppError {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for i := range t.imports {
+		if existing := &t.imports[i]; existing.ImportID == imp.ImportID {
+			if existing.Attempt != imp.Attempt {
+				return transactionImportTakenOverErr(imp.ImportID)
+			}
+			existing.NextRow, existing.PostedCount, existing.FailedCount = imp.NextRow, imp.PostedCount, imp.FailedCount
+			existing.Status, existing.UpdatedAt, existing.CompletedAt = imp.Status, imp.UpdatedAt, imp.CompletedAt
+			return nil
+		}
+	}
+	return errs.NotFoundErr(MsgTransactionImportNotFound)
+}
+
+// Imports() returns a copy of every import recorded, oldest first
+func (t *TransactionImportRepositoryStub) Imports() []TransactionImport {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return append([]TransactionImport(nil), t.imports...)
+}
+
+// NewTransactionImportRepositoryStub() returns an empty transaction import repository stub
+func NewTransactionImportRepositoryStub() *TransactionImportRepositoryStub {
+	return &TransactionImportRepositoryStub{}
+}
@@ -1,14 +1,17 @@
 package domain
 
 import (
+	"context"
 	"database/sql"
 	"strconv"
 	"strings"
 
 	"github.com/gtaylor314/Banking-Lib/errs"
-	"github.com/gtaylor314/Banking-Lib/logger"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
 
 	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
 )
 
 // TransactionRepositoryDb is an adapter which implements the port TransactionRepository
@@ -16,12 +19,18 @@ type TransactionRepositoryDb struct {
 	db_conn *sqlx.DB
 }
 
-func (t TransactionRepositoryDb) SaveTrans(tran Transaction) (*Transaction, *errs.AppError) {
+func (t TransactionRepositoryDb) SaveTrans(ctx context.Context, tran Transaction) (*Transaction, *errs.AppError) {
+	// the SaveTrans span is the parent of the insert, update, and commit spans below so each step's latency is visible
+	ctx, span := tracing.Start(ctx, "TransactionRepositoryDb.SaveTrans")
+	defer span.End()
+
 	// if the transaction is successfully inserted but the account table fails to update, we want to be able to rollback
 	// all changes to the database - to do this, we use a sql transaction
-	tx, err := t.db_conn.DB.Begin()
+	// BeginTx() ties the sql transaction to ctx - if ctx is cancelled before Commit(), the sql transaction is rolled back
+	tx, err := t.db_conn.DB.BeginTx(ctx, nil)
 	if err != nil {
-		logger.Error("error while creating database transaction tx " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating database transaction tx", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected error creating database transaction tx")
 	}
 
@@ -33,17 +42,21 @@ func (t TransactionRepositoryDb) SaveTrans(tran Transaction) (*Transaction, *err
 	// define the SQL Insert command - transactions is the name of the table in our banking db
 	sqlInsertCmd := "INSERT INTO transactions (account_id, amount, transaction_type, transaction_date) values (?, ?, ?, ?)"
 
-	// Exec() executes the query/command using the query string and arguments - returns no rows
-	result, err := tx.Exec(sqlInsertCmd, tran.AccountID, tran.Amount, tran.TransactionType, tran.TransactionDate)
+	insertCtx, insertSpan := tracing.StartDB(ctx, "insert transaction", sqlInsertCmd)
+	// ExecContext() executes the query/command using the query string and arguments - returns no rows
+	result, err := tx.ExecContext(insertCtx, sqlInsertCmd, tran.AccountID, tran.Amount, tran.TransactionType, tran.TransactionDate)
+	insertSpan.End()
 	if err != nil {
-		logger.Error("error while inserting transaction " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while inserting transaction", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error during transaction insert")
 	}
 	// LastInsertId() returns the last integer generated by executing a database command (e.g. the transaction id
 	// auto-generated) when inserting a transaction into the transactions table
 	tranId, err := result.LastInsertId()
 	if err != nil {
-		logger.Error("error while retrieving the transaction ID " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while retrieving the transaction ID", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error while retrieving the transaction id")
 	}
 
@@ -52,45 +65,69 @@ func (t TransactionRepositoryDb) SaveTrans(tran Transaction) (*Transaction, *err
 
 	// UpdateAcctAmount() updates the accounts table based on if the transaction type was a withdrawal or a deposit using
 	// the amount of the transaction
-	transaction, appErr := t.UpdateAcctAmount(tran, tx)
+	transaction, appErr := t.UpdateAcctAmount(ctx, tran, tx)
 	if appErr != nil {
 		return nil, appErr
 	}
 
-	if err = tx.Commit(); err != nil {
-		logger.Error("error while committing the transaction to the database " + err.Error())
+	// the TransactionPosted event carries the new balance and is written by the same sql transaction
+	eventCtx, eventSpan := tracing.StartDB(ctx, "insert outbox event", "INSERT INTO outbox_events")
+	err = insertOutboxEvent(eventCtx, tx, NewTransactionPostedEvent(*transaction))
+	eventSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while recording the TransactionPosted event", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error while recording the transaction")
+	}
+
+	_, commitSpan := tracing.StartDB(ctx, "commit", "COMMIT")
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while committing the transaction to the database", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error while committing the transaction")
 	}
 
 	return transaction, nil
 }
 
-func (t TransactionRepositoryDb) GetAcctAmount(acctId string) (*float64, *errs.AppError) {
-	// define the SQL query to get the account amount (or balance) via the account ID
-	sqlSelAccountAmount := "SELECT amount FROM accounts WHERE account_id = ?"
+// GetAccount() returns the account with the given account ID - the transaction service uses its balance to validate
+// withdrawals and its account type when recording metrics
+func (t TransactionRepositoryDb) GetAccount(ctx context.Context, acctId string) (*Account, *errs.AppError) {
+	// define the SQL query to get the account via the account ID
+	sqlSelAccount := "SELECT account_id, customer_id, opening_date, account_type, amount, status FROM accounts WHERE account_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "TransactionRepositoryDb.GetAccount", sqlSelAccount)
+	defer span.End()
 
-	var amount float64
-	err := t.db_conn.Get(&amount, sqlSelAccountAmount, acctId)
+	var acct Account
+	err := t.db_conn.GetContext(ctx, &acct, sqlSelAccount, acctId)
 	if err != nil {
-		logger.Error("error while retrieving account's balance " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while retrieving account", zap.Error(err))
 		if err == sql.ErrNoRows {
 			// the provided account id wasn't found in the accounts table
-			return nil, errs.NotFoundErr("account id provided was not found in database")
+			return nil, AccountNotFoundErr
 		}
 		return nil, errs.UnexpectedErr("unexpected database error while retrieving the account's current balance")
 	}
-	return &amount, nil
+	return &acct, nil
 }
 
-func (t TransactionRepositoryDb) UpdateAcctAmount(tran Transaction, tx *sql.Tx) (*Transaction, *errs.AppError) {
+func (t TransactionRepositoryDb) UpdateAcctAmount(ctx context.Context, tran Transaction, tx *sql.Tx) (*Transaction, *errs.AppError) {
+	ctx, span := tracing.StartDB(ctx, "update balance", "UPDATE accounts SET amount = ? WHERE account_id = ?")
+	defer span.End()
+
 	// if the transaction type is a withdrawal
 	if strings.ToLower(tran.TransactionType) == "withdrawal" {
 		// update AcctAmount (account balance) by subtracting the amount withdrawn
 		tran.AcctAmount = tran.AcctAmount - tran.Amount
 		sqlUpdateAcctAmount := "UPDATE accounts SET amount = ? WHERE account_id = ?"
-		_, err := tx.Exec(sqlUpdateAcctAmount, tran.AcctAmount, tran.AccountID)
+		_, err := tx.ExecContext(ctx, sqlUpdateAcctAmount, tran.AcctAmount, tran.AccountID)
 		if err != nil {
-			logger.Error("error while updating account's balance " + err.Error())
+			tracing.RecordError(span, err)
+			logging.Error(ctx, "error while updating account's balance", zap.Error(err))
 			return nil, errs.UnexpectedErr("unexpected database error while updating account's balance")
 		}
 		return &tran, nil
@@ -99,9 +136,10 @@ func (t TransactionRepositoryDb) UpdateAcctAmount(tran Transaction, tx *sql.Tx)
 	// update AcctAmount (account balance) by adding the amount deposited
 	tran.AcctAmount = tran.AcctAmount + tran.Amount
 	sqlUpdateAcctAmount := "UPDATE accounts SET amount = ? WHERE account_id = ?"
-	_, err := tx.Exec(sqlUpdateAcctAmount, tran.AcctAmount, tran.AccountID)
+	_, err := tx.ExecContext(ctx, sqlUpdateAcctAmount, tran.AcctAmount, tran.AccountID)
 	if err != nil {
-		logger.Error("error while updating account's balance " + err.Error())
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating account's balance", zap.Error(err))
 		return nil, errs.UnexpectedErr("unexpected database error while updating account's balance")
 	}
 	return &tran, nil
@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// TransactionRepositoryStub is an in-memory "adapter" for the TransactionRepository "port" - it holds its own set of
+// accounts so that transactions can be posted without a database
+type TransactionRepositoryStub struct {
+	// mu guards accounts, transactions and lastID as handlers may post transactions concurrently
+	mu           sync.Mutex
+	accounts     map[string]*Account
+	transactions []Transaction
+	lastID       int
+}
+
+// SaveTrans implementation for type TransactionRepositoryStub - the account's balance is updated along with the insert, as
+// TransactionRepositoryDb does within a single database transaction
+func (t *TransactionRepositoryStub) SaveTrans(ctx context.Context, tran Transaction) (*Transaction, *errs.AppError) {
+	// as with the database, nothing is saved once ctx is cancelled
+	if ctx.Err() != nil {
+		return nil, errs.UnexpectedErr("unexpected error creating database transaction tx")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acct, ok := t.accounts[tran.AccountID]
+	if !ok {
+		return nil, AccountNotFoundErr
+	}
+	if strings.ToLower(tran.TransactionType) == "withdrawal" {
+		acct.Amount -= tran.Amount
+	} else {
+		acct.Amount += tran.Amount
+	}
+
+	t.lastID++
+	tran.TransactionID = strconv.Itoa(t.lastID)
+	tran.AcctAmount = acct.Amount
+	t.transactions = append(t.transactions, tran)
+	return &tran, nil
+}
+
+// GetAccount implementation for type TransactionRepositoryStub
+func (t *TransactionRepositoryStub) GetAccount(ctx context.Context, accountID string) (*Account, *errs.AppError) {
+	if ctx.Err() != nil {
+		return nil, errs.UnexpectedErr("unexpected database error while retrieving the account's current balance")
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	acct, ok := t.accounts[accountID]
+	if !ok {
+		return nil, AccountNotFoundErr
+	}
+	// a copy is returned so that the caller cannot change the balance held by the stub
+	acctCopy := *acct
+	return &acctCopy, nil
+}
+
+// NewTransactionRepositoryStub is a helper function which creates a new transaction repository stub holding a predefined
+// set of accounts to post transactions against
+func NewTransactionRepositoryStub() *TransactionRepositoryStub {
+	// creating dummy accounts
+	accounts := []Account{
+		{AccountID: "101", CustomerID: "1001", OpeningDate: "2010-09-15 10:20:00", AccountType: "saving", Amount: 1000.00, Status: "1"},
+		{AccountID: "102", CustomerID: "1002", OpeningDate: "2000-01-13 08:15:05", AccountType: "saving", Amount: 15000.00, Status: "1"},
+		{AccountID: "103", CustomerID: "1002", OpeningDate: "2001-05-20 08:10:04", AccountType: "checking", Amount: 18854.00, Status: "1"},
+	}
+
+	stub := &TransactionRepositoryStub{accounts: make(map[string]*Account)}
+	for i := range accounts {
+		stub.accounts[accounts[i].AccountID] = &accounts[i]
+	}
+	return stub
+}
@@ -0,0 +1,58 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// MsgInvalidCredentials is returned for an unknown username and a wrong password alike so that a failed login does not
+// reveal which usernames exist
+const MsgInvalidCredentials = "invalid username or password"
+
+// user roles as stored in the role column
+const (
+	RoleAdmin = "admin"
+	RoleUser  = "user"
+)
+
+// User represents a login held in the users table - users with the user role belong to a customer, admins do not
+type User struct {
+	Username   string         `db:"username"`
+	Password   string         `db:"password"`
+	Role       string         `db:"role"`
+	CustomerID sql.NullString `db:"customer_id"`
+	CreatedOn  string         `db:"created_on"`
+	// PasswordChangedAt is null until the user first changes their password
+	PasswordChangedAt sql.NullString `db:"password_changed_at"`
+}
+
+// UserRepository is a "port" implemented by the server side - UserRepositoryDb is its adapter
+type UserRepository interface {
+	// FindByUsername returns the user with the given username - an unknown username is MsgInvalidCredentials
+	FindByUsername(context.Context, string) (*User, *errs.AppError)
+	// UpdatePassword stores a new password hash for the username along with the time it was changed
+	UpdatePassword(ctx context.Context, username string, hash string, changedAt string) *errs.AppError
+}
+
+// PasswordMatches() reports whether password is the user's password - Password holds a bcrypt hash
+func (user User) PasswordMatches(password string) bool {
+	return comparePassword(user.Password, password)
+}
+
+// IssuedBeforePasswordChange() reports whether a token issued at issuedAt may predate the user's last password change -
+// such a token is no longer accepted for a refresh, so changing a password signs out every other session
+func (user User) IssuedBeforePasswordChange(issuedAt time.Time) bool {
+	if !user.PasswordChangedAt.Valid {
+		return false
+	}
+	// the column holds local time to the second, as written by UpdatePassword(), and issuedAt is also to the second - a
+	// token issued within the second of the change cannot be told apart from one issued before it, so it is refused too
+	changedAt, err := time.ParseInLocation("2006-01-02 15:04:05", user.PasswordChangedAt.String, time.Local)
+	if err != nil {
+		return true
+	}
+	return !issuedAt.After(changedAt)
+}
@@ -0,0 +1,91 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// UserRepositoryDb is an adapter that implements the UserRepository (port) interface
+type UserRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// FindByUsername() returns the user with the given username
+func (u UserRepositoryDb) FindByUsername(ctx context.Context, username string) (*User, *errs.AppError) {
+	findSql := "select username, password, role, customer_id, created_on, password_changed_at from users where username = ?"
+
+	ctx, span := tracing.StartDB(ctx, "UserRepositoryDb.FindByUsername", findSql)
+	defer span.End()
+
+	var user User
+	err := u.db_conn.GetContext(ctx, &user, findSql, username)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.AuthorizationErr(MsgInvalidCredentials)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of users table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &user, nil
+}
+
+// UpdatePassword() stores the new password hash of the user
+func (u UserRepositoryDb) UpdatePassword(ctx context.Context, username string, hash string, changedAt string) *errs.AppError {
+	updateSql := "update users set password = ?, password_changed_at = ? where username = ?"
+
+	ctx, span := tracing.StartDB(ctx, "UserRepositoryDb.UpdatePassword", updateSql)
+	defer span.End()
+
+	result, err := u.db_conn.ExecContext(ctx, updateSql, hash, changedAt, username)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating the password of a user", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+	if rows, err := result.RowsAffected(); err == nil && rows == 0 {
+		return errs.NotFoundErr("user not found")
+	}
+	return nil
+}
+
+// HashPlaintextPasswords() replaces every password which is not yet a bcrypt hash with its hash at cost and returns the
+// number of users updated - it backs the one-off cmd/hashpasswords command and is safe to run more than once
+func (u UserRepositoryDb) HashPlaintextPasswords(ctx context.Context, cost int) (int, error) {
+	var users []User
+	if err := u.db_conn.SelectContext(ctx, &users, "select username, password from users"); err != nil {
+		return 0, err
+	}
+	updated := 0
+	for _, user := range users {
+		if IsPasswordHash(user.Password) {
+			continue
+		}
+		hash, err := HashPassword(user.Password, cost)
+		if err != nil {
+			return updated, err
+		}
+		// matching on the old password leaves a row alone if it was changed while the command ran
+		result, err := u.db_conn.ExecContext(ctx, "update users set password = ? where username = ? and password = ?",
+			hash, user.Username, user.Password)
+		if err != nil {
+			return updated, err
+		}
+		if rows, err := result.RowsAffected(); err == nil {
+			updated += int(rows)
+		}
+	}
+	return updated, nil
+}
+
+// NewUserRepositoryDb() takes a db connection and returns a UserRepositoryDb
+func NewUserRepositoryDb(db_conn *sqlx.DB) UserRepositoryDb {
+	return UserRepositoryDb{db_conn: db_conn}
+}
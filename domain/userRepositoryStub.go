@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// seedPasswordHash is the bcrypt hash (cost 12) of passw0rd123, the password of the seeded users - migration 000006
+// stores the same hash
+const seedPasswordHash = "$2a$12$6P8rHY9E.2lov5N5XbSQ3.ccnuzCy4iu7VEYtQprbtUZW4X6hP9VC"
+
+// UserRepositoryStub is an in-memory "adapter" for the UserRepository "port" - the mutex guards users as password
+// changes may be made concurrently
+type UserRepositoryStub struct {
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+// FindByUsername implementation for type UserRepositoryStub
+func (u *UserRepositoryStub) FindByUsername(ctx context.Context, username string) (*User, *errs.AppError) {
+	u.mu.RLock()
+	defer u.mu.RUnlock()
+	user, ok := u.users[username]
+	if !ok {
+		return nil, errs.AuthorizationErr(MsgInvalidCredentials)
+	}
+	return &user, nil
+}
+
+// UpdatePassword implementation for type UserRepositoryStub
+func (u *UserRepositoryStub) UpdatePassword(ctx context.Context, username string, hash string, changedAt string) *errs.AppError {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	user, ok := u.users[username]
+	if !ok {
+		return errs.NotFoundErr("user not found")
+	}
+	user.Password = hash
+	user.PasswordChangedAt = sql.NullString{String: changedAt, Valid: true}
+	u.users[username] = user
+	return nil
+}
+
+// NewUserRepositoryStub is a helper function which creates a new user repository stub holding an admin and a user
+// belonging to customer 1001 - both with the password passw0rd123
+func NewUserRepositoryStub() *UserRepositoryStub {
+	return &UserRepositoryStub{users: map[string]User{
+		"admin": {Username: "admin", Password: seedPasswordHash, Role: RoleAdmin, CreatedOn: "2022-08-17 08:15:10"},
+		"1001": {Username: "1001", Password: seedPasswordHash, Role: RoleUser, CustomerID: sql.NullString{String: "1001", Valid: true},
+			CreatedOn: "2022-08-17 08:15:05"},
+	}}
+}
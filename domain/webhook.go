@@ -0,0 +1,242 @@
+package domain
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// messages returned for a subscription_id or delivery_id which does not exist
+const (
+	MsgWebhookSubscriptionNotFound = "webhook subscription not found"
+	MsgWebhookDeliveryNotFound     = "webhook delivery not found"
+)
+
+// webhookSecretPrefix starts every generated webhook secret so that a leaked secret is easy to recognise
+const webhookSecretPrefix = "whsec_"
+
+// webhook subscription statuses - deliveries are only queued and attempted for an active subscription
+const (
+	WebhookSubscriptionActive   = "active"
+	WebhookSubscriptionDisabled = "disabled"
+)
+
+// webhook delivery statuses - a pending delivery is due at its next_attempt_at, a failed one has used up its attempts
+// and is only attempted again when it is replayed
+const (
+	WebhookPending   = "pending"
+	WebhookDelivered = "delivered"
+	WebhookFailed    = "failed"
+)
+
+// WebhookEventTypes are the event types a subscription may list - the types written to the outbox
+var WebhookEventTypes = []string{EventAccountOpened, EventTransactionPosted, EventCustomerDeactivated, EventCustomerReactivated}
+
+// WebhookSubscription represents a row of the webhook_subscriptions table - EventTypes holds the comma separated event
+// types delivered to URL, and Secret the key every delivery is signed with
+type WebhookSubscription struct {
+	SubscriptionID string         `db:"subscription_id"`
+	URL            string         `db:"url"`
+	EventTypes     string         `db:"event_types"`
+	Secret         string         `db:"secret"`
+	CreatedBy      string         `db:"created_by"`
+	CreatedAt      string         `db:"created_at"`
+	DisabledAt     sql.NullString `db:"disabled_at"`
+}
+
+// WebhookEvent is an event to be delivered to the subscriptions to its type - Payload is the body POSTed to them
+type WebhookEvent struct {
+	EventID   string
+	EventType string
+	Payload   string
+}
+
+// WebhookDelivery represents a row of the webhook_deliveries table - one event to one subscription
+type WebhookDelivery struct {
+	DeliveryID     string         `db:"delivery_id"`
+	SubscriptionID string         `db:"subscription_id"`
+	EventID        string         `db:"event_id"`
+	EventType      string         `db:"event_type"`
+	Payload        string         `db:"payload"`
+	Status         string         `db:"status"`
+	Attempts       int            `db:"attempts"`
+	NextAttemptAt  string         `db:"next_attempt_at"`
+	CreatedAt      string         `db:"created_at"`
+	DeliveredAt    sql.NullString `db:"delivered_at"`
+}
+
+// WebhookDispatch is a claimed delivery along with the URL and secret of its subscription
+type WebhookDispatch struct {
+	WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+	// ClaimedBy is the claim token the delivery was leased under - its attempt is only recorded while it still holds it
+	ClaimedBy string `db:"claimed_by"`
+}
+
+// WebhookAttempt represents a row of the webhook_delivery_attempts table - StatusCode is 0 when no response was received,
+// and Error is empty for a delivered attempt
+type WebhookAttempt struct {
+	AttemptID   string `db:"attempt_id"`
+	DeliveryID  string `db:"delivery_id"`
+	AttemptedAt string `db:"attempted_at"`
+	StatusCode  int    `db:"status_code"`
+	Error       string `db:"error"`
+	DurationMs  int64  `db:"duration_ms"`
+}
+
+// WebhookRepository is a "port" implemented by the server side - WebhookRepositoryDb is its adapter - the deliveries are
+// queued by the outbox relay and attempted by the webhook worker
+type WebhookRepository interface {
+	FindAllSubscriptions(context.Context) ([]WebhookSubscription, *errs.AppError)
+	// FindSubscription returns the subscription with the given subscription_id - an unknown one is
+	// MsgWebhookSubscriptionNotFound
+	FindSubscription(ctx context.Context, subscriptionID string) (*WebhookSubscription, *errs.AppError)
+	// SaveSubscription inserts the subscription and returns it with its subscription_id
+	SaveSubscription(context.Context, WebhookSubscription) (*WebhookSubscription, *errs.AppError)
+	// DisableSubscription stops the deliveries to the subscription - disabling a disabled subscription is a 409
+	DisableSubscription(ctx context.Context, subscriptionID string, disabledAt string) (*WebhookSubscription, *errs.AppError)
+	// Enqueue queues a delivery of event, due at createdAt, for every active subscription to its type and returns how
+	// many were queued - a subscription the event is already queued for is skipped, so an event published more than once
+	// is delivered once
+	Enqueue(ctx context.Context, event WebhookEvent, createdAt string) (int64, *errs.AppError)
+	// ClaimDeliveries leases up to limit pending deliveries of active subscriptions due at now, oldest first, until
+	// leaseUntil
+	ClaimDeliveries(ctx context.Context, now string, leaseUntil string, limit int) ([]WebhookDispatch, *errs.AppError)
+	// RecordAttempt logs attempt, counts it against its delivery, sets the delivery's status and next attempt and
+	// releases its claim - a delivered delivery is delivered at attempt.AttemptedAt - nothing is recorded (a 409) when the
+	// delivery is no longer claimed by claimedBy, as its lease ended and another worker claimed it
+	RecordAttempt(ctx context.Context, claimedBy string, attempt WebhookAttempt, status string, nextAttemptAt string) *errs.AppError
+	// FindDeliveries returns up to limit deliveries to the subscription, newest first - an empty status matches every
+	// delivery
+	FindDeliveries(ctx context.Context, subscriptionID string, status string, limit int) ([]WebhookDelivery, *errs.AppError)
+	// FindDelivery returns the delivery to the subscription with the given delivery_id - an unknown one is
+	// MsgWebhookDeliveryNotFound
+	FindDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*WebhookDelivery, *errs.AppError)
+	// FindAttempts returns the attempts logged for the delivery, oldest first
+	FindAttempts(ctx context.Context, deliveryID string) ([]WebhookAttempt, *errs.AppError)
+	// Replay queues the delivery again, due at now and with its attempts reset, whatever its status - replaying a
+	// delivery of a disabled subscription is a 409
+	Replay(ctx context.Context, subscriptionID string, deliveryID string, now string) (*WebhookDelivery, *errs.AppError)
+}
+
+// EventTypeList() returns the event types delivered to the subscription
+func (sub WebhookSubscription) EventTypeList() []string {
+	if sub.EventTypes == "" {
+		return []string{}
+	}
+	return strings.Split(sub.EventTypes, ",")
+}
+
+// Status() returns whether the subscription is active or disabled
+func (sub WebhookSubscription) Status() string {
+	if sub.DisabledAt.Valid {
+		return WebhookSubscriptionDisabled
+	}
+	return WebhookSubscriptionActive
+}
+
+// Receives() reports whether the subscription is active and lists eventType
+func (sub WebhookSubscription) Receives(eventType string) bool {
+	if sub.DisabledAt.Valid {
+		return false
+	}
+	for _, listed := range sub.EventTypeList() {
+		if listed == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// ToDto() returns the subscription without its secret - the secret is only added to the response by the service when
+// the subscription is created
+func (sub WebhookSubscription) ToDto() dto.WebhookSubscriptionResponse {
+	return dto.WebhookSubscriptionResponse{
+		SubscriptionID: sub.SubscriptionID,
+		URL:            sub.URL,
+		EventTypes:     sub.EventTypeList(),
+		Status:         sub.Status(),
+		CreatedBy:      sub.CreatedBy,
+		CreatedAt:      sub.CreatedAt,
+		DisabledAt:     sub.DisabledAt.String,
+	}
+}
+
+// ToDto() converts the delivery and the attempts logged for it to its response - attempts is nil when the delivery is
+// listed rather than read on its own
+func (delivery WebhookDelivery) ToDto(attempts []WebhookAttempt) dto.WebhookDeliveryResponse {
+	resp := dto.WebhookDeliveryResponse{
+		DeliveryID:     delivery.DeliveryID,
+		SubscriptionID: delivery.SubscriptionID,
+		EventID:        delivery.EventID,
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		CreatedAt:      delivery.CreatedAt,
+		DeliveredAt:    delivery.DeliveredAt.String,
+		Payload:        json.RawMessage(delivery.Payload),
+	}
+	// only a pending delivery is attempted again
+	if delivery.Status == WebhookPending {
+		resp.NextAttemptAt = delivery.NextAttemptAt
+	}
+	if attempts != nil {
+		resp.AttemptLog = make([]dto.WebhookAttemptResponse, 0, len(attempts))
+		for _, attempt := range attempts {
+			resp.AttemptLog = append(resp.AttemptLog, dto.WebhookAttemptResponse{
+				AttemptID:   attempt.AttemptID,
+				AttemptedAt: attempt.AttemptedAt,
+				StatusCode:  attempt.StatusCode,
+				Error:       attempt.Error,
+				DurationMs:  attempt.DurationMs,
+			})
+		}
+	}
+	return resp
+}
+
+// ValidateEventTypes() returns a 422 *errs.AppError naming each event type which is not one of WebhookEventTypes
+func ValidateEventTypes(eventTypes []string) *errs.AppError {
+	var problems []string
+	for _, eventType := range eventTypes {
+		known := false
+		for _, webhookEventType := range WebhookEventTypes {
+			known = known || eventType == webhookEventType
+		}
+		if !known {
+			problems = append(problems, fmt.Sprintf("%s is not an event type", eventType))
+		}
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	return errs.ValidationErr("invalid event_types: " + strings.Join(problems, ", "))
+}
+
+// NewWebhookSecret() returns a new random secret for a subscription which was created without one
+func NewWebhookSecret() (string, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return "", err
+	}
+	return webhookSecretPrefix + base64.RawURLEncoding.EncodeToString(secret), nil
+}
+
+// webhookLeaseLostErr() returns the 409 *errs.AppError for recording an attempt of a delivery claimed by another worker
+func webhookLeaseLostErr(deliveryID string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("webhook delivery %s is claimed by another worker", deliveryID)}
+}
+
+// webhookSubscriptionDisabledErr() returns the 409 *errs.AppError for changing or replaying to a disabled subscription
+func webhookSubscriptionDisabledErr(subscriptionID string) *errs.AppError {
+	return &errs.AppError{Code: http.StatusConflict, Message: fmt.Sprintf("webhook subscription %s is disabled", subscriptionID)}
+}
@@ -0,0 +1,317 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"github.com/jmoiron/sqlx"
+	"go.uber.org/zap"
+)
+
+// the columns of the webhook tables read into a WebhookSubscription, WebhookDelivery and WebhookAttempt
+const (
+	webhookSubscriptionColumns = "subscription_id, url, event_types, secret, created_by, created_at, disabled_at"
+	webhookDeliveryColumns     = "delivery_id, subscription_id, event_id, event_type, payload, status, attempts, next_attempt_at, created_at, delivered_at"
+	webhookAttemptColumns      = "attempt_id, delivery_id, attempted_at, status_code, error, duration_ms"
+)
+
+// WebhookRepositoryDb is an adapter that implements the WebhookRepository (port) interface
+type WebhookRepositoryDb struct {
+	db_conn *sqlx.DB
+}
+
+// FindAllSubscriptions() returns every subscription, disabled subscriptions included, newest first
+func (w WebhookRepositoryDb) FindAllSubscriptions(ctx context.Context) ([]WebhookSubscription, *errs.AppError) {
+	findAllSql := "select " + webhookSubscriptionColumns + " from webhook_subscriptions order by subscription_id desc"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.FindAllSubscriptions", findAllSql)
+	defer span.End()
+
+	subs := make([]WebhookSubscription, 0)
+	if err := w.db_conn.SelectContext(ctx, &subs, findAllSql); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_subscriptions table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return subs, nil
+}
+
+// FindSubscription() returns the subscription with the given subscription_id
+func (w WebhookRepositoryDb) FindSubscription(ctx context.Context, subscriptionID string) (*WebhookSubscription, *errs.AppError) {
+	findSql := "select " + webhookSubscriptionColumns + " from webhook_subscriptions where subscription_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.FindSubscription", findSql)
+	defer span.End()
+
+	var sub WebhookSubscription
+	if err := w.db_conn.GetContext(ctx, &sub, findSql, subscriptionID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFoundErr(MsgWebhookSubscriptionNotFound)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_subscriptions table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &sub, nil
+}
+
+// SaveSubscription() inserts a new subscription and sets the subscription_id auto-incremented on insert
+func (w WebhookRepositoryDb) SaveSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, *errs.AppError) {
+	insertSql := "insert into webhook_subscriptions (url, event_types, secret, created_by, created_at) values (?, ?, ?, ?, ?)"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.SaveSubscription", insertSql)
+	defer span.End()
+
+	result, err := w.db_conn.ExecContext(ctx, insertSql, sub.URL, sub.EventTypes, sub.Secret, sub.CreatedBy, sub.CreatedAt)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while creating webhook subscription", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during webhook subscription creation")
+	}
+	subscriptionID, err := result.LastInsertId()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while getting last insert id for new webhook subscription", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during webhook subscription creation")
+	}
+	sub.SubscriptionID = strconv.FormatInt(subscriptionID, 10)
+	return &sub, nil
+}
+
+// DisableSubscription() marks an active subscription disabled - when no row is changed the subscription is read to tell
+// an unknown subscription (404) from a disabled one (409)
+func (w WebhookRepositoryDb) DisableSubscription(ctx context.Context, subscriptionID string, disabledAt string) (*WebhookSubscription, *errs.AppError) {
+	updateSql := "update webhook_subscriptions set disabled_at = ? where subscription_id = ? and disabled_at is null"
+
+	updateCtx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.DisableSubscription", updateSql)
+	result, err := w.db_conn.ExecContext(updateCtx, updateSql, disabledAt, subscriptionID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		span.End()
+		logging.Error(ctx, "error while disabling webhook subscription", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error during webhook subscription update")
+	}
+	span.End()
+	rows, err := result.RowsAffected()
+	sub, appErr := w.FindSubscription(ctx, subscriptionID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if err == nil && rows == 0 {
+		return nil, webhookSubscriptionDisabledErr(subscriptionID)
+	}
+	return sub, nil
+}
+
+// Enqueue() queues the deliveries with a single insert from the matching subscriptions - the unique key on
+// (subscription_id, event_id) turns a second insert of the same event into a no-op
+func (w WebhookRepositoryDb) Enqueue(ctx context.Context, event WebhookEvent, createdAt string) (int64, *errs.AppError) {
+	insertSql := "insert into webhook_deliveries (subscription_id, event_id, event_type, payload, status, next_attempt_at, created_at)" +
+		" select subscription_id, ?, ?, ?, ?, ?, ? from webhook_subscriptions where disabled_at is null and find_in_set(?, event_types) > 0" +
+		" on duplicate key update delivery_id = delivery_id"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.Enqueue", insertSql)
+	defer span.End()
+
+	result, err := w.db_conn.ExecContext(ctx, insertSql, event.EventID, event.EventType, event.Payload, WebhookPending,
+		createdAt, createdAt, event.EventType)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while queueing webhook deliveries", zap.Error(err))
+		return 0, errs.UnexpectedErr("unexpected database error")
+	}
+	// RowsAffected() is supported by the MySQL driver, and is 0 for a row left unchanged by the duplicate key clause
+	queued, _ := result.RowsAffected()
+	return queued, nil
+}
+
+// ClaimDeliveries() leases the due deliveries to a claim token of its own with a single update, as the outbox relay
+// claims events, and then reads back the deliveries holding the token along with their subscriptions' URL and secret
+func (w WebhookRepositoryDb) ClaimDeliveries(ctx context.Context, now string, leaseUntil string, limit int) ([]WebhookDispatch, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "WebhookRepositoryDb.ClaimDeliveries")
+	defer span.End()
+
+	claim := newEventID()
+	// MySQL does not allow order by and limit on an update joining tables, so the subscriptions are checked by a subquery
+	claimSql := "update webhook_deliveries set claimed_by = ?, claimed_until = ? where status = ? and next_attempt_at <= ?" +
+		" and (claimed_until is null or claimed_until < ?)" +
+		" and subscription_id in (select subscription_id from webhook_subscriptions where disabled_at is null)" +
+		" order by delivery_id limit ?"
+	claimCtx, claimSpan := tracing.StartDB(ctx, "claim webhook deliveries", claimSql)
+	_, err := w.db_conn.ExecContext(claimCtx, claimSql, claim, leaseUntil, WebhookPending, now, now, limit)
+	claimSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while claiming webhook deliveries", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+
+	selectSql := "select d.delivery_id, d.subscription_id, d.event_id, d.event_type, d.payload, d.status, d.attempts," +
+		" d.next_attempt_at, d.created_at, d.delivered_at, d.claimed_by, s.url, s.secret" +
+		" from webhook_deliveries d join webhook_subscriptions s on s.subscription_id = d.subscription_id" +
+		" where d.claimed_by = ? order by d.delivery_id"
+	selectCtx, selectSpan := tracing.StartDB(ctx, "select claimed webhook deliveries", selectSql)
+	dispatches := make([]WebhookDispatch, 0)
+	err = w.db_conn.SelectContext(selectCtx, &dispatches, selectSql, claim)
+	selectSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_deliveries table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return dispatches, nil
+}
+
+// RecordAttempt() logs the attempt and updates its delivery in one sql transaction, so that the log always explains the
+// delivery's attempts - the update only matches while the delivery holds claimedBy, and the attempt is rolled back when it
+// does not, so that a worker whose lease ended cannot overwrite the outcome recorded by the worker which claimed it next
+func (w WebhookRepositoryDb) RecordAttempt(ctx context.Context, claimedBy string, attempt WebhookAttempt, status string, nextAttemptAt string) *errs.AppError {
+	ctx, span := tracing.Start(ctx, "WebhookRepositoryDb.RecordAttempt")
+	defer span.End()
+
+	tx, err := w.db_conn.BeginTxx(ctx, nil)
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while starting a new transaction for a webhook attempt", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+	// Rollback() is a no-op once the transaction has been committed
+	defer tx.Rollback()
+
+	insertSql := "insert into webhook_delivery_attempts (delivery_id, attempted_at, status_code, error, duration_ms) values (?, ?, ?, ?, ?)"
+	insertCtx, insertSpan := tracing.StartDB(ctx, "insert webhook attempt", insertSql)
+	_, err = tx.ExecContext(insertCtx, insertSql, attempt.DeliveryID, attempt.AttemptedAt, attempt.StatusCode, attempt.Error, attempt.DurationMs)
+	insertSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while logging webhook attempt", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+
+	var deliveredAt sql.NullString
+	if status == WebhookDelivered {
+		deliveredAt = sql.NullString{String: attempt.AttemptedAt, Valid: true}
+	}
+	updateSql := "update webhook_deliveries set attempts = attempts + 1, status = ?, next_attempt_at = ?, delivered_at = ?," +
+		" claimed_by = null, claimed_until = null where delivery_id = ? and claimed_by = ?"
+	updateCtx, updateSpan := tracing.StartDB(ctx, "update webhook delivery", updateSql)
+	result, err := tx.ExecContext(updateCtx, updateSql, status, nextAttemptAt, deliveredAt, attempt.DeliveryID, claimedBy)
+	updateSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while updating webhook delivery", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+	if updated, err := result.RowsAffected(); err != nil || updated == 0 {
+		return webhookLeaseLostErr(attempt.DeliveryID)
+	}
+
+	_, commitSpan := tracing.StartDB(ctx, "commit", "COMMIT")
+	err = tx.Commit()
+	commitSpan.End()
+	if err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error while committing webhook attempt", zap.Error(err))
+		return errs.UnexpectedErr("unexpected database error")
+	}
+	return nil
+}
+
+// FindDeliveries() returns the subscription's deliveries, newest first
+func (w WebhookRepositoryDb) FindDeliveries(ctx context.Context, subscriptionID string, status string, limit int) ([]WebhookDelivery, *errs.AppError) {
+	findSql := "select " + webhookDeliveryColumns + " from webhook_deliveries where subscription_id = ?"
+	args := []interface{}{subscriptionID}
+	if status != "" {
+		findSql += " and status = ?"
+		args = append(args, status)
+	}
+	findSql += " order by delivery_id desc limit ?"
+	args = append(args, limit)
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.FindDeliveries", findSql)
+	defer span.End()
+
+	deliveries := make([]WebhookDelivery, 0)
+	if err := w.db_conn.SelectContext(ctx, &deliveries, findSql, args...); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_deliveries table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return deliveries, nil
+}
+
+// FindDelivery() returns the delivery with the given delivery_id if it belongs to the subscription
+func (w WebhookRepositoryDb) FindDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*WebhookDelivery, *errs.AppError) {
+	findSql := "select " + webhookDeliveryColumns + " from webhook_deliveries where delivery_id = ? and subscription_id = ?"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.FindDelivery", findSql)
+	defer span.End()
+
+	var delivery WebhookDelivery
+	if err := w.db_conn.GetContext(ctx, &delivery, findSql, deliveryID, subscriptionID); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, errs.NotFoundErr(MsgWebhookDeliveryNotFound)
+		}
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_deliveries table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return &delivery, nil
+}
+
+// FindAttempts() returns the attempts logged for the delivery, oldest first
+func (w WebhookRepositoryDb) FindAttempts(ctx context.Context, deliveryID string) ([]WebhookAttempt, *errs.AppError) {
+	findSql := "select " + webhookAttemptColumns + " from webhook_delivery_attempts where delivery_id = ? order by attempt_id"
+
+	ctx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.FindAttempts", findSql)
+	defer span.End()
+
+	attempts := make([]WebhookAttempt, 0)
+	if err := w.db_conn.SelectContext(ctx, &attempts, findSql, deliveryID); err != nil {
+		tracing.RecordError(span, err)
+		logging.Error(ctx, "error during query of webhook_delivery_attempts table", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	return attempts, nil
+}
+
+// Replay() reads the delivery and its subscription before queueing the delivery again, so that an unknown delivery (404)
+// is told from one of a disabled subscription (409)
+func (w WebhookRepositoryDb) Replay(ctx context.Context, subscriptionID string, deliveryID string, now string) (*WebhookDelivery, *errs.AppError) {
+	sub, appErr := w.FindSubscription(ctx, subscriptionID)
+	if appErr != nil {
+		if appErr.Message == MsgWebhookSubscriptionNotFound {
+			return nil, errs.NotFoundErr(MsgWebhookDeliveryNotFound)
+		}
+		return nil, appErr
+	}
+	if _, appErr := w.FindDelivery(ctx, subscriptionID, deliveryID); appErr != nil {
+		return nil, appErr
+	}
+	if sub.DisabledAt.Valid {
+		return nil, webhookSubscriptionDisabledErr(subscriptionID)
+	}
+
+	updateSql := "update webhook_deliveries set status = ?, attempts = 0, next_attempt_at = ?, delivered_at = null," +
+		" claimed_by = null, claimed_until = null where delivery_id = ?"
+	updateCtx, span := tracing.StartDB(ctx, "WebhookRepositoryDb.Replay", updateSql)
+	_, err := w.db_conn.ExecContext(updateCtx, updateSql, WebhookPending, now, deliveryID)
+	if err != nil {
+		tracing.RecordError(span, err)
+		span.End()
+		logging.Error(ctx, "error while replaying webhook delivery", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected database error")
+	}
+	span.End()
+	return w.FindDelivery(ctx, subscriptionID, deliveryID)
+}
+
+// NewWebhookRepositoryDb() takes a db connection and returns a WebhookRepositoryDb
+func NewWebhookRepositoryDb(db_conn *sqlx.DB) WebhookRepositoryDb {
+	return WebhookRepositoryDb{db_conn: db_conn}
+}
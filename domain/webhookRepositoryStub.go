@@ -0,0 +1,256 @@
+package domain
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"sync"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// WebhookRepositoryStub is an in-memory "adapter" for the WebhookRepository "port" - it starts without subscriptions
+type WebhookRepositoryStub struct {
+	// mu guards the slices as handlers, the outbox relay and the webhook worker may use the stub concurrently
+	mu            sync.Mutex
+	subscriptions []WebhookSubscription
+	deliveries    []WebhookDelivery
+	attempts      []WebhookAttempt
+	// claimedUntil holds the lease of each claimed delivery, and claimedBy its claim token, by delivery_id
+	claimedUntil map[string]string
+	claimedBy    map[string]string
+	// lastIDs holds the last id handed out for subscriptions, deliveries and attempts
+	lastIDs [3]int64
+}
+
+// nextID() returns the next id of the kind (0 subscriptions, 1 deliveries, 2 attempts) - w.mu must be held
+func (w *WebhookRepositoryStub) nextID(kind int) string {
+	w.lastIDs[kind]++
+	return strconv.FormatInt(w.lastIDs[kind], 10)
+}
+
+// FindAllSubscriptions implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) FindAllSubscriptions(ctx context.Context) ([]WebhookSubscription, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	// newest first, as the subscriptions are appended in order
+	subs := make([]WebhookSubscription, 0, len(w.subscriptions))
+	for i := len(w.subscriptions) - 1; i >= 0; i-- {
+		subs = append(subs, w.subscriptions[i])
+	}
+	return subs, nil
+}
+
+// FindSubscription implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) FindSubscription(ctx context.Context, subscriptionID string) (*WebhookSubscription, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sub := w.subscription(subscriptionID)
+	if sub == nil {
+		return nil, errs.NotFoundErr(MsgWebhookSubscriptionNotFound)
+	}
+	found := *sub
+	return &found, nil
+}
+
+// SaveSubscription implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) SaveSubscription(ctx context.Context, sub WebhookSubscription) (*WebhookSubscription, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sub.SubscriptionID = w.nextID(0)
+	w.subscriptions = append(w.subscriptions, sub)
+	return &sub, nil
+}
+
+// DisableSubscription implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) DisableSubscription(ctx context.Context, subscriptionID string, disabledAt string) (*WebhookSubscription, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	sub := w.subscription(subscriptionID)
+	if sub == nil {
+		return nil, errs.NotFoundErr(MsgWebhookSubscriptionNotFound)
+	}
+	if sub.DisabledAt.Valid {
+		return nil, webhookSubscriptionDisabledErr(subscriptionID)
+	}
+	sub.DisabledAt = sql.NullString{String: disabledAt, Valid: true}
+	disabled := *sub
+	return &disabled, nil
+}
+
+// Enqueue implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) Enqueue(ctx context.Context, event WebhookEvent, createdAt string) (int64, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var queued int64
+	for _, sub := range w.subscriptions {
+		if !sub.Receives(event.EventType) || w.queued(sub.SubscriptionID, event.EventID) {
+			continue
+		}
+		w.deliveries = append(w.deliveries, WebhookDelivery{
+			DeliveryID:     w.nextID(1),
+			SubscriptionID: sub.SubscriptionID,
+			EventID:        event.EventID,
+			EventType:      event.EventType,
+			Payload:        event.Payload,
+			Status:         WebhookPending,
+			NextAttemptAt:  createdAt,
+			CreatedAt:      createdAt,
+		})
+		queued++
+	}
+	return queued, nil
+}
+
+// ClaimDeliveries implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) ClaimDeliveries(ctx context.Context, now string, leaseUntil string, limit int) ([]WebhookDispatch, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	claim := newEventID()
+	claimed := make([]WebhookDispatch, 0)
+	for _, delivery := range w.deliveries {
+		if len(claimed) == limit {
+			break
+		}
+		lease, isClaimed := w.claimedUntil[delivery.DeliveryID]
+		sub := w.subscription(delivery.SubscriptionID)
+		if delivery.Status != WebhookPending || delivery.NextAttemptAt > now || (isClaimed && lease >= now) || sub.DisabledAt.Valid {
+			continue
+		}
+		w.claimedUntil[delivery.DeliveryID] = leaseUntil
+		w.claimedBy[delivery.DeliveryID] = claim
+		claimed = append(claimed, WebhookDispatch{WebhookDelivery: delivery, URL: sub.URL, Secret: sub.Secret, ClaimedBy: claim})
+	}
+	return claimed, nil
+}
+
+// RecordAttempt implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) RecordAttempt(ctx context.Context, claimedBy string, attempt WebhookAttempt, status string, nextAttemptAt string) *errs.AppError {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delivery := w.delivery(attempt.DeliveryID)
+	if delivery == nil {
+		return errs.NotFoundErr(MsgWebhookDeliveryNotFound)
+	}
+	if w.claimedBy[attempt.DeliveryID] != claimedBy {
+		return webhookLeaseLostErr(attempt.DeliveryID)
+	}
+	attempt.AttemptID = w.nextID(2)
+	w.attempts = append(w.attempts, attempt)
+	delivery.Attempts++
+	delivery.Status = status
+	delivery.NextAttemptAt = nextAttemptAt
+	delivery.DeliveredAt = sql.NullString{}
+	if status == WebhookDelivered {
+		delivery.DeliveredAt = sql.NullString{String: attempt.AttemptedAt, Valid: true}
+	}
+	delete(w.claimedUntil, attempt.DeliveryID)
+	delete(w.claimedBy, attempt.DeliveryID)
+	return nil
+}
+
+// FindDeliveries implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) FindDeliveries(ctx context.Context, subscriptionID string, status string, limit int) ([]WebhookDelivery, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	deliveries := make([]WebhookDelivery, 0)
+	for i := len(w.deliveries) - 1; i >= 0 && len(deliveries) < limit; i-- {
+		delivery := w.deliveries[i]
+		if delivery.SubscriptionID == subscriptionID && (status == "" || delivery.Status == status) {
+			deliveries = append(deliveries, delivery)
+		}
+	}
+	return deliveries, nil
+}
+
+// FindDelivery implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) FindDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*WebhookDelivery, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delivery := w.delivery(deliveryID)
+	if delivery == nil || delivery.SubscriptionID != subscriptionID {
+		return nil, errs.NotFoundErr(MsgWebhookDeliveryNotFound)
+	}
+	found := *delivery
+	return &found, nil
+}
+
+// FindAttempts implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) FindAttempts(ctx context.Context, deliveryID string) ([]WebhookAttempt, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	attempts := make([]WebhookAttempt, 0)
+	for _, attempt := range w.attempts {
+		if attempt.DeliveryID == deliveryID {
+			attempts = append(attempts, attempt)
+		}
+	}
+	return attempts, nil
+}
+
+// Replay implementation for type WebhookRepositoryStub
+func (w *WebhookRepositoryStub) Replay(ctx context.Context, subscriptionID string, deliveryID string, now string) (*WebhookDelivery, *errs.AppError) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	delivery := w.delivery(deliveryID)
+	if delivery == nil || delivery.SubscriptionID != subscriptionID {
+		return nil, errs.NotFoundErr(MsgWebhookDeliveryNotFound)
+	}
+	if w.subscription(subscriptionID).DisabledAt.Valid {
+		return nil, webhookSubscriptionDisabledErr(subscriptionID)
+	}
+	delivery.Status = WebhookPending
+	delivery.Attempts = 0
+	delivery.NextAttemptAt = now
+	delivery.DeliveredAt = sql.NullString{}
+	delete(w.claimedUntil, deliveryID)
+	delete(w.claimedBy, deliveryID)
+	replayed := *delivery
+	return &replayed, nil
+}
+
+// subscription() returns the subscription with the given id, or nil - w.mu must be held
+func (w *WebhookRepositoryStub) subscription(subscriptionID string) *WebhookSubscription {
+	for i := range w.subscriptions {
+		if w.subscriptions[i].SubscriptionID == subscriptionID {
+			return &w.subscriptions[i]
+		}
+	}
+	return nil
+}
+
+// delivery() returns the delivery with the given id, or nil - w.mu must be held
+func (w *WebhookRepositoryStub) delivery(deliveryID string) *WebhookDelivery {
+	for i := range w.deliveries {
+		if w.deliveries[i].DeliveryID == deliveryID {
+			return &w.deliveries[i]
+		}
+	}
+	return nil
+}
+
+// queued() reports whether the event is already queued for the subscription - w.mu must be held
+func (w *WebhookRepositoryStub) queued(subscriptionID string, eventID string) bool {
+	for _, delivery := range w.deliveries {
+		if delivery.SubscriptionID == subscriptionID && delivery.EventID == eventID {
+			return true
+		}
+	}
+	return false
+}
+
+// NewWebhookRepositoryStub is a helper function which creates a new, empty webhook repository stub
+func NewWebhookRepositoryStub() *WebhookRepositoryStub {
+	return &WebhookRepositoryStub{claimedUntil: make(map[string]string), claimedBy: make(map[string]string)}
+}
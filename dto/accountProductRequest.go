@@ -0,0 +1,48 @@
+package dto
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// productCodePattern is the form of an account product code once normalised - it is sent by clients as the account_type
+// of a new account so it is kept short and free of spaces
+var productCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,31}$`)
+
+// NormalizeAccountType() returns the account product code for an account type sent by a client - "Saving " and "saving"
+// are the same product
+func NormalizeAccountType(accountType string) string {
+	return strings.ToLower(strings.TrimSpace(accountType))
+}
+
+// AccountProductRequest is a dto which provides the terms of an account product when it is created or updated - on update
+// the code is taken from the URL
+type AccountProductRequest struct {
+	Code             string  `json:"code"`
+	DisplayName      string  `json:"display_name"`
+	MinimumDeposit   float64 `json:"minimum_deposit"`
+	InterestRate     float64 `json:"interest_rate"`
+	OverdraftAllowed bool    `json:"overdraft_allowed"`
+	MonthlyFee       float64 `json:"monthly_fee"`
+}
+
+// ValidateFields() checks every field of the AccountProductRequest and returns all of the problems found
+func (req AccountProductRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(productCodePattern.MatchString(NormalizeAccountType(req.Code)), "code",
+		"error: code must start with a letter and contain at most 32 letters, digits or underscores")
+	displayName := strings.TrimSpace(req.DisplayName)
+	v.Check(displayName != "", "display_name", "error: a display name is required")
+	v.Check(len(displayName) <= 255, "display_name", "error: display name must be at most 255 characters")
+	v.Check(req.MinimumDeposit >= 0, "minimum_deposit", "error: minimum deposit must not be negative")
+	v.Check(req.InterestRate >= 0 && req.InterestRate <= 100, "interest_rate", "error: interest rate must be a percentage between 0 and 100")
+	v.Check(req.MonthlyFee >= 0, "monthly_fee", "error: monthly fee must not be negative")
+	return v.Errors()
+}
+
+// Validate() confirms that the AccountProductRequest meets all criteria for creating or updating an account product
+func (req AccountProductRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
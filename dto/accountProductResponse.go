@@ -0,0 +1,34 @@
+package dto
+
+// AccountProductResponse is an entry of the account product catalogue - InterestRate is an annual percentage rate
+type AccountProductResponse struct {
+	Code             string  `json:"code"`
+	DisplayName      string  `json:"display_name"`
+	MinimumDeposit   float64 `json:"minimum_deposit"`
+	InterestRate     float64 `json:"interest_rate"`
+	OverdraftAllowed bool    `json:"overdraft_allowed"`
+	MonthlyFee       float64 `json:"monthly_fee"`
+}
+
+// AccountProductResponseV2 is the /v2 shape of AccountProductResponse - the minimum deposit and monthly fee are money and
+// so are decimal strings with two places, the interest rate is a rate rather than money and stays a number
+type AccountProductResponseV2 struct {
+	Code             string  `json:"code"`
+	DisplayName      string  `json:"display_name"`
+	MinimumDeposit   string  `json:"minimum_deposit"`
+	InterestRate     float64 `json:"interest_rate"`
+	OverdraftAllowed bool    `json:"overdraft_allowed"`
+	MonthlyFee       string  `json:"monthly_fee"`
+}
+
+// ToV2() maps an AccountProductResponse to its /v2 shape
+func (resp AccountProductResponse) ToV2() AccountProductResponseV2 {
+	return AccountProductResponseV2{
+		Code:             resp.Code,
+		DisplayName:      resp.DisplayName,
+		MinimumDeposit:   FormatMoney(resp.MinimumDeposit),
+		InterestRate:     resp.InterestRate,
+		OverdraftAllowed: resp.OverdraftAllowed,
+		MonthlyFee:       FormatMoney(resp.MonthlyFee),
+	}
+}
@@ -0,0 +1,36 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// MaxAPIKeyLifetimeDays is the longest lifetime an API key may be created with
+const MaxAPIKeyLifetimeDays = 365
+
+// NewAPIKeyRequest is a dto which provides the name, scopes (route names) and lifetime of a new API key - an
+// ExpiresInDays of zero creates a key which does not expire
+type NewAPIKeyRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expires_in_days"`
+}
+
+// ValidateFields() checks every field of the NewAPIKeyRequest and returns all of the problems found - whether the scopes
+// name real routes is checked by the service
+func (req NewAPIKeyRequest) ValidateFields() []FieldError {
+	var v Validator
+	name := strings.TrimSpace(req.Name)
+	v.Check(name != "", "name", "error: a name is required")
+	v.Check(len(name) <= 100, "name", "error: name must be at most 100 characters")
+	v.Check(len(req.Scopes) > 0, "scopes", "error: at least one scope is required")
+	v.Check(req.ExpiresInDays >= 0 && req.ExpiresInDays <= MaxAPIKeyLifetimeDays, "expires_in_days",
+		"error: expires_in_days must be between 0 (never) and 365")
+	return v.Errors()
+}
+
+// Validate() confirms that the NewAPIKeyRequest meets all criteria for creating an API key
+func (req NewAPIKeyRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
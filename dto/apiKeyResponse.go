@@ -0,0 +1,16 @@
+package dto
+
+// APIKeyResponse is a dto which describes an API key - Key is only set in the response to creating or rotating the key,
+// as only its hash is stored
+type APIKeyResponse struct {
+	KeyID     string   `json:"key_id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	Status    string   `json:"status"`
+	CreatedBy string   `json:"created_by"`
+	CreatedAt string   `json:"created_at"`
+	ExpiresAt string   `json:"expires_at,omitempty"`
+	RevokedAt string   `json:"revoked_at,omitempty"`
+	RotatedAt string   `json:"rotated_at,omitempty"`
+	Key       string   `json:"key,omitempty"`
+}
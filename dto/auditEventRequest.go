@@ -0,0 +1,93 @@
+package dto
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// limits on the number of audit events returned by a single search
+const (
+	DefaultAuditEventLimit = 100
+	MaxAuditEventLimit     = 1000
+)
+
+// AuditEventSearchParams are the query parameters accepted by GET /audit-events
+var AuditEventSearchParams = []string{"actor", "action", "outcome", "customer_id", "from", "to", "limit", "cursor"}
+
+// AuditEventSearchRequest holds the filters and page of an audit log search as sent by the client - as with
+// CustomerSearchRequest, every field is the raw query parameter
+type AuditEventSearchRequest struct {
+	Actor      string
+	Action     string // a route name, e.g. NewTransaction
+	Outcome    string // success, denied or failure
+	CustomerID string
+	From       string // inclusive, a date (YYYY-MM-DD) or an RFC 3339 timestamp
+	To         string // exclusive, a date (YYYY-MM-DD) or an RFC 3339 timestamp
+	Limit      string
+	Cursor     string // the next_cursor of the previous page
+}
+
+// ValidateFields() checks every filter of the AuditEventSearchRequest and returns all of the problems found
+func (req AuditEventSearchRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(len(req.Actor) <= 255, "actor", "error: actor must be at most 255 characters")
+	v.Check(len(req.Action) <= 100, "action", "error: action must be at most 100 characters")
+	v.Check(req.Outcome == "" || OneOf(req.Outcome, "success", "denied", "failure"), "outcome", "error: outcome must be one of success, denied, failure")
+	v.Check(req.CustomerID == "" || isDigits(req.CustomerID), "customer_id", "error: customer_id must be a number")
+
+	from, fromErr := ParseAuditTime(req.From)
+	v.Check(fromErr == nil, "from", "error: from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+	to, toErr := ParseAuditTime(req.To)
+	v.Check(toErr == nil, "to", "error: to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+	if fromErr == nil && toErr == nil && !from.IsZero() && !to.IsZero() {
+		v.Check(from.Before(to), "from", "error: from must be earlier than to")
+	}
+
+	if req.Limit != "" {
+		limit, err := strconv.Atoi(req.Limit)
+		v.Check(err == nil && limit >= 1 && limit <= MaxAuditEventLimit, "limit", "error: limit must be a number between 1 and "+strconv.Itoa(MaxAuditEventLimit))
+	}
+	if req.Cursor != "" {
+		cursor, err := strconv.ParseInt(req.Cursor, 10, 64)
+		v.Check(err == nil && cursor > 0, "cursor", "error: cursor must be the next_cursor of the previous page")
+	}
+	return v.Errors()
+}
+
+// Validate() confirms the AuditEventSearchRequest is valid - invalid filters are the client's error (400)
+func (req AuditEventSearchRequest) Validate() *errs.AppError {
+	return ToBadRequestErr(req.ValidateFields())
+}
+
+// LimitValue() returns the page size - DefaultAuditEventLimit when no limit was sent (ValidateFields() has already
+// checked the value)
+func (req AuditEventSearchRequest) LimitValue() int {
+	limit, err := strconv.Atoi(req.Limit)
+	if err != nil {
+		return DefaultAuditEventLimit
+	}
+	return limit
+}
+
+// ParseAuditTime() parses a date (midnight, local time) or an RFC 3339 timestamp - an empty value is the zero time.Time
+func ParseAuditTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.ParseInLocation(dateLayout, value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// isDigits() reports whether value is made up of digits only
+func isDigits(value string) bool {
+	for _, c := range value {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return value != ""
+}
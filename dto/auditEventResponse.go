@@ -0,0 +1,34 @@
+package dto
+
+// AuditEventResponse is a dto which describes an entry of the audit log - Hash and PrevHash let the client check the
+// chain for itself
+type AuditEventResponse struct {
+	EntryID    string            `json:"entry_id"`
+	OccurredAt string            `json:"occurred_at"`
+	Actor      string            `json:"actor"`
+	Action     string            `json:"action"`
+	Targets    map[string]string `json:"targets"`
+	Summary    string            `json:"summary"`
+	Outcome    string            `json:"outcome"`
+	Status     string            `json:"status"`
+	RequestID  string            `json:"request_id,omitempty"`
+	PrevHash   string            `json:"prev_hash"`
+	Hash       string            `json:"hash"`
+}
+
+// AuditEventPageResponse is a page of audit events, newest first - NextCursor is empty on the last page, otherwise it is
+// sent as the cursor query parameter to fetch the next (older) page
+type AuditEventPageResponse struct {
+	Events     []AuditEventResponse `json:"events"`
+	NextCursor string               `json:"next_cursor,omitempty"`
+}
+
+// AuditVerificationResponse is the result of checking the hash chain of the audit log - BrokenAtEntryID is the first entry
+// which does not follow the entry before it, when the chain is not intact
+type AuditVerificationResponse struct {
+	Intact          bool   `json:"intact"`
+	VerifiedEntries int64  `json:"verified_entries"`
+	HeadEntryID     string `json:"head_entry_id"`
+	HeadHash        string `json:"head_hash"`
+	BrokenAtEntryID string `json:"broken_at_entry_id,omitempty"`
+}
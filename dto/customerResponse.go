@@ -9,4 +9,14 @@ type CustomerResponse struct {
 	Zipcode     string `json:"zipcode"`
 	DateofBirth string `json:"date_of_birth"`
 	Status      string `json:"status"` // if customer is active or not
+	// StatusReason and StatusChangedAt describe the last deactivation or reactivation, if any
+	StatusReason    string `json:"status_reason,omitempty"`
+	StatusChangedAt string `json:"status_changed_at,omitempty"`
+}
+
+// CustomerPageResponse is a page of customers returned by a search - NextCursor is empty on the last page, otherwise it is
+// sent as the cursor query parameter to fetch the next page
+type CustomerPageResponse struct {
+	Customers  []CustomerResponse `json:"customers"`
+	NextCursor string             `json:"next_cursor,omitempty"`
 }
@@ -0,0 +1,108 @@
+package dto
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// limits on the number of customers returned by a single search - DefaultCustomerLimit applies when no limit is sent,
+// unless the search is Unpaged
+const (
+	DefaultCustomerLimit = 50
+	MaxCustomerLimit     = 500
+)
+
+// CustomerSortFields are the fields customers can be sorted by - a leading "-" (e.g. -name) sorts in descending order
+var CustomerSortFields = []string{"customer_id", "name", "city", "zipcode", "date_of_birth"}
+
+// CustomerSearchParams are the query parameters accepted by GET /customers
+var CustomerSearchParams = []string{"status", "name", "city", "zipcode", "born_after", "born_before", "sort", "limit", "cursor"}
+
+// dateLayout is the layout of the date of birth filters, which matches the date_of_birth column
+const dateLayout = "2006-01-02"
+
+var zipcodePattern = regexp.MustCompile(`^[0-9]{1,10}$`)
+
+// CustomerSearchRequest holds the filters, sort order and page of a customer search as sent by the client - every field
+// is the raw query parameter so that ValidateFields() can report each invalid value rather than it being ignored
+type CustomerSearchRequest struct {
+	Status     string // active, inactive or empty for every customer
+	NamePrefix string
+	City       string
+	Zipcode    string
+	BornAfter  string // inclusive, YYYY-MM-DD
+	BornBefore string // inclusive, YYYY-MM-DD
+	Sort       string // one of CustomerSortFields, optionally preceded by "-"
+	Limit      string
+	Cursor     string // the next_cursor of the previous page
+	// Unpaged is set by /v1 and the unversioned routes, which returned every customer before customers were paged - a
+	// search sending neither a limit nor a cursor then still returns every matching customer
+	Unpaged bool
+}
+
+// ValidateFields() checks every filter of the CustomerSearchRequest and returns all of the problems found - the cursor is
+// opaque to the dto and is checked by the service
+func (req CustomerSearchRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(req.Status == "" || OneOf(req.Status, "active", "inactive"), "status", "error: status must be either active or inactive")
+	v.Check(len(req.NamePrefix) <= 255, "name", "error: name must be at most 255 characters")
+	v.Check(len(req.City) <= 255, "city", "error: city must be at most 255 characters")
+	v.Check(req.Zipcode == "" || zipcodePattern.MatchString(req.Zipcode), "zipcode", "error: zipcode must be up to 10 digits")
+
+	bornAfter, afterErr := parseOptionalDate(req.BornAfter)
+	v.Check(afterErr == nil, "born_after", "error: born_after must be a date (YYYY-MM-DD)")
+	bornBefore, beforeErr := parseOptionalDate(req.BornBefore)
+	v.Check(beforeErr == nil, "born_before", "error: born_before must be a date (YYYY-MM-DD)")
+	if afterErr == nil && beforeErr == nil && !bornAfter.IsZero() && !bornBefore.IsZero() {
+		v.Check(!bornAfter.After(bornBefore), "born_after", "error: born_after must not be later than born_before")
+	}
+
+	sortField, _ := req.SortField()
+	v.Check(OneOf(sortField, CustomerSortFields...), "sort", "error: sort must be one of "+strings.Join(CustomerSortFields, ", ")+" optionally preceded by -")
+	if req.Limit != "" {
+		limit, err := strconv.Atoi(req.Limit)
+		v.Check(err == nil && limit >= 1 && limit <= MaxCustomerLimit, "limit", "error: limit must be a number between 1 and "+strconv.Itoa(MaxCustomerLimit))
+	}
+	return v.Errors()
+}
+
+// Validate() confirms the CustomerSearchRequest is valid - invalid filters are the client's error (400)
+func (req CustomerSearchRequest) Validate() *errs.AppError {
+	return ToBadRequestErr(req.ValidateFields())
+}
+
+// SortField() returns the field to sort by (customer_id by default) and whether the order is descending
+func (req CustomerSearchRequest) SortField() (string, bool) {
+	if req.Sort == "" {
+		return "customer_id", false
+	}
+	if strings.HasPrefix(req.Sort, "-") {
+		return strings.ToLower(req.Sort[1:]), true
+	}
+	return strings.ToLower(req.Sort), false
+}
+
+// LimitValue() returns the page size - DefaultCustomerLimit when no limit was sent (ValidateFields() has already checked
+// the value), or 0 for every customer when an Unpaged search sent neither a limit nor a cursor
+func (req CustomerSearchRequest) LimitValue() int {
+	limit, err := strconv.Atoi(req.Limit)
+	if err != nil {
+		if req.Unpaged && req.Cursor == "" {
+			return 0
+		}
+		return DefaultCustomerLimit
+	}
+	return limit
+}
+
+// parseOptionalDate() parses a YYYY-MM-DD date - an empty value is the zero time.Time
+func parseOptionalDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	return time.Parse(dateLayout, value)
+}
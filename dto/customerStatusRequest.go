@@ -0,0 +1,27 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// CustomerStatusRequest is a dto which provides the reason a customer is being deactivated or reactivated - the reason is
+// recorded alongside the change
+type CustomerStatusRequest struct {
+	Reason string `json:"reason"`
+}
+
+// ValidateFields() checks every field of the CustomerStatusRequest and returns all of the problems found
+func (req CustomerStatusRequest) ValidateFields() []FieldError {
+	var v Validator
+	reason := strings.TrimSpace(req.Reason)
+	v.Check(reason != "", "reason", "error: a reason is required")
+	v.Check(len(reason) <= 255, "reason", "error: reason must be at most 255 characters")
+	return v.Errors()
+}
+
+// Validate() confirms that the CustomerStatusRequest meets all criteria for changing a customer's status
+func (req CustomerStatusRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
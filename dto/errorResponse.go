@@ -0,0 +1,41 @@
+package dto
+
+// ErrorResponse is the body returned with every error response, by every handler and middleware - Code is a stable,
+// machine-readable identifier (see the Err* constants) while Message is meant for humans and may change
+type ErrorResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"request_id,omitempty"`
+}
+
+// FieldError describes a problem with a single field of the request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// error codes returned in ErrorResponse.Code - once published, a code must never change meaning
+const (
+	ErrBadRequest          = "BAD_REQUEST"
+	ErrValidationFailed    = "VALIDATION_FAILED"
+	ErrInsufficientFunds   = "INSUFFICIENT_FUNDS"
+	ErrUnauthorized        = "UNAUTHORIZED"
+	ErrForbidden           = "FORBIDDEN"
+	ErrNotFound            = "NOT_FOUND"
+	ErrCustomerNotFound    = "CUSTOMER_NOT_FOUND"
+	ErrAccountNotFound     = "ACCOUNT_NOT_FOUND"
+	ErrProductNotFound     = "PRODUCT_NOT_FOUND"
+	ErrCustomerInactive    = "CUSTOMER_INACTIVE"
+	ErrCustomerUnderage    = "CUSTOMER_UNDERAGE"
+	ErrAccountLimitReached = "ACCOUNT_LIMIT_REACHED"
+	ErrConflict            = "CONFLICT"
+	ErrRouteNotFound       = "ROUTE_NOT_FOUND"
+	ErrMethodNotAllowed    = "METHOD_NOT_ALLOWED"
+	ErrBodyTooLarge        = "BODY_TOO_LARGE"
+	ErrUnsupportedMedia    = "UNSUPPORTED_MEDIA_TYPE"
+	ErrRateLimited         = "RATE_LIMITED"
+	ErrTimeout             = "TIMEOUT"
+	ErrInternal            = "INTERNAL_ERROR"
+	ErrServiceUnavailable  = "SERVICE_UNAVAILABLE"
+)
@@ -0,0 +1,16 @@
+package dto
+
+// HealthResponse is the body returned by /healthz and /readyz - Checks is only populated by /readyz and holds the status of
+// each dependency keyed by its name (e.g. database, auth)
+type HealthResponse struct {
+	Status string                        `json:"status"`
+	Checks map[string]DependencyResponse `json:"checks,omitempty"`
+}
+
+// DependencyResponse is the status of a single dependency checked by /readyz - Error is "timeout" or "unavailable" when it
+// is down, never the error of the check itself
+type DependencyResponse struct {
+	Status    string `json:"status"`
+	LatencyMs int64  `json:"latency_ms"`
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,63 @@
+package dto
+
+import (
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// LoginRequest is a dto which provides the credentials checked against the users table
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// ValidateFields() checks every field of the LoginRequest and returns all of the problems found
+func (req LoginRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(strings.TrimSpace(req.Username) != "", "username", "error: a username is required")
+	v.Check(req.Password != "", "password", "error: a password is required")
+	return v.Errors()
+}
+
+// Validate() confirms that the LoginRequest holds both credentials
+func (req LoginRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
+
+// RefreshTokenRequest is a dto which provides the refresh token returned by a login
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ValidateFields() checks every field of the RefreshTokenRequest and returns all of the problems found
+func (req RefreshTokenRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(req.RefreshToken != "", "refresh_token", "error: a refresh token is required")
+	return v.Errors()
+}
+
+// Validate() confirms that the RefreshTokenRequest holds a refresh token
+func (req RefreshTokenRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
+
+// ChangePasswordRequest is a dto which provides the current password, to confirm the caller's identity once more, and
+// the new password - the new password's policy is checked by the service
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password"`
+	NewPassword     string `json:"new_password"`
+}
+
+// ValidateFields() checks every field of the ChangePasswordRequest and returns all of the problems found
+func (req ChangePasswordRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(req.CurrentPassword != "", "current_password", "error: the current password is required")
+	v.Check(req.NewPassword != "", "new_password", "error: a new password is required")
+	return v.Errors()
+}
+
+// Validate() confirms that the ChangePasswordRequest holds both passwords
+func (req ChangePasswordRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
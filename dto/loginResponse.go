@@ -0,0 +1,11 @@
+package dto
+
+// LoginResponse holds the tokens issued by a login or a refresh - the access token is sent as "Authorization: Bearer" on
+// every other request, and the refresh token to POST /auth/refresh for a new access token once it expires (ExpiresIn
+// seconds after it was issued)
+type LoginResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
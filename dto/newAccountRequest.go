@@ -13,14 +13,29 @@ type NewAccountRequest struct {
 	Amount      float64 `json:"amount"`
 }
 
+// ValidateFields() checks every field of the NewAccountRequest and returns all of the problems found - the account type and
+// the minimum deposit depend on the account product catalogue and are checked against it by ValidateProduct()
+func (req NewAccountRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(req.Amount > 0, "amount", "error: amount must be greater than zero")
+	v.Check(NormalizeAccountType(req.AccountType) != "", "account_type", "error: an account type is required")
+	return v.Errors()
+}
+
+// ValidateProduct() checks the request against the account product it names - product is nil when the account type is not
+// in the catalogue, in which case codes lists the account types which are
+func (req NewAccountRequest) ValidateProduct(product *AccountProductResponse, codes []string) []FieldError {
+	var v Validator
+	if product == nil {
+		v.Check(false, "account_type", "error: account type must be one of "+strings.Join(codes, ", "))
+		return v.Errors()
+	}
+	v.Check(req.Amount >= product.MinimumDeposit, "amount",
+		"error: must deposit at least "+FormatMoney(product.MinimumDeposit)+" to open a "+product.DisplayName)
+	return v.Errors()
+}
+
 // Validate() confirms that the NewAccountRequest meets all criteria for creating a new account
 func (req NewAccountRequest) Validate() *errs.AppError {
-	if req.Amount < 5000.00 {
-		return errs.ValidationErr("error: must deposit at least 5,000.00 to create new account")
-	}
-	// strings.ToLower ensures that req.AccountType is all lower case when compared against "saving" or "checking"
-	if strings.ToLower(req.AccountType) != "saving" && strings.ToLower(req.AccountType) != "checking" {
-		return errs.ValidationErr("error: account type must be either saving or checking")
-	}
-	return nil
+	return ToAppError(req.ValidateFields())
 }
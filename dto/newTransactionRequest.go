@@ -6,30 +6,44 @@ import (
 	"github.com/gtaylor314/Banking-Lib/errs"
 )
 
+// MsgInsufficientFunds is the message of InsufficientFundsErr
+const MsgInsufficientFunds = "error: transaction amount must be less than or equal to account's balance"
+
+// InsufficientFundsErr is returned when a withdrawal exceeds the account's balance - the app maps the error itself, not
+// its message, to the INSUFFICIENT_FUNDS error code
+var InsufficientFundsErr = errs.ValidationErr(MsgInsufficientFunds)
+
 // NewTransactionRequest is a dto which provides customer sourced data to the domain for transaction creation
 type NewTransactionRequest struct {
+	// CustomerID is the customer the account must belong to - it is taken from the route, never from the body
+	CustomerID      string  `json:"-"`
 	AccountID       string  `json:"account_id"`
 	Amount          float64 `json:"amount"`
 	TransactionType string  `json:"transaction_type"`
 }
 
-func (req NewTransactionRequest) Validate(acctAmount float64) *errs.AppError {
-	// confirm the transaction type is either withdrawal or deposit
-	// strings.ToLower() ensures that the transaction type is all lower case prior to comparison
-	if strings.ToLower(req.TransactionType) != "withdrawal" && strings.ToLower(req.TransactionType) != "deposit" {
-		return errs.ValidationErr("error: transaction type must be either withdrawal or deposit")
-	}
-
+// ValidateFields() checks every field of the NewTransactionRequest on its own and returns all of the problems found - the
+// balance check needs the account and is made by Validate()
+func (req NewTransactionRequest) ValidateFields() []FieldError {
+	var v Validator
+	// confirm the transaction type is either withdrawal or deposit - OneOf() ignores case
+	v.Check(OneOf(req.TransactionType, "withdrawal", "deposit"), "transaction_type", "error: transaction type must be either withdrawal or deposit")
 	// confirm the transaction amount is positive (0 or greater)
-	if req.Amount < 0 {
-		return errs.ValidationErr("error: transaction amount must be zero or greater")
+	v.Check(req.Amount >= 0, "amount", "error: transaction amount must be zero or greater")
+	return v.Errors()
+}
+
+// Validate() confirms the NewTransactionRequest is valid for an account with the given balance (acctAmount)
+func (req NewTransactionRequest) Validate(acctAmount float64) *errs.AppError {
+	if appErr := ToAppError(req.ValidateFields()); appErr != nil {
+		return appErr
 	}
 
 	// confirm, if transaction type is withdrawl, that the account amount is greater than or equal to the amount of the
 	// transaction
 	if strings.ToLower(req.TransactionType) == "withdrawal" {
 		if req.Amount > acctAmount {
-			return errs.ValidationErr("error: transaction amount must be less than or equal to account's balance")
+			return InsufficientFundsErr
 		}
 	}
 
@@ -1,6 +1,30 @@
 package dto
 
+import (
+	"strconv"
+)
+
 type NewTransactionResponse struct {
 	TransactionID string  `json:"transaction_id"`
 	AcctAmount    float64 `json:"balance"`
 }
+
+// NewTransactionResponseV2 is the /v2 shape of NewTransactionResponse - the balance is a decimal string with two places
+// (e.g. "1050.00") so that clients never have to round a floating point number to display or store it
+type NewTransactionResponseV2 struct {
+	TransactionID string `json:"transaction_id"`
+	AcctAmount    string `json:"balance"`
+}
+
+// ToV2() maps a NewTransactionResponse to its /v2 shape
+func (resp NewTransactionResponse) ToV2() NewTransactionResponseV2 {
+	return NewTransactionResponseV2{
+		TransactionID: resp.TransactionID,
+		AcctAmount:    FormatMoney(resp.AcctAmount),
+	}
+}
+
+// FormatMoney() formats amount as a decimal string with two places, as money is returned by /v2
+func FormatMoney(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
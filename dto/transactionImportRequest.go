@@ -0,0 +1,114 @@
+package dto
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// TransactionImportColumns are the columns of a transaction import file - the header row names them, in any order
+var TransactionImportColumns = []string{"account_id", "amount", "transaction_type"}
+
+// utf8BOM is written at the start of CSV files by some spreadsheet programs
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// TransactionImportRequest is a CSV file of transactions to post - with DryRun set, every row is validated but none is
+// posted and the file is not recorded
+type TransactionImportRequest struct {
+	FileName string
+	Content  []byte
+	DryRun   bool
+}
+
+// TransactionImportRow is a row of a transaction import file - Line is its line in the file (the header is line 1) and
+// Errors holds the problems found while reading it, e.g. an amount which is not a number
+type TransactionImportRow struct {
+	Line    int
+	Request NewTransactionRequest
+	Errors  []string
+}
+
+// ParseTransactionCSV() reads the rows of a transaction import file - the returned field errors are problems with the
+// file as a whole (a missing column, malformed CSV, too many rows), which leave no rows to check - problems with a single
+// row are left in its Errors
+func ParseTransactionCSV(content []byte, maxRows int) ([]TransactionImportRow, []FieldError) {
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(content, utf8BOM)))
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, []FieldError{{Field: "file", Message: "error: file must have a header row naming the columns " + strings.Join(TransactionImportColumns, ", ")}}
+	}
+	if err != nil {
+		return nil, []FieldError{{Field: "file", Message: "error: " + err.Error()}}
+	}
+	columns, fieldErrs := headerColumns(header)
+	if len(fieldErrs) > 0 {
+		return nil, fieldErrs
+	}
+
+	rows := make([]TransactionImportRow, 0)
+	for {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			// a csv.ParseError names the line and column of the problem
+			return nil, []FieldError{{Field: "file", Message: "error: " + err.Error()}}
+		}
+		if len(rows) == maxRows {
+			return nil, []FieldError{{Field: "file", Message: "error: file must have at most " + strconv.Itoa(maxRows) + " rows"}}
+		}
+		line, _ := reader.FieldPos(0)
+		rows = append(rows, parseTransactionRow(line, record, columns))
+	}
+	if len(rows) == 0 {
+		return nil, []FieldError{{Field: "file", Message: "error: file must have at least one row after the header"}}
+	}
+	return rows, nil
+}
+
+// headerColumns() returns the position of each column named by the header row - the names are matched ignoring case and
+// surrounding spaces
+func headerColumns(header []string) (map[string]int, []FieldError) {
+	var v Validator
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		_, seen := columns[name]
+		v.Check(!seen, "header", "error: column "+name+" appears more than once")
+		v.Check(OneOf(name, TransactionImportColumns...), "header", "error: unknown column "+name)
+		columns[name] = i
+	}
+	for _, name := range TransactionImportColumns {
+		_, ok := columns[name]
+		v.Check(ok, "header", "error: missing column "+name)
+	}
+	return columns, v.Errors()
+}
+
+// parseTransactionRow() turns a record into a NewTransactionRequest and checks its fields on their own
+func parseTransactionRow(line int, record []string, columns map[string]int) TransactionImportRow {
+	row := TransactionImportRow{Line: line}
+	row.Request.AccountID = strings.TrimSpace(record[columns["account_id"]])
+	row.Request.TransactionType = strings.TrimSpace(record[columns["transaction_type"]])
+
+	if !isDigits(row.Request.AccountID) {
+		row.Errors = append(row.Errors, "error: account_id must be a number")
+	}
+	amount, err := strconv.ParseFloat(strings.TrimSpace(record[columns["amount"]]), 64)
+	if err != nil || math.IsNaN(amount) || math.IsInf(amount, 0) {
+		row.Errors = append(row.Errors, "error: amount must be a number")
+		return row
+	}
+	row.Request.Amount = amount
+	for _, fieldErr := range row.Request.ValidateFields() {
+		row.Errors = append(row.Errors, fieldErr.Message)
+	}
+	return row
+}
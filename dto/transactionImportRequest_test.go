@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestParseTransactionCSV() should pass when the header's columns are found in any order and case, each row keeps its
+// line in the file, and a problem with the file as a whole is reported without any rows
+func TestParseTransactionCSV(t *testing.T) {
+	// Arrange - setup test
+	tests := []struct {
+		name      string
+		content   string
+		wantLines []int
+		wantErr   string
+	}{
+		{name: "columns in any order", content: "\xEF\xBB\xBFTransaction_Type, Amount ,account_id\r\ndeposit,10.5,101\r\n\r\nwithdrawal,2,102\r\n", wantLines: []int{2, 4}},
+		{name: "missing column", content: "account_id,amount\n101,10\n", wantErr: "error: missing column transaction_type"},
+		{name: "unknown column", content: "account_id,amount,transaction_type,memo\n101,10,deposit,salary\n", wantErr: "error: unknown column memo"},
+		{name: "repeated column", content: "account_id,amount,amount,transaction_type\n", wantErr: "error: column amount appears more than once"},
+		{name: "empty file", content: "", wantErr: "error: file must have a header row"},
+		{name: "header only", content: "account_id,amount,transaction_type\n", wantErr: "error: file must have at least one row"},
+		{name: "wrong number of fields", content: "account_id,amount,transaction_type\n101,10\n", wantErr: "wrong number of fields"},
+		{name: "too many rows", content: "account_id,amount,transaction_type\n101,1,deposit\n101,2,deposit\n101,3,deposit\n", wantErr: "error: file must have at most 2 rows"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act - execute test
+			rows, fieldErrs := ParseTransactionCSV([]byte(test.content), 2)
+
+			// Assert - test expectations
+			if test.wantErr != "" {
+				if len(fieldErrs) == 0 || !strings.Contains(fieldErrs[0].Message, test.wantErr) || rows != nil {
+					t.Errorf("received %d rows and errors %+v, want no rows and %q", len(rows), fieldErrs, test.wantErr)
+				}
+				return
+			}
+			if len(fieldErrs) > 0 || len(rows) != len(test.wantLines) {
+				t.Fatalf("received %d rows and errors %+v, want %d rows", len(rows), fieldErrs, len(test.wantLines))
+			}
+			for i, row := range rows {
+				if row.Line != test.wantLines[i] || len(row.Errors) > 0 {
+					t.Errorf("received row %+v, want line %d without errors", row, test.wantLines[i])
+				}
+			}
+			if rows[0].Request != (NewTransactionRequest{AccountID: "101", Amount: 10.5, TransactionType: "deposit"}) {
+				t.Errorf("received request %+v, want the first row's transaction", rows[0].Request)
+			}
+		})
+	}
+}
+
+// TestParseTransactionCSVRowErrors() should pass when every problem with a row is kept with the row rather than failing
+// the file
+func TestParseTransactionCSVRowErrors(t *testing.T) {
+	// Arrange - setup test
+	content := "account_id,amount,transaction_type\n10a,NaN,deposit\n101,-5,refund\n"
+
+	// Act - execute test
+	rows, fieldErrs := ParseTransactionCSV([]byte(content), 10)
+
+	// Assert - test expectations
+	if len(fieldErrs) > 0 || len(rows) != 2 {
+		t.Fatalf("received %d rows and errors %+v, want 2 rows", len(rows), fieldErrs)
+	}
+	first, second := strings.Join(rows[0].Errors, ", "), strings.Join(rows[1].Errors, ", ")
+	if first != "error: account_id must be a number, error: amount must be a number" {
+		t.Errorf("received %q for line 2", first)
+	}
+	if second != "error: transaction type must be either withdrawal or deposit, error: transaction amount must be zero or greater" {
+		t.Errorf("received %q for line 3", second)
+	}
+}
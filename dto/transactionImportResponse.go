@@ -0,0 +1,39 @@
+package dto
+
+// statuses of a row of a transaction import - a row is skipped when the import was interrupted before it was posted
+const (
+	TransactionImportRowValid   = "valid"
+	TransactionImportRowInvalid = "invalid"
+	TransactionImportRowPosted  = "posted"
+	TransactionImportRowFailed  = "failed"
+	TransactionImportRowSkipped = "skipped"
+)
+
+// TransactionImportResponse reports a transaction import row by row - a dry run has no import_id and its status is valid
+// or invalid, an import's status is completed or interrupted - money is a decimal string with two places, as in /v2
+// an interrupted (or crashed) import is resumed by importing its file again - ResumedAfter is then the number of rows it
+// recorded as attempted, which are not listed in Rows, while PostedCount and FailedCount count the rows of both attempts
+type TransactionImportResponse struct {
+	ImportID     string                         `json:"import_id,omitempty"`
+	ResumedAfter int                            `json:"resumed_after,omitempty"`
+	Checksum     string                         `json:"checksum"`
+	FileName     string                         `json:"file_name,omitempty"`
+	DryRun       bool                           `json:"dry_run"`
+	Status       string                         `json:"status"`
+	RowCount     int                            `json:"row_count"`
+	PostedCount  int                            `json:"posted_count"`
+	FailedCount  int                            `json:"failed_count"`
+	Rows         []TransactionImportRowResponse `json:"rows"`
+}
+
+// TransactionImportRowResponse is the outcome of a single row - transaction_id and balance are set once it is posted
+type TransactionImportRowResponse struct {
+	Line            int      `json:"line"`
+	AccountID       string   `json:"account_id"`
+	Amount          string   `json:"amount"`
+	TransactionType string   `json:"transaction_type"`
+	Status          string   `json:"status"`
+	TransactionID   string   `json:"transaction_id,omitempty"`
+	Balance         string   `json:"balance,omitempty"`
+	Errors          []string `json:"errors,omitempty"`
+}
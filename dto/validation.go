@@ -0,0 +1,68 @@
+package dto
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// Validatable is implemented by every request dto - ValidateFields() checks the dto on its own (without touching the
+// database) and returns every problem found rather than only the first
+type Validatable interface {
+	ValidateFields() []FieldError
+}
+
+// Validator collects field errors while a dto is checked
+type Validator struct {
+	errors []FieldError
+}
+
+// Check() records message against field when ok is false
+func (v *Validator) Check(ok bool, field string, message string) {
+	if !ok {
+		v.errors = append(v.errors, FieldError{Field: field, Message: message})
+	}
+}
+
+// Errors() returns every field error recorded, or nil if the dto is valid
+func (v *Validator) Errors() []FieldError {
+	return v.errors
+}
+
+// ToAppError() turns field errors into a single validation AppError for callers which cannot return field errors (e.g. the
+// services) - the messages are joined so no problem is lost - nil is returned when there are no field errors
+func ToAppError(fieldErrs []FieldError) *errs.AppError {
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return errs.ValidationErr(joinMessages(fieldErrs))
+}
+
+// ToBadRequestErr() is ToAppError() for requests whose problems are reported as a bad request (400) rather than a failed
+// validation (422) - e.g. invalid query parameters
+func ToBadRequestErr(fieldErrs []FieldError) *errs.AppError {
+	if len(fieldErrs) == 0 {
+		return nil
+	}
+	return &errs.AppError{Code: http.StatusBadRequest, Message: joinMessages(fieldErrs)}
+}
+
+// joinMessages() joins the messages of fieldErrs so no problem is lost
+func joinMessages(fieldErrs []FieldError) string {
+	messages := make([]string, 0, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		messages = append(messages, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// OneOf() reports whether value matches one of options, ignoring case
+func OneOf(value string, options ...string) bool {
+	for _, option := range options {
+		if strings.EqualFold(value, option) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package dto
+
+import (
+	"net/url"
+	"strconv"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+)
+
+// limits on the number of webhook deliveries returned by a single listing
+const (
+	DefaultWebhookDeliveryLimit = 50
+	MaxWebhookDeliveryLimit     = 500
+)
+
+// WebhookDeliverySearchParams are the query parameters accepted by GET /webhooks/{subscription_id}/deliveries
+var WebhookDeliverySearchParams = []string{"status", "limit"}
+
+// NewWebhookSubscriptionRequest is a dto which provides the URL, event types and secret of a new webhook subscription - an
+// empty Secret has the service generate one
+type NewWebhookSubscriptionRequest struct {
+	URL        string   `json:"url"`
+	EventTypes []string `json:"event_types"`
+	Secret     string   `json:"secret"`
+}
+
+// ValidateFields() checks every field of the NewWebhookSubscriptionRequest and returns all of the problems found - whether
+// the event types are real is checked by the service
+func (req NewWebhookSubscriptionRequest) ValidateFields() []FieldError {
+	var v Validator
+	u, err := url.Parse(req.URL)
+	v.Check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "", "url", "error: url must be an absolute http or https URL")
+	v.Check(len(req.URL) <= 2048, "url", "error: url must be at most 2048 characters")
+	v.Check(len(req.EventTypes) > 0, "event_types", "error: at least one event type is required")
+	seen := map[string]bool{}
+	for _, eventType := range req.EventTypes {
+		v.Check(!seen[eventType], "event_types", "error: "+eventType+" is listed more than once")
+		seen[eventType] = true
+	}
+	// a short secret would make the signatures guessable
+	v.Check(req.Secret == "" || (len(req.Secret) >= 16 && len(req.Secret) <= 128), "secret",
+		"error: secret must be between 16 and 128 characters, or absent to have one generated")
+	return v.Errors()
+}
+
+// Validate() confirms that the NewWebhookSubscriptionRequest meets all criteria for creating a subscription
+func (req NewWebhookSubscriptionRequest) Validate() *errs.AppError {
+	return ToAppError(req.ValidateFields())
+}
+
+// WebhookDeliverySearchRequest holds the filters of a listing of a subscription's deliveries - as with
+// AuditEventSearchRequest, every field is the raw query parameter
+type WebhookDeliverySearchRequest struct {
+	Status string // pending, delivered or failed
+	Limit  string
+}
+
+// ValidateFields() checks every filter of the WebhookDeliverySearchRequest and returns all of the problems found
+func (req WebhookDeliverySearchRequest) ValidateFields() []FieldError {
+	var v Validator
+	v.Check(req.Status == "" || OneOf(req.Status, "pending", "delivered", "failed"), "status", "error: status must be one of pending, delivered, failed")
+	if req.Limit != "" {
+		limit, err := strconv.Atoi(req.Limit)
+		v.Check(err == nil && limit >= 1 && limit <= MaxWebhookDeliveryLimit, "limit", "error: limit must be a number between 1 and "+strconv.Itoa(MaxWebhookDeliveryLimit))
+	}
+	return v.Errors()
+}
+
+// Validate() confirms the WebhookDeliverySearchRequest is valid - invalid filters are the client's error (400)
+func (req WebhookDeliverySearchRequest) Validate() *errs.AppError {
+	return ToBadRequestErr(req.ValidateFields())
+}
+
+// LimitValue() returns the number of deliveries to list - DefaultWebhookDeliveryLimit when no limit was sent
+// (ValidateFields() has already checked the value)
+func (req WebhookDeliverySearchRequest) LimitValue() int {
+	limit, err := strconv.Atoi(req.Limit)
+	if err != nil {
+		return DefaultWebhookDeliveryLimit
+	}
+	return limit
+}
@@ -0,0 +1,43 @@
+package dto
+
+import "encoding/json"
+
+// WebhookSubscriptionResponse is a dto which describes a webhook subscription - Secret is only set in the response to
+// creating the subscription
+type WebhookSubscriptionResponse struct {
+	SubscriptionID string   `json:"subscription_id"`
+	URL            string   `json:"url"`
+	EventTypes     []string `json:"event_types"`
+	Status         string   `json:"status"`
+	CreatedBy      string   `json:"created_by"`
+	CreatedAt      string   `json:"created_at"`
+	DisabledAt     string   `json:"disabled_at,omitempty"`
+	Secret         string   `json:"secret,omitempty"`
+}
+
+// WebhookDeliveryResponse is a dto which describes the delivery of an event to a subscription - Payload is the body
+// POSTed to the subscriber, NextAttemptAt is only set while the delivery is pending, and AttemptLog is only set when the
+// delivery is read on its own
+type WebhookDeliveryResponse struct {
+	DeliveryID     string                   `json:"delivery_id"`
+	SubscriptionID string                   `json:"subscription_id"`
+	EventID        string                   `json:"event_id"`
+	EventType      string                   `json:"event_type"`
+	Status         string                   `json:"status"`
+	Attempts       int                      `json:"attempts"`
+	NextAttemptAt  string                   `json:"next_attempt_at,omitempty"`
+	CreatedAt      string                   `json:"created_at"`
+	DeliveredAt    string                   `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage          `json:"payload"`
+	AttemptLog     []WebhookAttemptResponse `json:"attempt_log,omitempty"`
+}
+
+// WebhookAttemptResponse is a dto which describes one attempt to deliver - StatusCode is 0 when the subscriber did not
+// respond
+type WebhookAttemptResponse struct {
+	AttemptID   string `json:"attempt_id"`
+	AttemptedAt string `json:"attempted_at"`
+	StatusCode  int    `json:"status_code"`
+	Error       string `json:"error,omitempty"`
+	DurationMs  int64  `json:"duration_ms"`
+}
@@ -0,0 +1,118 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/logger"
+
+	"go.uber.org/zap"
+)
+
+// RequestIDHeader is the header used to receive and return the correlation ID of a request
+const RequestIDHeader = "X-Request-ID"
+
+// contextKey is unexported so that no other package can collide with the keys stored in the context
+type contextKey struct{}
+
+// requestInfo holds the per-request values attached to every log line - subject is filled in by the auth middleware after
+// the logging middleware has placed requestInfo in the context, so it is guarded by a mutex
+type requestInfo struct {
+	requestID string
+	mu        sync.Mutex
+	subject   string
+}
+
+// NewContext() returns a copy of ctx carrying requestID - the logging middleware calls it once per request
+func NewContext(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, contextKey{}, &requestInfo{requestID: requestID})
+}
+
+// RequestID() returns the request ID carried by ctx, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		return info.requestID
+	}
+	return ""
+}
+
+// SetSubject() records the authenticated subject (e.g. the username from the token) for the request carried by ctx
+func SetSubject(ctx context.Context, subject string) {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		info.mu.Lock()
+		info.subject = subject
+		info.mu.Unlock()
+	}
+}
+
+// Subject() returns the authenticated subject recorded for the request carried by ctx, if any
+func Subject(ctx context.Context) string {
+	if info, ok := ctx.Value(contextKey{}).(*requestInfo); ok {
+		info.mu.Lock()
+		defer info.mu.Unlock()
+		return info.subject
+	}
+	return ""
+}
+
+// detachedContext carries the values of parent (the request ID and subject, the trace span) but not its deadline or
+// cancellation
+type detachedContext struct {
+	parent context.Context
+}
+
+func (c detachedContext) Deadline() (time.Time, bool)       { return time.Time{}, false }
+func (c detachedContext) Done() <-chan struct{}             { return nil }
+func (c detachedContext) Err() error                        { return nil }
+func (c detachedContext) Value(key interface{}) interface{} { return c.parent.Value(key) }
+
+// Detach() returns a context carrying the values of ctx but not its deadline or cancellation - for the work which must
+// finish, and still be logged and traced as part of the request, once the request timed out or the client went away
+func Detach(ctx context.Context) context.Context {
+	return detachedContext{parent: ctx}
+}
+
+// NewRequestID() returns a random 128-bit request ID encoded as 32 hex characters
+func NewRequestID() string {
+	b := make([]byte, 16)
+	// Read() only fails if the operating system's random source is unavailable
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
+
+// ValidRequestID() reports whether a client supplied request ID is safe to reuse - it must be 1 to 64 characters made up
+// of letters, digits, dashes and underscores so that it cannot inject anything into our logs or response headers
+func ValidRequestID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		isAlphaNum := (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+		if !isAlphaNum && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
+
+// Info() logs message at the InfoLevel with the request ID carried by ctx added to fields
+func Info(ctx context.Context, message string, fields ...zap.Field) {
+	logger.Info(message, withRequestID(ctx, fields)...)
+}
+
+// Error() logs message at the ErrorLevel with the request ID carried by ctx added to fields
+func Error(ctx context.Context, message string, fields ...zap.Field) {
+	logger.Error(message, withRequestID(ctx, fields)...)
+}
+
+func withRequestID(ctx context.Context, fields []zap.Field) []zap.Field {
+	if requestID := RequestID(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	return fields
+}
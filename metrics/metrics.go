@@ -0,0 +1,206 @@
+package metrics
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// namespace prefixes every metric exported by the service (e.g. banking_http_requests_total)
+const namespace = "banking"
+
+// registry holds every collector exposed on /metrics - we use our own registry rather than the global default so that only
+// the metrics below (plus the Go runtime and process collectors) are exported
+var registry = prometheus.NewRegistry()
+
+var (
+	// httpRequests counts the requests served, labelled by mux route name (e.g. GetAllCustomers), method and status code
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "Number of HTTP requests served, by mux route name, method and status code.",
+	}, []string{"route", "method", "status"})
+
+	// deprecatedRequests counts the requests served by the deprecated, unversioned routes so that remaining clients can be
+	// chased before the routes are removed
+	deprecatedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_deprecated_requests_total",
+		Help:      "Number of HTTP requests served by deprecated routes, by mux route name.",
+	}, []string{"route"})
+
+	// httpDuration records how long each request took to serve
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests, by mux route name, method and status code.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"route", "method", "status"})
+
+	// authDuration records the latency of each call to the auth server, labelled by outcome (authorized, denied, error)
+	authDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "auth_request_duration_seconds",
+		Help:      "Latency of token verification calls to the auth server, by outcome.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"outcome"})
+
+	// authFailures counts the calls to the auth server which could not be completed (as opposed to a denied token)
+	authFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "auth_request_failures_total",
+		Help:      "Number of token verification calls to the auth server which failed, by reason.",
+	}, []string{"reason"})
+
+	// rateLimited counts the requests refused by the rate limiting middleware, by mux route name
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_rate_limited_requests_total",
+		Help:      "Number of HTTP requests refused with 429 by the rate limiter, by mux route name.",
+	}, []string{"route"})
+
+	// auditWriteFailures counts the audit entries which could not be appended to the audit log, by action
+	auditWriteFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "audit_write_failures_total",
+		Help:      "Number of audit entries which could not be appended to the audit log, by action.",
+	}, []string{"action"})
+
+	// outboxPublished counts the outbox events published by the relay and outboxFailures the attempts which failed, by event
+	// type
+	outboxPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_events_published_total",
+		Help:      "Number of outbox events published by the relay, by event type.",
+	}, []string{"event_type"})
+	outboxFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "outbox_publish_failures_total",
+		Help:      "Number of attempts to publish an outbox event which failed and will be retried, by event type.",
+	}, []string{"event_type"})
+
+	// webhookAttempts counts the attempts to deliver to a webhook subscription, by event type and outcome (delivered,
+	// retry or failed)
+	webhookAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "webhook_delivery_attempts_total",
+		Help:      "Number of attempts to deliver an event to a webhook subscription, by event type and outcome.",
+	}, []string{"event_type", "outcome"})
+
+	// accountsOpened counts the accounts opened and accountsOpenedAmount sums their opening deposits, by account type
+	accountsOpened = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "accounts_opened_total",
+		Help:      "Number of accounts opened, by account type.",
+	}, []string{"account_type"})
+	accountsOpenedAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "accounts_opened_amount_total",
+		Help:      "Sum of the opening deposits of accounts opened, by account type.",
+	}, []string{"account_type"})
+
+	// transactions counts the transactions posted and transactionsAmount sums their amounts, by transaction type (deposit
+	// or withdrawal) and account type
+	transactions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_total",
+		Help:      "Number of transactions posted, by transaction type and account type.",
+	}, []string{"transaction_type", "account_type"})
+	transactionsAmount = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transactions_amount_total",
+		Help:      "Sum of the amounts of transactions posted, by transaction type and account type.",
+	}, []string{"transaction_type", "account_type"})
+)
+
+// init() registers every collector with the registry once at startup
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests, httpDuration, deprecatedRequests, rateLimited,
+		authDuration, authFailures, auditWriteFailures,
+		outboxPublished, outboxFailures, webhookAttempts,
+		accountsOpened, accountsOpenedAmount,
+		transactions, transactionsAmount,
+	)
+}
+
+// Handler() returns the http.Handler which serves the registry in the Prometheus exposition format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// RegisterDBStats() exports the connection pool statistics of db (sql.DB.Stats()) - open, in use and idle connections,
+// waits and closed connections - labelled with dbName
+func RegisterDBStats(db *sql.DB, dbName string) {
+	registry.MustRegister(collectors.NewDBStatsCollector(db, dbName))
+}
+
+// ObserveHTTPRequest() records a served request
+func ObserveHTTPRequest(route string, method string, status string, elapsed time.Duration) {
+	httpRequests.WithLabelValues(route, method, status).Inc()
+	httpDuration.WithLabelValues(route, method, status).Observe(elapsed.Seconds())
+}
+
+// DeprecatedRequest() records a request served by a deprecated route
+func DeprecatedRequest(route string) {
+	deprecatedRequests.WithLabelValues(route).Inc()
+}
+
+// RateLimited() records a request refused by the rate limiter
+func RateLimited(route string) {
+	rateLimited.WithLabelValues(route).Inc()
+}
+
+// AuditWriteFailed() records an audit entry for action which could not be appended to the audit log
+func AuditWriteFailed(action string) {
+	auditWriteFailures.WithLabelValues(action).Inc()
+}
+
+// OutboxPublished() records an outbox event of eventType published by the relay
+func OutboxPublished(eventType string) {
+	outboxPublished.WithLabelValues(eventType).Inc()
+}
+
+// OutboxPublishFailed() records a failed attempt to publish an outbox event of eventType
+func OutboxPublishFailed(eventType string) {
+	outboxFailures.WithLabelValues(eventType).Inc()
+}
+
+// WebhookAttempt() records an attempt to deliver an event of eventType to a webhook subscription - outcome is delivered,
+// retry (the delivery will be attempted again) or failed (the delivery was given up)
+func WebhookAttempt(eventType string, outcome string) {
+	webhookAttempts.WithLabelValues(eventType, outcome).Inc()
+}
+
+// ObserveAuthRequest() records a call to the auth server - outcome is authorized, denied or error
+func ObserveAuthRequest(outcome string, elapsed time.Duration) {
+	authDuration.WithLabelValues(outcome).Observe(elapsed.Seconds())
+}
+
+// AuthFailure() records a call to the auth server which could not be completed - reason is a short, fixed description
+// (e.g. request, decode) so that the label's cardinality stays small
+func AuthFailure(reason string) {
+	authFailures.WithLabelValues(reason).Inc()
+}
+
+// AccountOpened() records an account opened with its opening deposit
+func AccountOpened(accountType string, amount float64) {
+	accountType = strings.ToLower(accountType)
+	accountsOpened.WithLabelValues(accountType).Inc()
+	accountsOpenedAmount.WithLabelValues(accountType).Add(amount)
+}
+
+// TransactionPosted() records a deposit or withdrawal posted to an account of the given type
+func TransactionPosted(transactionType string, accountType string, amount float64) {
+	transactionType = strings.ToLower(transactionType)
+	accountType = strings.ToLower(accountType)
+	transactions.WithLabelValues(transactionType, accountType).Inc()
+	transactionsAmount.WithLabelValues(transactionType, accountType).Add(amount)
+}
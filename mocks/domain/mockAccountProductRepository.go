@@ -0,0 +1,111 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/gtaylor314/Banking-MS/domain (interfaces: AccountProductRepository)
+
+// Package domain is a generated GoMock package.
+package domain
+
+import (
+	context "context"
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	errs "github.com/gtaylor314/Banking-Lib/errs"
+	domain "github.com/gtaylor314/Banking-MS/domain"
+)
+
+// MockAccountProductRepository is a mock of AccountProductRepository interface.
+type MockAccountProductRepository struct {
+	ctrl     *gomock.Controller
+	recorder *MockAccountProductRepositoryMockRecorder
+}
+
+// MockAccountProductRepositoryMockRecorder is the mock recorder for MockAccountProductRepository.
+type MockAccountProductRepositoryMockRecorder struct {
+	mock *MockAccountProductRepository
+}
+
+// NewMockAccountProductRepository creates a new mock instance.
+func NewMockAccountProductRepository(ctrl *gomock.Controller) *MockAccountProductRepository {
+	mock := &MockAccountProductRepository{ctrl: ctrl}
+	mock.recorder = &MockAccountProductRepositoryMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAccountProductRepository) EXPECT() *MockAccountProductRepositoryMockRecorder {
+	return m.recorder
+}
+
+// Delete mocks base method.
+func (m *MockAccountProductRepository) Delete(arg0 context.Context, arg1 string) *errs.AppError {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Delete", arg0, arg1)
+	ret0, _ := ret[0].(*errs.AppError)
+	return ret0
+}
+
+// Delete indicates an expected call of Delete.
+func (mr *MockAccountProductRepositoryMockRecorder) Delete(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Delete", reflect.TypeOf((*MockAccountProductRepository)(nil).Delete), arg0, arg1)
+}
+
+// FindAll mocks base method.
+func (m *MockAccountProductRepository) FindAll(arg0 context.Context) ([]domain.AccountProduct, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindAll", arg0)
+	ret0, _ := ret[0].([]domain.AccountProduct)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// FindAll indicates an expected call of FindAll.
+func (mr *MockAccountProductRepositoryMockRecorder) FindAll(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindAll", reflect.TypeOf((*MockAccountProductRepository)(nil).FindAll), arg0)
+}
+
+// FindByCode mocks base method.
+func (m *MockAccountProductRepository) FindByCode(arg0 context.Context, arg1 string) (*domain.AccountProduct, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindByCode", arg0, arg1)
+	ret0, _ := ret[0].(*domain.AccountProduct)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// FindByCode indicates an expected call of FindByCode.
+func (mr *MockAccountProductRepositoryMockRecorder) FindByCode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindByCode", reflect.TypeOf((*MockAccountProductRepository)(nil).FindByCode), arg0, arg1)
+}
+
+// Save mocks base method.
+func (m *MockAccountProductRepository) Save(arg0 context.Context, arg1 domain.AccountProduct) (*domain.AccountProduct, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Save", arg0, arg1)
+	ret0, _ := ret[0].(*domain.AccountProduct)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// Save indicates an expected call of Save.
+func (mr *MockAccountProductRepositoryMockRecorder) Save(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAccountProductRepository)(nil).Save), arg0, arg1)
+}
+
+// Update mocks base method.
+func (m *MockAccountProductRepository) Update(arg0 context.Context, arg1 domain.AccountProduct) (*domain.AccountProduct, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Update", arg0, arg1)
+	ret0, _ := ret[0].(*domain.AccountProduct)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// Update indicates an expected call of Update.
+func (mr *MockAccountProductRepositoryMockRecorder) Update(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Update", reflect.TypeOf((*MockAccountProductRepository)(nil).Update), arg0, arg1)
+}
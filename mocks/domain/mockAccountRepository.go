@@ -5,11 +5,12 @@
 package domain
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	domain "github.com/gtaylor314/Banking-MS/domain"
 	errs "github.com/gtaylor314/Banking-Lib/errs"
+	domain "github.com/gtaylor314/Banking-MS/domain"
 )
 
 // MockAccountRepository is a mock of AccountRepository interface.
@@ -36,16 +37,16 @@ func (m *MockAccountRepository) EXPECT() *MockAccountRepositoryMockRecorder {
 }
 
 // Save mocks base method.
-func (m *MockAccountRepository) Save(arg0 domain.Account) (*domain.Account, *errs.AppError) {
+func (m *MockAccountRepository) Save(arg0 context.Context, arg1 domain.Account, arg2 domain.AccountCheck) (*domain.Account, *errs.AppError) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Save", arg0)
+	ret := m.ctrl.Call(m, "Save", arg0, arg1, arg2)
 	ret0, _ := ret[0].(*domain.Account)
 	ret1, _ := ret[1].(*errs.AppError)
 	return ret0, ret1
 }
 
 // Save indicates an expected call of Save.
-func (mr *MockAccountRepositoryMockRecorder) Save(arg0 interface{}) *gomock.Call {
+func (mr *MockAccountRepositoryMockRecorder) Save(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAccountRepository)(nil).Save), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Save", reflect.TypeOf((*MockAccountRepository)(nil).Save), arg0, arg1, arg2)
 }
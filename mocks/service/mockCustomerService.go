@@ -5,11 +5,12 @@
 package service
 
 import (
+	context "context"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
-	dto "github.com/gtaylor314/Banking-MS/dto"
 	errs "github.com/gtaylor314/Banking-Lib/errs"
+	dto "github.com/gtaylor314/Banking-MS/dto"
 )
 
 // MockCustomerService is a mock of CustomerService interface.
@@ -35,32 +36,62 @@ func (m *MockCustomerService) EXPECT() *MockCustomerServiceMockRecorder {
 	return m.recorder
 }
 
+// DeactivateCustomer mocks base method.
+func (m *MockCustomerService) DeactivateCustomer(arg0 context.Context, arg1 string, arg2 dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeactivateCustomer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*dto.CustomerResponse)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// DeactivateCustomer indicates an expected call of DeactivateCustomer.
+func (mr *MockCustomerServiceMockRecorder) DeactivateCustomer(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeactivateCustomer", reflect.TypeOf((*MockCustomerService)(nil).DeactivateCustomer), arg0, arg1, arg2)
+}
+
 // GetAllCustomers mocks base method.
-func (m *MockCustomerService) GetAllCustomers(arg0 string) ([]dto.CustomerResponse, *errs.AppError) {
+func (m *MockCustomerService) GetAllCustomers(arg0 context.Context, arg1 dto.CustomerSearchRequest) (*dto.CustomerPageResponse, *errs.AppError) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetAllCustomers", arg0)
-	ret0, _ := ret[0].([]dto.CustomerResponse)
+	ret := m.ctrl.Call(m, "GetAllCustomers", arg0, arg1)
+	ret0, _ := ret[0].(*dto.CustomerPageResponse)
 	ret1, _ := ret[1].(*errs.AppError)
 	return ret0, ret1
 }
 
 // GetAllCustomers indicates an expected call of GetAllCustomers.
-func (mr *MockCustomerServiceMockRecorder) GetAllCustomers(arg0 interface{}) *gomock.Call {
+func (mr *MockCustomerServiceMockRecorder) GetAllCustomers(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllCustomers", reflect.TypeOf((*MockCustomerService)(nil).GetAllCustomers), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllCustomers", reflect.TypeOf((*MockCustomerService)(nil).GetAllCustomers), arg0, arg1)
 }
 
 // GetCustomer mocks base method.
-func (m *MockCustomerService) GetCustomer(arg0 string) (*dto.CustomerResponse, *errs.AppError) {
+func (m *MockCustomerService) GetCustomer(arg0 context.Context, arg1 string) (*dto.CustomerResponse, *errs.AppError) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetCustomer", arg0)
+	ret := m.ctrl.Call(m, "GetCustomer", arg0, arg1)
 	ret0, _ := ret[0].(*dto.CustomerResponse)
 	ret1, _ := ret[1].(*errs.AppError)
 	return ret0, ret1
 }
 
 // GetCustomer indicates an expected call of GetCustomer.
-func (mr *MockCustomerServiceMockRecorder) GetCustomer(arg0 interface{}) *gomock.Call {
+func (mr *MockCustomerServiceMockRecorder) GetCustomer(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomer", reflect.TypeOf((*MockCustomerService)(nil).GetCustomer), arg0, arg1)
+}
+
+// ReactivateCustomer mocks base method.
+func (m *MockCustomerService) ReactivateCustomer(arg0 context.Context, arg1 string, arg2 dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReactivateCustomer", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*dto.CustomerResponse)
+	ret1, _ := ret[1].(*errs.AppError)
+	return ret0, ret1
+}
+
+// ReactivateCustomer indicates an expected call of ReactivateCustomer.
+func (mr *MockCustomerServiceMockRecorder) ReactivateCustomer(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCustomer", reflect.TypeOf((*MockCustomerService)(nil).GetCustomer), arg0)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReactivateCustomer", reflect.TypeOf((*MockCustomerService)(nil).ReactivateCustomer), arg0, arg1, arg2)
 }
@@ -0,0 +1,45 @@
+// Package openapi holds the OpenAPI 3 document describing the Banking-MS HTTP API - the document is embedded in the binary,
+// served on /openapi.json and checked against the real handlers by the conformance tests in the app package
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+)
+
+// Spec is the OpenAPI document (openapi.json) - any change to a route, request, or response must be reflected here or the
+// conformance tests fail
+//
+//go:embed openapi.json
+var Spec []byte
+
+// RouteNames() returns the route name of every operation in Spec - the x-route-name extension of a versioned operation,
+// or else its operationId
+func RouteNames() (map[string]bool, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(Spec, &doc); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, items := range doc.Paths {
+		for _, item := range items {
+			var operation struct {
+				OperationID string `json:"operationId"`
+				RouteName   string `json:"x-route-name"`
+			}
+			// path items may also hold fields which are not operations (e.g. a parameters list) - they are skipped
+			if json.Unmarshal(item, &operation) != nil {
+				continue
+			}
+			switch {
+			case operation.RouteName != "":
+				names[operation.RouteName] = true
+			case operation.OperationID != "":
+				names[operation.OperationID] = true
+			}
+		}
+	}
+	return names, nil
+}
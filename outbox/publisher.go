@@ -0,0 +1,189 @@
+// Package outbox holds the relay which publishes the domain events written to the outbox_events table, and the
+// publishers it delivers them through - a Publisher only has to deliver one message at a time, the relay takes care of
+// claiming, retrying and marking the events as published
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+)
+
+// Message is the envelope an event is published in - EventID is the same on every delivery of an event, so that a
+// consumer can ignore an event it has already seen
+type Message struct {
+	EventID     string          `json:"event_id"`
+	EventType   string          `json:"event_type"`
+	AggregateID string          `json:"aggregate_id"`
+	OccurredAt  string          `json:"occurred_at"`
+	Payload     json.RawMessage `json:"payload"`
+}
+
+// NewMessage() returns the message publishing event
+func NewMessage(event domain.OutboxEvent) Message {
+	return Message{
+		EventID:     event.EventID,
+		EventType:   event.EventType,
+		AggregateID: event.AggregateID,
+		OccurredAt:  event.OccurredAt,
+		Payload:     json.RawMessage(event.Payload),
+	}
+}
+
+// Publisher delivers messages to the systems subscribed to the events - a nil error means the message was delivered,
+// any error has the relay try again later
+type Publisher interface {
+	Publish(ctx context.Context, msg Message) error
+}
+
+// WriterPublisher writes each message to w as a line of JSON
+type WriterPublisher struct {
+	// mu keeps the lines of messages published at once from interleaving
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Publish implementation for type WriterPublisher
+func (p *WriterPublisher) Publish(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	_, err = p.w.Write(append(line, '\n'))
+	return err
+}
+
+// NewWriterPublisher() returns a publisher writing to w (e.g. os.Stdout)
+func NewWriterPublisher(w io.Writer) *WriterPublisher {
+	return &WriterPublisher{w: w}
+}
+
+// FilePublisher appends each message to a file as a line of JSON - the file is synced after every line, so that a
+// message is on disk before the event is marked as published
+type FilePublisher struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+// Publish implementation for type FilePublisher
+func (p *FilePublisher) Publish(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, err := p.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return p.file.Sync()
+}
+
+// Close() closes the file
+func (p *FilePublisher) Close() error {
+	return p.file.Close()
+}
+
+// NewFilePublisher() opens the file at path for appending, creating it if needed
+func NewFilePublisher(path string) (*FilePublisher, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return nil, err
+	}
+	return &FilePublisher{file: file}, nil
+}
+
+// WebhookPublisher POSTs each message as JSON to a URL - the event's ID and type are also sent in the X-Event-ID and
+// X-Event-Type headers, and any 2xx response counts as delivered
+type WebhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// Publish implementation for type WebhookPublisher
+func (p WebhookPublisher) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("X-Event-ID", msg.EventID)
+	request.Header.Set("X-Event-Type", msg.EventType)
+
+	response, err := p.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	// the body is drained so that the connection can be reused
+	_, _ = io.Copy(io.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with status %d", response.StatusCode)
+	}
+	return nil
+}
+
+// NewWebhookPublisher() returns a publisher POSTing to url which gives up on a request after timeout
+func NewWebhookPublisher(url string, timeout time.Duration) WebhookPublisher {
+	return WebhookPublisher{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// SubscriptionPublisher queues a delivery of each message for every webhook subscription to its type - the deliveries
+// are made, signed and retried by the webhook worker, so the message is published once it is queued
+type SubscriptionPublisher struct {
+	repo domain.WebhookRepository
+}
+
+// Publish implementation for type SubscriptionPublisher
+func (p SubscriptionPublisher) Publish(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	event := domain.WebhookEvent{EventID: msg.EventID, EventType: msg.EventType, Payload: string(body)}
+	if _, appErr := p.repo.Enqueue(ctx, event, time.Now().Format(timeLayout)); appErr != nil {
+		return errors.New(appErr.Message)
+	}
+	return nil
+}
+
+// NewSubscriptionPublisher() returns a publisher queueing deliveries in repo
+func NewSubscriptionPublisher(repo domain.WebhookRepository) SubscriptionPublisher {
+	return SubscriptionPublisher{repo: repo}
+}
+
+// NewPublisher() returns the publisher selected by cfg.Publisher - webhooks holds the subscriptions of the subscriptions
+// publisher - the file publisher holds its file open, so the returned publisher should be closed when it implements
+// io.Closer
+func NewPublisher(cfg config.OutboxConfig, webhooks domain.WebhookRepository) (Publisher, error) {
+	switch cfg.Publisher {
+	case config.OutboxPublisherSubscriptions:
+		return NewSubscriptionPublisher(webhooks), nil
+	case config.OutboxPublisherStdout:
+		return NewWriterPublisher(os.Stdout), nil
+	case config.OutboxPublisherFile:
+		return NewFilePublisher(cfg.FilePath)
+	case config.OutboxPublisherWebhook:
+		return NewWebhookPublisher(cfg.WebhookURL, cfg.WebhookTimeout.Std()), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", cfg.Publisher)
+	}
+}
@@ -0,0 +1,156 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/domain"
+)
+
+// testMessage is the message published by the publisher tests
+var testMessage = Message{
+	EventID:     "0123456789abcdef0123456789abcdef",
+	EventType:   "TransactionPosted",
+	AggregateID: "95471",
+	OccurredAt:  "2026-10-19 09:00:00",
+	Payload:     json.RawMessage(`{"transaction_id":"1","amount":50}`),
+}
+
+// TestWebhookPublisher() should pass when the message is POSTed as JSON with its ID and type in the headers, and a non 2xx
+// response is an error
+func TestWebhookPublisher(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		wantErr bool
+	}{
+		{"accepted", http.StatusAccepted, false},
+		{"server error", http.StatusServiceUnavailable, true},
+		{"redirect not followed", http.StatusNotModified, true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Arrange - setup test
+			var received Message
+			var header http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				header = r.Header
+				body, _ := io.ReadAll(r.Body)
+				_ = json.Unmarshal(body, &received)
+				w.WriteHeader(test.status)
+			}))
+			defer server.Close()
+			publisher := NewWebhookPublisher(server.URL, time.Second)
+
+			// Act - execute test
+			err := publisher.Publish(context.Background(), testMessage)
+
+			// Assert - test expectations
+			if (err != nil) != test.wantErr {
+				t.Fatalf("received error %v, want error %t", err, test.wantErr)
+			}
+			if header.Get("X-Event-ID") != testMessage.EventID || header.Get("X-Event-Type") != testMessage.EventType ||
+				header.Get("Content-Type") != "application/json" {
+				t.Errorf("received headers %v", header)
+			}
+			if received.EventID != testMessage.EventID || string(received.Payload) != string(testMessage.Payload) {
+				t.Errorf("received message %+v, want %+v", received, testMessage)
+			}
+		})
+	}
+}
+
+// TestWebhookPublisherTimeout() should pass when a webhook which does not answer in time is an error
+func TestWebhookPublisherTimeout(t *testing.T) {
+	// Arrange - setup test
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer server.Close()
+	defer close(release)
+	publisher := NewWebhookPublisher(server.URL, 50*time.Millisecond)
+
+	// Act - execute test
+	err := publisher.Publish(context.Background(), testMessage)
+
+	// Assert - test expectations
+	if err == nil {
+		t.Error("expected an error from a webhook which timed out")
+	}
+}
+
+// TestFilePublisher() should pass when each message is appended to the file as a line of JSON, keeping the lines
+// already there
+func TestFilePublisher(t *testing.T) {
+	// Arrange - setup test
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	if err := os.WriteFile(path, []byte("{}\n"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	publisher, err := NewFilePublisher(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Act - execute test
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), testMessage); err != nil {
+			t.Fatalf("publish received %v", err)
+		}
+	}
+	publisher.Close()
+
+	// Assert - test expectations
+	content, _ := os.ReadFile(path)
+	lines := strings.Split(strings.TrimSuffix(string(content), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != "{}" {
+		t.Fatalf("received lines %q, want 3", lines)
+	}
+	var published Message
+	if err := json.Unmarshal([]byte(lines[2]), &published); err != nil || published.EventID != testMessage.EventID {
+		t.Errorf("received line %q (error %v), want the message", lines[2], err)
+	}
+}
+
+// TestSubscriptionPublisher() should pass when a message is queued once for each active subscription to its type, even
+// when it is published again
+func TestSubscriptionPublisher(t *testing.T) {
+	// Arrange - setup test
+	repo := domain.NewWebhookRepositoryStub()
+	for _, eventTypes := range []string{"TransactionPosted", "AccountOpened", "AccountOpened,TransactionPosted"} {
+		sub := domain.WebhookSubscription{URL: "https://partner.example/hooks", EventTypes: eventTypes, Secret: "whsec_0123456789abcdef"}
+		if _, appErr := repo.SaveSubscription(context.Background(), sub); appErr != nil {
+			t.Fatal(appErr.Message)
+		}
+	}
+	publisher := NewSubscriptionPublisher(repo)
+
+	// Act - execute test
+	for i := 0; i < 2; i++ {
+		if err := publisher.Publish(context.Background(), testMessage); err != nil {
+			t.Fatalf("publish received %v", err)
+		}
+	}
+
+	// Assert - test expectations
+	first, _ := repo.FindDeliveries(context.Background(), "1", "", 10)
+	second, _ := repo.FindDeliveries(context.Background(), "2", "", 10)
+	third, _ := repo.FindDeliveries(context.Background(), "3", "", 10)
+	if len(first) != 1 || len(second) != 0 || len(third) != 1 {
+		t.Fatalf("received %d, %d and %d deliveries, want 1, 0 and 1", len(first), len(second), len(third))
+	}
+	var queued Message
+	if err := json.Unmarshal([]byte(first[0].Payload), &queued); err != nil || queued.EventID != testMessage.EventID ||
+		first[0].EventID != testMessage.EventID || first[0].Status != domain.WebhookPending {
+		t.Errorf("received delivery %+v (error %v), want the message pending", first[0], err)
+	}
+}
@@ -0,0 +1,147 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout of the DATETIME columns of the outbox_events table
+const timeLayout = "2006-01-02 15:04:05"
+
+// maxErrorLength is the size of the outbox_events.last_error column
+const maxErrorLength = 1024
+
+// purgeInterval is how often the relay removes the published events older than the retention
+const purgeInterval = time.Hour
+
+// Relay publishes the events in the outbox - an event is marked as published only once the publisher has delivered it,
+// so an event is delivered at least once: a relay stopping between the two delivers it again once the event's lease ends
+type Relay struct {
+	repo      domain.OutboxRepository
+	publisher Publisher
+	cfg       config.OutboxConfig
+	// now is time.Now outside of tests
+	now func() time.Time
+}
+
+// RelayOnce() claims a batch of due events and publishes them one by one, oldest first, and returns how many were
+// claimed - an event which fails is retried after a backoff without holding up the events after it, so events are not
+// guaranteed to be delivered in order - the batch is leased for Lease, which validation keeps longer than BatchSize events
+// each taking WebhookTimeout to publish
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	now := r.now()
+	events, appErr := r.repo.Claim(ctx, now.Format(timeLayout), now.Add(r.cfg.Lease.Std()).Format(timeLayout), r.cfg.BatchSize)
+	if appErr != nil {
+		return 0, errors.New(appErr.Message)
+	}
+	for _, event := range events {
+		r.publish(ctx, event)
+	}
+	return len(events), nil
+}
+
+// publish() publishes event and records the outcome
+func (r *Relay) publish(ctx context.Context, event domain.OutboxEvent) {
+	ctx, span := tracing.Start(ctx, "Relay.publish", trace.WithAttributes(
+		tracing.Attr("event.type", event.EventType), tracing.Attr("event.id", event.EventID)))
+	defer span.End()
+
+	err := r.publisher.Publish(ctx, NewMessage(event))
+	if err == nil {
+		metrics.OutboxPublished(event.EventType)
+		if appErr := r.repo.MarkPublished(ctx, event.ID, event.ClaimedBy.String, r.now().Format(timeLayout)); appErr != nil {
+			// the event is published again once its lease ends - or, when the lease had already ended, its outcome is
+			// recorded by the relay which claimed it next
+			logging.Error(ctx, "error while marking outbox event as published", zap.String("event_id", event.EventID),
+				zap.String("error", appErr.Message))
+		}
+		return
+	}
+
+	tracing.RecordError(span, err)
+	metrics.OutboxPublishFailed(event.EventType)
+	logging.Error(ctx, "error while publishing outbox event", zap.String("event_id", event.EventID),
+		zap.String("event_type", event.EventType), zap.Int("attempts", event.Attempts+1), zap.Error(err))
+	lastError := err.Error()
+	if len(lastError) > maxErrorLength {
+		lastError = lastError[:maxErrorLength]
+	}
+	nextAttemptAt := r.now().Add(r.backoff(event.Attempts)).Format(timeLayout)
+	if appErr := r.repo.MarkFailed(ctx, event.ID, event.ClaimedBy.String, nextAttemptAt, lastError); appErr != nil {
+		logging.Error(ctx, "error while marking outbox event as failed", zap.String("event_id", event.EventID),
+			zap.String("error", appErr.Message))
+	}
+}
+
+// backoff() returns the delay before the next attempt of an event which has already failed attempts times - 1s, 2s, 4s
+// and so on, up to MaxBackoff
+func (r *Relay) backoff(attempts int) time.Duration {
+	maxBackoff := r.cfg.MaxBackoff.Std()
+	// beyond 2^30s the delay is past any sensible MaxBackoff, and shifting further would overflow
+	if attempts > 30 {
+		return maxBackoff
+	}
+	if delay := time.Second << uint(attempts); delay < maxBackoff {
+		return delay
+	}
+	return maxBackoff
+}
+
+// Purge() removes the events published more than Retention ago - nothing is removed when Retention is zero
+func (r *Relay) Purge(ctx context.Context) (int64, error) {
+	if r.cfg.Retention.Std() <= 0 {
+		return 0, nil
+	}
+	purged, appErr := r.repo.PurgePublished(ctx, r.now().Add(-r.cfg.Retention.Std()).Format(timeLayout))
+	if appErr != nil {
+		return 0, errors.New(appErr.Message)
+	}
+	return purged, nil
+}
+
+// Run() relays events until ctx is cancelled - a full batch is followed at once by the next one, otherwise the relay
+// waits PollInterval before claiming again
+func (r *Relay) Run(ctx context.Context) {
+	lastPurge := time.Time{}
+	for {
+		if r.now().Sub(lastPurge) >= purgeInterval {
+			if purged, err := r.Purge(ctx); err != nil {
+				logging.Error(ctx, "error while purging published outbox events", zap.Error(err))
+			} else if purged > 0 {
+				logging.Info(ctx, "purged published outbox events", zap.Int64("purged", purged))
+			}
+			lastPurge = r.now()
+		}
+
+		claimed, err := r.RelayOnce(ctx)
+		if err != nil {
+			logging.Error(ctx, "error while relaying outbox events", zap.Error(err))
+		}
+		if err == nil && claimed == r.cfg.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(r.cfg.PollInterval.Std()):
+		}
+	}
+}
+
+// NewRelay() returns a relay publishing the events in repo through publisher as configured by cfg
+func NewRelay(repo domain.OutboxRepository, publisher Publisher, cfg config.OutboxConfig) *Relay {
+	return &Relay{repo: repo, publisher: publisher, cfg: cfg, now: time.Now}
+}
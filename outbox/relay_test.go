@@ -0,0 +1,135 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+)
+
+// flakyPublisher fails the first failures messages it is given and records every message it delivers
+type flakyPublisher struct {
+	failures  int
+	err       error
+	delivered []Message
+}
+
+// Publish implementation for type flakyPublisher
+func (p *flakyPublisher) Publish(ctx context.Context, msg Message) error {
+	if p.failures > 0 {
+		p.failures--
+		return p.err
+	}
+	p.delivered = append(p.delivered, msg)
+	return nil
+}
+
+// newTestRelay() returns a relay over a stub holding one AccountOpened event, whose clock is read from *now
+func newTestRelay(publisher Publisher, now *time.Time) (*Relay, *domain.OutboxRepositoryStub) {
+	repo := domain.NewOutboxRepositoryStub()
+	repo.Add(domain.NewAccountOpenedEvent(domain.Account{
+		AccountID: "95471", CustomerID: "2000", AccountType: "saving", Amount: 6000, OpeningDate: "2026-10-19 09:00:00",
+	}))
+	cfg := config.Default().Outbox
+	cfg.MaxBackoff = config.Duration(4 * time.Second)
+	relay := NewRelay(repo, publisher, cfg)
+	relay.now = func() time.Time { return *now }
+	return relay, repo
+}
+
+// TestRelayRetriesWithBackoff() should pass when a failed event is retried after 1s, 2s, 4s and then no more than the
+// maximum backoff, and is marked as published once delivered
+func TestRelayRetriesWithBackoff(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	publisher := &flakyPublisher{failures: 4, err: errors.New("connection refused")}
+	relay, repo := newTestRelay(publisher, &now)
+
+	// Act - execute test
+	var retries []string
+	for i := 0; i < 5; i++ {
+		if claimed, err := relay.RelayOnce(context.Background()); err != nil || claimed != 1 {
+			t.Fatalf("attempt %d claimed %d events (error %v), want 1", i+1, claimed, err)
+		}
+		event := repo.Events()[0]
+		if event.PublishedAt.Valid {
+			break
+		}
+		retries = append(retries, event.NextAttemptAt)
+		// nothing is due before the next attempt
+		if claimed, _ := relay.RelayOnce(context.Background()); claimed != 0 {
+			t.Fatalf("claimed %d events before the next attempt, want 0", claimed)
+		}
+		now, _ = time.ParseInLocation(timeLayout, event.NextAttemptAt, time.Local)
+	}
+
+	// Assert - test expectations
+	want := []string{"2026-10-19 09:00:01", "2026-10-19 09:00:03", "2026-10-19 09:00:07", "2026-10-19 09:00:11"}
+	if strings.Join(retries, ",") != strings.Join(want, ",") {
+		t.Errorf("received retries at %v, want %v", retries, want)
+	}
+	event := repo.Events()[0]
+	if !event.PublishedAt.Valid || event.Attempts != 4 || event.LastError.Valid {
+		t.Errorf("received event %+v, want it published after 4 failed attempts", event)
+	}
+	if len(publisher.delivered) != 1 || publisher.delivered[0].EventID != event.EventID ||
+		string(publisher.delivered[0].Payload) != event.Payload {
+		t.Errorf("received deliveries %+v, want the event once", publisher.delivered)
+	}
+}
+
+// TestRelayLease() should pass when a claimed event is not handed to a second relay until its lease ends, and is then
+// delivered again - the first relay having stopped before marking it as published, and being refused when it does so
+// after the second relay claimed the event
+func TestRelayLease(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	publisher := &flakyPublisher{}
+	relay, repo := newTestRelay(publisher, &now)
+	lease := relay.cfg.Lease.Std()
+	stopped, appErr := repo.Claim(context.Background(), now.Format(timeLayout), now.Add(lease).Format(timeLayout), 10)
+	if appErr != nil || len(stopped) != 1 {
+		t.Fatalf("claim received %d events and %v, want 1", len(stopped), appErr)
+	}
+
+	// Act - execute test
+	during, _ := relay.RelayOnce(context.Background())
+	now = now.Add(lease + time.Second)
+	after, _ := relay.RelayOnce(context.Background())
+	// the first relay comes back after its lease ended and tries to record a failure of its own
+	lateErr := repo.MarkFailed(context.Background(), stopped[0].ID, stopped[0].ClaimedBy.String, now.Format(timeLayout), "timeout")
+
+	// Assert - test expectations
+	if during != 0 || after != 1 || len(publisher.delivered) != 1 {
+		t.Errorf("claimed %d events during the lease and %d after it, want 0 and 1", during, after)
+	}
+	if !repo.Events()[0].PublishedAt.Valid || repo.Events()[0].Attempts != 0 {
+		t.Error("expected the event to be published once the lease ended")
+	}
+	if lateErr == nil || lateErr.Code != http.StatusConflict {
+		t.Errorf("received %v recording the first relay's outcome, want 409", lateErr)
+	}
+}
+
+// TestRelayPurge() should pass when only the events published more than the retention ago are removed
+func TestRelayPurge(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	relay, repo := newTestRelay(&flakyPublisher{}, &now)
+	relay.RelayOnce(context.Background())
+	repo.Add(domain.NewAccountOpenedEvent(domain.Account{AccountID: "95472", OpeningDate: "2026-10-19 09:00:00"}))
+
+	// Act - execute test
+	now = now.Add(relay.cfg.Retention.Std() + time.Second)
+	purged, err := relay.Purge(context.Background())
+
+	// Assert - test expectations
+	if err != nil || purged != 1 || len(repo.Events()) != 1 || repo.Events()[0].AggregateID != "95472" {
+		t.Errorf("purged %d events (error %v) leaving %+v, want only the unpublished event left", purged, err, repo.Events())
+	}
+}
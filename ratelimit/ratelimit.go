@@ -0,0 +1,112 @@
+// Package ratelimit holds the token buckets behind the rate limiting middleware - the buckets are kept in a Store, in
+// memory by default (MemoryStore), so that a store shared between instances (e.g. Redis) can be swapped in later
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Limit is the size and refill rate of a token bucket - a client may make Burst requests at once and Rate requests per
+// second after that
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Decision is the outcome of taking a token - when the request is not allowed RetryAfter is the time until the bucket
+// holds a token again
+type Decision struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration
+}
+
+// Store is the "port" the rate limiting middleware takes tokens through - an implementation must apply the refill and
+// the take of a single call atomically, as requests with the same key may arrive at once
+type Store interface {
+	// Take takes a token from the bucket at key, which is created full and refills according to limit
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// bucket is a token bucket as of updated
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// refill() adds the tokens earned since the bucket was last updated, up to its burst
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.updated).Seconds()*b.limit.Rate)
+	b.updated = now
+}
+
+// refused() is the decision for a bucket holding less than a token - RetryAfter is the time until it holds one again
+func (b *bucket) refused() Decision {
+	wait := (1 - b.tokens) / b.limit.Rate
+	return Decision{Allowed: false, RetryAfter: time.Duration(wait * float64(time.Second))}
+}
+
+// sweepInterval is how often MemoryStore drops the buckets which have refilled - a full bucket is the same as no bucket
+const sweepInterval = time.Minute
+
+// MemoryStore is a Store held in the memory of a single instance - with several instances behind a load balancer each
+// instance enforces the limits on its own share of the requests
+type MemoryStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	// now is time.Now outside of tests
+	now func() time.Time
+}
+
+// Take implementation for type MemoryStore
+func (m *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	b := m.bucket(key, limit)
+	if b.tokens < 1 {
+		return b.refused(), nil
+	}
+	b.tokens--
+	return Decision{Allowed: true, Remaining: int(b.tokens)}, nil
+}
+
+// bucket() returns the bucket at key, created full and refilled as of now - the caller holds mu
+func (m *MemoryStore) bucket(key string, limit Limit) *bucket {
+	now := m.now()
+	m.sweep(now)
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	// the limit of a key may change with the configuration, the bucket always follows the latest
+	b.limit = limit
+	b.refill(now)
+	return b
+}
+
+// sweep() drops the buckets which would be full by now, at most once per sweepInterval, so that clients seen once do not
+// stay in memory - the caller holds mu
+func (m *MemoryStore) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < sweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, b := range m.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+}
+
+// NewMemoryStore() returns an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{buckets: make(map[string]*bucket), now: time.Now}
+}
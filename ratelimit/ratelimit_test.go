@@ -0,0 +1,73 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// newTestStore() returns a MemoryStore whose clock is advanced by the test
+func newTestStore(now *time.Time) *MemoryStore {
+	store := NewMemoryStore()
+	store.now = func() time.Time { return *now }
+	return store
+}
+
+// TestMemoryStoreTake() should pass when a bucket allows its burst at once, refuses the next request until a token has
+// refilled and reports how long that takes, and keys do not share buckets
+func TestMemoryStoreTake(t *testing.T) {
+	// Arrange - setup test
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	limit := Limit{Rate: 0.5, Burst: 2}
+	ctx := context.Background()
+
+	// Act - execute test
+	first, _ := store.Take(ctx, "client-a", limit)
+	second, _ := store.Take(ctx, "client-a", limit)
+	refused, _ := store.Take(ctx, "client-a", limit)
+	otherKey, _ := store.Take(ctx, "client-b", limit)
+	now = now.Add(time.Second)
+	stillRefused, _ := store.Take(ctx, "client-a", limit)
+	now = now.Add(time.Second)
+	refilled, _ := store.Take(ctx, "client-a", limit)
+
+	// Assert - test expectations
+	if !first.Allowed || first.Remaining != 1 || !second.Allowed || second.Remaining != 0 {
+		t.Errorf("received %+v and %+v, want the burst of 2 allowed", first, second)
+	}
+	if refused.Allowed || refused.RetryAfter != 2*time.Second {
+		t.Errorf("received %+v, want a refusal retrying after 2s", refused)
+	}
+	if !otherKey.Allowed {
+		t.Error("expected another key to have its own bucket")
+	}
+	if stillRefused.Allowed || stillRefused.RetryAfter != time.Second {
+		t.Errorf("received %+v, want a refusal retrying after 1s", stillRefused)
+	}
+	if !refilled.Allowed {
+		t.Errorf("received %+v, want a token once 2s have passed", refilled)
+	}
+}
+
+// TestMemoryStoreSweep() should pass when a bucket which has refilled is dropped while one still refilling is kept
+func TestMemoryStoreSweep(t *testing.T) {
+	// Arrange - setup test
+	now := time.Date(2026, time.June, 1, 12, 0, 0, 0, time.UTC)
+	store := newTestStore(&now)
+	ctx := context.Background()
+	store.Take(ctx, "fast", Limit{Rate: 1, Burst: 1})
+	store.Take(ctx, "slow", Limit{Rate: 0.001, Burst: 1})
+
+	// Act - execute test
+	now = now.Add(sweepInterval)
+	store.Take(ctx, "other", Limit{Rate: 1, Burst: 1})
+
+	// Assert - test expectations
+	if _, ok := store.buckets["fast"]; ok {
+		t.Error("expected the refilled bucket to be dropped")
+	}
+	if _, ok := store.buckets["slow"]; !ok {
+		t.Error("expected the bucket still refilling to be kept")
+	}
+}
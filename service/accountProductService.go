@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// AccountProductService is a "port" implemented by the domain - it manages the account product catalogue
+type AccountProductService interface {
+	GetAllProducts(context.Context) ([]dto.AccountProductResponse, *errs.AppError)
+	GetProduct(context.Context, string) (*dto.AccountProductResponse, *errs.AppError)
+	NewProduct(context.Context, dto.AccountProductRequest) (*dto.AccountProductResponse, *errs.AppError)
+	UpdateProduct(context.Context, dto.AccountProductRequest) (*dto.AccountProductResponse, *errs.AppError)
+	DeleteProduct(context.Context, string) *errs.AppError
+}
+
+// DefaultAccountProductService is an "adapter" that implements the AccountProductService "port"
+type DefaultAccountProductService struct {
+	repo domain.AccountProductRepository
+}
+
+// GetAllProducts() returns the whole catalogue ordered by code
+func (d DefaultAccountProductService) GetAllProducts(ctx context.Context) ([]dto.AccountProductResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAccountProductService.GetAllProducts")
+	defer span.End()
+
+	products, err := d.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp := make([]dto.AccountProductResponse, 0, len(products))
+	for _, product := range products {
+		resp = append(resp, product.ToDto())
+	}
+	return resp, nil
+}
+
+// GetProduct() returns the product with the given code - the code is normalised as the account_type of a new account is
+func (d DefaultAccountProductService) GetProduct(ctx context.Context, code string) (*dto.AccountProductResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAccountProductService.GetProduct", trace.WithAttributes(tracing.Attr("code", code)))
+	defer span.End()
+
+	product, err := d.repo.FindByCode(ctx, dto.NormalizeAccountType(code))
+	if err != nil {
+		return nil, err
+	}
+	resp := product.ToDto()
+	return &resp, nil
+}
+
+// NewProduct() adds a product to the catalogue
+func (d DefaultAccountProductService) NewProduct(ctx context.Context, req dto.AccountProductRequest) (*dto.AccountProductResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAccountProductService.NewProduct", trace.WithAttributes(tracing.Attr("code", req.Code)))
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	product, err := d.repo.Save(ctx, productFromRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	resp := product.ToDto()
+	return &resp, nil
+}
+
+// UpdateProduct() replaces the terms of an existing product - accounts already opened under it are not affected by a change
+// of the minimum deposit
+func (d DefaultAccountProductService) UpdateProduct(ctx context.Context, req dto.AccountProductRequest) (*dto.AccountProductResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAccountProductService.UpdateProduct", trace.WithAttributes(tracing.Attr("code", req.Code)))
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	product, err := d.repo.Update(ctx, productFromRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	resp := product.ToDto()
+	return &resp, nil
+}
+
+// DeleteProduct() removes a product which no account is held under
+func (d DefaultAccountProductService) DeleteProduct(ctx context.Context, code string) *errs.AppError {
+	ctx, span := tracing.Start(ctx, "DefaultAccountProductService.DeleteProduct", trace.WithAttributes(tracing.Attr("code", code)))
+	defer span.End()
+
+	return d.repo.Delete(ctx, dto.NormalizeAccountType(code))
+}
+
+// productFromRequest() maps a validated AccountProductRequest to a domain.AccountProduct with a normalised code
+func productFromRequest(req dto.AccountProductRequest) domain.AccountProduct {
+	return domain.AccountProduct{
+		Code:             dto.NormalizeAccountType(req.Code),
+		DisplayName:      strings.TrimSpace(req.DisplayName),
+		MinimumDeposit:   req.MinimumDeposit,
+		InterestRate:     req.InterestRate,
+		OverdraftAllowed: req.OverdraftAllowed,
+		MonthlyFee:       req.MonthlyFee,
+	}
+}
+
+// NewAccountProductService() takes an account product repository and returns a DefaultAccountProductService
+func NewAccountProductService(repo domain.AccountProductRepository) DefaultAccountProductService {
+	return DefaultAccountProductService{repo: repo}
+}
@@ -0,0 +1,166 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gtaylor314/Banking-Lib/errs"
+	realdomain "github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/mocks/domain"
+)
+
+// premiumRequest is a valid request for a product, sent with the stray spaces and capitals a client may use
+var premiumRequest = dto.AccountProductRequest{
+	Code:           " Premium_Saving ",
+	DisplayName:    "  Premium Savings ",
+	MinimumDeposit: 25000,
+	InterestRate:   2.5,
+	MonthlyFee:     10,
+}
+
+// premiumProduct is premiumRequest as the repository receives it
+var premiumProduct = realdomain.AccountProduct{
+	Code:           "premium_saving",
+	DisplayName:    "Premium Savings",
+	MinimumDeposit: 25000,
+	InterestRate:   2.5,
+	MonthlyFee:     10,
+}
+
+// TestNewProduct() should pass when the code and display name are normalised before the product is saved, an invalid
+// request is refused with a 422 before Save() is called, and a code already in use is reported as a conflict
+func TestNewProduct(t *testing.T) {
+	invalid := premiumRequest
+	invalid.Code = "premium saving"
+	conflict := &errs.AppError{Code: http.StatusConflict, Message: "account product premium_saving already exists"}
+	tests := []struct {
+		name     string
+		req      dto.AccountProductRequest
+		saveErr  *errs.AppError
+		wantSave bool
+		wantCode int
+	}{
+		{"normalised", premiumRequest, nil, true, 0},
+		{"invalid code", invalid, nil, false, http.StatusUnprocessableEntity},
+		{"duplicate code", premiumRequest, conflict, true, http.StatusConflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			ctrl := gomock.NewController(t)
+			productRepo := domain.NewMockAccountProductRepository(ctrl)
+			// Save() has no expectation when the request is invalid - a call to it fails the test
+			if test.wantSave {
+				saved := premiumProduct
+				productRepo.EXPECT().Save(gomock.Any(), premiumProduct).Return(&saved, test.saveErr)
+			}
+			productService := NewAccountProductService(productRepo)
+
+			// Act - execute test
+			resp, appErr := productService.NewProduct(context.Background(), test.req)
+
+			// Assert - test expectations
+			if test.wantCode != 0 {
+				if appErr == nil || appErr.Code != test.wantCode {
+					t.Errorf("received %v, want %d", appErr, test.wantCode)
+				}
+				return
+			}
+			if appErr != nil {
+				t.Fatalf("unexpected error %q", appErr.Message)
+			}
+			if resp.Code != "premium_saving" || resp.DisplayName != "Premium Savings" {
+				t.Errorf("received %+v, want the normalised product", *resp)
+			}
+		})
+	}
+}
+
+// TestUpdateProduct() should pass when the product is updated under its normalised code, an invalid request is refused
+// before Update() is called and an unknown product is a 404
+func TestUpdateProduct(t *testing.T) {
+	invalid := premiumRequest
+	invalid.InterestRate = 101
+	tests := []struct {
+		name       string
+		req        dto.AccountProductRequest
+		updateErr  *errs.AppError
+		wantUpdate bool
+		wantCode   int
+	}{
+		{"normalised", premiumRequest, nil, true, 0},
+		{"invalid interest rate", invalid, nil, false, http.StatusUnprocessableEntity},
+		{"unknown product", premiumRequest, realdomain.ProductNotFoundErr, true, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			ctrl := gomock.NewController(t)
+			productRepo := domain.NewMockAccountProductRepository(ctrl)
+			if test.wantUpdate {
+				updated := premiumProduct
+				productRepo.EXPECT().Update(gomock.Any(), premiumProduct).Return(&updated, test.updateErr)
+			}
+			productService := NewAccountProductService(productRepo)
+
+			// Act - execute test
+			resp, appErr := productService.UpdateProduct(context.Background(), test.req)
+
+			// Assert - test expectations
+			if test.wantCode != 0 {
+				if appErr == nil || appErr.Code != test.wantCode {
+					t.Errorf("received %v, want %d", appErr, test.wantCode)
+				}
+				return
+			}
+			if appErr != nil || resp.Code != "premium_saving" {
+				t.Errorf("received %v, %v, want the updated product", resp, appErr)
+			}
+		})
+	}
+}
+
+// TestDeleteProduct() should pass when the product is deleted under its normalised code, and a product still held by
+// accounts or an unknown product is reported by the repository's error
+func TestDeleteProduct(t *testing.T) {
+	inUse := &errs.AppError{Code: http.StatusConflict, Message: "account product saving is held by existing accounts"}
+	tests := []struct {
+		name      string
+		code      string
+		deleteErr *errs.AppError
+		wantCode  int
+	}{
+		{"normalised", " Premium_Saving", nil, 0},
+		{"in use", "saving", inUse, http.StatusConflict},
+		{"unknown product", "loan", realdomain.ProductNotFoundErr, http.StatusNotFound},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			ctrl := gomock.NewController(t)
+			productRepo := domain.NewMockAccountProductRepository(ctrl)
+			productRepo.EXPECT().Delete(gomock.Any(), dto.NormalizeAccountType(test.code)).Return(test.deleteErr)
+			productService := NewAccountProductService(productRepo)
+
+			// Act - execute test
+			appErr := productService.DeleteProduct(context.Background(), test.code)
+
+			// Assert - test expectations
+			if test.wantCode == 0 && appErr != nil {
+				t.Errorf("unexpected error %q", appErr.Message)
+			}
+			if test.wantCode != 0 && (appErr == nil || appErr.Code != test.wantCode) {
+				t.Errorf("received %v, want %d", appErr, test.wantCode)
+			}
+		})
+	}
+}
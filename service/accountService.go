@@ -1,42 +1,111 @@
 package service
 
 import (
+	"context"
+	"time"
+
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/domain"
 	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // AccountService is a "port" implemented by the domain
 type AccountService interface {
-	NewAccount(dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError)
+	NewAccount(context.Context, dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError)
 }
 
 // DefaultAccountService is an "adapter" that implements the AccountService "port"
 type DefaultAccountService struct {
 	repo domain.AccountRepository
+	// custRepo is used to check the customer exists and may open new accounts
+	custRepo domain.CustomerRepository
+	// productRepo holds the account types which may be opened and their minimum deposits
+	productRepo domain.AccountProductRepository
+	// policy limits the accounts a customer may open
+	policy domain.AccountPolicy
 }
 
 // NewAccount() takes an account request dto, populates the account object
-func (d DefaultAccountService) NewAccount(req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
+func (d DefaultAccountService) NewAccount(ctx context.Context, req dto.NewAccountRequest) (*dto.NewAccountResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAccountService.NewAccount", trace.WithAttributes(tracing.Attr("customer_id", req.CustomerID)))
+	defer span.End()
+
 	// validate the incoming request
 	err := req.Validate()
 	if err != nil {
 		return nil, err
 	}
-	// create account
-	acct := domain.NewAccountNoID(req.CustomerID, req.AccountType, req.Amount)
-	// Save() inserts the account into the accounts table and returns an account object with the AccountID which auto-generated
-	// upon insert
-	account, err := d.repo.Save(acct)
+	product, err := d.findProduct(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	// create account - the account type is stored as the product's code whatever casing the client sent
+	acct := domain.NewAccountNoID(req.CustomerID, product.Code, req.Amount)
+	// the customer is checked before saving so that a missing customer is a 404 rather than a foreign key failure
+	check, err := d.policyCheck(ctx, acct)
 	if err != nil {
 		return nil, err
 	}
+	// Save() inserts the account into the accounts table, once the account policy allows it, and returns an account object
+	// with the AccountID which auto-generated upon insert
+	account, err := d.repo.Save(ctx, acct, check)
+	if err != nil {
+		return nil, err
+	}
+	metrics.AccountOpened(account.AccountType, account.Amount)
 
 	// the account object needs to be converted to a dto prior to responding to the user
 	return account.ToNewAccountResponseDto(), nil
 }
 
-// NewAccountService() takes an account repository and creates a new DefaultAccountService
-func NewAccountService(repo domain.AccountRepository) DefaultAccountService {
-	return DefaultAccountService{repo: repo}
+// findProduct() returns the account product named by the request's account type - an account type which is not in the
+// catalogue, or an amount below the product's minimum deposit, fails validation
+func (d DefaultAccountService) findProduct(ctx context.Context, req dto.NewAccountRequest) (*domain.AccountProduct, *errs.AppError) {
+	// the catalogue is small, so it is read whole - the codes are listed in the error when the account type is unknown
+	products, err := d.productRepo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	code := dto.NormalizeAccountType(req.AccountType)
+	codes := make([]string, 0, len(products))
+	for _, product := range products {
+		if product.Code == code {
+			productResp := product.ToDto()
+			if appErr := dto.ToAppError(req.ValidateProduct(&productResp, nil)); appErr != nil {
+				return nil, appErr
+			}
+			return &product, nil
+		}
+		codes = append(codes, product.Code)
+	}
+	return nil, dto.ToAppError(req.ValidateProduct(nil, codes))
+}
+
+// policyCheck() returns an error if the customer of acct does not exist or has been deactivated - otherwise it returns the
+// check of the account policy passed to Save(), which counts the customer's accounts while no other may be opened for them
+func (d DefaultAccountService) policyCheck(ctx context.Context, acct domain.Account) (domain.AccountCheck, *errs.AppError) {
+	cust, err := d.custRepo.FindById(ctx, acct.CustomerID)
+	if err != nil {
+		return nil, err
+	}
+	// a deactivated customer keeps their existing accounts but cannot open new ones
+	if !cust.IsActive() {
+		return nil, domain.CustomerInactiveErr
+	}
+	// the opening date is the time the account is created
+	openedAt, _ := time.ParseInLocation("2006-01-02 15:04:05", acct.OpeningDate, time.Local)
+	return func(openAccounts int) *errs.AppError {
+		return d.policy.Check(*cust, openAccounts, openedAt)
+	}, nil
+}
+
+// NewAccountService() takes an account repository, a customer repository, an account product repository and the account
+// policy and creates a new DefaultAccountService
+func NewAccountService(repo domain.AccountRepository, custRepo domain.CustomerRepository,
+	productRepo domain.AccountProductRepository, policy domain.AccountPolicy) DefaultAccountService {
+	return DefaultAccountService{repo: repo, custRepo: custRepo, productRepo: productRepo, policy: policy}
 }
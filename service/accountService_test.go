@@ -1,6 +1,9 @@
 package service
 
 import (
+	"context"
+	"net/http"
+	"sync"
 	"testing"
 	"time"
 
@@ -13,6 +16,11 @@ import (
 )
 
 var mockRepo *domain.MockAccountRepository
+var mockCustRepo *domain.MockCustomerRepository
+
+// testPolicy and activeCustomer are shared by the account service tests - activeCustomer meets testPolicy
+var testPolicy = realdomain.AccountPolicy{MaxPerType: 2, MinimumAge: 18}
+var activeCustomer = realdomain.Customer{ID: "1", DateofBirth: "1990-01-01", Status: realdomain.CustomerActive}
 var service AccountService
 
 // setup() sets up the mock controller, mock repository, account service and resets the service to nil
@@ -21,8 +29,11 @@ func setup(t *testing.T) func() {
 	ctrl := gomock.NewController(t)
 	// create a mock repository
 	mockRepo = domain.NewMockAccountRepository(ctrl)
+	// create a mock customer repository - unless a test says otherwise, the customer exists, is active and is an adult
+	mockCustRepo = domain.NewMockCustomerRepository(ctrl)
+	mockCustRepo.EXPECT().FindById(gomock.Any(), gomock.Any()).Return(&activeCustomer, nil).AnyTimes()
 	// create a service
-	service = NewAccountService(mockRepo)
+	service = NewAccountService(mockRepo, mockCustRepo, realdomain.NewAccountProductRepositoryStub(), testPolicy)
 	// returned function resets the service back to nil
 	return func() {
 		service = nil
@@ -43,13 +54,13 @@ func TestNewAccountValidationErr(t *testing.T) {
 	}
 
 	// create a service
-	// NewAccountService() generally takes an account repository; however, since the validation should fail and the test
-	// pass before the repository is used within the NewAccount() method, we simply pass nil instead of creating a repo which
-	// isn't needed
-	service := NewAccountService(nil)
+	// NewAccountService() generally takes an account and a customer repository; however, since the validation against the
+	// account product should fail and the test pass before either repository is used within the NewAccount() method, we
+	// simply pass nil instead of creating repos which aren't needed
+	service := NewAccountService(nil, nil, realdomain.NewAccountProductRepositoryStub(), testPolicy)
 
 	// Act - execute test
-	_, appError := service.NewAccount(acctReq)
+	_, appError := service.NewAccount(context.Background(), acctReq)
 
 	// Assert - test expectations
 	// we expect an error from the new account request validation, if it is nil, the test has failed
@@ -83,10 +94,10 @@ func TestNewAccountFailedSave(t *testing.T) {
 		Status: "1",
 	}
 	// define expectations
-	mockRepo.EXPECT().Save(account).Return(nil, errs.UnexpectedErr("Unexpected database error"))
+	mockRepo.EXPECT().Save(gomock.Any(), account, gomock.Any()).Return(nil, errs.UnexpectedErr("Unexpected database error"))
 
 	// Act - execute test
-	_, appErr := service.NewAccount(acctReq)
+	_, appErr := service.NewAccount(context.Background(), acctReq)
 
 	// Assert - test expectations
 	// we expect an error, in which, if appErr is nil, then the test has failed
@@ -123,10 +134,10 @@ func TestNewAccountSuccess(t *testing.T) {
 	acctWithID := account
 	acctWithID.AccountID = "101"
 	// define expectations
-	mockRepo.EXPECT().Save(account).Return(&acctWithID, nil)
+	mockRepo.EXPECT().Save(gomock.Any(), account, gomock.Any()).Return(&acctWithID, nil)
 
 	// Act - execute test
-	returnedAcct, appErr := service.NewAccount(acctReq)
+	returnedAcct, appErr := service.NewAccount(context.Background(), acctReq)
 
 	// Assert - test expectations
 	// we expect no errors during a successful account creation
@@ -138,3 +149,195 @@ func TestNewAccountSuccess(t *testing.T) {
 		t.Error("failed while creating new account - account ID does not match")
 	}
 }
+
+// TestNewAccountInactiveCustomer() should pass when a deactivated customer is refused a new account before Save() is called
+func TestNewAccountInactiveCustomer(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	ctrl := gomock.NewController(t)
+	custRepo := domain.NewMockCustomerRepository(ctrl)
+	inactiveCustomer := activeCustomer
+	inactiveCustomer.Status = realdomain.CustomerInactive
+	custRepo.EXPECT().FindById(gomock.Any(), "1").Return(&inactiveCustomer, nil)
+	// the account repository has no expectations - a call to Save() fails the test
+	acctService := NewAccountService(domain.NewMockAccountRepository(ctrl), custRepo, realdomain.NewAccountProductRepositoryStub(), testPolicy)
+	acctReq := dto.NewAccountRequest{CustomerID: "1", AccountType: "saving", Amount: 5000.00}
+
+	// Act - execute test
+	_, appErr := acctService.NewAccount(context.Background(), acctReq)
+
+	// Assert - test expectations
+	if appErr == nil || appErr.Message != realdomain.MsgCustomerInactive {
+		t.Errorf("received %v, want %q", appErr, realdomain.MsgCustomerInactive)
+	}
+}
+
+// TestNewAccountPolicy() should pass when an unknown customer is a 404, and a customer at the account limit or below the
+// minimum age is refused with a 422 by the check passed to Save(), before the account is inserted
+func TestNewAccountPolicy(t *testing.T) {
+	underage := activeCustomer
+	underage.DateofBirth = time.Now().AddDate(-17, 0, 0).Format("2006-01-02")
+	tests := []struct {
+		name         string
+		customer     *realdomain.Customer
+		custErr      *errs.AppError
+		openAccounts int
+		wantCode     int
+		wantMessage  string
+	}{
+		{"unknown customer", nil, errs.NotFoundErr(realdomain.MsgCustomerNotFound), 0, http.StatusNotFound, realdomain.MsgCustomerNotFound},
+		{"account limit", &activeCustomer, nil, 2, http.StatusUnprocessableEntity, realdomain.MsgAccountLimitReached},
+		{"underage", &underage, nil, 0, http.StatusUnprocessableEntity, realdomain.MsgCustomerUnderage},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			ctrl := gomock.NewController(t)
+			custRepo := domain.NewMockCustomerRepository(ctrl)
+			custRepo.EXPECT().FindById(gomock.Any(), "1").Return(test.customer, test.custErr)
+			// Save() passes the check the customer's open accounts and, as the repository would, inserts nothing when the
+			// check refuses them - the unknown customer never reaches Save()
+			acctRepo := domain.NewMockAccountRepository(ctrl)
+			acctRepo.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+				func(_ context.Context, acct realdomain.Account, check realdomain.AccountCheck) (*realdomain.Account, *errs.AppError) {
+					if appErr := check(test.openAccounts); appErr != nil {
+						return nil, appErr
+					}
+					return &acct, nil
+				}).MaxTimes(1)
+			acctService := NewAccountService(acctRepo, custRepo, realdomain.NewAccountProductRepositoryStub(), testPolicy)
+
+			// Act - execute test
+			_, appErr := acctService.NewAccount(context.Background(), dto.NewAccountRequest{CustomerID: "1", AccountType: "saving", Amount: 5000.00})
+
+			// Assert - test expectations
+			if appErr == nil || appErr.Code != test.wantCode || appErr.Message != test.wantMessage {
+				t.Errorf("received %v, want %d %q", appErr, test.wantCode, test.wantMessage)
+			}
+		})
+	}
+}
+
+// TestNewAccountProduct() should pass when the account type is normalised to the product's code before Save() is called
+// and an unknown account type is refused with a 422 listing the products
+func TestNewAccountProduct(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	resetService := setup(t)
+	defer resetService()
+	// the account is saved with the product code whatever casing the client sent
+	mockRepo.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(func(_ context.Context, acct realdomain.Account, _ realdomain.AccountCheck) (*realdomain.Account, *errs.AppError) {
+		if acct.AccountType != "checking" {
+			t.Errorf("saved account type %q, want checking", acct.AccountType)
+		}
+		acct.AccountID = "107"
+		return &acct, nil
+	})
+
+	// Act - execute test
+	_, appErr := service.NewAccount(context.Background(), dto.NewAccountRequest{CustomerID: "1", AccountType: " Checking", Amount: 5000.00})
+	_, unknownErr := service.NewAccount(context.Background(), dto.NewAccountRequest{CustomerID: "1", AccountType: "loan", Amount: 5000.00})
+
+	// Assert - test expectations
+	if appErr != nil {
+		t.Errorf("unexpected error %q", appErr.Message)
+	}
+	if unknownErr == nil || unknownErr.Code != http.StatusUnprocessableEntity || unknownErr.Message != "error: account type must be one of checking, saving" {
+		t.Errorf("received %v, want a 422 listing the products", unknownErr)
+	}
+}
+
+// TestNewAccountLimitConcurrent() should pass when accounts opened for one customer at the same time are counted one after
+// another, so that no more than the account policy's limit are opened
+func TestNewAccountLimitConcurrent(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	ctrl := gomock.NewController(t)
+	custRepo := domain.NewMockCustomerRepository(ctrl)
+	custRepo.EXPECT().FindById(gomock.Any(), "1").Return(&activeCustomer, nil).AnyTimes()
+	acctService := NewAccountService(realdomain.NewAccountRepositoryStub(), custRepo, realdomain.NewAccountProductRepositoryStub(), testPolicy)
+	const attempts = 10
+	results := make(chan *errs.AppError, attempts)
+
+	// Act - execute test
+	// every request has passed the customer checks before any account is saved
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, appErr := acctService.NewAccount(context.Background(), dto.NewAccountRequest{CustomerID: "1", AccountType: "saving", Amount: 5000.00})
+			results <- appErr
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// Assert - test expectations
+	opened := 0
+	for appErr := range results {
+		switch {
+		case appErr == nil:
+			opened++
+		case appErr.Message != realdomain.MsgAccountLimitReached:
+			t.Errorf("unexpected error %q", appErr.Message)
+		}
+	}
+	if opened != testPolicy.MaxPerType {
+		t.Errorf("opened %d accounts, want %d", opened, testPolicy.MaxPerType)
+	}
+}
+
+// TestNewAccountOpeningDeposit() should pass when the amount of the request is saved as the account's opening deposit -
+// which Save() records as the account's first transaction - once it reaches the product's minimum deposit, and an amount
+// below the minimum is refused with a 422 before Save() is called
+func TestNewAccountOpeningDeposit(t *testing.T) {
+	tests := []struct {
+		name     string
+		amount   float64
+		wantCode int
+	}{
+		{"at the minimum", 5000.00, 0},
+		{"above the minimum", 7250.50, 0},
+		{"below the minimum", 4999.99, http.StatusUnprocessableEntity},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// using AAA format for testing
+			// Arrange - setup test
+			resetService := setup(t)
+			defer resetService()
+			var saved *realdomain.Account
+			// Save() has no expectation when the amount is refused - a call to it fails the test
+			if test.wantCode == 0 {
+				mockRepo.EXPECT().Save(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+					func(_ context.Context, acct realdomain.Account, _ realdomain.AccountCheck) (*realdomain.Account, *errs.AppError) {
+						acct.AccountID = "107"
+						saved = &acct
+						return &acct, nil
+					})
+			}
+
+			// Act - execute test
+			_, appErr := service.NewAccount(context.Background(), dto.NewAccountRequest{CustomerID: "1", AccountType: "saving", Amount: test.amount})
+
+			// Assert - test expectations
+			if test.wantCode != 0 {
+				if appErr == nil || appErr.Code != test.wantCode {
+					t.Errorf("received %v, want %d", appErr, test.wantCode)
+				}
+				return
+			}
+			if appErr != nil {
+				t.Fatalf("unexpected error %q", appErr.Message)
+			}
+			// the opening deposit is dated with the account, so the opening date must be one the database accepts
+			if _, err := time.Parse("2006-01-02 15:04:05", saved.OpeningDate); err != nil || saved.Amount != test.amount || saved.Status != "1" {
+				t.Errorf("saved %+v, want an active account opened now with an amount of %.2f", *saved, test.amount)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// APIKeyService is a "port" implemented by the domain - it manages the API keys accepted in the X-API-Key header
+type APIKeyService interface {
+	GetAllAPIKeys(context.Context) ([]dto.APIKeyResponse, *errs.AppError)
+	NewAPIKey(context.Context, dto.NewAPIKeyRequest) (*dto.APIKeyResponse, *errs.AppError)
+	RevokeAPIKey(context.Context, string) (*dto.APIKeyResponse, *errs.AppError)
+	RotateAPIKey(context.Context, string) (*dto.APIKeyResponse, *errs.AppError)
+}
+
+// DefaultAPIKeyService is an "adapter" that implements the APIKeyService "port" - routeNames holds the route names a
+// scope may name
+type DefaultAPIKeyService struct {
+	repo       domain.APIKeyRepository
+	routeNames map[string]bool
+}
+
+// GetAllAPIKeys() returns every API key without its hash
+func (d DefaultAPIKeyService) GetAllAPIKeys(ctx context.Context) ([]dto.APIKeyResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAPIKeyService.GetAllAPIKeys")
+	defer span.End()
+
+	keys, err := d.repo.FindAll(ctx)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	resp := make([]dto.APIKeyResponse, 0, len(keys))
+	for _, key := range keys {
+		resp = append(resp, key.ToDto(now))
+	}
+	return resp, nil
+}
+
+// NewAPIKey() creates an API key for the requested scopes - the key is only returned by this call
+func (d DefaultAPIKeyService) NewAPIKey(ctx context.Context, req dto.NewAPIKeyRequest) (*dto.APIKeyResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAPIKeyService.NewAPIKey")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	if appErr := domain.ValidateScopes(req.Scopes, d.routeNames); appErr != nil {
+		return nil, appErr
+	}
+	keyID, secret, hash, err := domain.NewAPIKeySecret("")
+	if err != nil {
+		logging.Error(ctx, "error while generating an api key", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error while creating the api key")
+	}
+	now := time.Now()
+	key := domain.APIKey{
+		KeyID:  keyID,
+		Name:   strings.TrimSpace(req.Name),
+		Hash:   hash,
+		Scopes: strings.Join(req.Scopes, ","),
+		// the subject of the caller's token - empty when authorization is disabled
+		CreatedBy: logging.Subject(ctx),
+		CreatedAt: now.Format("2006-01-02 15:04:05"),
+	}
+	if req.ExpiresInDays > 0 {
+		key.ExpiresAt = sql.NullString{String: now.AddDate(0, 0, req.ExpiresInDays).Format("2006-01-02 15:04:05"), Valid: true}
+	}
+	saved, appErr := d.repo.Save(ctx, key)
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := saved.ToDto(now)
+	resp.Key = secret
+	return &resp, nil
+}
+
+// RevokeAPIKey() revokes the API key with the given key_id - it is refused from then on
+func (d DefaultAPIKeyService) RevokeAPIKey(ctx context.Context, keyID string) (*dto.APIKeyResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAPIKeyService.RevokeAPIKey", trace.WithAttributes(tracing.Attr("key_id", keyID)))
+	defer span.End()
+
+	now := time.Now()
+	key, appErr := d.repo.Revoke(ctx, keyID, now.Format("2006-01-02 15:04:05"))
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := key.ToDto(now)
+	return &resp, nil
+}
+
+// RotateAPIKey() issues a new key under the same key_id, name, scopes and expiry - the old key stops working at once
+func (d DefaultAPIKeyService) RotateAPIKey(ctx context.Context, keyID string) (*dto.APIKeyResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAPIKeyService.RotateAPIKey", trace.WithAttributes(tracing.Attr("key_id", keyID)))
+	defer span.End()
+
+	_, secret, hash, err := domain.NewAPIKeySecret(keyID)
+	if err != nil {
+		logging.Error(ctx, "error while generating an api key", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error while rotating the api key")
+	}
+	now := time.Now()
+	key, appErr := d.repo.Rotate(ctx, keyID, hash, now.Format("2006-01-02 15:04:05"))
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := key.ToDto(now)
+	resp.Key = secret
+	return &resp, nil
+}
+
+// NewAPIKeyService() takes an API key repository and the route names scopes may name and returns a DefaultAPIKeyService
+func NewAPIKeyService(repo domain.APIKeyRepository, routeNames map[string]bool) DefaultAPIKeyService {
+	return DefaultAPIKeyService{repo: repo, routeNames: routeNames}
+}
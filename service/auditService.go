@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/tracing"
+)
+
+// auditVerifyBatch is the number of entries read at a time while the hash chain is verified
+const auditVerifyBatch = 1000
+
+// AuditService is a "port" implemented by the domain - it reads the audit log written by the audit middleware
+type AuditService interface {
+	GetAuditEvents(context.Context, dto.AuditEventSearchRequest) (*dto.AuditEventPageResponse, *errs.AppError)
+	VerifyAuditLog(context.Context) (*dto.AuditVerificationResponse, *errs.AppError)
+}
+
+// DefaultAuditService is an "adapter" that implements the AuditService "port"
+type DefaultAuditService struct {
+	repo domain.AuditRepository
+}
+
+// GetAuditEvents() returns a page of the audit events matching the search, newest first - the next page is fetched by
+// sending the returned NextCursor with the same filters
+func (d DefaultAuditService) GetAuditEvents(ctx context.Context, req dto.AuditEventSearchRequest) (*dto.AuditEventPageResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAuditService.GetAuditEvents")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+
+	limit := req.LimitValue()
+	query := domain.AuditQuery{
+		Actor:      req.Actor,
+		Action:     req.Action,
+		Outcome:    strings.ToLower(req.Outcome),
+		CustomerID: req.CustomerID,
+		// one entry more than the page holds is fetched to learn whether there is a next page
+		Limit: limit + 1,
+	}
+	// the times are compared with occurred_at, which holds the local time the entry was written
+	if from, _ := dto.ParseAuditTime(req.From); !from.IsZero() {
+		query.From = from.Local().Format("2006-01-02 15:04:05")
+	}
+	if to, _ := dto.ParseAuditTime(req.To); !to.IsZero() {
+		query.To = to.Local().Format("2006-01-02 15:04:05")
+	}
+	// the cursor is the entry_id of the last entry of the previous page (Validate() has already checked it)
+	if req.Cursor != "" {
+		query.BeforeID, _ = strconv.ParseInt(req.Cursor, 10, 64)
+	}
+
+	entries, appErr := d.repo.Find(ctx, query)
+	if appErr != nil {
+		return nil, appErr
+	}
+	page := &dto.AuditEventPageResponse{Events: make([]dto.AuditEventResponse, 0, limit)}
+	if len(entries) > limit {
+		entries = entries[:limit]
+		page.NextCursor = strconv.FormatInt(entries[limit-1].EntryID, 10)
+	}
+	for _, entry := range entries {
+		page.Events = append(page.Events, entry.ToDto())
+	}
+	return page, nil
+}
+
+// VerifyAuditLog() walks the whole audit log, oldest first, checking that every entry is chained to the one before it and
+// that the last entry is the chain head - a changed, removed or inserted entry breaks the chain
+func (d DefaultAuditService) VerifyAuditLog(ctx context.Context) (*dto.AuditVerificationResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAuditService.VerifyAuditLog")
+	defer span.End()
+
+	// the head is read first - entries appended while the log is walked are beyond it and are not verified
+	head, appErr := d.repo.Head(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+	verifier := domain.NewAuditChainVerifier()
+	var afterID int64
+	for afterID < head.EntryID {
+		entries, appErr := d.repo.Chain(ctx, afterID, auditVerifyBatch)
+		if appErr != nil {
+			return nil, appErr
+		}
+		// entries beyond the head were appended after it was read
+		for i, entry := range entries {
+			if entry.EntryID > head.EntryID {
+				entries = entries[:i]
+				break
+			}
+		}
+		if len(entries) == 0 || !verifier.Next(entries) {
+			break
+		}
+		afterID = entries[len(entries)-1].EntryID
+	}
+	verifier.Finish(*head)
+
+	resp := &dto.AuditVerificationResponse{
+		Intact:          verifier.BrokenAt == 0,
+		VerifiedEntries: verifier.Verified,
+		HeadEntryID:     strconv.FormatInt(head.EntryID, 10),
+		HeadHash:        head.Hash,
+	}
+	if !resp.Intact {
+		resp.BrokenAtEntryID = strconv.FormatInt(verifier.BrokenAt, 10)
+	}
+	return resp, nil
+}
+
+// NewAuditService() takes an AuditRepository and returns a DefaultAuditService
+func NewAuditService(repo domain.AuditRepository) DefaultAuditService {
+	return DefaultAuditService{repo: repo}
+}
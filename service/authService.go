@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.uber.org/zap"
+)
+
+// AuthService is a "port" implemented by the domain - it issues the tokens verified by domain.PolicyAuthRepository
+type AuthService interface {
+	Login(context.Context, dto.LoginRequest) (*dto.LoginResponse, *errs.AppError)
+	Refresh(context.Context, dto.RefreshTokenRequest) (*dto.LoginResponse, *errs.AppError)
+	ChangePassword(ctx context.Context, username string, req dto.ChangePasswordRequest) *errs.AppError
+}
+
+// DefaultAuthService is an "adapter" that implements the AuthService "port"
+type DefaultAuthService struct {
+	repo     domain.UserRepository
+	signer   domain.TokenSigner
+	policy   domain.PasswordPolicy
+	hashCost int
+	// decoy is checked against the password given for an unknown username so that such a login takes as long as one
+	// with a wrong password - it is hashed on first use as hashing at hashCost takes a noticeable time
+	decoy *decoyHash
+}
+
+// decoyHash is the hash of a password no user holds, computed once
+type decoyHash struct {
+	once sync.Once
+	user domain.User
+}
+
+// Login() checks the credentials against the users table and issues an access and a refresh token
+func (d DefaultAuthService) Login(ctx context.Context, req dto.LoginRequest) (*dto.LoginResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAuthService.Login")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	user, appErr := d.repo.FindByUsername(ctx, req.Username)
+	if appErr != nil {
+		if appErr.Message == domain.MsgInvalidCredentials {
+			d.checkDecoy(req.Password)
+		}
+		return nil, appErr
+	}
+	// a wrong password is reported exactly as an unknown username is
+	if !user.PasswordMatches(req.Password) {
+		return nil, errs.AuthorizationErr(domain.MsgInvalidCredentials)
+	}
+	return d.issue(ctx, *user)
+}
+
+// Refresh() exchanges a refresh token for a new access and refresh token - the user is read again so that a changed role
+// or a deleted user takes effect at the next refresh
+func (d DefaultAuthService) Refresh(ctx context.Context, req dto.RefreshTokenRequest) (*dto.LoginResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultAuthService.Refresh")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	claims, err := d.signer.Verify(req.RefreshToken, domain.TokenTypeRefresh)
+	if err != nil {
+		return nil, errs.AuthorizationErr("invalid refresh token")
+	}
+	user, appErr := d.repo.FindByUsername(ctx, claims.Username)
+	// a refresh token issued before the password was last changed belongs to a session the change signed out
+	if appErr != nil || user.IssuedBeforePasswordChange(claims.IssuedAt.Time) {
+		return nil, errs.AuthorizationErr("invalid refresh token")
+	}
+	return d.issue(ctx, *user)
+}
+
+// ChangePassword() replaces the password of username once the current password is confirmed and the new one meets the
+// password policy - refresh tokens issued before the change are no longer accepted
+func (d DefaultAuthService) ChangePassword(ctx context.Context, username string, req dto.ChangePasswordRequest) *errs.AppError {
+	ctx, span := tracing.Start(ctx, "DefaultAuthService.ChangePassword")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return appErr
+	}
+	user, appErr := d.repo.FindByUsername(ctx, username)
+	if appErr != nil {
+		return appErr
+	}
+	if !user.PasswordMatches(req.CurrentPassword) {
+		return &errs.AppError{Code: http.StatusForbidden, Message: "the current password is incorrect"}
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return errs.ValidationErr("the new password must differ from the current password")
+	}
+	if appErr := d.policy.Check(username, req.NewPassword); appErr != nil {
+		return appErr
+	}
+	hash, err := domain.HashPassword(req.NewPassword, d.hashCost)
+	if err != nil {
+		logging.Error(ctx, "error while hashing a password", zap.Error(err))
+		return errs.UnexpectedErr("unexpected error while changing the password")
+	}
+	return d.repo.UpdatePassword(ctx, username, hash, time.Now().Format("2006-01-02 15:04:05"))
+}
+
+// checkDecoy() compares password against the decoy hash and discards the result
+func (d DefaultAuthService) checkDecoy(password string) {
+	d.decoy.once.Do(func() {
+		// the decoy password is never compared for equality, any fixed value will do
+		hash, _ := domain.HashPassword("decoy password 0", d.hashCost)
+		d.decoy.user = domain.User{Password: hash}
+	})
+	d.decoy.user.PasswordMatches(password)
+}
+
+// issue() signs the tokens for user
+func (d DefaultAuthService) issue(ctx context.Context, user domain.User) (*dto.LoginResponse, *errs.AppError) {
+	tokens, err := d.signer.Issue(user, time.Now())
+	if err != nil {
+		logging.Error(ctx, "error while signing tokens", zap.Error(err))
+		return nil, errs.UnexpectedErr("unexpected error while issuing tokens")
+	}
+	// the username is recorded for the request log line as it would be for an authorized request
+	logging.SetSubject(ctx, user.Username)
+	return &dto.LoginResponse{
+		AccessToken:  tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(tokens.AccessExpiresIn.Seconds()),
+	}, nil
+}
+
+// NewAuthService() takes a user repository, the signer of the tokens, the policy new passwords must meet and the bcrypt
+// cost they are hashed at and returns a DefaultAuthService
+func NewAuthService(repo domain.UserRepository, signer domain.TokenSigner, policy domain.PasswordPolicy, hashCost int) DefaultAuthService {
+	return DefaultAuthService{repo: repo, signer: signer, policy: policy, hashCost: hashCost, decoy: &decoyHash{}}
+}
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	realdomain "github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// newTestAuthService() returns an auth service over the user stub - the lowest accepted cost keeps the hashing quick
+func newTestAuthService() DefaultAuthService {
+	signer := realdomain.NewTokenSigner("0123456789abcdef0123456789abcdef", time.Minute, time.Hour)
+	return NewAuthService(realdomain.NewUserRepositoryStub(), signer, realdomain.PasswordPolicy{MinLength: 12}, 10)
+}
+
+// TestLogin() should pass when the seeded password logs in and a wrong password or an unknown username are refused
+// with the same 401 error
+func TestLogin(t *testing.T) {
+	// Arrange - setup test
+	authService := newTestAuthService()
+	tests := []struct {
+		name       string
+		req        dto.LoginRequest
+		wantStatus int
+	}{
+		{"valid credentials", dto.LoginRequest{Username: "1001", Password: "passw0rd123"}, http.StatusOK},
+		{"wrong password", dto.LoginRequest{Username: "1001", Password: "passw0rd124"}, http.StatusUnauthorized},
+		{"unknown username", dto.LoginRequest{Username: "9999", Password: "passw0rd123"}, http.StatusUnauthorized},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		resp, appErr := authService.Login(context.Background(), test.req)
+
+		// Assert - test expectations
+		if test.wantStatus == http.StatusOK && (appErr != nil || resp.AccessToken == "") {
+			t.Errorf("%s: received %v, want tokens", test.name, appErr)
+		}
+		if test.wantStatus != http.StatusOK && (appErr == nil || appErr.Code != test.wantStatus || appErr.Message != realdomain.MsgInvalidCredentials) {
+			t.Errorf("%s: received %v, want %d %q", test.name, appErr, test.wantStatus, realdomain.MsgInvalidCredentials)
+		}
+	}
+}
+
+// TestChangePassword() should pass when the current password must be confirmed, the new password must meet the policy
+// and, once changed, only the new password logs in and earlier refresh tokens are refused
+func TestChangePassword(t *testing.T) {
+	// Arrange - setup test
+	authService := newTestAuthService()
+	ctx := context.Background()
+	before, _ := authService.Login(ctx, dto.LoginRequest{Username: "1001", Password: "passw0rd123"})
+	tests := []struct {
+		name       string
+		req        dto.ChangePasswordRequest
+		wantStatus int
+	}{
+		{"wrong current password", dto.ChangePasswordRequest{CurrentPassword: "guess", NewPassword: "correct horse 42"}, http.StatusForbidden},
+		{"same password", dto.ChangePasswordRequest{CurrentPassword: "passw0rd123", NewPassword: "passw0rd123"}, http.StatusUnprocessableEntity},
+		{"too weak", dto.ChangePasswordRequest{CurrentPassword: "passw0rd123", NewPassword: "short"}, http.StatusUnprocessableEntity},
+		{"contains username", dto.ChangePasswordRequest{CurrentPassword: "passw0rd123", NewPassword: "my id is 1001 ok"}, http.StatusUnprocessableEntity},
+		{"changed", dto.ChangePasswordRequest{CurrentPassword: "passw0rd123", NewPassword: "correct horse 42"}, http.StatusNoContent},
+	}
+
+	for _, test := range tests {
+		// Act - execute test
+		appErr := authService.ChangePassword(ctx, "1001", test.req)
+
+		// Assert - test expectations
+		if test.wantStatus == http.StatusNoContent && appErr != nil {
+			t.Errorf("%s: unexpected error %v", test.name, appErr)
+		}
+		if test.wantStatus != http.StatusNoContent && (appErr == nil || appErr.Code != test.wantStatus) {
+			t.Errorf("%s: received %v, want %d", test.name, appErr, test.wantStatus)
+		}
+	}
+
+	_, oldLogin := authService.Login(ctx, dto.LoginRequest{Username: "1001", Password: "passw0rd123"})
+	_, newLogin := authService.Login(ctx, dto.LoginRequest{Username: "1001", Password: "correct horse 42"})
+	_, refresh := authService.Refresh(ctx, dto.RefreshTokenRequest{RefreshToken: before.RefreshToken})
+	if oldLogin == nil || newLogin != nil {
+		t.Errorf("received %v for the old password and %v for the new one, want only the new one to log in", oldLogin, newLogin)
+	}
+	if refresh == nil || refresh.Code != http.StatusUnauthorized {
+		t.Errorf("received %v refreshing a token issued before the change, want 401", refresh)
+	}
+}
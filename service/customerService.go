@@ -1,9 +1,18 @@
 package service
 
 import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/domain"
 	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // to generate a mock of the CustomerService interface for testing, we use the comment ("tag") shown below
@@ -16,8 +25,10 @@ import (
 // CustomerService is another "port" (interface)  - users and external sources will interact with the business logic
 // via this "port"
 type CustomerService interface {
-	GetAllCustomers(string) ([]dto.CustomerResponse, *errs.AppError)
-	GetCustomer(string) (*dto.CustomerResponse, *errs.AppError)
+	GetAllCustomers(context.Context, dto.CustomerSearchRequest) (*dto.CustomerPageResponse, *errs.AppError)
+	GetCustomer(context.Context, string) (*dto.CustomerResponse, *errs.AppError)
+	DeactivateCustomer(context.Context, string, dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError)
+	ReactivateCustomer(context.Context, string, dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError)
 }
 
 // DefaultCustomerService "connects" to the CustomerRepository interface (in other words, it is dependent on it)
@@ -26,33 +37,69 @@ type DefaultCustomerService struct {
 	repo domain.CustomerRepository
 }
 
-func (d DefaultCustomerService) GetAllCustomers(status string) ([]dto.CustomerResponse, *errs.AppError) {
-	// if a status query parameter wasn't included, we retrieve all customers
-	custStatus := ""
-	// if a status query parameter was provided and it was active, we only retrieve active customers (status is 1 in db)
-	// if it was inactive, we only retrieve inactive customers (status is 0 in db)
-	if status == "active" {
-		custStatus = "1"
+// GetAllCustomers() returns a page of the customers matching the search - the next page is fetched by sending the returned
+// NextCursor with the same filters and sort order
+func (d DefaultCustomerService) GetAllCustomers(ctx context.Context, req dto.CustomerSearchRequest) (*dto.CustomerPageResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultCustomerService.GetAllCustomers")
+	defer span.End()
+
+	// invalid filters are rejected rather than ignored
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+
+	sortBy, descending := req.SortField()
+	limit := req.LimitValue()
+	query := domain.CustomerQuery{
+		NamePrefix: req.NamePrefix,
+		City:       req.City,
+		Zipcode:    req.Zipcode,
+		BornAfter:  req.BornAfter,
+		BornBefore: req.BornBefore,
+		SortBy:     sortBy,
+		Descending: descending,
+	}
+	// one customer more than the page holds is fetched to learn whether there is a next page - a limit of 0 (an unpaged
+	// search) fetches every matching customer, so there is none
+	if limit > 0 {
+		query.Limit = limit + 1
+	}
+	// we only retrieve active customers (status is 1 in db) or inactive customers (status is 0 in db) when asked to
+	switch strings.ToLower(req.Status) {
+	case "active":
+		query.Status = "1"
+	case "inactive":
+		query.Status = "0"
 	}
-	if status == "inactive" {
-		custStatus = "0"
+	if req.Cursor != "" {
+		cursor, err := domain.DecodeCustomerCursor(req.Cursor)
+		// a cursor only continues the sort order it was issued for
+		if err != nil || cursor.SortBy != sortBy || cursor.Descending != descending {
+			return nil, &errs.AppError{Code: http.StatusBadRequest, Message: "error: cursor is invalid or does not match the sort order"}
+		}
+		query.After = cursor
 	}
 
-	cust, err := d.repo.FindAll(custStatus)
+	cust, err := d.repo.FindAll(ctx, query)
 	if err != nil {
 		return nil, err
 	}
-	// create a slice of type CustomerResponse with size 0 to begin with
-	custResponse := make([]dto.CustomerResponse, 0)
-
+	page := &dto.CustomerPageResponse{Customers: make([]dto.CustomerResponse, 0, limit)}
+	if limit > 0 && len(cust) > limit {
+		cust = cust[:limit]
+		page.NextCursor = domain.NewCustomerCursor(sortBy, descending, cust[limit-1]).Encode()
+	}
 	for _, customer := range cust {
-		custResponse = append(custResponse, customer.ToDto())
+		page.Customers = append(page.Customers, customer.ToDto())
 	}
-	return custResponse, nil
+	return page, nil
 }
 
-func (d DefaultCustomerService) GetCustomer(id string) (*dto.CustomerResponse, *errs.AppError) {
-	cust, err := d.repo.FindById(id)
+func (d DefaultCustomerService) GetCustomer(ctx context.Context, id string) (*dto.CustomerResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultCustomerService.GetCustomer", trace.WithAttributes(tracing.Attr("customer_id", id)))
+	defer span.End()
+
+	cust, err := d.repo.FindById(ctx, id)
 	if err != nil {
 		return nil, err
 	}
@@ -61,6 +108,45 @@ func (d DefaultCustomerService) GetCustomer(id string) (*dto.CustomerResponse, *
 	return &custResponse, nil
 }
 
+// DeactivateCustomer() marks the customer inactive, recording the reason and who made the change - the customer and their
+// accounts remain readable but no new accounts or transactions are accepted for them
+func (d DefaultCustomerService) DeactivateCustomer(ctx context.Context, id string, req dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultCustomerService.DeactivateCustomer", trace.WithAttributes(tracing.Attr("customer_id", id)))
+	defer span.End()
+
+	return d.changeStatus(ctx, id, domain.CustomerInactive, req)
+}
+
+// ReactivateCustomer() marks a deactivated customer active again, recording the reason and who made the change
+func (d DefaultCustomerService) ReactivateCustomer(ctx context.Context, id string, req dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultCustomerService.ReactivateCustomer", trace.WithAttributes(tracing.Attr("customer_id", id)))
+	defer span.End()
+
+	return d.changeStatus(ctx, id, domain.CustomerActive, req)
+}
+
+// changeStatus() validates the request and applies the status change to the customer
+func (d DefaultCustomerService) changeStatus(ctx context.Context, id string, status string, req dto.CustomerStatusRequest) (*dto.CustomerResponse, *errs.AppError) {
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	change := domain.CustomerStatusChange{
+		CustomerID: id,
+		Status:     status,
+		Reason:     strings.TrimSpace(req.Reason),
+		// the subject of the caller's token - empty when authorization is disabled
+		ChangedBy: logging.Subject(ctx),
+		// ChangedAt is formatted based on the provided layout which matches the layout the db expects
+		ChangedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	cust, err := d.repo.UpdateStatus(ctx, change)
+	if err != nil {
+		return nil, err
+	}
+	custResponse := cust.ToDto()
+	return &custResponse, nil
+}
+
 // NewCustomerService instantiates a DefaultCustomerService with a repository
 func NewCustomerService(repository domain.CustomerRepository) DefaultCustomerService {
 	return DefaultCustomerService{repo: repository}
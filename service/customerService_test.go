@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/gtaylor314/Banking-Lib/errs"
+	realdomain "github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/mocks/domain"
+)
+
+// TestGetAllCustomersPaging() should pass when following NextCursor returns every matching customer exactly once, in the
+// requested order, and the last page has no NextCursor
+func TestGetAllCustomersPaging(t *testing.T) {
+	// using AAA format for testing
+	// Arrange - setup test
+	custService := NewCustomerService(realdomain.NewCustomerRepositoryStub())
+	req := dto.CustomerSearchRequest{Status: "active", Sort: "-name", Limit: "2"}
+	var names []string
+
+	// Act - execute test
+	for pages := 0; pages < 5; pages++ {
+		page, appErr := custService.GetAllCustomers(context.Background(), req)
+		if appErr != nil {
+			t.Fatal(appErr.Message)
+		}
+		for _, customer := range page.Customers {
+			names = append(names, customer.Name)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		req.Cursor = page.NextCursor
+	}
+
+	// Assert - test expectations
+	if len(names) != 3 || names[0] != "Noah" || names[1] != "Kimberly" || names[2] != "Garrett" {
+		t.Errorf("received %v, want [Noah Kimberly Garrett]", names)
+	}
+}
+
+// TestGetAllCustomersDefaultLimit() should pass when a search without a limit is paged by DefaultCustomerLimit, while an
+// unpaged search (/v1 and the unversioned routes) returns every matching customer unless it sends a limit or cursor
+func TestGetAllCustomersDefaultLimit(t *testing.T) {
+	// Arrange - setup test
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+	mockRepo := domain.NewMockCustomerRepository(ctrl)
+	custService := NewCustomerService(mockRepo)
+	customers := make([]realdomain.Customer, dto.DefaultCustomerLimit+10)
+	for i := range customers {
+		customers[i] = realdomain.Customer{ID: strconv.Itoa(i + 1), Status: "1"}
+	}
+	// every query is answered as the database would, up to its limit
+	var limits []int
+	mockRepo.EXPECT().FindAll(gomock.Any(), gomock.Any()).DoAndReturn(func(ctx context.Context, query realdomain.CustomerQuery) ([]realdomain.Customer, *errs.AppError) {
+		limits = append(limits, query.Limit)
+		if query.Limit > 0 && query.Limit < len(customers) {
+			return customers[:query.Limit], nil
+		}
+		return customers, nil
+	}).Times(3)
+
+	// Act - execute test
+	paged, _ := custService.GetAllCustomers(context.Background(), dto.CustomerSearchRequest{})
+	unpaged, _ := custService.GetAllCustomers(context.Background(), dto.CustomerSearchRequest{Unpaged: true})
+	unpagedLimit, _ := custService.GetAllCustomers(context.Background(), dto.CustomerSearchRequest{Unpaged: true, Limit: "5"})
+
+	// Assert - test expectations
+	if len(paged.Customers) != dto.DefaultCustomerLimit || paged.NextCursor == "" {
+		t.Errorf("received %d customers and cursor %q, want a page of %d with a next cursor", len(paged.Customers),
+			paged.NextCursor, dto.DefaultCustomerLimit)
+	}
+	if len(unpaged.Customers) != len(customers) || unpaged.NextCursor != "" {
+		t.Errorf("received %d customers and cursor %q from the unpaged search, want all %d without a cursor",
+			len(unpaged.Customers), unpaged.NextCursor, len(customers))
+	}
+	if len(unpagedLimit.Customers) != 5 || unpagedLimit.NextCursor == "" {
+		t.Errorf("received %d customers from the unpaged search sending a limit, want a page of 5", len(unpagedLimit.Customers))
+	}
+	if want := []int{dto.DefaultCustomerLimit + 1, 0, 6}; !reflect.DeepEqual(limits, want) {
+		t.Errorf("received query limits %v, want %v", limits, want)
+	}
+}
+
+// TestGetAllCustomersInvalidSearch() should pass when invalid filters and a cursor issued for another sort order are
+// rejected with a http.StatusBadRequest code
+func TestGetAllCustomersInvalidSearch(t *testing.T) {
+	// Arrange - setup test
+	custService := NewCustomerService(realdomain.NewCustomerRepositoryStub())
+	first, _ := custService.GetAllCustomers(context.Background(), dto.CustomerSearchRequest{Sort: "name", Limit: "1"})
+	tests := []dto.CustomerSearchRequest{
+		{Status: "closed"},
+		{BornAfter: "2000-01-01", BornBefore: "1999-01-01"},
+		{Cursor: "not a cursor"},
+		{Sort: "-name", Cursor: first.NextCursor},
+	}
+
+	for _, req := range tests {
+		// Act - execute test
+		_, appErr := custService.GetAllCustomers(context.Background(), req)
+
+		// Assert - test expectations
+		if appErr == nil || appErr.Code != http.StatusBadRequest {
+			t.Errorf("%+v: received %v, want a 400 error", req, appErr)
+		}
+	}
+}
+
+// TestCustomerStatusChanges() should pass when a deactivation records its reason, a repeated deactivation is a conflict
+// and a reactivation restores the customer
+func TestCustomerStatusChanges(t *testing.T) {
+	// Arrange - setup test
+	custService := NewCustomerService(realdomain.NewCustomerRepositoryStub())
+	req := dto.CustomerStatusRequest{Reason: "  account holder deceased "}
+
+	// Act - execute test
+	deactivated, appErr := custService.DeactivateCustomer(context.Background(), "1001", req)
+	_, repeatErr := custService.DeactivateCustomer(context.Background(), "1001", req)
+	_, reasonErr := custService.ReactivateCustomer(context.Background(), "1001", dto.CustomerStatusRequest{Reason: " "})
+	reactivated, reactivateErr := custService.ReactivateCustomer(context.Background(), "1001", dto.CustomerStatusRequest{Reason: "probate complete"})
+
+	// Assert - test expectations
+	if appErr != nil || deactivated.Status != "inactive" || deactivated.StatusReason != "account holder deceased" || deactivated.StatusChangedAt == "" {
+		t.Errorf("DeactivateCustomer() = %+v, %v", deactivated, appErr)
+	}
+	if repeatErr == nil || repeatErr.Code != http.StatusConflict {
+		t.Errorf("repeated deactivation: received %v, want a 409 error", repeatErr)
+	}
+	if reasonErr == nil || reasonErr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("missing reason: received %v, want a 422 error", reasonErr)
+	}
+	if reactivateErr != nil || reactivated.Status != "active" || reactivated.StatusReason != "probate complete" {
+		t.Errorf("ReactivateCustomer() = %+v, %v", reactivated, reactivateErr)
+	}
+}
@@ -0,0 +1,264 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// TransactionImportService is a "port" implemented by the domain - it posts the transactions of a CSV file
+type TransactionImportService interface {
+	ImportTransactions(context.Context, dto.TransactionImportRequest) (*dto.TransactionImportResponse, *errs.AppError)
+}
+
+// DefaultTransactionImportService is an "adapter" that implements the TransactionImportService "port" - every row is
+// posted through the transaction service, so an imported transaction is checked, recorded and published exactly as one
+// posted by POST /customers/{customer_id}/transaction
+type DefaultTransactionImportService struct {
+	repo         domain.TransactionImportRepository
+	transactions TransactionService
+	// tranRepo and custRepo are used to check every row before the first one is posted
+	tranRepo domain.TransactionRepository
+	custRepo domain.CustomerRepository
+	maxRows  int
+	// staleAfter is how long an import may be processing without recording its progress before it is taken to have
+	// crashed, and may be resumed
+	staleAfter time.Duration
+}
+
+// importTimeLayout is the layout of the times recorded on a transaction import
+const importTimeLayout = "2006-01-02 15:04:05"
+
+// ImportTransactions() validates every row of the file and, unless the file has an invalid row or this is a dry run,
+// posts the rows one by one - a file is refused when a file with the same checksum was imported before, unless that
+// import was interrupted or went stale, in which case it is resumed from the first row it did not record as attempted
+func (d DefaultTransactionImportService) ImportTransactions(ctx context.Context, req dto.TransactionImportRequest) (*dto.TransactionImportResponse, *errs.AppError) {
+	sum := sha256.Sum256(req.Content)
+	checksum := hex.EncodeToString(sum[:])
+	ctx, span := tracing.Start(ctx, "DefaultTransactionImportService.ImportTransactions", trace.WithAttributes(
+		tracing.Attr("import.checksum", checksum), tracing.Attr("import.dry_run", strconv.FormatBool(req.DryRun))))
+	defer span.End()
+
+	// a dry run of a file which was imported before is refused too, so that it cannot be mistaken for a new file - only an
+	// interrupted import, or one whose process crashed, may be imported (or dry run) again, to post the rows it did not
+	// get to
+	now := time.Now()
+	staleBefore := now.Add(-d.staleAfter).Format(importTimeLayout)
+	existing, appErr := d.repo.FindByChecksum(ctx, checksum)
+	if appErr == nil && !existing.Resumable(staleBefore) {
+		return nil, existing.DuplicateErr()
+	}
+	if appErr != nil && appErr.Code != http.StatusNotFound {
+		return nil, appErr
+	}
+	resumed := existing
+
+	rows, fieldErrs := dto.ParseTransactionCSV(req.Content, d.maxRows)
+	if appErr := dto.ToAppError(fieldErrs); appErr != nil {
+		return nil, appErr
+	}
+	resp := &dto.TransactionImportResponse{Checksum: checksum, FileName: req.FileName, DryRun: req.DryRun, RowCount: len(rows)}
+	if resumed != nil {
+		// the rows the import recorded as attempted are neither checked nor posted again - they are the rows before NextRow,
+		// which is recorded after each row is posted
+		resp.ImportID = resumed.ImportID
+		resp.ResumedAfter = resumed.NextRow
+		resp.PostedCount, resp.FailedCount = resumed.PostedCount, resumed.FailedCount
+		rows = rows[resp.ResumedAfter:]
+	}
+	problems, appErr := d.validateRows(ctx, rows, resp)
+	if appErr != nil {
+		return nil, appErr
+	}
+	if req.DryRun {
+		resp.Status = dto.TransactionImportRowValid
+		if len(problems) > 0 {
+			resp.Status = dto.TransactionImportRowInvalid
+		}
+		return resp, nil
+	}
+	// nothing is posted from a file with an invalid row, so that it can be fixed and imported as a whole
+	if len(problems) > 0 {
+		return nil, errs.ValidationErr(fmt.Sprintf("%d of %d rows are invalid and no row was posted: %s",
+			len(problems), len(rows), strings.Join(problems, "; ")))
+	}
+
+	var imp *domain.TransactionImport
+	if resumed != nil {
+		// Resume() refuses an import which another resume of the same file started first, and moves the import to its next
+		// attempt, so that a process it is taken over from stops at its next row
+		imp, appErr = d.repo.Resume(ctx, resumed.ImportID, staleBefore, now.Format(importTimeLayout))
+	} else {
+		imp, appErr = d.repo.Start(ctx, domain.TransactionImport{
+			Checksum:  checksum,
+			FileName:  req.FileName,
+			RowCount:  len(rows),
+			CreatedBy: logging.Subject(ctx),
+			CreatedAt: now.Format(importTimeLayout),
+		})
+	}
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp.ImportID = imp.ImportID
+	d.postRows(ctx, *imp, rows, resp)
+	return resp, nil
+}
+
+// validateRows() checks every row, adds its result to resp and returns a problem ("line N: message") for each invalid
+// row - on top of the checks made by ParseTransactionCSV(), the account must exist, its customer must be active and a
+// withdrawal must be covered by the balance left by the rows before it
+func (d DefaultTransactionImportService) validateRows(ctx context.Context, rows []dto.TransactionImportRow, resp *dto.TransactionImportResponse) ([]string, *errs.AppError) {
+	// balances holds the balance of each account after the rows checked so far, owners its customer and accountErrs the
+	// reason an account cannot be posted to, so that each account is only read once
+	balances := make(map[string]float64)
+	owners := make(map[string]string)
+	accountErrs := make(map[string]string)
+	var problems []string
+	for i, row := range rows {
+		result := dto.TransactionImportRowResponse{
+			Line:            row.Line,
+			AccountID:       row.Request.AccountID,
+			Amount:          dto.FormatMoney(row.Request.Amount),
+			TransactionType: row.Request.TransactionType,
+			Errors:          row.Errors,
+		}
+		if len(result.Errors) == 0 {
+			balance, appErr := d.balance(ctx, row.Request.AccountID, balances, owners, accountErrs)
+			if appErr != nil {
+				return nil, appErr
+			}
+			// a row is posted as the account's own customer, as TransactionService refuses an account of another customer
+			rows[i].Request.CustomerID = owners[row.Request.AccountID]
+			if message, ok := accountErrs[row.Request.AccountID]; ok {
+				result.Errors = append(result.Errors, message)
+			} else if appErr := row.Request.Validate(balance); appErr != nil {
+				result.Errors = append(result.Errors, appErr.Message)
+			} else if strings.EqualFold(row.Request.TransactionType, "withdrawal") {
+				balances[row.Request.AccountID] = balance - row.Request.Amount
+			} else {
+				balances[row.Request.AccountID] = balance + row.Request.Amount
+			}
+		}
+		result.Status = dto.TransactionImportRowValid
+		if len(result.Errors) > 0 {
+			result.Status = dto.TransactionImportRowInvalid
+			problems = append(problems, fmt.Sprintf("line %d: %s", row.Line, strings.Join(result.Errors, ", ")))
+		}
+		resp.Rows = append(resp.Rows, result)
+	}
+	return problems, nil
+}
+
+// balance() returns the balance of the account as of the rows checked so far - the first time an account is seen it is
+// read, along with its customer, which is kept in owners, and the reason it cannot be posted to (if any) is kept in
+// accountErrs - only an unexpected error is returned
+func (d DefaultTransactionImportService) balance(ctx context.Context, accountID string, balances map[string]float64, owners map[string]string, accountErrs map[string]string) (float64, *errs.AppError) {
+	if balance, ok := balances[accountID]; ok {
+		return balance, nil
+	}
+	if _, ok := accountErrs[accountID]; ok {
+		return 0, nil
+	}
+	acct, appErr := d.tranRepo.GetAccount(ctx, accountID)
+	if appErr == nil {
+		appErr = checkCustomerActive(ctx, d.custRepo, acct.CustomerID)
+	}
+	if appErr != nil {
+		if appErr.Code == http.StatusInternalServerError {
+			return 0, appErr
+		}
+		accountErrs[accountID] = appErr.Message
+		return 0, nil
+	}
+	balances[accountID] = acct.Amount
+	owners[accountID] = acct.CustomerID
+	return acct.Amount, nil
+}
+
+// postRows() posts the rows one by one and records the import's progress after each - ctx is checked before every row,
+// and once it is cancelled the rows left are skipped - a row may still fail as it is posted, e.g. when a withdrawal made
+// since the file was validated leaves too little to cover it, but a row which fails as ctx was cancelled mid-post is
+// skipped instead, so that it is posted when the import is resumed
+func (d DefaultTransactionImportService) postRows(ctx context.Context, imp domain.TransactionImport, rows []dto.TransactionImportRow, resp *dto.TransactionImportResponse) {
+	// the progress is recorded whether or not ctx was cancelled, as an import which stops part way must say how far it got
+	recordCtx := logging.Detach(ctx)
+	resp.Status = domain.TransactionImportCompleted
+	for i := range rows {
+		if ctx.Err() != nil {
+			resp.Status = domain.TransactionImportInterrupted
+			skipRows(resp, i)
+			break
+		}
+		posted, appErr := d.transactions.NewTransaction(ctx, rows[i].Request)
+		if appErr != nil && ctx.Err() != nil {
+			resp.Status = domain.TransactionImportInterrupted
+			skipRows(resp, i)
+			break
+		}
+		if appErr != nil {
+			resp.FailedCount++
+			resp.Rows[i].Status = dto.TransactionImportRowFailed
+			resp.Rows[i].Errors = []string{appErr.Message}
+		} else {
+			resp.PostedCount++
+			resp.Rows[i].Status = dto.TransactionImportRowPosted
+			resp.Rows[i].TransactionID = posted.TransactionID
+			resp.Rows[i].Balance = dto.FormatMoney(posted.AcctAmount)
+		}
+		// the row is recorded as attempted before the next one is posted - when the progress cannot be recorded, e.g. as
+		// the import was taken over by another process, the import stops, as a resume would post the row again
+		imp.NextRow = resp.ResumedAfter + i + 1
+		if appErr := d.recordProgress(recordCtx, &imp, resp, domain.TransactionImportProcessing, ""); appErr != nil {
+			resp.Status = domain.TransactionImportInterrupted
+			skipRows(resp, i+1)
+			logging.Error(ctx, "transaction import stopped as its progress could not be recorded", zap.String("import_id", resp.ImportID),
+				zap.String("error", appErr.Message))
+			break
+		}
+	}
+	// a failed final update is logged by the repository - the import is then resumed once it goes stale
+	_ = d.recordProgress(recordCtx, &imp, resp, resp.Status, time.Now().Format(importTimeLayout))
+	logging.Info(ctx, "transaction import finished", zap.String("import_id", resp.ImportID), zap.String("status", resp.Status),
+		zap.Int("rows", resp.RowCount), zap.Int("resumed_after", resp.ResumedAfter), zap.Int("posted", resp.PostedCount),
+		zap.Int("failed", resp.FailedCount))
+}
+
+// recordProgress() records the counts of resp and status on imp - completedAt is empty while the import is processing
+func (d DefaultTransactionImportService) recordProgress(ctx context.Context, imp *domain.TransactionImport, resp *dto.TransactionImportResponse, status string, completedAt string) *errs.AppError {
+	imp.PostedCount, imp.FailedCount, imp.Status = resp.PostedCount, resp.FailedCount, status
+	imp.UpdatedAt = sql.NullString{String: time.Now().Format(importTimeLayout), Valid: true}
+	imp.CompletedAt = sql.NullString{String: completedAt, Valid: completedAt != ""}
+	return d.repo.UpdateProgress(ctx, *imp)
+}
+
+// skipRows() marks the rows of resp from the index from on as skipped
+func skipRows(resp *dto.TransactionImportResponse, from int) {
+	for i := from; i < len(resp.Rows); i++ {
+		resp.Rows[i].Status = dto.TransactionImportRowSkipped
+	}
+}
+
+// NewTransactionImportService() takes a transaction import repository, the transaction service rows are posted
+// through, the repositories rows are checked against, the most rows a file may hold and how long an import may go without
+// recording its progress before it may be resumed, and returns a DefaultTransactionImportService
+func NewTransactionImportService(repo domain.TransactionImportRepository, transactions TransactionService,
+	tranRepo domain.TransactionRepository, custRepo domain.CustomerRepository, maxRows int, staleAfter time.Duration) DefaultTransactionImportService {
+	return DefaultTransactionImportService{repo: repo, transactions: transactions, tranRepo: tranRepo, custRepo: custRepo,
+		maxRows: maxRows, staleAfter: staleAfter}
+}
@@ -0,0 +1,296 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	realdomain "github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+)
+
+// importStubs holds the repositories a transaction import service built by newTestImportService() works on
+type importStubs struct {
+	imports      *realdomain.TransactionImportRepositoryStub
+	transactions *realdomain.TransactionRepositoryStub
+	customers    *realdomain.CustomerRepositoryStub
+}
+
+// newTestImportService() returns an import service over stubs which resumes an import not updated for staleAfter - wrap,
+// when not nil, wraps the transaction service the rows are posted through
+func newTestImportService(staleAfter time.Duration, wrap func(TransactionService) TransactionService) (DefaultTransactionImportService, importStubs) {
+	stubs := importStubs{
+		imports:      realdomain.NewTransactionImportRepositoryStub(),
+		transactions: realdomain.NewTransactionRepositoryStub(),
+		customers:    realdomain.NewCustomerRepositoryStub(),
+	}
+	var transactions TransactionService = NewTransactionService(stubs.transactions, stubs.customers)
+	if wrap != nil {
+		transactions = wrap(transactions)
+	}
+	return NewTransactionImportService(stubs.imports, transactions, stubs.transactions, stubs.customers, 100, staleAfter), stubs
+}
+
+// cancellingTransactionService cancels the import's context once it has posted after transactions
+type cancellingTransactionService struct {
+	TransactionService
+	cancel context.CancelFunc
+	after  int
+}
+
+// NewTransaction implementation for type cancellingTransactionService
+func (c *cancellingTransactionService) NewTransaction(ctx context.Context, req dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError) {
+	resp, appErr := c.TransactionService.NewTransaction(ctx, req)
+	if c.after--; c.after == 0 {
+		c.cancel()
+	}
+	return resp, appErr
+}
+
+// midPostCancellingTransactionService cancels the import's context as its after-th transaction is being posted, so that
+// the transaction fails because of the cancelled context
+type midPostCancellingTransactionService struct {
+	TransactionService
+	cancel context.CancelFunc
+	after  int
+}
+
+// NewTransaction implementation for type midPostCancellingTransactionService
+func (c *midPostCancellingTransactionService) NewTransaction(ctx context.Context, req dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError) {
+	if c.after--; c.after == 0 {
+		c.cancel()
+	}
+	return c.TransactionService.NewTransaction(ctx, req)
+}
+
+// rowStatuses() returns the status of every row of resp
+func rowStatuses(resp *dto.TransactionImportResponse) string {
+	statuses := make([]string, 0, len(resp.Rows))
+	for _, row := range resp.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	return strings.Join(statuses, ",")
+}
+
+// TestImportTransactionsValidatesEveryRow() should pass when a dry run reports every invalid row - withdrawals are
+// checked against the balance left by the rows before them - and an import of the same file posts nothing
+func TestImportTransactionsValidatesEveryRow(t *testing.T) {
+	// Arrange - setup test
+	importService, stubs := newTestImportService(time.Hour, nil)
+	_, _ = stubs.customers.UpdateStatus(context.Background(), realdomain.CustomerStatusChange{CustomerID: "1002", Status: realdomain.CustomerInactive})
+	content := []byte("account_id,amount,transaction_type\n" +
+		"101,100,deposit\n" + // 1100 after this row
+		"101,1050,withdrawal\n" + // 50 after this row
+		"101,100,withdrawal\n" + // more than the 50 left
+		"999,10,deposit\n" + // no such account
+		"102,10,deposit\n" + // the customer is deactivated
+		"abc,ten,refund\n")
+
+	// Act - execute test
+	dryRun, dryRunErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content, DryRun: true})
+	_, importErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+
+	// Assert - test expectations
+	if dryRunErr != nil {
+		t.Fatalf("dry run received error %s", dryRunErr.Message)
+	}
+	if dryRun.Status != dto.TransactionImportRowInvalid || rowStatuses(dryRun) != "valid,valid,invalid,invalid,invalid,invalid" {
+		t.Errorf("received status %s and rows %s, want invalid and valid,valid,invalid,invalid,invalid,invalid", dryRun.Status, rowStatuses(dryRun))
+	}
+	wantErrors := map[int]string{
+		4: dto.MsgInsufficientFunds,
+		5: realdomain.MsgAccountNotFound,
+		6: realdomain.MsgCustomerInactive,
+		7: "error: account_id must be a number, error: amount must be a number",
+	}
+	for _, row := range dryRun.Rows {
+		if got := strings.Join(row.Errors, ", "); got != wantErrors[row.Line] {
+			t.Errorf("received errors %q for line %d, want %q", got, row.Line, wantErrors[row.Line])
+		}
+	}
+	if importErr == nil || importErr.Code != http.StatusUnprocessableEntity || !strings.HasPrefix(importErr.Message, "4 of 6 rows are invalid") {
+		t.Errorf("received import error %+v, want a 422 naming the 4 invalid rows", importErr)
+	}
+	acct, _ := stubs.transactions.GetAccount(context.Background(), "101")
+	if acct.Amount != 1000 || len(stubs.imports.Imports()) != 0 {
+		t.Errorf("received balance %.2f and %d imports recorded, want nothing posted or recorded", acct.Amount, len(stubs.imports.Imports()))
+	}
+}
+
+// TestImportTransactionsPostsEveryRow() should pass when every row of a valid file is posted and reported, the import is
+// recorded as completed and the same file is refused afterwards, even for a dry run
+func TestImportTransactionsPostsEveryRow(t *testing.T) {
+	// Arrange - setup test
+	importService, stubs := newTestImportService(time.Hour, nil)
+	content := []byte("transaction_type,account_id,amount\ndeposit,101,100\nwithdrawal,101,1100\ndeposit,102,0.5\ndeposit,103,1\nDeposit,101,5\n")
+	req := dto.TransactionImportRequest{FileName: "payroll.csv", Content: content}
+
+	// Act - execute test
+	resp, appErr := importService.ImportTransactions(context.Background(), req)
+	_, againErr := importService.ImportTransactions(context.Background(), req)
+	_, dryRunErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content, DryRun: true})
+
+	// Assert - test expectations
+	if appErr != nil {
+		t.Fatalf("received error %s", appErr.Message)
+	}
+	if resp.ImportID != "1" || resp.Status != realdomain.TransactionImportCompleted || resp.PostedCount != 5 || resp.FailedCount != 0 ||
+		rowStatuses(resp) != "posted,posted,posted,posted,posted" {
+		t.Errorf("received %+v, want every row posted", resp)
+	}
+	if resp.Rows[1].Balance != "0.00" || resp.Rows[4].Balance != "5.00" || resp.Rows[4].TransactionID == "" {
+		t.Errorf("received rows %+v, want the balance after each row", resp.Rows)
+	}
+	imports := stubs.imports.Imports()
+	if len(imports) != 1 || imports[0].Status != realdomain.TransactionImportCompleted || imports[0].PostedCount != 5 || imports[0].NextRow != 5 ||
+		imports[0].FileName != "payroll.csv" || !imports[0].CompletedAt.Valid || imports[0].Checksum != resp.Checksum {
+		t.Errorf("received imports %+v, want the completed import recorded", imports)
+	}
+	for _, err := range []*errs.AppError{againErr, dryRunErr} {
+		if err == nil || err.Code != http.StatusConflict || !strings.Contains(err.Message, "import 1") {
+			t.Errorf("received error %+v, want a 409 naming import 1", err)
+		}
+	}
+}
+
+// TestImportTransactionsInterrupted() should pass when an import whose context is cancelled posts no further row, skips
+// the rows left and is recorded as interrupted with the rows it posted
+func TestImportTransactionsInterrupted(t *testing.T) {
+	// Arrange - setup test
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	importService, stubs := newTestImportService(time.Hour, func(transactions TransactionService) TransactionService {
+		return &cancellingTransactionService{TransactionService: transactions, cancel: cancel, after: 1}
+	})
+	content := []byte("account_id,amount,transaction_type\n101,1,deposit\n101,2,deposit\n101,3,deposit\n101,4,deposit\n")
+
+	// Act - execute test
+	resp, appErr := importService.ImportTransactions(ctx, dto.TransactionImportRequest{Content: content})
+
+	// Assert - test expectations
+	if appErr != nil {
+		t.Fatalf("received error %s", appErr.Message)
+	}
+	if resp.Status != realdomain.TransactionImportInterrupted || rowStatuses(resp) != "posted,skipped,skipped,skipped" {
+		t.Errorf("received status %s and rows %s, want interrupted and posted,skipped,skipped,skipped", resp.Status, rowStatuses(resp))
+	}
+	imports := stubs.imports.Imports()
+	if len(imports) != 1 || imports[0].Status != realdomain.TransactionImportInterrupted || imports[0].PostedCount != 1 ||
+		imports[0].NextRow != 1 || !imports[0].CompletedAt.Valid {
+		t.Errorf("received imports %+v, want the interrupted import recorded with 1 row posted", imports)
+	}
+}
+
+// TestImportTransactionsCancelledMidRow() should pass when a row which fails as the context is cancelled while it is
+// posted is skipped rather than counted as failed, so that resuming the import posts it
+func TestImportTransactionsCancelledMidRow(t *testing.T) {
+	// Arrange - setup test
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	importService, stubs := newTestImportService(time.Hour, func(transactions TransactionService) TransactionService {
+		return &midPostCancellingTransactionService{TransactionService: transactions, cancel: cancel, after: 2}
+	})
+	content := []byte("account_id,amount,transaction_type\n101,1,deposit\n101,2,deposit\n101,3,deposit\n")
+
+	// Act - execute test
+	resp, appErr := importService.ImportTransactions(ctx, dto.TransactionImportRequest{Content: content})
+	resumed, resumeErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+
+	// Assert - test expectations
+	if appErr != nil || resumeErr != nil {
+		t.Fatalf("received errors %v and %v", appErr, resumeErr)
+	}
+	if resp.Status != realdomain.TransactionImportInterrupted || resp.FailedCount != 0 || rowStatuses(resp) != "posted,skipped,skipped" {
+		t.Errorf("received status %s, %d failed and rows %s, want interrupted, none failed and posted,skipped,skipped",
+			resp.Status, resp.FailedCount, rowStatuses(resp))
+	}
+	if resumed.ResumedAfter != 1 || resumed.PostedCount != 3 || resumed.FailedCount != 0 || rowStatuses(resumed) != "posted,posted" {
+		t.Errorf("received %+v, want the 2 rows left posted by the resumed import", resumed)
+	}
+	acct, _ := stubs.transactions.GetAccount(context.Background(), "101")
+	if acct.Amount != 1006 {
+		t.Errorf("received balance %.2f, want 1006.00 with every row posted once", acct.Amount)
+	}
+}
+
+// TestImportTransactionsTakesOverStale() should pass when an import left processing is refused while it records its
+// progress, and resumed from its last recorded row once it went stale - the process it was taken from then can no longer
+// record its progress
+func TestImportTransactionsTakesOverStale(t *testing.T) {
+	// Arrange - setup test
+	importService, stubs := newTestImportService(time.Minute, nil)
+	content := []byte("account_id,amount,transaction_type\n101,1,deposit\n101,2,deposit\n101,3,deposit\n")
+	sum := sha256.Sum256(content)
+	crashed, _ := stubs.imports.Start(context.Background(), realdomain.TransactionImport{
+		Checksum: hex.EncodeToString(sum[:]), RowCount: 3, CreatedAt: time.Now().Format(importTimeLayout)})
+	crashed.NextRow, crashed.PostedCount = 1, 1
+	_ = stubs.imports.UpdateProgress(context.Background(), *crashed)
+
+	// Act - execute test
+	_, runningErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+	crashed.UpdatedAt = sql.NullString{String: time.Now().Add(-2 * time.Minute).Format(importTimeLayout), Valid: true}
+	_ = stubs.imports.UpdateProgress(context.Background(), *crashed)
+	resp, appErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+	crashed.NextRow = 2
+	takenOverErr := stubs.imports.UpdateProgress(context.Background(), *crashed)
+
+	// Assert - test expectations
+	if runningErr == nil || runningErr.Code != http.StatusConflict {
+		t.Errorf("received %v importing the file of a running import, want 409", runningErr)
+	}
+	if appErr != nil {
+		t.Fatalf("received error %s from the takeover", appErr.Message)
+	}
+	if resp.Status != realdomain.TransactionImportCompleted || resp.ResumedAfter != 1 || resp.PostedCount != 3 || rowStatuses(resp) != "posted,posted" {
+		t.Errorf("received %+v, want the 2 rows left posted", resp)
+	}
+	imports := stubs.imports.Imports()
+	if takenOverErr == nil || takenOverErr.Code != http.StatusConflict || imports[0].Attempt != 2 || imports[0].NextRow != 3 {
+		t.Errorf("received %v and imports %+v, want the crashed process refused and attempt 2 completed", takenOverErr, imports)
+	}
+}
+
+// TestImportTransactionsResumesInterrupted() should pass when importing the file of an interrupted import again posts
+// only the rows it did not get to and completes it, and the completed import is then refused like any other
+func TestImportTransactionsResumesInterrupted(t *testing.T) {
+	// Arrange - setup test
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	importService, stubs := newTestImportService(time.Hour, func(transactions TransactionService) TransactionService {
+		return &cancellingTransactionService{TransactionService: transactions, cancel: cancel, after: 1}
+	})
+	content := []byte("account_id,amount,transaction_type\n101,1,deposit\n101,2,deposit\n101,3,deposit\n101,4,deposit\n")
+	if _, appErr := importService.ImportTransactions(ctx, dto.TransactionImportRequest{Content: content}); appErr != nil {
+		t.Fatalf("received error %s from the interrupted import", appErr.Message)
+	}
+
+	// Act - execute test
+	dryRun, dryRunErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content, DryRun: true})
+	resp, appErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+	_, againErr := importService.ImportTransactions(context.Background(), dto.TransactionImportRequest{Content: content})
+
+	// Assert - test expectations
+	if dryRunErr != nil || dryRun.Status != dto.TransactionImportRowValid || dryRun.ResumedAfter != 1 || len(dryRun.Rows) != 3 {
+		t.Errorf("received dry run %+v (error %v), want the 3 rows left checked", dryRun, dryRunErr)
+	}
+	if appErr != nil {
+		t.Fatalf("received error %s from the resumed import", appErr.Message)
+	}
+	if resp.Status != realdomain.TransactionImportCompleted || resp.ImportID != "1" || resp.ResumedAfter != 1 ||
+		resp.PostedCount != 4 || rowStatuses(resp) != "posted,posted,posted" || resp.Rows[0].Line != 3 || resp.Rows[2].Balance != "1010.00" {
+		t.Errorf("received %+v, want import 1 completed with the last 3 rows posted", resp)
+	}
+	imports := stubs.imports.Imports()
+	if len(imports) != 1 || imports[0].Status != realdomain.TransactionImportCompleted || imports[0].PostedCount != 4 {
+		t.Errorf("received imports %+v, want the import completed with 4 rows posted", imports)
+	}
+	if againErr == nil || againErr.Code != http.StatusConflict {
+		t.Errorf("received %v importing the completed file again, want 409", againErr)
+	}
+}
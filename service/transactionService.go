@@ -1,25 +1,35 @@
 package service
 
 import (
+	"context"
 	"time"
 
 	"github.com/gtaylor314/Banking-Lib/errs"
 	"github.com/gtaylor314/Banking-MS/domain"
 	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
 // TransactionService is a "port" implemented by the domain
 type TransactionService interface {
-	NewTransaction(dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError)
+	NewTransaction(context.Context, dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError)
 }
 
 // DefaultTransactionService is an "adapter" that implements the TransactionService "port"
 type DefaultTransactionService struct {
 	repo domain.TransactionRepository
+	// custRepo is used to check the account's customer may post new transactions
+	custRepo domain.CustomerRepository
 }
 
 // NewTransaction() takes a NewTransactionRequest and returns a NewTransactionResponse and an error if any
-func (d DefaultTransactionService) NewTransaction(req dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError) {
+func (d DefaultTransactionService) NewTransaction(ctx context.Context, req dto.NewTransactionRequest) (*dto.NewTransactionResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultTransactionService.NewTransaction", trace.WithAttributes(tracing.Attr("account_id", req.AccountID)))
+	defer span.End()
+
 	// create transaction
 	tran := domain.Transaction{
 		// TransactionID is auto-generated when the insert command is run on the db
@@ -32,35 +42,60 @@ func (d DefaultTransactionService) NewTransaction(req dto.NewTransactionRequest)
 		AcctAmount:      0,
 	}
 
-	// get the current account amount (account balance)
-	acctAmount, err := d.repo.GetAcctAmount(tran.AccountID)
+	// get the account to find its current amount (account balance)
+	acct, err := d.repo.GetAccount(ctx, tran.AccountID)
 	if err != nil {
 		return nil, err
 	}
-	// set AcctAmount in transaction tran to the current account amount (acctAmount)
-	tran.AcctAmount = *acctAmount
+	// an account of another customer is reported as not found, so that the route cannot be used to learn which accounts
+	// exist - the caller was only authorized for the customer in the request
+	if acct.CustomerID != req.CustomerID {
+		return nil, domain.AccountNotFoundErr
+	}
+	// the history of a deactivated customer's accounts stays readable but no new transactions are posted to them
+	if err = checkCustomerActive(ctx, d.custRepo, acct.CustomerID); err != nil {
+		return nil, err
+	}
+	// set AcctAmount in transaction tran to the current account amount
+	tran.AcctAmount = acct.Amount
+	tran.CustomerID = acct.CustomerID
 
 	// validate the incoming request
-	err = req.Validate(*acctAmount)
+	err = req.Validate(acct.Amount)
 	if err != nil {
 		return nil, err
 	}
 
 	// SaveTrans() inserts the transaction into the transactions table and returns a Transaction object with the Transaction ID
 	// which auto-generated upon insert
-	transaction, err := d.repo.SaveTrans(tran)
+	transaction, err := d.repo.SaveTrans(ctx, tran)
 	if err != nil {
 		return nil, err
 	}
 
+	// record the deposit or withdrawal against the account's type
+	metrics.TransactionPosted(transaction.TransactionType, acct.AccountType, transaction.Amount)
+
 	// ToNewTransactionResponseDto() returns a NewTransactionResponse object
 	resp := transaction.ToNewTransactionResponseDto()
 
 	return &resp, err
 }
 
-// NewTransactionService() takes a TransactionRepository and returns a DefaultTransactionService with the repo property
-// initialized to the passed in TransactionRepository
-func NewTransactionService(repo domain.TransactionRepository) DefaultTransactionService {
-	return DefaultTransactionService{repo: repo}
+// checkCustomerActive() returns an error if the customer does not exist or has been deactivated
+func checkCustomerActive(ctx context.Context, custRepo domain.CustomerRepository, customerID string) *errs.AppError {
+	cust, err := custRepo.FindById(ctx, customerID)
+	if err != nil {
+		return err
+	}
+	if !cust.IsActive() {
+		return domain.CustomerInactiveErr
+	}
+	return nil
+}
+
+// NewTransactionService() takes a TransactionRepository and a CustomerRepository and returns a DefaultTransactionService
+// with the repo and custRepo properties initialized to them
+func NewTransactionService(repo domain.TransactionRepository, custRepo domain.CustomerRepository) DefaultTransactionService {
+	return DefaultTransactionService{repo: repo, custRepo: custRepo}
 }
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/gtaylor314/Banking-Lib/errs"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/dto"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// WebhookService is a "port" implemented by the domain - it manages the webhook subscriptions and lets admins inspect and
+// replay their deliveries
+type WebhookService interface {
+	GetAllWebhookSubscriptions(context.Context) ([]dto.WebhookSubscriptionResponse, *errs.AppError)
+	NewWebhookSubscription(context.Context, dto.NewWebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, *errs.AppError)
+	GetWebhookSubscription(ctx context.Context, subscriptionID string) (*dto.WebhookSubscriptionResponse, *errs.AppError)
+	DisableWebhookSubscription(ctx context.Context, subscriptionID string) (*dto.WebhookSubscriptionResponse, *errs.AppError)
+	GetWebhookDeliveries(ctx context.Context, subscriptionID string, req dto.WebhookDeliverySearchRequest) ([]dto.WebhookDeliveryResponse, *errs.AppError)
+	GetWebhookDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError)
+	ReplayWebhookDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError)
+}
+
+// DefaultWebhookService is an "adapter" that implements the WebhookService "port"
+type DefaultWebhookService struct {
+	repo domain.WebhookRepository
+}
+
+// GetAllWebhookSubscriptions() returns every subscription without its secret
+func (d DefaultWebhookService) GetAllWebhookSubscriptions(ctx context.Context) ([]dto.WebhookSubscriptionResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.GetAllWebhookSubscriptions")
+	defer span.End()
+
+	subs, appErr := d.repo.FindAllSubscriptions(ctx)
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := make([]dto.WebhookSubscriptionResponse, 0, len(subs))
+	for _, sub := range subs {
+		resp = append(resp, sub.ToDto())
+	}
+	return resp, nil
+}
+
+// NewWebhookSubscription() subscribes the requested URL to the requested event types - the secret, generated when none
+// was sent, is only returned by this call
+func (d DefaultWebhookService) NewWebhookSubscription(ctx context.Context, req dto.NewWebhookSubscriptionRequest) (*dto.WebhookSubscriptionResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.NewWebhookSubscription")
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	if appErr := domain.ValidateEventTypes(req.EventTypes); appErr != nil {
+		return nil, appErr
+	}
+	secret := req.Secret
+	if secret == "" {
+		generated, err := domain.NewWebhookSecret()
+		if err != nil {
+			logging.Error(ctx, "error while generating a webhook secret", zap.Error(err))
+			return nil, errs.UnexpectedErr("unexpected error while creating the webhook subscription")
+		}
+		secret = generated
+	}
+	sub := domain.WebhookSubscription{
+		URL:        req.URL,
+		EventTypes: strings.Join(req.EventTypes, ","),
+		Secret:     secret,
+		// the subject of the caller's token - empty when authorization is disabled
+		CreatedBy: logging.Subject(ctx),
+		CreatedAt: time.Now().Format("2006-01-02 15:04:05"),
+	}
+	saved, appErr := d.repo.SaveSubscription(ctx, sub)
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := saved.ToDto()
+	resp.Secret = secret
+	return &resp, nil
+}
+
+// GetWebhookSubscription() returns the subscription with the given subscription_id without its secret
+func (d DefaultWebhookService) GetWebhookSubscription(ctx context.Context, subscriptionID string) (*dto.WebhookSubscriptionResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.GetWebhookSubscription", trace.WithAttributes(tracing.Attr("subscription_id", subscriptionID)))
+	defer span.End()
+
+	sub, appErr := d.repo.FindSubscription(ctx, subscriptionID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := sub.ToDto()
+	return &resp, nil
+}
+
+// DisableWebhookSubscription() stops the deliveries to the subscription - its pending deliveries are kept, but no longer
+// attempted
+func (d DefaultWebhookService) DisableWebhookSubscription(ctx context.Context, subscriptionID string) (*dto.WebhookSubscriptionResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.DisableWebhookSubscription", trace.WithAttributes(tracing.Attr("subscription_id", subscriptionID)))
+	defer span.End()
+
+	sub, appErr := d.repo.DisableSubscription(ctx, subscriptionID, time.Now().Format("2006-01-02 15:04:05"))
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := sub.ToDto()
+	return &resp, nil
+}
+
+// GetWebhookDeliveries() returns the subscription's most recent deliveries, newest first
+func (d DefaultWebhookService) GetWebhookDeliveries(ctx context.Context, subscriptionID string, req dto.WebhookDeliverySearchRequest) ([]dto.WebhookDeliveryResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.GetWebhookDeliveries", trace.WithAttributes(tracing.Attr("subscription_id", subscriptionID)))
+	defer span.End()
+
+	if appErr := req.Validate(); appErr != nil {
+		return nil, appErr
+	}
+	// an unknown subscription is a 404 rather than an empty list
+	if _, appErr := d.repo.FindSubscription(ctx, subscriptionID); appErr != nil {
+		return nil, appErr
+	}
+	deliveries, appErr := d.repo.FindDeliveries(ctx, subscriptionID, req.Status, req.LimitValue())
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := make([]dto.WebhookDeliveryResponse, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		resp = append(resp, delivery.ToDto(nil))
+	}
+	return resp, nil
+}
+
+// GetWebhookDelivery() returns the delivery along with the log of its attempts
+func (d DefaultWebhookService) GetWebhookDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.GetWebhookDelivery", trace.WithAttributes(tracing.Attr("delivery_id", deliveryID)))
+	defer span.End()
+
+	delivery, appErr := d.repo.FindDelivery(ctx, subscriptionID, deliveryID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	attempts, appErr := d.repo.FindAttempts(ctx, deliveryID)
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := delivery.ToDto(attempts)
+	return &resp, nil
+}
+
+// ReplayWebhookDelivery() queues the delivery to be attempted again at once, with a fresh set of attempts - the payload
+// and X-Event-ID are those of the original delivery
+func (d DefaultWebhookService) ReplayWebhookDelivery(ctx context.Context, subscriptionID string, deliveryID string) (*dto.WebhookDeliveryResponse, *errs.AppError) {
+	ctx, span := tracing.Start(ctx, "DefaultWebhookService.ReplayWebhookDelivery", trace.WithAttributes(tracing.Attr("delivery_id", deliveryID)))
+	defer span.End()
+
+	delivery, appErr := d.repo.Replay(ctx, subscriptionID, deliveryID, time.Now().Format("2006-01-02 15:04:05"))
+	if appErr != nil {
+		return nil, appErr
+	}
+	resp := delivery.ToDto(nil)
+	return &resp, nil
+}
+
+// NewWebhookService() takes a webhook repository and returns a DefaultWebhookService
+func NewWebhookService(repo domain.WebhookRepository) DefaultWebhookService {
+	return DefaultWebhookService{repo: repo}
+}
@@ -0,0 +1,103 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gtaylor314/Banking-MS/config"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the spans created by this service's own instrumentation
+const instrumentationName = "github.com/gtaylor314/Banking-MS"
+
+// Setup() installs the global tracer provider and the W3C trace context propagator based on cfg - the returned function
+// flushes any buffered spans and must be called before the process exits - when the exporter is "none", the global no-op
+// tracer provider is left in place so spans cost (almost) nothing, but the propagator is still installed so that incoming
+// trace context is forwarded to the auth server
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	// TraceContext handles the traceparent/tracestate headers and Baggage handles the baggage header
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case config.TracingExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case config.TracingExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case config.TracingExporterOTLP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(cfg.OTLPEndpoint)}
+		if cfg.OTLPInsecure {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("creating %s trace exporter: %w", cfg.Exporter, err)
+	}
+
+	// the resource describes the process producing the spans - the service name is how traces are grouped in the backend
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(cfg.ServiceName)))
+	if err != nil {
+		return nil, fmt.Errorf("creating trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		// the batcher exports spans in the background rather than on the request path
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		// ParentBased() follows the sampling decision of an incoming trace and samples new traces at SampleRatio
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start() starts a span named name as a child of any span held in ctx - the returned context carries the new span and must
+// be passed on so that spans started further down the call chain become its children
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(instrumentationName).Start(ctx, name, opts...)
+}
+
+// StartDB() starts a client span for a single SQL statement against the banking database
+func StartDB(ctx context.Context, name string, statement string) (context.Context, trace.Span) {
+	return Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMySQL, semconv.DBStatement(statement)),
+	)
+}
+
+// RecordError() records err on span and marks the span as failed
+func RecordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// InjectHeaders() writes the trace context held in ctx into an outbound request's headers (traceparent, tracestate and
+// baggage) so that the receiving service continues the same trace
+func InjectHeaders(ctx context.Context, carrier propagation.TextMapCarrier) {
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+}
+
+// ExtractHeaders() returns a copy of ctx carrying the trace context found in an inbound request's headers, if any
+func ExtractHeaders(ctx context.Context, carrier propagation.TextMapCarrier) context.Context {
+	return otel.GetTextMapPropagator().Extract(ctx, carrier)
+}
+
+// Attr is shorthand for attribute.String() so that callers do not need to import the attribute package
+func Attr(key string, value string) attribute.KeyValue {
+	return attribute.String(key, value)
+}
@@ -0,0 +1,62 @@
+// Package webhook delivers the events queued for the webhook subscriptions - every delivery is signed with the
+// subscription's secret so that a subscriber can check that it came from the service and was not changed or replayed
+// by anyone else
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// the headers sent with every delivery - the event headers match those of the outbox webhook publisher
+const (
+	HeaderSignature  = "X-Webhook-Signature"
+	HeaderTimestamp  = "X-Webhook-Timestamp"
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderEventID    = "X-Event-ID"
+	HeaderEventType  = "X-Event-Type"
+)
+
+// signaturePrefix names the algorithm of the signature, so that another may be added later without breaking subscribers
+const signaturePrefix = "sha256="
+
+// errors returned by Verify()
+var (
+	ErrInvalidSignature = errors.New("webhook signature does not match")
+	ErrStaleTimestamp   = errors.New("webhook timestamp is missing or outside the tolerance")
+)
+
+// Sign() returns the X-Webhook-Signature of body sent at timestamp (Unix seconds) - the hex HMAC-SHA256, keyed by
+// secret, of the timestamp, a dot and the body - signing the timestamp keeps a captured delivery from being replayed
+// later
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return signaturePrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify() checks a delivery as a subscriber should - the signature must match the body and timestamp headers, and the
+// timestamp must be within tolerance of now
+func Verify(secret string, timestamp string, signature string, body []byte, now time.Time, tolerance time.Duration) error {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrStaleTimestamp
+	}
+	if age := now.Sub(time.Unix(sentAt, 0)); age > tolerance || age < -tolerance {
+		return ErrStaleTimestamp
+	}
+	if !strings.HasPrefix(signature, signaturePrefix) {
+		return ErrInvalidSignature
+	}
+	// the signatures are compared in constant time so that the expected signature cannot be found byte by byte
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, sentAt, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}
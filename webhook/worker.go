@@ -0,0 +1,170 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+	"github.com/gtaylor314/Banking-MS/logging"
+	"github.com/gtaylor314/Banking-MS/metrics"
+	"github.com/gtaylor314/Banking-MS/tracing"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// timeLayout is the layout of the DATETIME columns of the webhook tables
+const timeLayout = "2006-01-02 15:04:05"
+
+// maxErrorLength is the size of the webhook_delivery_attempts.error column
+const maxErrorLength = 1024
+
+// Worker delivers the pending deliveries - a delivery is marked delivered only once the subscriber has answered with a
+// 2xx status, so a subscriber may receive a delivery more than once and should ignore an X-Event-ID it has already seen
+type Worker struct {
+	repo   domain.WebhookRepository
+	client *http.Client
+	cfg    config.WebhookConfig
+	// now is time.Now outside of tests
+	now func() time.Time
+}
+
+// DeliverOnce() claims a batch of due deliveries and attempts them one by one, oldest first, and returns how many were
+// claimed - the batch is leased for Lease, which validation keeps longer than BatchSize deliveries each taking Timeout
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	now := w.now()
+	dispatches, appErr := w.repo.ClaimDeliveries(ctx, now.Format(timeLayout), now.Add(w.cfg.Lease.Std()).Format(timeLayout), w.cfg.BatchSize)
+	if appErr != nil {
+		return 0, errors.New(appErr.Message)
+	}
+	for _, dispatch := range dispatches {
+		w.deliver(ctx, dispatch)
+	}
+	return len(dispatches), nil
+}
+
+// deliver() attempts the delivery, logs the attempt and schedules the next one if it failed - after MaxAttempts failed
+// attempts the delivery is given up until it is replayed
+func (w *Worker) deliver(ctx context.Context, dispatch domain.WebhookDispatch) {
+	ctx, span := tracing.Start(ctx, "Worker.deliver", trace.WithAttributes(
+		tracing.Attr("webhook.delivery_id", dispatch.DeliveryID), tracing.Attr("event.type", dispatch.EventType)))
+	defer span.End()
+
+	attemptedAt := w.now()
+	started := time.Now()
+	statusCode, err := w.post(ctx, dispatch, attemptedAt)
+	attempt := domain.WebhookAttempt{
+		DeliveryID:  dispatch.DeliveryID,
+		AttemptedAt: attemptedAt.Format(timeLayout),
+		StatusCode:  statusCode,
+		DurationMs:  time.Since(started).Milliseconds(),
+	}
+
+	status, nextAttemptAt, outcome := domain.WebhookDelivered, attempt.AttemptedAt, domain.WebhookDelivered
+	if err != nil {
+		tracing.RecordError(span, err)
+		attempt.Error = err.Error()
+		if len(attempt.Error) > maxErrorLength {
+			attempt.Error = attempt.Error[:maxErrorLength]
+		}
+		status, outcome = domain.WebhookPending, "retry"
+		nextAttemptAt = attemptedAt.Add(w.backoff(dispatch.Attempts)).Format(timeLayout)
+		if dispatch.Attempts+1 >= w.cfg.MaxAttempts {
+			status, outcome = domain.WebhookFailed, domain.WebhookFailed
+		}
+		logging.Error(ctx, "error while delivering webhook", zap.String("delivery_id", dispatch.DeliveryID),
+			zap.String("subscription_id", dispatch.SubscriptionID), zap.Int("attempts", dispatch.Attempts+1),
+			zap.String("status", status), zap.Error(err))
+	}
+	metrics.WebhookAttempt(dispatch.EventType, outcome)
+	if appErr := w.repo.RecordAttempt(ctx, dispatch.ClaimedBy, attempt, status, nextAttemptAt); appErr != nil {
+		// the delivery is attempted again once its lease ends - or, when the lease had already ended, its outcome is
+		// recorded by the worker which claimed it next
+		logging.Error(ctx, "error while recording webhook attempt", zap.String("delivery_id", dispatch.DeliveryID),
+			zap.String("error", appErr.Message))
+	}
+}
+
+// post() POSTs the delivery's payload to the subscription's URL and returns the status code of the response, or 0 when
+// there was none - any status other than 2xx is an error
+func (w *Worker) post(ctx context.Context, dispatch domain.WebhookDispatch, sentAt time.Time) (int, error) {
+	body := []byte(dispatch.Payload)
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, dispatch.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := sentAt.Unix()
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(HeaderDeliveryID, dispatch.DeliveryID)
+	request.Header.Set(HeaderEventID, dispatch.EventID)
+	request.Header.Set(HeaderEventType, dispatch.EventType)
+	request.Header.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	request.Header.Set(HeaderSignature, Sign(dispatch.Secret, timestamp, body))
+
+	response, err := w.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	defer response.Body.Close()
+	// the body is drained so that the connection can be reused - a subscriber's reply is not kept
+	_, _ = io.Copy(io.Discard, io.LimitReader(response.Body, 64<<10))
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return response.StatusCode, fmt.Errorf("subscriber responded with status %d", response.StatusCode)
+	}
+	return response.StatusCode, nil
+}
+
+// backoff() returns the delay before the next attempt of a delivery which has already failed attempts times -
+// InitialBackoff, doubling with every attempt, up to MaxBackoff
+func (w *Worker) backoff(attempts int) time.Duration {
+	maxBackoff := w.cfg.MaxBackoff.Std()
+	delay := w.cfg.InitialBackoff.Std()
+	for i := 0; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
+
+// Run() delivers until ctx is cancelled - a full batch is followed at once by the next one, otherwise the worker waits
+// PollInterval before claiming again
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		claimed, err := w.DeliverOnce(ctx)
+		if err != nil {
+			logging.Error(ctx, "error while delivering webhooks", zap.Error(err))
+		}
+		if err == nil && claimed == w.cfg.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.cfg.PollInterval.Std()):
+		}
+	}
+}
+
+// NewWorker() returns a worker delivering the deliveries in repo as configured by cfg - redirects are not followed, so
+// that a subscriber cannot send the signed payload on to another URL
+func NewWorker(repo domain.WebhookRepository, cfg config.WebhookConfig) *Worker {
+	client := &http.Client{
+		Timeout: cfg.Timeout.Std(),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return &Worker{repo: repo, client: client, cfg: cfg, now: time.Now}
+}
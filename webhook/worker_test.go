@@ -0,0 +1,267 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gtaylor314/Banking-MS/config"
+	"github.com/gtaylor314/Banking-MS/domain"
+)
+
+// testSecret is the secret of the subscription the test receiver is subscribed with
+const testSecret = "whsec_test-secret-0123456789"
+
+// receiver is a subscriber answering the first failures deliveries with 503 - it records every delivery whose signature
+// it could verify at *now, and the number of those it could not
+type receiver struct {
+	mu        sync.Mutex
+	now       *time.Time
+	failures  int
+	rejected  int
+	delivered []*http.Request
+	bodies    []string
+}
+
+// ServeHTTP implementation for type receiver
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+
+	err := Verify(testSecret, r.Header.Get(HeaderTimestamp), r.Header.Get(HeaderSignature), body, *rc.now, 5*time.Minute)
+	if err != nil {
+		rc.rejected++
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if rc.failures > 0 {
+		rc.failures--
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	rc.delivered = append(rc.delivered, r)
+	rc.bodies = append(rc.bodies, string(body))
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// newTestWorker() returns a worker over a stub holding one AccountOpened delivery to url, whose clock is read from *now
+func newTestWorker(t *testing.T, url string, now *time.Time) (*Worker, *domain.WebhookRepositoryStub) {
+	t.Helper()
+	repo := domain.NewWebhookRepositoryStub()
+	_, appErr := repo.SaveSubscription(context.Background(), domain.WebhookSubscription{
+		URL: url, EventTypes: domain.EventAccountOpened, Secret: testSecret, CreatedAt: "2026-10-19 09:00:00",
+	})
+	if appErr != nil {
+		t.Fatalf("received error %s while saving the subscription", appErr.Message)
+	}
+	event := domain.WebhookEvent{EventID: "0123456789abcdef0123456789abcdef", EventType: domain.EventAccountOpened, Payload: `{"account_id":"95471"}`}
+	if queued, appErr := repo.Enqueue(context.Background(), event, "2026-10-19 09:00:00"); appErr != nil || queued != 1 {
+		t.Fatalf("queued %d deliveries, want 1", queued)
+	}
+	cfg := config.Default().Webhooks
+	cfg.MaxAttempts = 4
+	cfg.InitialBackoff = config.Duration(10 * time.Second)
+	cfg.MaxBackoff = config.Duration(30 * time.Second)
+	worker := NewWorker(repo, cfg)
+	worker.now = func() time.Time { return *now }
+	return worker, repo
+}
+
+// delivery() returns the only delivery in repo
+func delivery(t *testing.T, repo *domain.WebhookRepositoryStub) domain.WebhookDelivery {
+	t.Helper()
+	found, appErr := repo.FindDelivery(context.Background(), "1", "1")
+	if appErr != nil {
+		t.Fatalf("received error %s while finding the delivery", appErr.Message)
+	}
+	return *found
+}
+
+// TestWorkerRetriesWithBackoff() should pass when a delivery the subscriber fails is retried after 10s, 20s and 30s, is
+// signed so that the subscriber can verify it, and every attempt is logged
+func TestWorkerRetriesWithBackoff(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	rc := &receiver{now: &now, failures: 3}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	worker, repo := newTestWorker(t, server.URL, &now)
+
+	// Act - execute test
+	var retries []string
+	for i := 0; i < 4; i++ {
+		if claimed, err := worker.DeliverOnce(context.Background()); err != nil || claimed != 1 {
+			t.Fatalf("attempt %d claimed %d deliveries (error %v), want 1", i+1, claimed, err)
+		}
+		found := delivery(t, repo)
+		if found.Status != domain.WebhookPending {
+			break
+		}
+		retries = append(retries, found.NextAttemptAt)
+		// nothing is due before the next attempt
+		if claimed, _ := worker.DeliverOnce(context.Background()); claimed != 0 {
+			t.Fatalf("claimed %d deliveries before the next attempt, want 0", claimed)
+		}
+		now, _ = time.ParseInLocation(timeLayout, found.NextAttemptAt, time.Local)
+	}
+
+	// Assert - test expectations
+	want := []string{"2026-10-19 09:00:10", "2026-10-19 09:00:30", "2026-10-19 09:01:00"}
+	if strings.Join(retries, ",") != strings.Join(want, ",") {
+		t.Errorf("received retries at %v, want %v", retries, want)
+	}
+	found := delivery(t, repo)
+	if found.Status != domain.WebhookDelivered || found.Attempts != 4 || found.DeliveredAt.String != "2026-10-19 09:01:00" {
+		t.Errorf("received delivery %+v, want it delivered at the 4th attempt", found)
+	}
+	if rc.rejected != 0 || len(rc.delivered) != 1 {
+		t.Fatalf("receiver rejected %d and accepted %d deliveries, want 0 and 1", rc.rejected, len(rc.delivered))
+	}
+	headers := rc.delivered[0].Header
+	if headers.Get(HeaderDeliveryID) != "1" || headers.Get(HeaderEventID) != found.EventID ||
+		headers.Get(HeaderEventType) != domain.EventAccountOpened || rc.bodies[0] != found.Payload {
+		t.Errorf("received delivery headers %v and body %s, want those of delivery %+v", headers, rc.bodies[0], found)
+	}
+	attempts, _ := repo.FindAttempts(context.Background(), "1")
+	codes := make([]int, 0, len(attempts))
+	for _, attempt := range attempts {
+		codes = append(codes, attempt.StatusCode)
+	}
+	if len(codes) != 4 || codes[0] != 503 || codes[2] != 503 || codes[3] != 204 ||
+		attempts[0].Error != "subscriber responded with status 503" || attempts[3].Error != "" {
+		t.Errorf("received attempts %+v, want three 503s and a 204", attempts)
+	}
+}
+
+// TestWorkerGivesUp() should pass when a delivery is marked failed after MaxAttempts failed attempts and is delivered
+// again once it is replayed
+func TestWorkerGivesUp(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	rc := &receiver{now: &now, failures: 4}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	worker, repo := newTestWorker(t, server.URL, &now)
+
+	// Act - execute test
+	for i := 0; i < 4; i++ {
+		_, _ = worker.DeliverOnce(context.Background())
+		now = now.Add(time.Hour)
+	}
+	failed := delivery(t, repo)
+	afterFailure, _ := worker.DeliverOnce(context.Background())
+	_, replayErr := repo.Replay(context.Background(), "1", "1", now.Format(timeLayout))
+	afterReplay, _ := worker.DeliverOnce(context.Background())
+
+	// Assert - test expectations
+	if failed.Status != domain.WebhookFailed || failed.Attempts != 4 || afterFailure != 0 {
+		t.Errorf("received delivery %+v and %d claimed after it, want it failed after 4 attempts", failed, afterFailure)
+	}
+	if replayErr != nil || afterReplay != 1 {
+		t.Fatalf("claimed %d deliveries after replay, want 1", afterReplay)
+	}
+	replayed := delivery(t, repo)
+	attempts, _ := repo.FindAttempts(context.Background(), "1")
+	// the attempt log keeps the attempts made before the replay
+	if replayed.Status != domain.WebhookDelivered || replayed.Attempts != 1 || len(attempts) != 5 || len(rc.delivered) != 1 {
+		t.Errorf("received delivery %+v with %d attempts logged, want it delivered with 5", replayed, len(attempts))
+	}
+}
+
+// TestWorkerLostLease() should pass when the attempt of a worker whose lease ended is not recorded once another worker
+// claimed the delivery, so that it cannot overwrite the outcome recorded by the worker holding the lease
+func TestWorkerLostLease(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	rc := &receiver{now: &now}
+	server := httptest.NewServer(rc)
+	defer server.Close()
+	worker, repo := newTestWorker(t, server.URL, &now)
+	// a slow worker claims the delivery and is still attempting it when its lease ends
+	slow, _ := repo.ClaimDeliveries(context.Background(), now.Format(timeLayout), now.Add(time.Minute).Format(timeLayout), 1)
+	now = now.Add(2 * time.Minute)
+
+	// Act - execute test
+	claimed, err := worker.DeliverOnce(context.Background())
+	lateErr := repo.RecordAttempt(context.Background(), slow[0].ClaimedBy, domain.WebhookAttempt{
+		DeliveryID: "1", AttemptedAt: now.Format(timeLayout), Error: "timeout"}, domain.WebhookPending, now.Format(timeLayout))
+
+	// Assert - test expectations
+	if err != nil || claimed != 1 {
+		t.Fatalf("claimed %d deliveries after the lease ended (error %v), want 1", claimed, err)
+	}
+	if lateErr == nil || lateErr.Code != http.StatusConflict {
+		t.Errorf("received %v recording the slow worker's attempt, want 409", lateErr)
+	}
+	found := delivery(t, repo)
+	attempts, _ := repo.FindAttempts(context.Background(), "1")
+	if found.Status != domain.WebhookDelivered || found.Attempts != 1 || len(attempts) != 1 {
+		t.Errorf("received delivery %+v with %d attempts logged, want it delivered with 1", found, len(attempts))
+	}
+}
+
+// TestWorkerDoesNotFollowRedirects() should pass when a redirect is treated as a failed attempt rather than followed
+func TestWorkerDoesNotFollowRedirects(t *testing.T) {
+	// Arrange - setup test
+	now, _ := time.ParseInLocation(timeLayout, "2026-10-19 09:00:00", time.Local)
+	rc := &receiver{now: &now}
+	target := httptest.NewServer(rc)
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusTemporaryRedirect))
+	defer redirect.Close()
+	worker, repo := newTestWorker(t, redirect.URL, &now)
+
+	// Act - execute test
+	_, err := worker.DeliverOnce(context.Background())
+
+	// Assert - test expectations
+	found := delivery(t, repo)
+	if err != nil || found.Status != domain.WebhookPending || found.Attempts != 1 {
+		t.Errorf("received delivery %+v, want it pending after a failed attempt", found)
+	}
+	if len(rc.delivered)+rc.rejected != 0 {
+		t.Errorf("redirect target received %d deliveries, want 0", len(rc.delivered)+rc.rejected)
+	}
+}
+
+// TestVerify() should pass when only an unchanged body with a fresh timestamp and the subscription's secret verifies
+func TestVerify(t *testing.T) {
+	// Arrange - setup test
+	now := time.Unix(1792400000, 0)
+	body := []byte(`{"event_id":"1"}`)
+	signature := Sign(testSecret, now.Unix(), body)
+	tests := []struct {
+		name      string
+		secret    string
+		timestamp string
+		signature string
+		body      string
+		want      error
+	}{
+		{name: "valid", secret: testSecret, timestamp: "1792400000", signature: signature, body: string(body)},
+		{name: "tampered body", secret: testSecret, timestamp: "1792400000", signature: signature, body: `{"event_id":"2"}`, want: ErrInvalidSignature},
+		{name: "other secret", secret: "whsec_other-secret-0123456789", timestamp: "1792400000", signature: signature, body: string(body), want: ErrInvalidSignature},
+		{name: "changed timestamp", secret: testSecret, timestamp: "1792400001", signature: signature, body: string(body), want: ErrInvalidSignature},
+		{name: "stale timestamp", secret: testSecret, timestamp: "1792399000", signature: Sign(testSecret, 1792399000, body), body: string(body), want: ErrStaleTimestamp},
+		{name: "missing timestamp", secret: testSecret, signature: signature, body: string(body), want: ErrStaleTimestamp},
+		{name: "missing prefix", secret: testSecret, timestamp: "1792400000", signature: strings.TrimPrefix(signature, "sha256="), body: string(body), want: ErrInvalidSignature},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			// Act - execute test
+			err := Verify(test.secret, test.timestamp, test.signature, []byte(test.body), now, 5*time.Minute)
+
+			// Assert - test expectations
+			if err != test.want {
+				t.Errorf("received error %v, want %v", err, test.want)
+			}
+		})
+	}
+}